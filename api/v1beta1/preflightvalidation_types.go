@@ -0,0 +1,89 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// VerificationTrue a driver container exists or can be built
+	VerificationTrue string = "True"
+	// VerificationFalse no driver container for the target kernel
+	VerificationFalse string = "False"
+)
+
+// PreflightValidationSpec defines the desired state of PreflightValidation
+type PreflightValidationSpec struct {
+	// UpdateImage release payload of the OCP version the cluster is going
+	// to be upgraded to, the target kernel is read from its DTK
+	// +kubebuilder:validation:Required
+	UpdateImage string `json:"updateImage"`
+	// KernelVersion overrides the kernel read from the DTK of UpdateImage
+	// +kubebuilder:validation:Optional
+	KernelVersion string `json:"kernelVersion,omitempty"`
+}
+
+// PreflightValidationSRStatus verification result of one SpecialResource
+type PreflightValidationSRStatus struct {
+	Name string `json:"name"`
+	// VerificationStatus True if a driver container for the target kernel
+	// exists or can be built, False otherwise
+	// +kubebuilder:validation:Enum=True;False
+	VerificationStatus string `json:"verificationStatus"`
+	// +kubebuilder:validation:Optional
+	StatusReason string `json:"statusReason,omitempty"`
+	// +kubebuilder:validation:Optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// PreflightValidationStatus defines the observed state of PreflightValidation
+type PreflightValidationStatus struct {
+	// +kubebuilder:validation:Optional
+	DriverToolkitImage string `json:"driverToolkitImage,omitempty"`
+	// +kubebuilder:validation:Optional
+	KernelVersion string `json:"kernelVersion,omitempty"`
+	// +kubebuilder:validation:Optional
+	SRStatuses []PreflightValidationSRStatus `json:"srStatuses,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// PreflightValidation verifies before an upgrade that every SpecialResource
+// has a driver container for the kernel of the target OCP version
+// +kubebuilder:resource:path=preflightvalidations,scope=Cluster,shortName=pv
+type PreflightValidation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	// +kubebuilder:validation:Required
+	Spec   PreflightValidationSpec   `json:"spec,omitempty"`
+	Status PreflightValidationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PreflightValidationList contains a list of PreflightValidation
+type PreflightValidationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PreflightValidation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PreflightValidation{}, &PreflightValidationList{})
+}
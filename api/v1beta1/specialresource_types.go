@@ -19,6 +19,7 @@ package v1beta1
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	helmerv1beta1 "github.com/openshift-psap/special-resource-operator/pkg/helmer/api/v1beta1"
 )
@@ -83,6 +84,82 @@ type SpecialResourceDriverContainer struct {
 	Source SpecialResourceSource `json:"source,omitempty"`
 	// +kubebuilder:validation:Optional
 	Artifacts SpecialResourceArtifacts `json:"artifacts,omitempty"`
+	// +kubebuilder:validation:Optional
+	Promote SpecialResourcePromote `json:"promote,omitempty"`
+	// Prebuilt never builds in-cluster, the driver container Image has to
+	// exist for the kernel of every selected node
+	// +kubebuilder:validation:Optional
+	Prebuilt bool `json:"prebuilt,omitempty"`
+	// Image pull spec of the prebuilt driver container, ${KERNEL_VERSION}
+	// is replaced with the kernel of the nodes
+	// +kubebuilder:validation:Optional
+	Image string `json:"image,omitempty"`
+	// +kubebuilder:validation:Optional
+	Firmware SpecialResourceFirmware `json:"firmware,omitempty"`
+	// +kubebuilder:validation:Optional
+	SBOM SpecialResourceSBOM `json:"sbom,omitempty"`
+	// VerifyModules adds a readiness probe to the driver container that
+	// checks the modules of the kernel-modules annotation are loaded
+	// +kubebuilder:validation:Optional
+	VerifyModules bool `json:"verifyModules,omitempty"`
+	// +kubebuilder:validation:Optional
+	Signing SpecialResourceSigning `json:"signing,omitempty"`
+}
+
+// SpecialResourceSigning signs the kernel modules of driver containers built
+// in-cluster so they load on nodes with Secure Boot
+type SpecialResourceSigning struct {
+	// KeySecret Secret in the SpecialResource namespace with the private
+	// key key.pem and the certificate cert.pem the modules are signed with,
+	// modules are not signed if empty
+	// +kubebuilder:validation:Optional
+	KeySecret string `json:"keySecret,omitempty"`
+	// Image with the kernel devel tree of the node kernel the modules are
+	// signed in, defaults to the IMAGE build arg of the BuildConfig, the
+	// driver-toolkit in the charts
+	// +kubebuilder:validation:Optional
+	Image string `json:"image,omitempty"`
+	// Enroll creates a MachineConfig per pool of the driver nodes that
+	// imports the certificate as Machine Owner Key, KeySecret has to hold
+	// the mokutil password hash mok.hash
+	// +kubebuilder:validation:Optional
+	Enroll bool `json:"enroll,omitempty"`
+}
+
+// SpecialResourceSBOM software bill of materials of driver containers built
+// in-cluster, pushed next to the image once the build completed
+type SpecialResourceSBOM struct {
+	// Format of the SBOM, no SBOM is generated if empty
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=spdx;cyclonedx
+	Format string `json:"format,omitempty"`
+}
+
+// SpecialResourceFirmware firmware the driver container installs on the
+// host with the specialresource.firmware chart helpers
+type SpecialResourceFirmware struct {
+	// Source directory in the driver container holding the firmware files,
+	// firmware management is disabled if empty
+	// +kubebuilder:validation:Optional
+	Source string `json:"source,omitempty"`
+	// HostPath the firmware is copied to and removed from with the driver
+	// container, the kernel has to search it, e.g. with the kernel argument
+	// firmware_class.path, defaults to /var/lib/firmware
+	// +kubebuilder:validation:Optional
+	HostPath string `json:"hostPath,omitempty"`
+}
+
+// SpecialResourcePromote copies driver containers built in-cluster to an
+// external registry once the build completed
+type SpecialResourcePromote struct {
+	// Repository the images are pushed to, e.g. quay.io/org, the name and
+	// tag of the ImageStreamTag are appended
+	// +kubebuilder:validation:Optional
+	Repository string `json:"repository,omitempty"`
+	// PushSecret dockercfg Secret in the SpecialResource namespace with the
+	// credentials for Repository
+	// +kubebuilder:validation:Optional
+	PushSecret string `json:"pushSecret,omitempty"`
 }
 
 // SpecialResourceSpec defines the desired state of SpecialResource
@@ -103,8 +180,408 @@ type SpecialResourceSpec struct {
 	DriverContainer SpecialResourceDriverContainer `json:"driverContainer,omitempty"`
 	// +kubebuilder:validation:Optional
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// NodeExclusionSelector nodes with all of these labels are ignored, on
+	// top of the Windows and other non-Linux nodes
+	// +kubebuilder:validation:Optional
+	NodeExclusionSelector map[string]string `json:"nodeExclusionSelector,omitempty"`
+	// ResyncPeriod the SpecialResource is reconciled again after a
+	// successful reconcile, e.g. 10m, empty only reconciles on changes and
+	// the sync period of the operator
+	// +kubebuilder:validation:Optional
+	ResyncPeriod metav1.Duration `json:"resyncPeriod,omitempty"`
+	// +kubebuilder:validation:Optional
+	Triggers SpecialResourceTriggers `json:"triggers,omitempty"`
 	// +kubebuilder:validation:Optional
 	Dependencies []SpecialResourceDependency `json:"dependencies,omitempty"`
+	// DependsOn SpecialResources that have to be Ready before this one is
+	// reconciled, the dependencies of all SpecialResources form a DAG
+	// +kubebuilder:validation:Optional
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// ImagePullSecrets in the SpecialResource namespace that are consulted
+	// before the global pull secret when the operator accesses registries
+	// +kubebuilder:validation:Optional
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+	// +kubebuilder:validation:Optional
+	Verification SpecialResourceVerification `json:"verification,omitempty"`
+	// +kubebuilder:validation:Optional
+	DriverToolkit SpecialResourceDriverToolkit `json:"driverToolkit,omitempty"`
+	// +kubebuilder:validation:Optional
+	Build SpecialResourceBuild `json:"build,omitempty"`
+	// +kubebuilder:validation:Optional
+	Rollout SpecialResourceRollout `json:"rollout,omitempty"`
+	// +kubebuilder:validation:Optional
+	ModuleBlacklist SpecialResourceModuleBlacklist `json:"moduleBlacklist,omitempty"`
+	// +kubebuilder:validation:Optional
+	NodeFeatures SpecialResourceNodeFeatures `json:"nodeFeatures,omitempty"`
+	// CleanupPolicy what happens to the resources of the chart when the
+	// SpecialResource is deleted, Orphan keeps them, Delete removes them and
+	// DeleteAndWait only lets the deletion finish once the driver modules
+	// are unloaded on all nodes, defaults to Delete
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Orphan;Delete;DeleteAndWait
+	CleanupPolicy string `json:"cleanupPolicy,omitempty"`
+	// NamespaceManagement how spec.namespace and the listed target
+	// namespaces are managed, Create creates missing namespaces and deletes
+	// the ones it created with the SpecialResource, Adopt deletes existing
+	// ones as well and Ignore expects them to exist and never changes them,
+	// defaults to Create
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Create;Adopt;Ignore
+	NamespaceManagement string `json:"namespaceManagement,omitempty"`
+	// NamespaceLabels of the managed namespaces, e.g. the pod security
+	// labels, values may use the templates of the SpecialResource
+	// +kubebuilder:validation:Optional
+	NamespaceLabels map[string]string `json:"namespaceLabels,omitempty"`
+	// NamespaceAnnotations of the managed namespaces
+	// +kubebuilder:validation:Optional
+	NamespaceAnnotations map[string]string `json:"namespaceAnnotations,omitempty"`
+	// +kubebuilder:validation:Optional
+	Teardown SpecialResourceTeardown `json:"teardown,omitempty"`
+	// +kubebuilder:validation:Optional
+	ImageGC SpecialResourceImageGC `json:"imageGC,omitempty"`
+	// +kubebuilder:validation:Optional
+	ChartVerification SpecialResourceChartVerification `json:"chartVerification,omitempty"`
+	// +kubebuilder:validation:Optional
+	Targets SpecialResourceTargets `json:"targets,omitempty"`
+	// ServiceAccount in spec.namespace the operator impersonates to apply
+	// the objects of the chart, its RBAC bounds what the chart can do. The
+	// namespace, the image puller RoleBinding and pruning stay with the
+	// operator.
+	// +kubebuilder:validation:Optional
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	// +kubebuilder:validation:Optional
+	Proxy SpecialResourceProxy `json:"proxy,omitempty"`
+	// +kubebuilder:validation:Optional
+	FIPS SpecialResourceFIPS `json:"fips,omitempty"`
+	// +kubebuilder:validation:Optional
+	VulnerabilityScan SpecialResourceVulnerabilityScan `json:"vulnerabilityScan,omitempty"`
+	// +kubebuilder:validation:Optional
+	PostRender SpecialResourcePostRender `json:"postRender,omitempty"`
+}
+
+// SpecialResourcePostRender patches the rendered chart before it is applied
+type SpecialResourcePostRender struct {
+	// KustomizeConfigMap in the operator namespace with strategic merge
+	// patches of the rendered manifests, a kustomization.yaml key selects
+	// the patches and their targets, without one every *.yaml key is a patch
+	// of the object of its kind and name. Patches must not set images,
+	// security contexts, volumes, host namespaces or service accounts.
+	// +kubebuilder:validation:Optional
+	KustomizeConfigMap string `json:"kustomizeConfigMap,omitempty"`
+}
+
+// SpecialResourceVulnerabilityScan blocks the rollout of driver container
+// DaemonSets whose images have vulnerabilities of Severity or above,
+// disabled if neither Quay nor Webhook is set
+type SpecialResourceVulnerabilityScan struct {
+	// Quay URL of the Quay instance hosting the driver container images,
+	// e.g. https://quay.io, its security scan of the image digest is checked
+	// +kubebuilder:validation:Optional
+	Quay string `json:"quay,omitempty"`
+	// Webhook URL the image and digest are POSTed to, it answers with the
+	// vulnerabilities found, takes precedence over Quay
+	// +kubebuilder:validation:Optional
+	Webhook string `json:"webhook,omitempty"`
+	// TokenSecret Secret in the SpecialResource namespace whose key token
+	// is sent as bearer token
+	// +kubebuilder:validation:Optional
+	TokenSecret string `json:"tokenSecret,omitempty"`
+	// Severity lowest severity that blocks the rollout, defaults to High
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Low;Medium;High;Critical
+	Severity string `json:"severity,omitempty"`
+}
+
+// Enabled returns true if a scan provider is configured
+func (s SpecialResourceVulnerabilityScan) Enabled() bool {
+	return s.Quay != "" || s.Webhook != ""
+}
+
+// SpecialResourceFIPS handling of clusters running in FIPS mode
+type SpecialResourceFIPS struct {
+	// Strict refuses driver container images that are not labeled
+	// specialresource.openshift.io/fips: "true" on FIPS clusters, images
+	// built in-cluster are trusted to honor the FIPS build arg
+	// +kubebuilder:validation:Optional
+	Strict bool `json:"strict,omitempty"`
+}
+
+// SpecialResourceProxy injection of the cluster proxy into the
+// BuildConfigs, Jobs and DaemonSets of the chart
+type SpecialResourceProxy struct {
+	// Disabled leaves the proxy settings to the chart, objects can opt
+	// out with the annotation specialresource.openshift.io/proxy: "false"
+	// +kubebuilder:validation:Optional
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// SpecialResourceImageGC prunes the ImageStreamTags of driver containers
+// built for kernels that no node runs anymore
+type SpecialResourceImageGC struct {
+	// Enabled prunes the tags of the ImageStreams released by the chart
+	// whose name is a kernel version, e.g. v4.18.0-305.el8.x86_64
+	// +kubebuilder:validation:Optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Retention number of obsolete tags kept per ImageStream, the most
+	// recently pushed are kept, 0 removes all of them
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	Retention int32 `json:"retention,omitempty"`
+}
+
+// SpecialResourceNodeFeatures Node Feature Discovery labels the nodes of the
+// SpecialResource need, they are added to the nodeSelector and the chart is
+// only reconciled once NFD labeled at least one node
+type SpecialResourceNodeFeatures struct {
+	// Required NFD labels, key or key=value with value defaulting to true,
+	// the feature.node.kubernetes.io/ prefix may be omitted
+	// +kubebuilder:validation:Optional
+	Required []string `json:"required,omitempty"`
+	// PCI devices whose discovered pci-<id>.present label is added, e.g.
+	// 10de or 0302_10de, must match a single label
+	// +kubebuilder:validation:Optional
+	PCI []string `json:"pci,omitempty"`
+	// USB devices whose discovered usb-<id>.present label is added
+	// +kubebuilder:validation:Optional
+	USB []string `json:"usb,omitempty"`
+}
+
+// SpecialResourceModuleBlacklist in-tree kernel modules conflicting with the
+// driver, a MachineConfig blacklisting them is created for every pool and
+// the chart is only reconciled once the pools rolled it out
+type SpecialResourceModuleBlacklist struct {
+	// Modules blacklisted on the nodes, e.g. nouveau
+	// +kubebuilder:validation:Optional
+	Modules []string `json:"modules,omitempty"`
+	// MachineConfigPools the MachineConfigs are created for, defaults to
+	// the pools of the nodes running the driver
+	// +kubebuilder:validation:Optional
+	MachineConfigPools []string `json:"machineConfigPools,omitempty"`
+}
+
+// SpecialResourceRollout how new driver versions are rolled out to the nodes
+type SpecialResourceRollout struct {
+	// Strategy RollingUpdate replaces the driver pods as the DaemonSet
+	// specifies, Ordered upgrades the nodes one batch at a time, cordoning
+	// and optionally draining them, Canary upgrades the canary nodes first
+	// and only continues with the remaining batches once their driver pods
+	// are ready, defaults to RollingUpdate
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=RollingUpdate;Ordered;Canary
+	Strategy string `json:"strategy,omitempty"`
+	// CanaryNodes number of nodes updated first, defaults to 1
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	CanaryNodes int32 `json:"canaryNodes,omitempty"`
+	// CanaryNodeSelector selects the canary nodes, takes precedence over
+	// CanaryNodes
+	// +kubebuilder:validation:Optional
+	CanaryNodeSelector map[string]string `json:"canaryNodeSelector,omitempty"`
+	// MaxUnavailable nodes upgraded at the same time by the Ordered and
+	// Canary strategies, a number or a percentage of the nodes running the
+	// driver, defaults to 1
+	// +kubebuilder:validation:Optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// +kubebuilder:validation:Optional
+	Drain SpecialResourceDrain `json:"drain,omitempty"`
+}
+
+// SpecialResourceDrain evicts the workloads using the device before the
+// driver of a cordoned node is replaced
+type SpecialResourceDrain struct {
+	// Enabled drains the nodes, otherwise they are only cordoned
+	// +kubebuilder:validation:Optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Resources pods requesting any of these extended resources are
+	// evicted, e.g. nvidia.com/gpu
+	// +kubebuilder:validation:Optional
+	Resources []string `json:"resources,omitempty"`
+	// PodSelector pods matching these labels are evicted as well
+	// +kubebuilder:validation:Optional
+	PodSelector map[string]string `json:"podSelector,omitempty"`
+	// TimeoutSeconds to wait for the evicted pods, defaults to 300
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// SpecialResourceDriverToolkit configures builds for kernels without a DTK
+type SpecialResourceDriverToolkit struct {
+	// FallbackToEntitled builds with the RHEL entitlement of
+	// EntitlementSecret if no DTK matches the kernel of a node
+	// +kubebuilder:validation:Optional
+	FallbackToEntitled bool `json:"fallbackToEntitled,omitempty"`
+	// EntitlementSecret in the SpecialResource namespace with the
+	// subscription certificates, defaults to etc-pki-entitlement
+	// +kubebuilder:validation:Optional
+	EntitlementSecret string `json:"entitlementSecret,omitempty"`
+	// WatchedReleases release payloads of future OCP versions, driver
+	// containers are built with their DTK in addition to the kernels of the
+	// nodes. Builds of releases dropped from the list are pruned.
+	// +kubebuilder:validation:Optional
+	WatchedReleases []string `json:"watchedReleases,omitempty"`
+}
+
+// SpecialResourceBuild selects how driver containers are built in-cluster
+type SpecialResourceBuild struct {
+	// Backend the BuildConfigs of the chart are built with, Shipwright
+	// translates them into a Build and BuildRun, Job into a kaniko Job,
+	// defaults to BuildConfig or Job on clusters without BuildConfigs
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=BuildConfig;Shipwright;Job
+	Backend string `json:"backend,omitempty"`
+	// Retries failed builds are started again before the SpecialResource
+	// is Degraded, 0 disables retries
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	Retries int32 `json:"retries,omitempty"`
+	// BackoffSeconds before the first retry, doubled for every further
+	// retry, defaults to 30
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	BackoffSeconds int32 `json:"backoffSeconds,omitempty"`
+	// +kubebuilder:validation:Optional
+	Logs SpecialResourceBuildLogs `json:"logs,omitempty"`
+	// MaxConcurrent builds of the kernels of the cluster that run at the
+	// same time, the driver is only rolled out once all of them completed,
+	// defaults to 1
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	MaxConcurrent int32 `json:"maxConcurrent,omitempty"`
+	// +kubebuilder:validation:Optional
+	Cache SpecialResourceBuildCache `json:"cache,omitempty"`
+}
+
+// SpecialResourceBuildCache ccache volume of the driver container builds,
+// one PersistentVolumeClaim per kernel major.minor in the namespace of the
+// builds, only the Job backend can mount it
+type SpecialResourceBuildCache struct {
+	// Enabled mounts the cache at /ccache and passes the build arg
+	// CCACHE_DIR, the Dockerfile has to compile with ccache
+	// +kubebuilder:validation:Optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Size of the claims, defaults to 5Gi
+	// +kubebuilder:validation:Optional
+	Size string `json:"size,omitempty"`
+	// StorageClassName of the claims, defaults to the default class
+	// +kubebuilder:validation:Optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+}
+
+// SpecialResourceBuildLogs how much of the log of a failed build is kept
+type SpecialResourceBuildLogs struct {
+	// LimitBytes of the log tail kept in the status, defaults to 4096
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=512
+	// +kubebuilder:validation:Maximum=65536
+	LimitBytes int32 `json:"limitBytes,omitempty"`
+	// ConfigMap additionally stores the log tail of every failed build in
+	// a ConfigMap named after the build in the SpecialResource namespace
+	// +kubebuilder:validation:Optional
+	ConfigMap bool `json:"configMap,omitempty"`
+}
+
+// SpecialResourceBuildStatus retries of a failed driver container build
+type SpecialResourceBuildStatus struct {
+	// Name of the BuildConfig or BuildRun that is recreated for a retry
+	Name string `json:"name"`
+	// State of the chart the build belongs to
+	State string `json:"state"`
+	// LastBuild the last build that failed
+	// +kubebuilder:validation:Optional
+	LastBuild string `json:"lastBuild,omitempty"`
+	Retries   int32  `json:"retries"`
+	// +kubebuilder:validation:Optional
+	LastFailureTime metav1.Time `json:"lastFailureTime,omitempty"`
+	// LogTail last lines of the log of LastBuild
+	// +kubebuilder:validation:Optional
+	LogTail string `json:"logTail,omitempty"`
+	// LogConfigMap ConfigMap holding the log tail of LastBuild
+	// +kubebuilder:validation:Optional
+	LogConfigMap string `json:"logConfigMap,omitempty"`
+}
+
+// SpecialResourceVerification cosign signature verification of the DTK and
+// prebuilt driver container images, disabled if neither key nor roots are set
+type SpecialResourceVerification struct {
+	// PublicKeySecret Secret in the SpecialResource namespace, every key
+	// holds a PEM encoded cosign public key
+	// +kubebuilder:validation:Optional
+	PublicKeySecret string `json:"publicKeySecret,omitempty"`
+	// KeylessRootsConfigMap ConfigMap in the SpecialResource namespace with
+	// the PEM encoded Fulcio roots trusted for keyless signatures and the
+	// Rekor public key in rekor.pub
+	// +kubebuilder:validation:Optional
+	KeylessRootsConfigMap string `json:"keylessRootsConfigMap,omitempty"`
+	// KeylessIdentity the email or URI the keyless signing certificate has
+	// to be issued to, required with keylessRootsConfigMap
+	// +kubebuilder:validation:Optional
+	KeylessIdentity string `json:"keylessIdentity,omitempty"`
+	// KeylessIssuer the OIDC issuer the signer authenticated with, e.g.
+	// https://token.actions.githubusercontent.com, required with
+	// keylessRootsConfigMap
+	// +kubebuilder:validation:Optional
+	KeylessIssuer string `json:"keylessIssuer,omitempty"`
+	// SkipRepositories registries or repository prefixes whose images are
+	// not verified, e.g. image-registry.openshift-image-registry.svc:5000
+	// for the unsigned driver containers built in-cluster
+	// +kubebuilder:validation:Optional
+	SkipRepositories []string `json:"skipRepositories,omitempty"`
+}
+
+// Enabled returns true if any verification method is configured
+func (v SpecialResourceVerification) Enabled() bool {
+	return v.PublicKeySecret != "" || v.KeylessRootsConfigMap != ""
+}
+
+// SpecialResourceChartVerification signature verification of the charts of
+// the SpecialResource and its dependencies before they are rendered,
+// disabled if neither keyring nor key are set
+type SpecialResourceChartVerification struct {
+	// KeyringSecret Secret in the SpecialResource namespace, every key holds
+	// a binary GPG public keyring the .prov files of charts from HTTP, cm://
+	// and file:// repositories are verified against
+	// +kubebuilder:validation:Optional
+	KeyringSecret string `json:"keyringSecret,omitempty"`
+	// PublicKeySecret Secret in the SpecialResource namespace, every key
+	// holds a PEM encoded cosign public key the signatures of charts from
+	// OCI repositories are verified against
+	// +kubebuilder:validation:Optional
+	PublicKeySecret string `json:"publicKeySecret,omitempty"`
+}
+
+// Enabled returns true if any verification method is configured
+func (v SpecialResourceChartVerification) Enabled() bool {
+	return v.KeyringSecret != "" || v.PublicKeySecret != ""
+}
+
+// SpecialResourceTargets additional namespaces the chart is deployed to,
+// every namespace gets its own copy of the chart as if it was
+// spec.namespace
+type SpecialResourceTargets struct {
+	// Namespaces created by the SpecialResource if they do not exist
+	// +kubebuilder:validation:Optional
+	Namespaces []SpecialResourceTargetNamespace `json:"namespaces,omitempty"`
+	// NamespaceSelector selects existing namespaces, an entry of namespaces
+	// with the same name provides the values of a selected namespace
+	// +kubebuilder:validation:Optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// SpecialResourceTargetNamespace a namespace and the values overlaid on
+// spec.set for it
+type SpecialResourceTargetNamespace struct {
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:EmbeddedResource
+	Set unstructured.Unstructured `json:"set,omitempty"`
+}
+
+// Enabled returns true if any target namespace is configured
+func (t SpecialResourceTargets) Enabled() bool {
+	return len(t.Namespaces) > 0 || t.NamespaceSelector != nil
 }
 
 // SpecialResourceDependency a dependent helm chart
@@ -118,11 +595,213 @@ type SpecialResourceDependency struct {
 
 // SpecialResourceStatus defines the observed state of SpecialResource
 type SpecialResourceStatus struct {
-	State string `json:"state"`
+	// State last state of the chart that was reconciled, deprecated in
+	// favour of the Conditions
+	// +kubebuilder:validation:Optional
+	State string `json:"state,omitempty"`
+	// Images used by the last reconcile and the digest they resolved to
+	// +kubebuilder:validation:Optional
+	Images []SpecialResourceImageDigest `json:"images,omitempty"`
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Builds that failed and are retried
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=name
+	Builds []SpecialResourceBuildStatus `json:"builds,omitempty"`
+	// WatchedReleases the release payloads of spec.driverToolkit were
+	// resolved to
+	// +kubebuilder:validation:Optional
+	WatchedReleases []SpecialResourceWatchedRelease `json:"watchedReleases,omitempty"`
+	// Changes the last objects the operator updated and the fields it
+	// changed, oldest first, only kept if the operator runs with
+	// --diff-history
+	// +kubebuilder:validation:Optional
+	Changes []SpecialResourceChange `json:"changes,omitempty"`
+	// Teardown progress of the DeleteAndWait cleanup per node
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=node
+	Teardown []SpecialResourceNodeTeardown `json:"teardown,omitempty"`
+	// Kernels the rollout of the driver containers per kernel version
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=kernelFullVersion
+	Kernels []SpecialResourceKernelStatus `json:"kernels,omitempty"`
+}
+
+// SpecialResourceTriggers extra events the SpecialResource is reconciled on,
+// besides changes of the SpecialResource and the objects it owns
+type SpecialResourceTriggers struct {
+	// Nodes joining the cluster or whose labels changed, if they match the
+	// nodeSelector
+	// +kubebuilder:validation:Optional
+	Nodes bool `json:"nodes,omitempty"`
+	// MachineConfigPools that rendered a new config or updated machines
+	// +kubebuilder:validation:Optional
+	MachineConfigPools bool `json:"machineConfigPools,omitempty"`
+	// Secrets in the SpecialResource namespace whose data changed
+	// +kubebuilder:validation:Optional
+	Secrets []string `json:"secrets,omitempty"`
+}
+
+// SpecialResourceTeardown the ordered teardown of the DeleteAndWait cleanup
+// policy: consumers are evicted, the driver container is deleted and the
+// modules are unloaded
+type SpecialResourceTeardown struct {
+	// ConsumerSelector labels of the pods using the driver, they are
+	// evicted from the nodes of the driver before it is deleted
+	// +kubebuilder:validation:Optional
+	ConsumerSelector map[string]string `json:"consumerSelector,omitempty"`
+	// Unload runs modprobe -r for the modules of the kernel-modules
+	// annotation in a privileged pod with the driver container image,
+	// otherwise the driver container has to unload them when it stops
+	// +kubebuilder:validation:Optional
+	Unload bool `json:"unload,omitempty"`
+}
+
+// SpecialResourceNodeTeardown the teardown phase of a node. The drivers of
+// the node are recorded before their DaemonSets are deleted, retries verify
+// the unload against the record.
+type SpecialResourceNodeTeardown struct {
+	Node string `json:"node"`
+	// Phase Pending, Evicting, Unloading, Unloaded or Failed
+	Phase   string                          `json:"phase"`
+	Message string                          `json:"message,omitempty"`
+	Drivers []SpecialResourceTeardownDriver `json:"drivers,omitempty"`
+}
+
+// SpecialResourceTeardownDriver a driver container that ran on a node, its
+// image and service account run the unload and the check of the modules
+type SpecialResourceTeardownDriver struct {
+	Image          string   `json:"image"`
+	ServiceAccount string   `json:"serviceAccount,omitempty"`
+	Modules        []string `json:"modules,omitempty"`
+}
+
+const (
+	// TeardownPending the driver is recorded, nothing was done yet
+	TeardownPending string = "Pending"
+	// TeardownEvicting the consumers of the driver are evicted
+	TeardownEvicting string = "Evicting"
+	// TeardownUnloading the driver container is deleted and the modules
+	// are unloaded
+	TeardownUnloading string = "Unloading"
+	// TeardownUnloaded the modules are unloaded
+	TeardownUnloaded string = "Unloaded"
+	// TeardownFailed a step of the teardown failed, it is retried
+	TeardownFailed string = "Failed"
+)
+
+// SpecialResourceKernelStatus the driver containers of a kernel version, the
+// node counts sum up the DaemonSets of the kernel
+type SpecialResourceKernelStatus struct {
+	KernelFullVersion string `json:"kernelFullVersion"`
+	// DesiredNodes nodes that should run the driver containers
+	DesiredNodes int32 `json:"desiredNodes"`
+	// ReadyNodes nodes with a ready driver container
+	ReadyNodes int32 `json:"readyNodes"`
+	// UpdatedNodes nodes running the current driver container
+	UpdatedNodes int32 `json:"updatedNodes"`
+	// Images of the driver containers
+	// +kubebuilder:validation:Optional
+	Images []string `json:"images,omitempty"`
+	// BuildState phase of the last build for the kernel, empty if the
+	// driver container is not built in the cluster
+	// +kubebuilder:validation:Optional
+	BuildState string `json:"buildState,omitempty"`
+	// PendingNodes nodes whose driver container is missing, not ready or
+	// not updated yet
+	// +kubebuilder:validation:Optional
+	PendingNodes []string `json:"pendingNodes,omitempty"`
+}
+
+// SpecialResourceChange the fields of an object the operator updated, one
+// entry per field: path: live value -> desired value
+type SpecialResourceChange struct {
+	Time   metav1.Time `json:"time"`
+	Object string      `json:"object"`
+	Diff   []string    `json:"diff"`
+}
+
+// SpecialResourceWatchedRelease the OCP version and DTK of a release payload
+type SpecialResourceWatchedRelease struct {
+	Image              string `json:"image"`
+	Version            string `json:"version"`
+	KernelVersion      string `json:"kernelVersion"`
+	DriverToolkitImage string `json:"driverToolkitImage"`
+}
+
+const (
+	// StateReady is the State of a SpecialResource whose resources were
+	// all reconciled
+	StateReady string = "Ready"
+	// ConditionReady all resources of the chart were reconciled
+	ConditionReady string = "Ready"
+	// ConditionProgressing the chart is being reconciled
+	ConditionProgressing string = "Progressing"
+	// ConditionDegraded the last reconcile failed
+	ConditionDegraded string = "Degraded"
+	// ConditionBuildSucceeded the driver container builds of the chart
+	// completed, only set for charts that build
+	ConditionBuildSucceeded string = "BuildSucceeded"
+	// ConditionDriverLoaded the driver container is running on all
+	// selected nodes, only set for charts with a driver-container state
+	ConditionDriverLoaded string = "DriverLoaded"
+	// ConditionDependenciesReady is False while a SpecialResource waits for
+	// the SpecialResources listed in DependsOn
+	ConditionDependenciesReady string = "DependenciesReady"
+	// ConditionPaused is True while the paused annotation is set
+	ConditionPaused string = "Paused"
+	// ConditionPrebuiltImagesAvailable the prebuilt driver container exists
+	// for every kernel, only set for prebuilt driver containers
+	ConditionPrebuiltImagesAvailable string = "PrebuiltImagesAvailable"
+	// ConditionModulesBlacklisted the MachineConfigPools rolled out the
+	// module blacklist of the SpecialResource
+	ConditionModulesBlacklisted string = "ModulesBlacklisted"
+	// ConditionNodeFeaturesDiscovered nodes carry the NFD labels of
+	// spec.nodeFeatures
+	ConditionNodeFeaturesDiscovered string = "NodeFeaturesDiscovered"
+	// ConditionDryRun the chart was rendered into a ConfigMap instead of
+	// being applied
+	ConditionDryRun string = "DryRun"
+	// ConditionFieldConflicts fields of the chart objects were owned by
+	// other field managers and taken over by the last reconcile
+	ConditionFieldConflicts string = "FieldConflicts"
+	// ConditionValuesValid the values of the SpecialResource satisfy the
+	// values.schema.json of the chart, only set for charts with a schema
+	ConditionValuesValid string = "ValuesValid"
+	// ConditionVulnerabilityScanPassed the driver container images have no
+	// vulnerabilities of the blocking severity, only set if scans are enabled
+	ConditionVulnerabilityScanPassed string = "VulnerabilityScanPassed"
+
+	// CleanupPolicyOrphan keeps the resources of a deleted SpecialResource
+	CleanupPolicyOrphan string = "Orphan"
+	// CleanupPolicyDelete deletes the resources of a deleted SpecialResource
+	CleanupPolicyDelete string = "Delete"
+	// CleanupPolicyDeleteAndWait deletes the resources and waits for the
+	// driver modules to be unloaded
+	CleanupPolicyDeleteAndWait string = "DeleteAndWait"
+
+	// NamespaceManagementCreate creates missing namespaces and owns them
+	NamespaceManagementCreate string = "Create"
+	// NamespaceManagementAdopt owns existing namespaces as well
+	NamespaceManagementAdopt string = "Adopt"
+	// NamespaceManagementIgnore leaves the namespaces to the user
+	NamespaceManagementIgnore string = "Ignore"
+)
+
+// SpecialResourceImageDigest an image reference and its resolved digest
+type SpecialResourceImageDigest struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:storageversion
 
 // SpecialResource is the Schema for the specialresources API
 // +kubebuilder:resource:path=specialresources,scope=Cluster
@@ -0,0 +1,274 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	helmerv1beta1 "github.com/openshift-psap/special-resource-operator/pkg/helmer/api/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+var moduleName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ChartValidator loads the chart and validates values against its schema,
+// set by the operator so the API package does not depend on helm.
+var ChartValidator func(chart helmerv1beta1.HelmChart, values map[string]interface{}) error
+
+// ChartLocator checks that the repository of the chart lists its name and
+// version, set by the operator like ChartValidator.
+var ChartLocator func(chart helmerv1beta1.HelmChart) error
+
+// SetupWebhookWithManager registers the validating webhook
+func (r *SpecialResource) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-sro-openshift-io-v1beta1-specialresource,mutating=false,failurePolicy=fail,sideEffects=None,groups=sro.openshift.io,resources=specialresources,verbs=create;update,versions=v1beta1,name=vspecialresource.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &SpecialResource{}
+
+// ValidateCreate implements webhook.Validator
+func (r *SpecialResource) ValidateCreate() error {
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator
+func (r *SpecialResource) ValidateUpdate(old runtime.Object) error {
+	return r.validate()
+}
+
+// ValidateDelete implements webhook.Validator, deletion is always allowed
+func (r *SpecialResource) ValidateDelete() error {
+	return nil
+}
+
+func (r *SpecialResource) validate() error {
+
+	spec := field.NewPath("spec")
+
+	errs := validateChart(spec.Child("chart"), r.Spec.Chart)
+
+	for idx, dep := range r.Spec.Dependencies {
+		errs = append(errs, validateChart(spec.Child("dependencies").Index(idx).Child("chart"), dep.HelmChart)...)
+	}
+
+	if r.Spec.Namespace != "" {
+		for _, msg := range validation.IsDNS1123Label(r.Spec.Namespace) {
+			errs = append(errs, field.Invalid(spec.Child("namespace"), r.Spec.Namespace, msg))
+		}
+	}
+
+	for key, value := range r.Spec.NodeSelector {
+		path := spec.Child("nodeSelector").Key(key)
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, field.Invalid(path, key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			errs = append(errs, field.Invalid(path, value, msg))
+		}
+	}
+
+	for idx, dep := range r.Spec.DependsOn {
+		if dep == r.Name {
+			errs = append(errs, field.Invalid(spec.Child("dependsOn").Index(idx), dep, "a SpecialResource cannot depend on itself"))
+		}
+	}
+
+	if repo := r.Spec.DriverContainer.Promote.Repository; repo != "" {
+		if _, err := name.NewRepository(repo); err != nil {
+			errs = append(errs, field.Invalid(spec.Child("driverContainer", "promote", "repository"), repo, err.Error()))
+		}
+	}
+
+	if dc := r.Spec.DriverContainer; dc.Prebuilt {
+		path := spec.Child("driverContainer", "image")
+		if dc.Image == "" {
+			errs = append(errs, field.Required(path, "image is required for prebuilt driver containers"))
+		} else if _, err := name.ParseReference(strings.ReplaceAll(dc.Image, "${KERNEL_VERSION}", "0.0.0")); err != nil {
+			errs = append(errs, field.Invalid(path, dc.Image, err.Error()))
+		}
+	}
+
+	for idx, module := range r.Spec.ModuleBlacklist.Modules {
+		if !moduleName.MatchString(module) {
+			errs = append(errs, field.Invalid(spec.Child("moduleBlacklist", "modules").Index(idx), module, "must be a kernel module name"))
+		}
+	}
+
+	for idx, required := range r.Spec.NodeFeatures.Required {
+		path := spec.Child("nodeFeatures", "required").Index(idx)
+		key, value := required, "true"
+		if i := strings.Index(required, "="); i >= 0 {
+			key, value = required[:i], required[i+1:]
+		}
+		for _, msg := range append(validation.IsQualifiedName(key), validation.IsValidLabelValue(value)...) {
+			errs = append(errs, field.Invalid(path, required, msg))
+		}
+	}
+
+	if v := r.Spec.Verification; v.KeylessRootsConfigMap != "" {
+		if v.KeylessIdentity == "" {
+			errs = append(errs, field.Required(spec.Child("verification", "keylessIdentity"), "keyless verification needs the identity of the signer"))
+		}
+		if v.KeylessIssuer == "" {
+			errs = append(errs, field.Required(spec.Child("verification", "keylessIssuer"), "keyless verification needs the OIDC issuer of the signer"))
+		}
+	}
+
+	errs = append(errs, validateFirmware(spec.Child("driverContainer", "firmware"), r.Spec.DriverContainer.Firmware)...)
+
+	errs = append(errs, validateImages(spec.Child("set"), r.Spec.Set.Object)...)
+
+	// Only ask helm if the chart reference itself is sane
+	if len(errs) == 0 && ChartLocator != nil {
+		errs = append(errs, locateChart(spec.Child("chart"), r.Spec.Chart)...)
+		for idx, dep := range r.Spec.Dependencies {
+			errs = append(errs, locateChart(spec.Child("dependencies").Index(idx).Child("chart"), dep.HelmChart)...)
+		}
+	}
+
+	if len(errs) == 0 && ChartValidator != nil {
+		if err := ChartValidator(r.Spec.Chart, r.Spec.Set.Object); err != nil {
+			errs = append(errs, field.Invalid(spec.Child("set"), r.Spec.Chart.Name, err.Error()))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("SpecialResource").GroupKind(), r.Name, errs)
+}
+
+func validateChart(path *field.Path, chart helmerv1beta1.HelmChart) field.ErrorList {
+
+	errs := field.ErrorList{}
+
+	if chart.Name == "" {
+		errs = append(errs, field.Required(path.Child("name"), "chart name is required"))
+	}
+	if chart.Version == "" {
+		errs = append(errs, field.Required(path.Child("version"), "chart version is required"))
+	}
+	if chart.Repository.Name == "" {
+		errs = append(errs, field.Required(path.Child("repository", "name"), "repository name is required"))
+	}
+
+	// git remotes and refs are checked like the operator checks them before
+	// it fetches the chart
+	if strings.HasPrefix(chart.Repository.URL, helmerv1beta1.GitScheme) {
+		if _, _, _, err := helmerv1beta1.ParseGitURL(chart.Repository.URL); err != nil {
+			errs = append(errs, field.Invalid(path.Child("repository", "url"), chart.Repository.URL, err.Error()))
+		}
+		return errs
+	}
+
+	repoURL, err := url.Parse(chart.Repository.URL)
+	switch {
+	case chart.Repository.URL == "":
+		errs = append(errs, field.Required(path.Child("repository", "url"), "repository url is required"))
+	case err != nil:
+		errs = append(errs, field.Invalid(path.Child("repository", "url"), chart.Repository.URL, err.Error()))
+	case repoURL.Scheme == "oci" && repoURL.Host == "":
+		errs = append(errs, field.Invalid(path.Child("repository", "url"), chart.Repository.URL, "oci repository needs a registry host"))
+	case repoURL.Scheme != "http" && repoURL.Scheme != "https" && repoURL.Scheme != "file" && repoURL.Scheme != "oci":
+		errs = append(errs, field.NotSupported(path.Child("repository", "url"), repoURL.Scheme, []string{"http", "https", "file", "oci", "git+https", "git+ssh"}))
+	}
+
+	return errs
+}
+
+// locateChart reports a chart missing from the index of its repository at
+// admission instead of the first reconcile, OCI and git repositories have
+// no index
+func locateChart(path *field.Path, chart helmerv1beta1.HelmChart) field.ErrorList {
+
+	if strings.HasPrefix(chart.Repository.URL, helmerv1beta1.OCIScheme) || strings.HasPrefix(chart.Repository.URL, helmerv1beta1.GitScheme) {
+		return nil
+	}
+
+	if err := ChartLocator(chart); err != nil {
+		return field.ErrorList{field.Invalid(path, chart.Name+"-"+chart.Version, err.Error())}
+	}
+
+	return nil
+}
+
+// validateFirmware checks that the firmware is copied between absolute
+// paths and never onto the read-only or system directories of the host.
+func validateFirmware(path *field.Path, firmware SpecialResourceFirmware) field.ErrorList {
+
+	errs := field.ErrorList{}
+
+	if firmware.Source == "" {
+		if firmware.HostPath != "" {
+			errs = append(errs, field.Required(path.Child("source"), "source is required if hostPath is set"))
+		}
+		return errs
+	}
+
+	if !filepath.IsAbs(firmware.Source) {
+		errs = append(errs, field.Invalid(path.Child("source"), firmware.Source, "must be an absolute path"))
+	}
+
+	if hostPath := firmware.HostPath; hostPath != "" {
+		clean := filepath.Clean(hostPath)
+		switch {
+		case !filepath.IsAbs(hostPath):
+			errs = append(errs, field.Invalid(path.Child("hostPath"), hostPath, "must be an absolute path"))
+		case clean == "/" || clean == "/etc" || clean == "/var" || strings.HasPrefix(clean, "/usr/") || clean == "/usr":
+			errs = append(errs, field.Invalid(path.Child("hostPath"), hostPath, "must be a dedicated firmware directory"))
+		}
+	}
+
+	return errs
+}
+
+// validateImages checks every string value of a key ending in image, values
+// that are still templates are resolved during the reconcile and skipped.
+func validateImages(path *field.Path, values map[string]interface{}) field.ErrorList {
+
+	errs := field.ErrorList{}
+
+	for key, value := range values {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			errs = append(errs, validateImages(path.Child(key), value)...)
+		case string:
+			if !strings.HasSuffix(strings.ToLower(key), "image") || value == "" || strings.Contains(value, "{{") {
+				continue
+			}
+			if _, err := name.ParseReference(value); err != nil {
+				errs = append(errs, field.Invalid(path.Child(key), value, err.Error()))
+			}
+		}
+	}
+
+	return errs
+}
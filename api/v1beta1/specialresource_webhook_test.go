@@ -0,0 +1,65 @@
+package v1beta1
+
+import (
+	"testing"
+
+	helmerv1beta1 "github.com/openshift-psap/special-resource-operator/pkg/helmer/api/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidateChart(t *testing.T) {
+
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://example.com/charts", true},
+		{"http://example.com/charts", true},
+		{"file:///charts", true},
+		{"oci://registry.example.com/charts", true},
+		{"oci:///charts", false},
+		{"git+https://github.com/org/driver.git?ref=v1.0&path=charts/driver", true},
+		{"git+ssh://git@github.com/org/driver.git", true},
+		{"git+http://github.com/org/driver.git", false},
+		{"git+file:///etc", false},
+		{"git+ext::sh -c touch% /tmp/pwned", false},
+		{"git+https://github.com/org/driver.git?ref=--upload-pack=touch", false},
+		{"ftp://example.com/charts", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		chart := helmerv1beta1.HelmChart{
+			Name:       "simple-kmod",
+			Version:    "0.0.1",
+			Repository: helmerv1beta1.HelmRepo{Name: "example", URL: tt.url},
+		}
+		if errs := validateChart(field.NewPath("spec", "chart"), chart); (len(errs) == 0) != tt.valid {
+			t.Errorf("validateChart(%q) = %v, want valid %v", tt.url, errs, tt.valid)
+		}
+	}
+}
+
+func TestValidateAdmitsIndexlessCharts(t *testing.T) {
+
+	// OCI and git repositories have no index to look the chart up in
+	defer func(locator func(helmerv1beta1.HelmChart) error) { ChartLocator = locator }(ChartLocator)
+	ChartLocator = func(chart helmerv1beta1.HelmChart) error {
+		t.Errorf("ChartLocator(%s) called for a repository without index", chart.Repository.URL)
+		return nil
+	}
+
+	for _, url := range []string{"oci://registry.example.com/charts", "git+https://github.com/org/driver.git?ref=v1.0"} {
+		sr := &SpecialResource{}
+		sr.Name = "simple-kmod"
+		sr.Spec.Namespace = "simple-kmod"
+		sr.Spec.Chart = helmerv1beta1.HelmChart{
+			Name:       "simple-kmod",
+			Version:    "0.0.1",
+			Repository: helmerv1beta1.HelmRepo{Name: "example", URL: url},
+		}
+		if err := sr.ValidateCreate(); err != nil {
+			t.Errorf("ValidateCreate(%s) = %v", url, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SpecialResourceInventoryObject an object applied for a SpecialResource
+type SpecialResourceInventoryObject struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// +kubebuilder:validation:Optional
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Hash of the applied manifest, the specialresource.openshift.io/hash
+	// annotation of the object as long as nobody changed it
+	// +kubebuilder:validation:Optional
+	Hash string `json:"hash,omitempty"`
+}
+
+// SpecialResourceInventorySpec the objects of the last reconcile, kept in
+// the spec so that backups restore it
+type SpecialResourceInventorySpec struct {
+	// SpecialResource the objects were applied for
+	SpecialResource string `json:"specialResource"`
+	// Objects sorted by kind, namespace and name
+	// +kubebuilder:validation:Optional
+	Objects []SpecialResourceInventoryObject `json:"objects,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SpecialResourceInventory records the objects the operator applied for the
+// SpecialResource of the same name, objects missing from the next reconcile
+// are pruned. It is written by the operator only.
+// +kubebuilder:resource:path=specialresourceinventories,scope=Cluster,shortName=sri
+// +kubebuilder:printcolumn:name="SpecialResource",type=string,JSONPath=`.spec.specialResource`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type SpecialResourceInventory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SpecialResourceInventorySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SpecialResourceInventoryList contains a list of SpecialResourceInventory
+type SpecialResourceInventoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SpecialResourceInventory `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SpecialResourceInventory{}, &SpecialResourceInventoryList{})
+}
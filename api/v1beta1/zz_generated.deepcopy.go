@@ -5,16 +5,130 @@
 package v1beta1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightValidation) DeepCopyInto(out *PreflightValidation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightValidation.
+func (in *PreflightValidation) DeepCopy() *PreflightValidation {
+	if in == nil {
+		return nil
+	}
+	out := new(PreflightValidation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PreflightValidation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightValidationList) DeepCopyInto(out *PreflightValidationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PreflightValidation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightValidationList.
+func (in *PreflightValidationList) DeepCopy() *PreflightValidationList {
+	if in == nil {
+		return nil
+	}
+	out := new(PreflightValidationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PreflightValidationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightValidationSRStatus) DeepCopyInto(out *PreflightValidationSRStatus) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightValidationSRStatus.
+func (in *PreflightValidationSRStatus) DeepCopy() *PreflightValidationSRStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PreflightValidationSRStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightValidationSpec) DeepCopyInto(out *PreflightValidationSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightValidationSpec.
+func (in *PreflightValidationSpec) DeepCopy() *PreflightValidationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PreflightValidationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightValidationStatus) DeepCopyInto(out *PreflightValidationStatus) {
+	*out = *in
+	if in.SRStatuses != nil {
+		in, out := &in.SRStatuses, &out.SRStatuses
+		*out = make([]PreflightValidationSRStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightValidationStatus.
+func (in *PreflightValidationStatus) DeepCopy() *PreflightValidationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PreflightValidationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SpecialResource) DeepCopyInto(out *SpecialResource) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResource.
@@ -67,6 +181,23 @@ func (in *SpecialResourceArtifacts) DeepCopy() *SpecialResourceArtifacts {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceBuild) DeepCopyInto(out *SpecialResourceBuild) {
+	*out = *in
+	out.Logs = in.Logs
+	out.Cache = in.Cache
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceBuild.
+func (in *SpecialResourceBuild) DeepCopy() *SpecialResourceBuild {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceBuild)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SpecialResourceBuildArgs) DeepCopyInto(out *SpecialResourceBuildArgs) {
 	*out = *in
@@ -82,6 +213,88 @@ func (in *SpecialResourceBuildArgs) DeepCopy() *SpecialResourceBuildArgs {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceBuildCache) DeepCopyInto(out *SpecialResourceBuildCache) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceBuildCache.
+func (in *SpecialResourceBuildCache) DeepCopy() *SpecialResourceBuildCache {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceBuildCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceBuildLogs) DeepCopyInto(out *SpecialResourceBuildLogs) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceBuildLogs.
+func (in *SpecialResourceBuildLogs) DeepCopy() *SpecialResourceBuildLogs {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceBuildLogs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceBuildStatus) DeepCopyInto(out *SpecialResourceBuildStatus) {
+	*out = *in
+	in.LastFailureTime.DeepCopyInto(&out.LastFailureTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceBuildStatus.
+func (in *SpecialResourceBuildStatus) DeepCopy() *SpecialResourceBuildStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceBuildStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceChange) DeepCopyInto(out *SpecialResourceChange) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.Diff != nil {
+		in, out := &in.Diff, &out.Diff
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceChange.
+func (in *SpecialResourceChange) DeepCopy() *SpecialResourceChange {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceChange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceChartVerification) DeepCopyInto(out *SpecialResourceChartVerification) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceChartVerification.
+func (in *SpecialResourceChartVerification) DeepCopy() *SpecialResourceChartVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceChartVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SpecialResourceClaims) DeepCopyInto(out *SpecialResourceClaims) {
 	*out = *in
@@ -134,11 +347,42 @@ func (in *SpecialResourceDependency) DeepCopy() *SpecialResourceDependency {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceDrain) DeepCopyInto(out *SpecialResourceDrain) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceDrain.
+func (in *SpecialResourceDrain) DeepCopy() *SpecialResourceDrain {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceDrain)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SpecialResourceDriverContainer) DeepCopyInto(out *SpecialResourceDriverContainer) {
 	*out = *in
 	out.Source = in.Source
 	in.Artifacts.DeepCopyInto(&out.Artifacts)
+	out.Promote = in.Promote
+	out.Firmware = in.Firmware
+	out.SBOM = in.SBOM
+	out.Signing = in.Signing
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceDriverContainer.
@@ -152,146 +396,771 @@ func (in *SpecialResourceDriverContainer) DeepCopy() *SpecialResourceDriverConta
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SpecialResourceGit) DeepCopyInto(out *SpecialResourceGit) {
+func (in *SpecialResourceDriverToolkit) DeepCopyInto(out *SpecialResourceDriverToolkit) {
 	*out = *in
+	if in.WatchedReleases != nil {
+		in, out := &in.WatchedReleases, &out.WatchedReleases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceGit.
-func (in *SpecialResourceGit) DeepCopy() *SpecialResourceGit {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceDriverToolkit.
+func (in *SpecialResourceDriverToolkit) DeepCopy() *SpecialResourceDriverToolkit {
 	if in == nil {
 		return nil
 	}
-	out := new(SpecialResourceGit)
+	out := new(SpecialResourceDriverToolkit)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SpecialResourceImages) DeepCopyInto(out *SpecialResourceImages) {
+func (in *SpecialResourceFIPS) DeepCopyInto(out *SpecialResourceFIPS) {
 	*out = *in
-	if in.Paths != nil {
-		in, out := &in.Paths, &out.Paths
-		*out = make([]SpecialResourcePaths, len(*in))
-		copy(*out, *in)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceImages.
-func (in *SpecialResourceImages) DeepCopy() *SpecialResourceImages {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceFIPS.
+func (in *SpecialResourceFIPS) DeepCopy() *SpecialResourceFIPS {
 	if in == nil {
 		return nil
 	}
-	out := new(SpecialResourceImages)
+	out := new(SpecialResourceFIPS)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SpecialResourceList) DeepCopyInto(out *SpecialResourceList) {
+func (in *SpecialResourceFirmware) DeepCopyInto(out *SpecialResourceFirmware) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]SpecialResource, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceList.
-func (in *SpecialResourceList) DeepCopy() *SpecialResourceList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceFirmware.
+func (in *SpecialResourceFirmware) DeepCopy() *SpecialResourceFirmware {
 	if in == nil {
 		return nil
 	}
-	out := new(SpecialResourceList)
+	out := new(SpecialResourceFirmware)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *SpecialResourceList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SpecialResourcePaths) DeepCopyInto(out *SpecialResourcePaths) {
+func (in *SpecialResourceGit) DeepCopyInto(out *SpecialResourceGit) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourcePaths.
-func (in *SpecialResourcePaths) DeepCopy() *SpecialResourcePaths {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceGit.
+func (in *SpecialResourceGit) DeepCopy() *SpecialResourceGit {
 	if in == nil {
 		return nil
 	}
-	out := new(SpecialResourcePaths)
+	out := new(SpecialResourceGit)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SpecialResourceSource) DeepCopyInto(out *SpecialResourceSource) {
+func (in *SpecialResourceImageDigest) DeepCopyInto(out *SpecialResourceImageDigest) {
 	*out = *in
-	out.Git = in.Git
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceSource.
-func (in *SpecialResourceSource) DeepCopy() *SpecialResourceSource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceImageDigest.
+func (in *SpecialResourceImageDigest) DeepCopy() *SpecialResourceImageDigest {
 	if in == nil {
 		return nil
 	}
-	out := new(SpecialResourceSource)
+	out := new(SpecialResourceImageDigest)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SpecialResourceSpec) DeepCopyInto(out *SpecialResourceSpec) {
+func (in *SpecialResourceImageGC) DeepCopyInto(out *SpecialResourceImageGC) {
 	*out = *in
-	in.Chart.DeepCopyInto(&out.Chart)
-	in.Set.DeepCopyInto(&out.Set)
-	in.DriverContainer.DeepCopyInto(&out.DriverContainer)
-	if in.NodeSelector != nil {
-		in, out := &in.NodeSelector, &out.NodeSelector
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.Dependencies != nil {
-		in, out := &in.Dependencies, &out.Dependencies
-		*out = make([]SpecialResourceDependency, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceSpec.
-func (in *SpecialResourceSpec) DeepCopy() *SpecialResourceSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceImageGC.
+func (in *SpecialResourceImageGC) DeepCopy() *SpecialResourceImageGC {
 	if in == nil {
 		return nil
 	}
-	out := new(SpecialResourceSpec)
+	out := new(SpecialResourceImageGC)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SpecialResourceStatus) DeepCopyInto(out *SpecialResourceStatus) {
+func (in *SpecialResourceImages) DeepCopyInto(out *SpecialResourceImages) {
 	*out = *in
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]SpecialResourcePaths, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceStatus.
-func (in *SpecialResourceStatus) DeepCopy() *SpecialResourceStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceImages.
+func (in *SpecialResourceImages) DeepCopy() *SpecialResourceImages {
 	if in == nil {
 		return nil
 	}
-	out := new(SpecialResourceStatus)
+	out := new(SpecialResourceImages)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceInventory) DeepCopyInto(out *SpecialResourceInventory) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceInventory.
+func (in *SpecialResourceInventory) DeepCopy() *SpecialResourceInventory {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceInventory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SpecialResourceInventory) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceInventoryList) DeepCopyInto(out *SpecialResourceInventoryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SpecialResourceInventory, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceInventoryList.
+func (in *SpecialResourceInventoryList) DeepCopy() *SpecialResourceInventoryList {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceInventoryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SpecialResourceInventoryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceInventoryObject) DeepCopyInto(out *SpecialResourceInventoryObject) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceInventoryObject.
+func (in *SpecialResourceInventoryObject) DeepCopy() *SpecialResourceInventoryObject {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceInventoryObject)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceInventorySpec) DeepCopyInto(out *SpecialResourceInventorySpec) {
+	*out = *in
+	if in.Objects != nil {
+		in, out := &in.Objects, &out.Objects
+		*out = make([]SpecialResourceInventoryObject, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceInventorySpec.
+func (in *SpecialResourceInventorySpec) DeepCopy() *SpecialResourceInventorySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceInventorySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceKernelStatus) DeepCopyInto(out *SpecialResourceKernelStatus) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PendingNodes != nil {
+		in, out := &in.PendingNodes, &out.PendingNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceKernelStatus.
+func (in *SpecialResourceKernelStatus) DeepCopy() *SpecialResourceKernelStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceKernelStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceList) DeepCopyInto(out *SpecialResourceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SpecialResource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceList.
+func (in *SpecialResourceList) DeepCopy() *SpecialResourceList {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SpecialResourceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceModuleBlacklist) DeepCopyInto(out *SpecialResourceModuleBlacklist) {
+	*out = *in
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MachineConfigPools != nil {
+		in, out := &in.MachineConfigPools, &out.MachineConfigPools
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceModuleBlacklist.
+func (in *SpecialResourceModuleBlacklist) DeepCopy() *SpecialResourceModuleBlacklist {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceModuleBlacklist)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceNodeFeatures) DeepCopyInto(out *SpecialResourceNodeFeatures) {
+	*out = *in
+	if in.Required != nil {
+		in, out := &in.Required, &out.Required
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PCI != nil {
+		in, out := &in.PCI, &out.PCI
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.USB != nil {
+		in, out := &in.USB, &out.USB
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceNodeFeatures.
+func (in *SpecialResourceNodeFeatures) DeepCopy() *SpecialResourceNodeFeatures {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceNodeFeatures)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceNodeTeardown) DeepCopyInto(out *SpecialResourceNodeTeardown) {
+	*out = *in
+	if in.Drivers != nil {
+		in, out := &in.Drivers, &out.Drivers
+		*out = make([]SpecialResourceTeardownDriver, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceNodeTeardown.
+func (in *SpecialResourceNodeTeardown) DeepCopy() *SpecialResourceNodeTeardown {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceNodeTeardown)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourcePaths) DeepCopyInto(out *SpecialResourcePaths) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourcePaths.
+func (in *SpecialResourcePaths) DeepCopy() *SpecialResourcePaths {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourcePaths)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourcePostRender) DeepCopyInto(out *SpecialResourcePostRender) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourcePostRender.
+func (in *SpecialResourcePostRender) DeepCopy() *SpecialResourcePostRender {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourcePostRender)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourcePromote) DeepCopyInto(out *SpecialResourcePromote) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourcePromote.
+func (in *SpecialResourcePromote) DeepCopy() *SpecialResourcePromote {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourcePromote)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceProxy) DeepCopyInto(out *SpecialResourceProxy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceProxy.
+func (in *SpecialResourceProxy) DeepCopy() *SpecialResourceProxy {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceProxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceSBOM) DeepCopyInto(out *SpecialResourceSBOM) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceSBOM.
+func (in *SpecialResourceSBOM) DeepCopy() *SpecialResourceSBOM {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceSBOM)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceSigning) DeepCopyInto(out *SpecialResourceSigning) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceSigning.
+func (in *SpecialResourceSigning) DeepCopy() *SpecialResourceSigning {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceSigning)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceSource) DeepCopyInto(out *SpecialResourceSource) {
+	*out = *in
+	out.Git = in.Git
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceSource.
+func (in *SpecialResourceSource) DeepCopy() *SpecialResourceSource {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceRollout) DeepCopyInto(out *SpecialResourceRollout) {
+	*out = *in
+	if in.CanaryNodeSelector != nil {
+		in, out := &in.CanaryNodeSelector, &out.CanaryNodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	in.Drain.DeepCopyInto(&out.Drain)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceRollout.
+func (in *SpecialResourceRollout) DeepCopy() *SpecialResourceRollout {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceRollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceSpec) DeepCopyInto(out *SpecialResourceSpec) {
+	*out = *in
+	in.Chart.DeepCopyInto(&out.Chart)
+	in.Set.DeepCopyInto(&out.Set)
+	in.DriverContainer.DeepCopyInto(&out.DriverContainer)
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NodeExclusionSelector != nil {
+		in, out := &in.NodeExclusionSelector, &out.NodeExclusionSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	out.ResyncPeriod = in.ResyncPeriod
+	in.Triggers.DeepCopyInto(&out.Triggers)
+	if in.Dependencies != nil {
+		in, out := &in.Dependencies, &out.Dependencies
+		*out = make([]SpecialResourceDependency, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Verification.DeepCopyInto(&out.Verification)
+	in.DriverToolkit.DeepCopyInto(&out.DriverToolkit)
+	out.Build = in.Build
+	in.Rollout.DeepCopyInto(&out.Rollout)
+	in.ModuleBlacklist.DeepCopyInto(&out.ModuleBlacklist)
+	in.NodeFeatures.DeepCopyInto(&out.NodeFeatures)
+	if in.NamespaceLabels != nil {
+		in, out := &in.NamespaceLabels, &out.NamespaceLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NamespaceAnnotations != nil {
+		in, out := &in.NamespaceAnnotations, &out.NamespaceAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Teardown.DeepCopyInto(&out.Teardown)
+	out.ImageGC = in.ImageGC
+	out.ChartVerification = in.ChartVerification
+	in.Targets.DeepCopyInto(&out.Targets)
+	out.Proxy = in.Proxy
+	out.FIPS = in.FIPS
+	out.VulnerabilityScan = in.VulnerabilityScan
+	out.PostRender = in.PostRender
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceSpec.
+func (in *SpecialResourceSpec) DeepCopy() *SpecialResourceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceStatus) DeepCopyInto(out *SpecialResourceStatus) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]SpecialResourceImageDigest, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Builds != nil {
+		in, out := &in.Builds, &out.Builds
+		*out = make([]SpecialResourceBuildStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.WatchedReleases != nil {
+		in, out := &in.WatchedReleases, &out.WatchedReleases
+		*out = make([]SpecialResourceWatchedRelease, len(*in))
+		copy(*out, *in)
+	}
+	if in.Changes != nil {
+		in, out := &in.Changes, &out.Changes
+		*out = make([]SpecialResourceChange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Teardown != nil {
+		in, out := &in.Teardown, &out.Teardown
+		*out = make([]SpecialResourceNodeTeardown, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Kernels != nil {
+		in, out := &in.Kernels, &out.Kernels
+		*out = make([]SpecialResourceKernelStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceStatus.
+func (in *SpecialResourceStatus) DeepCopy() *SpecialResourceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceTargetNamespace) DeepCopyInto(out *SpecialResourceTargetNamespace) {
+	*out = *in
+	in.Set.DeepCopyInto(&out.Set)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceTargetNamespace.
+func (in *SpecialResourceTargetNamespace) DeepCopy() *SpecialResourceTargetNamespace {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceTargetNamespace)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceTargets) DeepCopyInto(out *SpecialResourceTargets) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]SpecialResourceTargetNamespace, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceTargets.
+func (in *SpecialResourceTargets) DeepCopy() *SpecialResourceTargets {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceTargets)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceTeardown) DeepCopyInto(out *SpecialResourceTeardown) {
+	*out = *in
+	if in.ConsumerSelector != nil {
+		in, out := &in.ConsumerSelector, &out.ConsumerSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceTeardown.
+func (in *SpecialResourceTeardown) DeepCopy() *SpecialResourceTeardown {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceTeardown)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceTeardownDriver) DeepCopyInto(out *SpecialResourceTeardownDriver) {
+	*out = *in
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceTeardownDriver.
+func (in *SpecialResourceTeardownDriver) DeepCopy() *SpecialResourceTeardownDriver {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceTeardownDriver)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceTriggers) DeepCopyInto(out *SpecialResourceTriggers) {
+	*out = *in
+	if in.Secrets != nil {
+		in, out := &in.Secrets, &out.Secrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceTriggers.
+func (in *SpecialResourceTriggers) DeepCopy() *SpecialResourceTriggers {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceTriggers)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceVerification) DeepCopyInto(out *SpecialResourceVerification) {
+	*out = *in
+	if in.SkipRepositories != nil {
+		in, out := &in.SkipRepositories, &out.SkipRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceVerification.
+func (in *SpecialResourceVerification) DeepCopy() *SpecialResourceVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceVulnerabilityScan) DeepCopyInto(out *SpecialResourceVulnerabilityScan) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceVulnerabilityScan.
+func (in *SpecialResourceVulnerabilityScan) DeepCopy() *SpecialResourceVulnerabilityScan {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceVulnerabilityScan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpecialResourceWatchedRelease) DeepCopyInto(out *SpecialResourceWatchedRelease) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpecialResourceWatchedRelease.
+func (in *SpecialResourceWatchedRelease) DeepCopy() *SpecialResourceWatchedRelease {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecialResourceWatchedRelease)
 	in.DeepCopyInto(out)
 	return out
 }
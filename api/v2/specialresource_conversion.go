@@ -0,0 +1,326 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+var _ conversion.Convertible = &SpecialResource{}
+
+// ConvertTo converts this SpecialResource to the v1beta1 hub version
+func (src *SpecialResource) ConvertTo(dstRaw conversion.Hub) error {
+
+	dst := dstRaw.(*srov1beta1.SpecialResource)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Status = src.Status
+
+	set, err := valuesToSet(src.Spec.Values)
+	if err != nil {
+		return errors.Wrap(err, "spec.values")
+	}
+
+	var dependencies []srov1beta1.SpecialResourceDependency
+	for idx, dep := range src.Spec.Dependencies {
+		depSet, err := valuesToSet(dep.Values)
+		if err != nil {
+			return errors.Wrapf(err, "spec.dependencies[%d].values", idx)
+		}
+		dependencies = append(dependencies, srov1beta1.SpecialResourceDependency{
+			HelmChart: dep.Chart,
+			Set:       depSet,
+		})
+	}
+
+	targets := srov1beta1.SpecialResourceTargets{NamespaceSelector: src.Spec.Targets.NamespaceSelector}
+	for idx, target := range src.Spec.Targets.Namespaces {
+		targetSet, err := valuesToSet(target.Values)
+		if err != nil {
+			return errors.Wrapf(err, "spec.targets.namespaces[%d].values", idx)
+		}
+		targets.Namespaces = append(targets.Namespaces, srov1beta1.SpecialResourceTargetNamespace{
+			Name: target.Name,
+			Set:  targetSet,
+		})
+	}
+
+	dst.Spec = srov1beta1.SpecialResourceSpec{
+		Chart:                 src.Spec.Chart,
+		Namespace:             src.Spec.Namespace,
+		ForceUpgrade:          src.Spec.ForceUpgrade,
+		Debug:                 src.Spec.Debug,
+		Set:                   set,
+		DriverContainer:       src.Spec.DriverContainer,
+		NodeSelector:          src.Spec.NodeSelector,
+		NodeExclusionSelector: src.Spec.NodeExclusionSelector,
+		ResyncPeriod:          src.Spec.ResyncPeriod,
+		Triggers:              src.Spec.Triggers,
+		Dependencies:          dependencies,
+		DependsOn:             src.Spec.DependsOn,
+		ImagePullSecrets:      src.Spec.ImagePullSecrets,
+		Verification:          src.Spec.Verification,
+		DriverToolkit:         src.Spec.DriverToolkit,
+		Build:                 src.Spec.Build,
+		Rollout:               src.Spec.Rollout,
+		ModuleBlacklist:       src.Spec.ModuleBlacklist,
+		NodeFeatures:          src.Spec.NodeFeatures,
+		CleanupPolicy:         src.Spec.CleanupPolicy,
+		NamespaceManagement:   src.Spec.NamespaceManagement,
+		NamespaceLabels:       src.Spec.NamespaceLabels,
+		NamespaceAnnotations:  src.Spec.NamespaceAnnotations,
+		Teardown:              src.Spec.Teardown,
+		ImageGC:               src.Spec.ImageGC,
+		ChartVerification:     src.Spec.ChartVerification,
+		Targets:               targets,
+		ServiceAccount:        src.Spec.ServiceAccount,
+		Proxy:                 src.Spec.Proxy,
+		FIPS:                  src.Spec.FIPS,
+		VulnerabilityScan:     src.Spec.VulnerabilityScan,
+		PostRender:            src.Spec.PostRender,
+	}
+
+	return nil
+}
+
+// ConvertFrom converts from the v1beta1 hub version to this version
+func (dst *SpecialResource) ConvertFrom(srcRaw conversion.Hub) error {
+
+	src := srcRaw.(*srov1beta1.SpecialResource)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Status = src.Status
+
+	values, err := setToValues(src.Spec.Set)
+	if err != nil {
+		return errors.Wrap(err, "spec.set")
+	}
+
+	var dependencies []SpecialResourceDependency
+	for idx, dep := range src.Spec.Dependencies {
+		depValues, err := setToValues(dep.Set)
+		if err != nil {
+			return errors.Wrapf(err, "spec.dependencies[%d].set", idx)
+		}
+		dependencies = append(dependencies, SpecialResourceDependency{
+			Chart:  dep.HelmChart,
+			Values: depValues,
+		})
+	}
+
+	targets := SpecialResourceTargets{NamespaceSelector: src.Spec.Targets.NamespaceSelector}
+	for idx, target := range src.Spec.Targets.Namespaces {
+		targetValues, err := setToValues(target.Set)
+		if err != nil {
+			return errors.Wrapf(err, "spec.targets.namespaces[%d].set", idx)
+		}
+		targets.Namespaces = append(targets.Namespaces, SpecialResourceTargetNamespace{
+			Name:   target.Name,
+			Values: targetValues,
+		})
+	}
+
+	dst.Spec = SpecialResourceSpec{
+		Chart:                 src.Spec.Chart,
+		Namespace:             src.Spec.Namespace,
+		ForceUpgrade:          src.Spec.ForceUpgrade,
+		Debug:                 src.Spec.Debug,
+		Values:                values,
+		DriverContainer:       src.Spec.DriverContainer,
+		NodeSelector:          src.Spec.NodeSelector,
+		NodeExclusionSelector: src.Spec.NodeExclusionSelector,
+		ResyncPeriod:          src.Spec.ResyncPeriod,
+		Triggers:              src.Spec.Triggers,
+		Dependencies:          dependencies,
+		DependsOn:             src.Spec.DependsOn,
+		ImagePullSecrets:      src.Spec.ImagePullSecrets,
+		Verification:          src.Spec.Verification,
+		DriverToolkit:         src.Spec.DriverToolkit,
+		Build:                 src.Spec.Build,
+		Rollout:               src.Spec.Rollout,
+		ModuleBlacklist:       src.Spec.ModuleBlacklist,
+		NodeFeatures:          src.Spec.NodeFeatures,
+		CleanupPolicy:         src.Spec.CleanupPolicy,
+		NamespaceManagement:   src.Spec.NamespaceManagement,
+		NamespaceLabels:       src.Spec.NamespaceLabels,
+		NamespaceAnnotations:  src.Spec.NamespaceAnnotations,
+		Teardown:              src.Spec.Teardown,
+		ImageGC:               src.Spec.ImageGC,
+		ChartVerification:     src.Spec.ChartVerification,
+		Targets:               targets,
+		ServiceAccount:        src.Spec.ServiceAccount,
+		Proxy:                 src.Spec.Proxy,
+		FIPS:                  src.Spec.FIPS,
+		VulnerabilityScan:     src.Spec.VulnerabilityScan,
+		PostRender:            src.Spec.PostRender,
+	}
+
+	return nil
+}
+
+// valuesToSet builds the nested values of v1beta1 from the typed values
+func valuesToSet(values []SpecialResourceValue) (unstructured.Unstructured, error) {
+
+	set := unstructured.Unstructured{}
+
+	if len(values) == 0 {
+		return set, nil
+	}
+
+	set.Object = make(map[string]interface{})
+
+	for _, value := range values {
+		typed, err := parseValue(value)
+		if err != nil {
+			return set, errors.Wrap(err, value.Name)
+		}
+		if err := unstructured.SetNestedField(set.Object, typed, splitValueName(value.Name)...); err != nil {
+			return set, errors.Wrap(err, value.Name)
+		}
+	}
+
+	return set, nil
+}
+
+// setToValues flattens the nested values of v1beta1, maps are walked and
+// every leaf becomes one value, lists are kept as a JSON document.
+func setToValues(set unstructured.Unstructured) ([]SpecialResourceValue, error) {
+
+	values := []SpecialResourceValue{}
+
+	if err := flattenValues(set.Object, []string{}, &values); err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+
+	return values, nil
+}
+
+func flattenValues(object map[string]interface{}, path []string, values *[]SpecialResourceValue) error {
+
+	for key, leaf := range object {
+
+		// Set is an EmbeddedResource, the reconciler fills in apiVersion
+		// and kind, they are not chart values
+		if len(path) == 0 && (key == "apiVersion" || key == "kind") {
+			continue
+		}
+
+		name := append(append([]string{}, path...), escapeValueKey(key))
+
+		value := SpecialResourceValue{Name: strings.Join(name, ".")}
+
+		switch leaf := leaf.(type) {
+		case map[string]interface{}:
+			if len(leaf) > 0 {
+				if err := flattenValues(leaf, name, values); err != nil {
+					return err
+				}
+				continue
+			}
+			value.Type = ValueTypeJSON
+			value.Value = "{}"
+		case string:
+			value.Type = ValueTypeString
+			value.Value = leaf
+		case bool:
+			value.Type = ValueTypeBool
+			value.Value = strconv.FormatBool(leaf)
+		case int64:
+			value.Type = ValueTypeInt
+			value.Value = strconv.FormatInt(leaf, 10)
+		case float64:
+			value.Type = ValueTypeFloat
+			value.Value = strconv.FormatFloat(leaf, 'g', -1, 64)
+		default:
+			raw, err := json.Marshal(leaf)
+			if err != nil {
+				return errors.Wrap(err, value.Name)
+			}
+			value.Type = ValueTypeJSON
+			value.Value = string(raw)
+		}
+
+		*values = append(*values, value)
+	}
+
+	return nil
+}
+
+func parseValue(value SpecialResourceValue) (interface{}, error) {
+
+	switch value.Type {
+	case ValueTypeString, "":
+		return value.Value, nil
+	case ValueTypeBool:
+		return strconv.ParseBool(value.Value)
+	case ValueTypeInt:
+		return strconv.ParseInt(value.Value, 10, 64)
+	case ValueTypeFloat:
+		return strconv.ParseFloat(value.Value, 64)
+	case ValueTypeJSON:
+		// util/json keeps integers as int64 like the API server does
+		var typed interface{}
+		if err := utiljson.Unmarshal([]byte(value.Value), &typed); err != nil {
+			return nil, err
+		}
+		return typed, nil
+	}
+
+	return nil, errors.New("Unsupported value type " + value.Type)
+}
+
+func escapeValueKey(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, `\`, `\\`), ".", `\.`)
+}
+
+// splitValueName splits a dotted name into its keys, a backslash escapes
+// the following character like helm --set does
+func splitValueName(name string) []string {
+
+	keys := []string{}
+	key := strings.Builder{}
+
+	for i := 0; i < len(name); i++ {
+		switch {
+		case name[i] == '\\' && i+1 < len(name):
+			i++
+			key.WriteByte(name[i])
+		case name[i] == '.':
+			keys = append(keys, key.String())
+			key.Reset()
+		default:
+			key.WriteByte(name[i])
+		}
+	}
+
+	return append(keys, key.String())
+}
@@ -0,0 +1,193 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	helmerv1beta1 "github.com/openshift-psap/special-resource-operator/pkg/helmer/api/v1beta1"
+)
+
+const (
+	// ValueTypeString the value is passed to the chart as is
+	ValueTypeString string = "String"
+	// ValueTypeBool the value is parsed as true or false
+	ValueTypeBool string = "Bool"
+	// ValueTypeInt the value is parsed as a 64 bit integer
+	ValueTypeInt string = "Int"
+	// ValueTypeFloat the value is parsed as a 64 bit float
+	ValueTypeFloat string = "Float"
+	// ValueTypeJSON the value is a JSON document, used for lists and
+	// objects that cannot be expressed as single values
+	ValueTypeJSON string = "JSON"
+)
+
+// SpecialResourceValue a single chart value, the equivalent of helm --set
+type SpecialResourceValue struct {
+	// Name dotted path of the value in the chart values, dots that are part
+	// of a key are escaped with a backslash, e.g. nodeSelector.kubernetes\.io/arch
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^([^.\\]|\\.)+(\.([^.\\]|\\.)+)*$`
+	Name string `json:"name"`
+	// +kubebuilder:validation:Required
+	Value string `json:"value"`
+	// Type of Value, defaults to String
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=String;Bool;Int;Float;JSON
+	// +kubebuilder:default=String
+	Type string `json:"type,omitempty"`
+}
+
+// SpecialResourceDependency a dependent helm chart
+type SpecialResourceDependency struct {
+	// +kubebuilder:validation:Required
+	Chart helmerv1beta1.HelmChart `json:"chart"`
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=name
+	Values []SpecialResourceValue `json:"values,omitempty"`
+}
+
+// SpecialResourceTargets additional namespaces the chart is deployed to,
+// every namespace gets its own copy of the chart as if it was
+// spec.namespace
+type SpecialResourceTargets struct {
+	// Namespaces created by the SpecialResource if they do not exist
+	// +kubebuilder:validation:Optional
+	Namespaces []SpecialResourceTargetNamespace `json:"namespaces,omitempty"`
+	// NamespaceSelector selects existing namespaces, an entry of namespaces
+	// with the same name provides the values of a selected namespace
+	// +kubebuilder:validation:Optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// SpecialResourceTargetNamespace a namespace and the values overlaid on
+// spec.values for it
+type SpecialResourceTargetNamespace struct {
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=name
+	Values []SpecialResourceValue `json:"values,omitempty"`
+}
+
+// SpecialResourceSpec defines the desired state of SpecialResource
+type SpecialResourceSpec struct {
+	// +kubebuilder:validation:Required
+	Chart helmerv1beta1.HelmChart `json:"chart"`
+	// +kubebuilder:validation:Required
+	Namespace string `json:"namespace"`
+	// +kubebuilder:validation:Optional
+	ForceUpgrade bool `json:"forceUpgrade"`
+	// +kubebuilder:validation:Optional
+	Debug bool `json:"debug"`
+	// Values passed to the chart, replaces the unstructured set of v1beta1
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=name
+	Values []SpecialResourceValue `json:"values,omitempty"`
+	// +kubebuilder:validation:Optional
+	DriverContainer srov1beta1.SpecialResourceDriverContainer `json:"driverContainer,omitempty"`
+	// +kubebuilder:validation:Optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// NodeExclusionSelector nodes with all of these labels are ignored, on
+	// top of the Windows and other non-Linux nodes
+	// +kubebuilder:validation:Optional
+	NodeExclusionSelector map[string]string `json:"nodeExclusionSelector,omitempty"`
+	// +kubebuilder:validation:Optional
+	ResyncPeriod metav1.Duration `json:"resyncPeriod,omitempty"`
+	// +kubebuilder:validation:Optional
+	Triggers srov1beta1.SpecialResourceTriggers `json:"triggers,omitempty"`
+	// +kubebuilder:validation:Optional
+	Dependencies []SpecialResourceDependency `json:"dependencies,omitempty"`
+	// DependsOn SpecialResources that have to be Ready before this one is
+	// reconciled, the dependencies of all SpecialResources form a DAG
+	// +kubebuilder:validation:Optional
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// ImagePullSecrets in the SpecialResource namespace that are consulted
+	// before the global pull secret when the operator accesses registries
+	// +kubebuilder:validation:Optional
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+	// +kubebuilder:validation:Optional
+	Verification srov1beta1.SpecialResourceVerification `json:"verification,omitempty"`
+	// +kubebuilder:validation:Optional
+	DriverToolkit srov1beta1.SpecialResourceDriverToolkit `json:"driverToolkit,omitempty"`
+	// +kubebuilder:validation:Optional
+	Build srov1beta1.SpecialResourceBuild `json:"build,omitempty"`
+	// +kubebuilder:validation:Optional
+	Rollout srov1beta1.SpecialResourceRollout `json:"rollout,omitempty"`
+	// +kubebuilder:validation:Optional
+	ModuleBlacklist srov1beta1.SpecialResourceModuleBlacklist `json:"moduleBlacklist,omitempty"`
+	// +kubebuilder:validation:Optional
+	NodeFeatures srov1beta1.SpecialResourceNodeFeatures `json:"nodeFeatures,omitempty"`
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Orphan;Delete;DeleteAndWait
+	CleanupPolicy string `json:"cleanupPolicy,omitempty"`
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Create;Adopt;Ignore
+	NamespaceManagement string `json:"namespaceManagement,omitempty"`
+	// +kubebuilder:validation:Optional
+	NamespaceLabels map[string]string `json:"namespaceLabels,omitempty"`
+	// +kubebuilder:validation:Optional
+	NamespaceAnnotations map[string]string `json:"namespaceAnnotations,omitempty"`
+	// +kubebuilder:validation:Optional
+	Teardown srov1beta1.SpecialResourceTeardown `json:"teardown,omitempty"`
+	// +kubebuilder:validation:Optional
+	ImageGC srov1beta1.SpecialResourceImageGC `json:"imageGC,omitempty"`
+	// +kubebuilder:validation:Optional
+	ChartVerification srov1beta1.SpecialResourceChartVerification `json:"chartVerification,omitempty"`
+	// +kubebuilder:validation:Optional
+	Targets SpecialResourceTargets `json:"targets,omitempty"`
+	// +kubebuilder:validation:Optional
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	// +kubebuilder:validation:Optional
+	Proxy srov1beta1.SpecialResourceProxy `json:"proxy,omitempty"`
+	// +kubebuilder:validation:Optional
+	FIPS srov1beta1.SpecialResourceFIPS `json:"fips,omitempty"`
+	// +kubebuilder:validation:Optional
+	VulnerabilityScan srov1beta1.SpecialResourceVulnerabilityScan `json:"vulnerabilityScan,omitempty"`
+	// +kubebuilder:validation:Optional
+	PostRender srov1beta1.SpecialResourcePostRender `json:"postRender,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// SpecialResource is the Schema for the specialresources API
+// +kubebuilder:resource:path=specialresources,scope=Cluster,shortName=sr
+type SpecialResource struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	// +kubebuilder:validation:Required
+	Spec   SpecialResourceSpec              `json:"spec,omitempty"`
+	Status srov1beta1.SpecialResourceStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SpecialResourceList contains a list of SpecialResource
+type SpecialResourceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SpecialResource `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SpecialResource{}, &SpecialResourceList{})
+}
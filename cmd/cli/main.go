@@ -0,0 +1,257 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// sro renders recipes without a cluster, for recipe development and CI:
+//
+//	sro render --specialresource sr.yaml --chart charts/example/simple-kmod-0.0.1 --facts facts.yaml
+//	sro preflight --release quay.io/openshift-release-dev/ocp-release:4.9.0-x86_64 --specialresource sr.yaml
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	srov2 "github.com/openshift-psap/special-resource-operator/api/v2"
+	"github.com/openshift-psap/special-resource-operator/controllers"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const usage = `Usage: sro <command> [flags]
+
+Commands:
+  render    render the chart of a SpecialResource with the facts of a cluster
+  preflight check that SpecialResources have a driver container for a release
+`
+
+func main() {
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+
+	switch os.Args[1] {
+	case "render":
+		err = render(os.Args[2:])
+	case "preflight":
+		err = preflight(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func render(args []string) error {
+
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+
+	var specialresource string
+	var chartPath string
+	var factsPath string
+	var outputDir string
+
+	flags.StringVar(&specialresource, "specialresource", "", "The SpecialResource YAML, v1beta1 or v2.")
+	flags.StringVar(&chartPath, "chart", "", "The chart directory or archive of the SpecialResource.")
+	flags.StringVar(&factsPath, "facts", "", "The YAML with the kernel, OpenShift and OS versions and the DTK image of the cluster.")
+	flags.StringVar(&outputDir, "output-dir", "", "Writes one file per state instead of printing the manifests.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if specialresource == "" || chartPath == "" || factsPath == "" {
+		flags.Usage()
+		return errors.New("--specialresource, --chart and --facts are required")
+	}
+
+	sr, err := readSpecialResource(specialresource)
+	if err != nil {
+		return err
+	}
+
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		return errors.Wrap(err, "Cannot load chart "+chartPath)
+	}
+
+	data, err := ioutil.ReadFile(factsPath)
+	if err != nil {
+		return errors.Wrap(err, "Cannot read facts")
+	}
+	facts := controllers.Facts{}
+	if err := yaml.UnmarshalStrict(data, &facts); err != nil {
+		return errors.Wrap(err, "Cannot parse facts "+factsPath)
+	}
+
+	manifests, err := controllers.RenderOffline(sr, ch, facts)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(manifests))
+	for key := range manifests {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if outputDir == "" {
+		for _, key := range keys {
+			fmt.Printf("---\n# %s\n%s\n", key, manifests[key])
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return errors.Wrap(err, "Cannot create "+outputDir)
+	}
+	for _, key := range keys {
+		if err := ioutil.WriteFile(filepath.Join(outputDir, key), []byte(manifests[key]), 0644); err != nil {
+			return errors.Wrap(err, "Cannot write "+key)
+		}
+	}
+	return nil
+}
+
+// files a flag that can be repeated
+type files []string
+
+func (f *files) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *files) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func preflight(args []string) error {
+
+	flags := flag.NewFlagSet("preflight", flag.ExitOnError)
+
+	var release string
+	var specialresources files
+	var arch string
+	var output string
+
+	flags.StringVar(&release, "release", "", "The pullspec of the release image the cluster is upgraded to.")
+	flags.Var(&specialresources, "specialresource", "A SpecialResource YAML, v1beta1 or v2, can be repeated.")
+	flags.StringVar(&arch, "arch", runtime.GOARCH, "The architecture of the nodes.")
+	flags.StringVar(&output, "output", "json", "The format of the report, json or yaml.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if release == "" || len(specialresources) == 0 {
+		flags.Usage()
+		return errors.New("--release and --specialresource are required")
+	}
+	if output != "json" && output != "yaml" {
+		return errors.New("Unsupported output " + output)
+	}
+
+	srs := []srov1beta1.SpecialResource{}
+	for _, file := range specialresources {
+		sr, err := readSpecialResource(file)
+		if err != nil {
+			return err
+		}
+		srs = append(srs, sr)
+	}
+
+	// Credentials are read from the docker config.json
+	registry.Standalone = true
+	registry.Architecture = arch
+
+	report, err := controllers.PreflightRelease(release, arch, srs)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	if output == "yaml" {
+		out, err = yaml.Marshal(report)
+	} else {
+		out, err = json.MarshalIndent(report, "", "  ")
+		out = append(out, '\n')
+	}
+	if err != nil {
+		return errors.Wrap(err, "Cannot encode report")
+	}
+	fmt.Print(string(out))
+
+	failed := 0
+	for _, result := range report.SpecialResources {
+		if !result.Verified {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.New(strconv.Itoa(failed) + " SpecialResources not ready for " + report.Version)
+	}
+	return nil
+}
+
+// readSpecialResource reads a v1beta1 SpecialResource, v2 ones are converted
+func readSpecialResource(file string) (srov1beta1.SpecialResource, error) {
+
+	sr := srov1beta1.SpecialResource{}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return sr, errors.Wrap(err, "Cannot read SpecialResource")
+	}
+
+	meta := metav1.TypeMeta{}
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return sr, errors.Wrap(err, "Cannot parse SpecialResource "+file)
+	}
+	if meta.Kind != "SpecialResource" {
+		return sr, errors.New(file + " is not a SpecialResource")
+	}
+
+	if meta.APIVersion != srov2.GroupVersion.String() {
+		err := yaml.Unmarshal(data, &sr)
+		return sr, errors.Wrap(err, "Cannot parse SpecialResource "+file)
+	}
+
+	v2 := srov2.SpecialResource{}
+	if err := yaml.Unmarshal(data, &v2); err != nil {
+		return sr, errors.Wrap(err, "Cannot parse SpecialResource "+file)
+	}
+	if err := v2.ConvertTo(&sr); err != nil {
+		return sr, errors.Wrap(err, "Cannot convert SpecialResource "+file)
+	}
+	return sr, nil
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"sort"
+	"strings"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/blacklist"
+	"github.com/openshift-psap/special-resource-operator/pkg/resource"
+	"github.com/openshift-psap/special-resource-operator/pkg/slice"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// reconcileModuleBlacklist creates a MachineConfig blacklisting the in-tree
+// modules conflicting with the driver for every pool. The chart is only
+// reconciled once the pools rebooted their nodes with the blacklist.
+func reconcileModuleBlacklist(r *SpecialResourceReconciler) error {
+
+	sr := &r.specialresource
+	modules := sr.Spec.ModuleBlacklist.Modules
+
+	available, err := blacklist.Available()
+	if err != nil {
+		return errors.Wrap(err, "Error discovering machineconfigs API resource")
+	}
+	if !available {
+		if len(modules) > 0 {
+			return errors.New("spec.moduleBlacklist needs MachineConfigs, the cluster has no machineconfigs API resource")
+		}
+		return nil
+	}
+
+	pools := []string{}
+	if len(modules) > 0 {
+		pools = machineConfigPools(sr.Spec.ModuleBlacklist.MachineConfigPools)
+		if len(pools) == 0 {
+			return errors.New("spec.moduleBlacklist is set but no MachineConfigPool selects the nodes")
+		}
+	}
+
+	names := []string{}
+	for _, pool := range pools {
+		names = append(names, blacklist.Name(sr.Name, pool))
+	}
+
+	// Dropped pools and modules are removed from the nodes as well
+	if err := blacklist.Prune(sr.Name, names); err != nil {
+		return err
+	}
+
+	if len(pools) == 0 {
+		return nil
+	}
+
+	for _, pool := range pools {
+		labels, err := blacklist.PoolLabels(pool)
+		if err != nil {
+			return err
+		}
+		mc := blacklist.MachineConfig(sr.Name, pool, labels, modules)
+		if err := resource.CRUD(mc, false, sr, sr.Name, sr.Spec.Namespace); err != nil {
+			return errors.Wrap(err, "Cannot reconcile MachineConfig "+mc.GetName())
+		}
+	}
+
+	waiting := []string{}
+	for idx, pool := range pools {
+		updated, reason, err := blacklist.PoolUpdated(pool, names[idx])
+		if err != nil {
+			return err
+		}
+		if !updated {
+			waiting = append(waiting, reason)
+		}
+	}
+
+	if len(waiting) > 0 {
+		err := errors.New("Waiting for the module blacklist: " + strings.Join(waiting, ", "))
+		setStatusCondition(sr, metav1.Condition{
+			Type:    srov1beta1.ConditionModulesBlacklisted,
+			Status:  metav1.ConditionFalse,
+			Reason:  "MachineConfigPoolUpdating",
+			Message: err.Error(),
+		})
+		return err
+	}
+
+	setStatusCondition(sr, metav1.Condition{
+		Type:    srov1beta1.ConditionModulesBlacklisted,
+		Status:  metav1.ConditionTrue,
+		Reason:  "MachineConfigPoolsUpdated",
+		Message: "Blacklisted " + strings.Join(modules, ", ") + " on MachineConfigPools " + strings.Join(pools, ", "),
+	})
+
+	return nil
+}
+
+// machineConfigPools returns the configured pools, defaults to the pools of
+// the nodes running the driver.
+func machineConfigPools(configured []string) []string {
+
+	if len(configured) > 0 {
+		return configured
+	}
+
+	pools := []string{}
+	for _, nodeVersion := range RunInfo.ClusterUpgradeInfo {
+		for _, pool := range nodeVersion.MachineConfigPools {
+			if !slice.Contains(pools, pool) {
+				pools = append(pools, pool)
+			}
+		}
+	}
+	sort.Strings(pools)
+
+	return pools
+}
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/poll"
+	"github.com/openshift-psap/special-resource-operator/pkg/resource"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const maxBuildBackoff = time.Hour
+
+// retryBuild handles a failed build of a state, the BuildConfig or BuildRun
+// is recreated after the backoff until spec.build.retries is exhausted. The
+// returned error replaces err.
+func retryBuild(sr *srov1beta1.SpecialResource, stateName string, err error) error {
+
+	failed := &poll.BuildFailedError{}
+	if !errors.As(err, &failed) {
+		return err
+	}
+
+	policy := sr.Spec.Build
+
+	status := srov1beta1.SpecialResourceBuildStatus{Name: failed.Name, State: stateName}
+	for _, build := range sr.Status.Builds {
+		if build.Name == failed.Name {
+			status = build
+		}
+	}
+
+	// A build that failed for the first time, later reconciles see the
+	// same failed build until it is recreated
+	if status.LastBuild != failed.Build {
+		status.LastBuild = failed.Build
+		status.LastFailureTime = metav1.Now()
+		status.LogTail = failed.LogTail
+		if policy.Logs.ConfigMap {
+			status.LogConfigMap = writeBuildLog(sr, failed)
+		}
+		setBuildStatus(sr, status)
+	}
+
+	if status.Retries >= policy.Retries {
+		return errors.New(fmt.Sprintf("%s, %d retries exhausted, log tail:\n%s", failed.Error(), policy.Retries, failed.LogTail))
+	}
+
+	backoff := buildBackoff(policy, status.Retries)
+	if wait := time.Until(status.LastFailureTime.Add(backoff)); wait > 0 {
+		return errors.Wrap(err, "Retrying build in "+wait.Round(time.Second).String())
+	}
+
+	log.Info("Retrying build", "kind", failed.Kind, "name", failed.Name, "retry", status.Retries+1, "retries", policy.Retries)
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(failed.APIVersion)
+	obj.SetKind(failed.Kind)
+
+	// The builds are owned by the BuildConfig and deleted with it, the
+	// next reconcile creates it again which triggers a new build
+	key := types.NamespacedName{Namespace: failed.Namespace, Name: failed.Name}
+	if err := clients.Workload().Get(context.TODO(), key, obj); err == nil {
+		if err := clients.Workload().Delete(context.TODO(), obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "Cannot delete "+failed.Kind+" "+failed.Name+" for retry")
+		}
+	}
+
+	status.Retries = status.Retries + 1
+	setBuildStatus(sr, status)
+
+	return errors.Wrap(err, fmt.Sprintf("Retrying build, retry %d of %d", status.Retries, policy.Retries))
+}
+
+// writeBuildLog stores the log tail of the failed build in a ConfigMap
+// owned by sr and returns its name, an empty name if it cannot be written.
+func writeBuildLog(sr *srov1beta1.SpecialResource, failed *poll.BuildFailedError) string {
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetName(failed.Build + "-log")
+	obj.SetNamespace(failed.Namespace)
+
+	data := map[string]interface{}{
+		"build": failed.Build,
+		"log":   failed.LogTail,
+	}
+	if err := unstructured.SetNestedMap(obj.Object, data, "data"); err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot set build log"))
+		return ""
+	}
+
+	if err := resource.CRUD(obj, false, sr, sr.Name, failed.Namespace); err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot write build log ConfigMap "+obj.GetName()))
+		return ""
+	}
+
+	return obj.GetName()
+}
+
+// buildBackoff doubles the backoff for every retry
+func buildBackoff(policy srov1beta1.SpecialResourceBuild, retries int32) time.Duration {
+
+	backoff := time.Duration(policy.BackoffSeconds) * time.Second
+	if backoff == 0 {
+		backoff = 30 * time.Second
+	}
+
+	for i := int32(0); i < retries && backoff < maxBuildBackoff; i++ {
+		backoff = backoff * 2
+	}
+
+	if backoff > maxBuildBackoff {
+		return maxBuildBackoff
+	}
+	return backoff
+}
+
+// clearBuildStatus drops the retries of the builds of a state once it
+// completed
+func clearBuildStatus(sr *srov1beta1.SpecialResource, stateName string) {
+
+	builds := []srov1beta1.SpecialResourceBuildStatus{}
+	for _, build := range sr.Status.Builds {
+		if build.State != stateName {
+			builds = append(builds, build)
+		}
+	}
+
+	if len(builds) == len(sr.Status.Builds) {
+		return
+	}
+
+	updateBuildStatus(sr, builds)
+}
+
+func setBuildStatus(sr *srov1beta1.SpecialResource, status srov1beta1.SpecialResourceBuildStatus) {
+
+	builds := []srov1beta1.SpecialResourceBuildStatus{}
+	for _, build := range sr.Status.Builds {
+		if build.Name != status.Name {
+			builds = append(builds, build)
+		}
+	}
+
+	updateBuildStatus(sr, append(builds, status))
+}
+
+func updateBuildStatus(sr *srov1beta1.SpecialResource, builds []srov1beta1.SpecialResourceBuildStatus) {
+
+	update := srov1beta1.SpecialResource{}
+
+	objectKey := types.NamespacedName{Name: sr.GetName(), Namespace: sr.GetNamespace()}
+	if err := clients.Interface.Get(context.TODO(), objectKey, &update); err != nil {
+		warn.OnError(errors.Wrap(err, "Is SR being deleted? Cannot get current instance"))
+		return
+	}
+
+	update.Status.Builds = builds
+
+	if err := clients.Interface.Status().Update(context.TODO(), &update); err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot update SpecialResource build status"))
+		return
+	}
+
+	update.Status.DeepCopyInto(&sr.Status)
+	sr.SetResourceVersion(update.GetResourceVersion())
+}
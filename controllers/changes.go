@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"context"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/resource"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// recordChanges appends the objects updated by the reconcile to the status
+// of sr, only the last history changes are kept
+func recordChanges(sr *srov1beta1.SpecialResource, diffs []resource.Diff, history int) {
+
+	if history < 1 || len(diffs) == 0 {
+		return
+	}
+
+	update := srov1beta1.SpecialResource{}
+
+	objectKey := types.NamespacedName{Name: sr.GetName(), Namespace: sr.GetNamespace()}
+	if err := clients.Interface.Get(context.TODO(), objectKey, &update); err != nil {
+		warn.OnError(errors.Wrap(err, "Is SR being deleted? Cannot get current instance"))
+		return
+	}
+
+	now := metav1.Now()
+	for _, diff := range diffs {
+		update.Status.Changes = append(update.Status.Changes, srov1beta1.SpecialResourceChange{
+			Time:   now,
+			Object: diff.Object,
+			Diff:   diff.Fields,
+		})
+	}
+	if len(update.Status.Changes) > history {
+		update.Status.Changes = update.Status.Changes[len(update.Status.Changes)-history:]
+	}
+
+	if err := clients.Interface.Status().Update(context.TODO(), &update); err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot update SpecialResource changes"))
+		return
+	}
+
+	sr.Status.Changes = update.Status.Changes
+}
@@ -0,0 +1,510 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/hash"
+	"github.com/openshift-psap/special-resource-operator/pkg/poll"
+	"github.com/openshift-psap/special-resource-operator/pkg/resource"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// KernelModulesAnnotation lists the modules a driver container
+	// DaemonSet loads, comma separated, DeleteAndWait checks that they are
+	// unloaded
+	KernelModulesAnnotation = resource.KernelModulesAnnotation
+	// TeardownTaintPrefix of the NoSchedule taint that keeps evicted
+	// consumers off a node until its drivers are unloaded
+	TeardownTaintPrefix = "teardown.specialresource.openshift.io/"
+)
+
+// reconcileCleanupPolicy keeps the orphan finalizer in sync with the
+// CleanupPolicy, the garbage collector then orphans the dependents on
+// deletion. Finalizers cannot be added once the deletion started.
+func reconcileCleanupPolicy(r *SpecialResourceReconciler) error {
+
+	orphan := r.specialresource.Spec.CleanupPolicy == srov1beta1.CleanupPolicyOrphan
+	found := contains(r.specialresource.GetFinalizers(), metav1.FinalizerOrphanDependents)
+
+	if orphan == found {
+		return nil
+	}
+
+	if orphan {
+		log.Info("Adding orphan finalizer to special resource")
+		controllerutil.AddFinalizer(&r.specialresource, metav1.FinalizerOrphanDependents)
+	} else {
+		log.Info("Removing orphan finalizer from special resource")
+		controllerutil.RemoveFinalizer(&r.specialresource, metav1.FinalizerOrphanDependents)
+	}
+
+	err := clients.Interface.Update(context.TODO(), &r.specialresource)
+	if err != nil {
+		log.Info("Updating orphan finalizer failed", "error", fmt.Sprintf("%v", err))
+		return err
+	}
+	return nil
+}
+
+// driverPod a driver container pod and the modules it loaded
+type driverPod struct {
+	node    string
+	image   string
+	account string
+	modules []string
+}
+
+// unloadDrivers tears down the driver container DaemonSets of the
+// SpecialResource: the consumers of the driver are evicted, the DaemonSets
+// deleted and the modules unloaded on every node they ran on. The drivers
+// of each node are recorded in the status before anything is deleted, an
+// error keeps the finalizer and the retry continues with the nodes of the
+// record that are not unloaded yet.
+func unloadDrivers(r *SpecialResourceReconciler) error {
+
+	namespace := r.specialresource.Spec.Namespace
+	teardown := r.specialresource.Spec.Teardown
+
+	owned, err := driverDaemonSets(r)
+	if err != nil {
+		return err
+	}
+
+	if len(r.specialresource.Status.Teardown) == 0 {
+		drivers := []driverPod{}
+		for _, ds := range owned {
+			pods, err := driverPods(ds)
+			if err != nil {
+				return err
+			}
+			drivers = append(drivers, pods...)
+		}
+		if err := updateTeardown(r, teardownPlan(drivers)); err != nil {
+			return errors.Wrap(err, "Cannot record the drivers to unload")
+		}
+	}
+
+	pending := []srov1beta1.SpecialResourceNodeTeardown{}
+	for _, node := range r.specialresource.Status.Teardown {
+		if node.Phase != srov1beta1.TeardownUnloaded {
+			pending = append(pending, node)
+		}
+	}
+
+	taint := teardownTaint(r.specialresource.Name)
+
+	if len(teardown.ConsumerSelector) > 0 {
+		for _, node := range pending {
+			setTeardownPhase(r, node.Node, srov1beta1.TeardownEvicting, "")
+			// The scheduler would put evicted consumers right back
+			if err := setTaint(node.Node, taint, true); err != nil {
+				setTeardownPhase(r, node.Node, srov1beta1.TeardownFailed, err.Error())
+				return err
+			}
+			if err := evictConsumers(node.Node, teardown.ConsumerSelector); err != nil {
+				setTeardownPhase(r, node.Node, srov1beta1.TeardownFailed, err.Error())
+				return err
+			}
+		}
+	}
+
+	for _, node := range pending {
+		setTeardownPhase(r, node.Node, srov1beta1.TeardownUnloading, "")
+	}
+
+	for _, ds := range owned {
+
+		log.Info("Deleting driver container", "DaemonSet", ds.GetName(), "Namespace", namespace)
+
+		policy := metav1.DeletePropagationForeground
+		err = clients.Workload().Delete(context.TODO(), ds, &client.DeleteOptions{PropagationPolicy: &policy})
+		if client.IgnoreNotFound(err) != nil {
+			return errors.Wrap(err, "Cannot delete DaemonSet "+ds.GetName())
+		}
+
+		if err := poll.ForResourceUnavailability(ds); err != nil {
+			return errors.Wrap(err, "DaemonSet "+ds.GetName()+" was not deleted")
+		}
+	}
+
+	for _, node := range pending {
+		for _, recorded := range node.Drivers {
+			driver := driverPod{node: node.Node, image: recorded.Image, account: recorded.ServiceAccount, modules: recorded.Modules}
+			if len(driver.modules) == 0 {
+				continue
+			}
+			if teardown.Unload {
+				if err := unloadModules(r.specialresource.Name, namespace, driver); err != nil {
+					setTeardownPhase(r, node.Node, srov1beta1.TeardownFailed, err.Error())
+					return err
+				}
+			}
+			if err := checkUnloaded(r.specialresource.Name, namespace, driver); err != nil {
+				setTeardownPhase(r, node.Node, srov1beta1.TeardownFailed, err.Error())
+				return err
+			}
+		}
+		if err := setTaint(node.Node, taint, false); err != nil {
+			setTeardownPhase(r, node.Node, srov1beta1.TeardownFailed, err.Error())
+			return err
+		}
+		setTeardownPhase(r, node.Node, srov1beta1.TeardownUnloaded, "")
+	}
+
+	return nil
+}
+
+// teardownTaint of the nodes whose drivers of the SpecialResource name are
+// unloaded, the name part of the key is at most 63 characters
+func teardownTaint(name string) v1.Taint {
+	return v1.Taint{
+		Key:    TeardownTaintPrefix + hash.Truncate(name, validation.LabelValueMaxLength),
+		Effect: v1.TaintEffectNoSchedule,
+	}
+}
+
+// setTaint adds or removes taint of node
+func setTaint(name string, taint v1.Taint, tainted bool) error {
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+
+		node, err := clients.Workload().CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		found := false
+		taints := []v1.Taint{}
+		for _, t := range node.Spec.Taints {
+			if t.MatchTaint(&taint) {
+				found = true
+				continue
+			}
+			taints = append(taints, t)
+		}
+		if found == tainted {
+			return nil
+		}
+		if tainted {
+			taints = append(taints, taint)
+		}
+
+		log.Info("Updating teardown taint", "Node", name, "Taint", taint.Key, "Tainted", tainted)
+		node.Spec.Taints = taints
+		_, err = clients.Workload().CoreV1().Nodes().Update(context.TODO(), node, metav1.UpdateOptions{})
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	return errors.Wrap(err, "Cannot update taints of node "+name)
+}
+
+// driverDaemonSets returns the driver container DaemonSets of the release
+// of the SpecialResource
+func driverDaemonSets(r *SpecialResourceReconciler) ([]*unstructured.Unstructured, error) {
+
+	namespace := r.specialresource.Spec.Namespace
+
+	daemonSets := &unstructured.UnstructuredList{}
+	daemonSets.SetAPIVersion("apps/v1")
+	daemonSets.SetKind("DaemonSetList")
+
+	err := clients.Workload().List(context.TODO(), daemonSets, client.InNamespace(namespace))
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot list DaemonSets in "+namespace)
+	}
+
+	owned := []*unstructured.Unstructured{}
+	for idx, ds := range daemonSets.Items {
+		annotations := ds.GetAnnotations()
+		if annotations["specialresource.openshift.io/state"] != "driver-container" ||
+			annotations["meta.helm.sh/release-name"] != r.specialresource.Name {
+			continue
+		}
+		owned = append(owned, &daemonSets.Items[idx])
+	}
+
+	return owned, nil
+}
+
+// teardownPlan returns the nodes of the driver pods with the drivers to
+// unload, in the Pending phase, nodes keep the order of their first pod
+func teardownPlan(drivers []driverPod) []srov1beta1.SpecialResourceNodeTeardown {
+
+	plan := []srov1beta1.SpecialResourceNodeTeardown{}
+	index := map[string]int{}
+
+	for _, pod := range drivers {
+		idx, found := index[pod.node]
+		if !found {
+			idx = len(plan)
+			index[pod.node] = idx
+			plan = append(plan, srov1beta1.SpecialResourceNodeTeardown{Node: pod.node, Phase: srov1beta1.TeardownPending})
+		}
+		plan[idx].Drivers = append(plan[idx].Drivers, srov1beta1.SpecialResourceTeardownDriver{
+			Image:          pod.image,
+			ServiceAccount: pod.account,
+			Modules:        pod.modules,
+		})
+	}
+
+	return plan
+}
+
+// evictConsumers evicts the pods matching selector from node and waits until
+// they are gone, evictions a PodDisruptionBudget rejects are retried
+func evictConsumers(node string, selector map[string]string) error {
+
+	opts := metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(selector).String(),
+		FieldSelector: "spec.nodeName=" + node,
+	}
+
+	consumers := func() ([]v1.Pod, error) {
+		pods, err := clients.Workload().CoreV1().Pods("").List(context.TODO(), opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot list consumers on node "+node)
+		}
+		return pods.Items, nil
+	}
+
+	pods, err := consumers()
+	if err != nil {
+		return err
+	}
+
+	for _, pod := range pods {
+
+		log.Info("Evicting consumer", "Pod", pod.GetName(), "Namespace", pod.GetNamespace(), "Node", node)
+
+		eviction := &policyv1beta1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{Name: pod.GetName(), Namespace: pod.GetNamespace()},
+		}
+
+		err := wait.Poll(poll.RetryInterval, poll.Timeout, func() (bool, error) {
+			err := clients.Workload().PolicyV1beta1().Evictions(pod.GetNamespace()).Evict(context.TODO(), eviction)
+			if apierrors.IsTooManyRequests(err) {
+				return false, nil
+			}
+			return true, client.IgnoreNotFound(err)
+		})
+		if err != nil {
+			return errors.Wrap(err, "Cannot evict consumer "+pod.GetNamespace()+"/"+pod.GetName())
+		}
+	}
+
+	err = wait.Poll(poll.RetryInterval, poll.Timeout, func() (bool, error) {
+		pods, err := consumers()
+		return len(pods) == 0, err
+	})
+	return errors.Wrap(err, "Consumers on node "+node+" were not evicted")
+}
+
+// setTeardownPhase updates the teardown phase of node in the status, the
+// recorded drivers of the node are kept
+func setTeardownPhase(r *SpecialResourceReconciler, node string, phase string, message string) {
+
+	teardown := []srov1beta1.SpecialResourceNodeTeardown{}
+	for _, status := range r.specialresource.Status.Teardown {
+		if status.Node == node {
+			status.Phase, status.Message = phase, message
+		}
+		teardown = append(teardown, status)
+	}
+
+	warn.OnError(errors.Wrap(updateTeardown(r, teardown), "Cannot update SpecialResource teardown"))
+}
+
+// updateTeardown writes teardown to the status of the SpecialResource
+func updateTeardown(r *SpecialResourceReconciler, teardown []srov1beta1.SpecialResourceNodeTeardown) error {
+
+	update := srov1beta1.SpecialResource{}
+
+	objectKey := types.NamespacedName{Name: r.specialresource.GetName(), Namespace: r.specialresource.GetNamespace()}
+	if err := clients.Interface.Get(context.TODO(), objectKey, &update); err != nil {
+		return errors.Wrap(err, "Cannot get current instance")
+	}
+
+	update.Status.Teardown = teardown
+
+	if err := clients.Interface.Status().Update(context.TODO(), &update); err != nil {
+		return err
+	}
+
+	r.specialresource.Status.Teardown = teardown
+	return nil
+}
+
+// driverPods returns the nodes running a pod of ds with the image and the
+// modules of the driver container, in the order they are loaded
+func driverPods(ds *unstructured.Unstructured) ([]driverPod, error) {
+
+	modules := resource.Modules(ds)
+
+	matchLabels, _, err := unstructured.NestedStringMap(ds.Object, "spec", "selector", "matchLabels")
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid selector of DaemonSet "+ds.GetName())
+	}
+
+	pods := &v1.PodList{}
+	if err := clients.Workload().List(context.TODO(), pods, client.InNamespace(ds.GetNamespace()), client.MatchingLabels(matchLabels)); err != nil {
+		return nil, errors.Wrap(err, "Cannot list pods of DaemonSet "+ds.GetName())
+	}
+
+	return podDrivers(ds, pods.Items, modules), nil
+}
+
+// podDrivers returns the drivers of the scheduled pods controlled by ds,
+// other pods matching the selector are not drivers of ds
+func podDrivers(ds *unstructured.Unstructured, pods []v1.Pod, modules []string) []driverPod {
+
+	drivers := []driverPod{}
+	for idx := range pods {
+		pod := &pods[idx]
+		if !metav1.IsControlledBy(pod, ds) || pod.Spec.NodeName == "" || len(pod.Spec.Containers) == 0 {
+			continue
+		}
+		drivers = append(drivers, driverPod{
+			node:    pod.Spec.NodeName,
+			image:   pod.Spec.Containers[0].Image,
+			account: pod.Spec.ServiceAccountName,
+			modules: modules,
+		})
+	}
+
+	return drivers
+}
+
+// unloadModules runs a privileged pod with the driver container image on the
+// node of driver that removes the modules in the reverse order of loading
+func unloadModules(name string, namespace string, driver driverPod) error {
+
+	command := unloadCommand(driver.modules)
+
+	log.Info("Unloading modules", "Node", driver.node, "Command", command)
+
+	succeeded, err := runNodePod(name+"-unload-", namespace, driver, command, true)
+	if err != nil {
+		return errors.Wrap(err, "Unload on node "+driver.node+" did not complete")
+	}
+	if !succeeded {
+		return errors.New("Cannot unload modules " + strings.Join(driver.modules, ", ") + " on node " + driver.node)
+	}
+
+	return nil
+}
+
+// checkUnloaded runs a pod with the driver container image on the node of
+// driver that fails while any of the modules is still loaded
+func checkUnloaded(name string, namespace string, driver driverPod) error {
+
+	command := checkCommand(driver.modules)
+
+	log.Info("Checking modules are unloaded", "Node", driver.node, "Modules", driver.modules)
+
+	succeeded, err := runNodePod(name+"-unload-check-", namespace, driver, command, false)
+	if err != nil {
+		return errors.Wrap(err, "Unload check on node "+driver.node+" did not complete")
+	}
+	if !succeeded {
+		return errors.New("Modules " + strings.Join(driver.modules, ", ") + " are still loaded on node " + driver.node)
+	}
+
+	return nil
+}
+
+// unloadCommand removes modules in the reverse order of loading, module
+// names are arguments, never part of a shell command
+func unloadCommand(modules []string) []string {
+
+	command := []string{"modprobe", "-r", "--"}
+	for idx := len(modules) - 1; idx >= 0; idx-- {
+		command = append(command, modules[idx])
+	}
+
+	return command
+}
+
+// checkCommand fails while any of modules is loaded, sysfs names modules
+// with underscores, the modules are the positional parameters of the script
+func checkCommand(modules []string) []string {
+
+	script := `for module in "$@"; do
+  if [ -d "/sys/module/$(echo "$module" | tr - _)" ]; then echo "$module is loaded"; exit 1; fi
+done`
+
+	return append([]string{"/bin/sh", "-c", script, "sh"}, modules...)
+}
+
+// runNodePod runs command in a pod with the driver container image on the
+// node of driver and returns whether it succeeded, the pod is deleted after
+func runNodePod(generateName string, namespace string, driver driverPod, command []string, privileged bool) (bool, error) {
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: generateName,
+			Namespace:    namespace,
+		},
+		Spec: v1.PodSpec{
+			NodeName:           driver.node,
+			RestartPolicy:      v1.RestartPolicyNever,
+			ServiceAccountName: driver.account,
+			Tolerations:        []v1.Toleration{{Operator: v1.TolerationOpExists}},
+			Containers: []v1.Container{{
+				Name:            "teardown",
+				Image:           driver.image,
+				Command:         command,
+				SecurityContext: &v1.SecurityContext{Privileged: &privileged},
+			}},
+		},
+	}
+
+	if err := clients.Workload().Create(context.TODO(), pod); err != nil {
+		return false, errors.Wrap(err, "Cannot create pod on node "+driver.node)
+	}
+
+	defer func() {
+		err := clients.Workload().Delete(context.TODO(), pod)
+		if client.IgnoreNotFound(err) != nil {
+			log.Info("Cannot delete teardown pod", "Pod", pod.GetName(), "error", fmt.Sprintf("%v", err))
+		}
+	}()
+
+	var phase v1.PodPhase
+	err := wait.Poll(poll.RetryInterval, poll.Timeout, func() (bool, error) {
+		found := &v1.Pod{}
+		if err := clients.Workload().Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: pod.GetName()}, found); err != nil {
+			return false, err
+		}
+		phase = found.Status.Phase
+		return phase == v1.PodSucceeded || phase == v1.PodFailed, nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return phase == v1.PodSucceeded, nil
+}
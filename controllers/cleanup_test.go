@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestTeardownPlan(t *testing.T) {
+
+	drivers := []driverPod{
+		{node: "worker-1", image: "driver:a", account: "driver", modules: []string{"nvidia", "nvidia_uvm"}},
+		{node: "worker-0", image: "driver:a", account: "driver", modules: []string{"nvidia", "nvidia_uvm"}},
+		{node: "worker-1", image: "peermem:a", modules: []string{"nvidia_peermem"}},
+	}
+
+	want := []srov1beta1.SpecialResourceNodeTeardown{
+		{
+			Node:  "worker-1",
+			Phase: srov1beta1.TeardownPending,
+			Drivers: []srov1beta1.SpecialResourceTeardownDriver{
+				{Image: "driver:a", ServiceAccount: "driver", Modules: []string{"nvidia", "nvidia_uvm"}},
+				{Image: "peermem:a", Modules: []string{"nvidia_peermem"}},
+			},
+		},
+		{
+			Node:  "worker-0",
+			Phase: srov1beta1.TeardownPending,
+			Drivers: []srov1beta1.SpecialResourceTeardownDriver{
+				{Image: "driver:a", ServiceAccount: "driver", Modules: []string{"nvidia", "nvidia_uvm"}},
+			},
+		},
+	}
+
+	if plan := teardownPlan(drivers); !reflect.DeepEqual(plan, want) {
+		t.Errorf("teardownPlan = %+v, want %+v", plan, want)
+	}
+
+	if plan := teardownPlan(nil); len(plan) != 0 {
+		t.Errorf("teardownPlan(nil) = %+v, want no nodes", plan)
+	}
+}
+
+func TestPodDrivers(t *testing.T) {
+
+	ds := &unstructured.Unstructured{}
+	ds.SetAPIVersion("apps/v1")
+	ds.SetKind("DaemonSet")
+	ds.SetName("simple-kmod-driver-container")
+	ds.SetUID(types.UID("ds"))
+
+	controller := true
+	pod := func(name string, uid types.UID, node string, containers ...v1.Container) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "apps/v1", Kind: "DaemonSet", Name: ds.GetName(), UID: uid, Controller: &controller,
+				}},
+			},
+			Spec: v1.PodSpec{NodeName: node, ServiceAccountName: "driver", Containers: containers},
+		}
+	}
+	driver := v1.Container{Name: "driver", Image: "driver:a"}
+
+	pods := []v1.Pod{
+		pod("driver-0", "ds", "worker-0", driver, v1.Container{Name: "sidecar", Image: "sidecar:a"}),
+		// A pod of another DaemonSet matching the selector
+		pod("other-0", "other", "worker-0", driver),
+		// Not scheduled yet
+		pod("driver-1", "ds", "", driver),
+		pod("driver-2", "ds", "worker-2"),
+		pod("driver-3", "ds", "worker-3", driver),
+	}
+
+	modules := []string{"simple-kmod", "simple-procfs-kmod"}
+	want := []driverPod{
+		{node: "worker-0", image: "driver:a", account: "driver", modules: modules},
+		{node: "worker-3", image: "driver:a", account: "driver", modules: modules},
+	}
+
+	if drivers := podDrivers(ds, pods, modules); !reflect.DeepEqual(drivers, want) {
+		t.Errorf("podDrivers = %+v, want %+v", drivers, want)
+	}
+}
+
+func TestUnloadCommand(t *testing.T) {
+
+	tests := []struct {
+		modules []string
+		command []string
+	}{
+		{
+			[]string{"nvidia", "nvidia_modeset", "nvidia_uvm"},
+			[]string{"modprobe", "-r", "--", "nvidia_uvm", "nvidia_modeset", "nvidia"},
+		},
+		// Names are arguments, not options or shell words
+		{
+			[]string{"-a", "kmod; reboot", "$(reboot)"},
+			[]string{"modprobe", "-r", "--", "$(reboot)", "kmod; reboot", "-a"},
+		},
+	}
+
+	for _, test := range tests {
+		if command := unloadCommand(test.modules); !reflect.DeepEqual(command, test.command) {
+			t.Errorf("unloadCommand(%q) = %q, want %q", test.modules, command, test.command)
+		}
+	}
+}
+
+func TestCheckCommand(t *testing.T) {
+
+	if _, err := os.Stat("/sys/module"); err != nil {
+		t.Skip("No /sys/module to check modules against")
+	}
+
+	marker := filepath.Join(t.TempDir(), "injected")
+
+	tests := []struct {
+		modules []string
+		loaded  bool
+	}{
+		{[]string{"sro-test-not-loaded"}, false},
+		{nil, false},
+		// Module names are never run
+		{[]string{"x; touch " + marker, "$(touch " + marker + ")", "`touch " + marker + "`"}, false},
+	}
+
+	// Any module of the running kernel, sysfs uses underscores for dashes
+	if entries, err := os.ReadDir("/sys/module"); err == nil && len(entries) > 0 {
+		name := strings.ReplaceAll(entries[0].Name(), "_", "-")
+		tests = append(tests, struct {
+			modules []string
+			loaded  bool
+		}{[]string{"sro-test-not-loaded", name}, true})
+	}
+
+	for _, test := range tests {
+
+		command := checkCommand(test.modules)
+		if command[0] != "/bin/sh" || command[3] != "sh" || !reflect.DeepEqual(command[4:], append([]string{}, test.modules...)) {
+			t.Errorf("checkCommand(%q) = %q, want the modules as positional parameters", test.modules, command)
+			continue
+		}
+
+		err := exec.Command(command[0], command[1:]...).Run() // #nosec G204 -- the command under test
+		if loaded := err != nil; loaded != test.loaded {
+			t.Errorf("checkCommand(%q) reports loaded = %v, want %v: %v", test.modules, loaded, test.loaded, err)
+		}
+	}
+
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("A module name was run as a command")
+	}
+}
+
+func TestTeardownTaint(t *testing.T) {
+
+	for _, name := range []string{
+		"simple-kmod",
+		strings.Repeat("a", validation.LabelValueMaxLength),
+		strings.Repeat("nvidia-gpu-", 20),
+	} {
+		taint := teardownTaint(name)
+
+		if errs := validation.IsQualifiedName(taint.Key); len(errs) > 0 {
+			t.Errorf("teardownTaint(%q) key %q is invalid: %v", name, taint.Key, errs)
+		}
+		if !strings.HasPrefix(taint.Key, TeardownTaintPrefix) || taint.Effect != v1.TaintEffectNoSchedule {
+			t.Errorf("teardownTaint(%q) = %+v, want a NoSchedule taint with prefix %s", name, taint, TeardownTaintPrefix)
+		}
+		if len(name) <= validation.LabelValueMaxLength && taint.Key != TeardownTaintPrefix+name {
+			t.Errorf("teardownTaint(%q) = %q, want the name unchanged", name, taint.Key)
+		}
+	}
+
+	// Long names that share a prefix get different taints
+	if teardownTaint(strings.Repeat("a", 70)+"-1").Key == teardownTaint(strings.Repeat("a", 70)+"-2").Key {
+		t.Errorf("teardownTaint of different names is equal")
+	}
+}
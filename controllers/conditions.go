@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/openshift-psap/special-resource-operator/pkg/resource"
+	"github.com/openshift-psap/special-resource-operator/pkg/scan"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// setStatusCondition updates a single condition of sr, the other status
+// fields are preserved.
+func setStatusCondition(sr *srov1beta1.SpecialResource, condition metav1.Condition) {
+	setStatusConditions(sr, condition)
+}
+
+// setStatusConditions updates the conditions of sr with one status update,
+// the observedGeneration is the generation of the current instance.
+func setStatusConditions(sr *srov1beta1.SpecialResource, conditions ...metav1.Condition) {
+
+	update := srov1beta1.SpecialResource{}
+
+	objectKey := types.NamespacedName{Name: sr.GetName(), Namespace: sr.GetNamespace()}
+	if err := clients.Interface.Get(context.TODO(), objectKey, &update); err != nil {
+		warn.OnError(errors.Wrap(err, "Is SR being deleted? Cannot get current instance"))
+		return
+	}
+
+	changed := false
+
+	for _, condition := range conditions {
+		condition.ObservedGeneration = update.GetGeneration()
+
+		if existing := meta.FindStatusCondition(update.Status.Conditions, condition.Type); existing != nil &&
+			existing.Status == condition.Status && existing.Reason == condition.Reason &&
+			existing.Message == condition.Message && existing.ObservedGeneration == condition.ObservedGeneration {
+			continue
+		}
+
+		meta.SetStatusCondition(&update.Status.Conditions, condition)
+		changed = true
+	}
+
+	if changed {
+		if err := clients.Interface.Status().Update(context.TODO(), &update); err != nil {
+			warn.OnError(errors.Wrap(err, "Cannot update SpecialResource conditions"))
+			return
+		}
+	}
+
+	// Only take the status, the spec of sr may be templated in memory
+	update.Status.DeepCopyInto(&sr.Status)
+	sr.SetResourceVersion(update.GetResourceVersion())
+}
+
+// conditionsReconciling marks sr as Progressing, Ready is only initialized
+// so that kubectl wait does not flap on every reconcile.
+func conditionsReconciling(sr *srov1beta1.SpecialResource) {
+
+	conditions := []metav1.Condition{{
+		Type:    srov1beta1.ConditionProgressing,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Reconciling",
+		Message: "Reconciling chart " + sr.Spec.Chart.Name,
+	}}
+
+	if meta.FindStatusCondition(sr.Status.Conditions, srov1beta1.ConditionReady) == nil {
+		conditions = append(conditions, metav1.Condition{
+			Type:    srov1beta1.ConditionReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Reconciling",
+			Message: "Reconciling chart " + sr.Spec.Chart.Name,
+		})
+	}
+
+	setStatusConditions(sr, conditions...)
+}
+
+// conditionsReconciled marks sr as Ready, the reason is NoChanges if the
+// reconcile did not create, update or prune any object
+func conditionsReconciled(sr *srov1beta1.SpecialResource) {
+
+	reason := "Reconciled"
+	message := "All resources of chart " + sr.Spec.Chart.Name + " reconciled"
+
+	if resource.Changes == 0 {
+		reason = "NoChanges"
+		message = "All resources of chart " + sr.Spec.Chart.Name + " up to date, nothing applied"
+	}
+
+	setStatusConditions(sr,
+		metav1.Condition{
+			Type:    srov1beta1.ConditionReady,
+			Status:  metav1.ConditionTrue,
+			Reason:  reason,
+			Message: message,
+		},
+		metav1.Condition{
+			Type:    srov1beta1.ConditionProgressing,
+			Status:  metav1.ConditionFalse,
+			Reason:  reason,
+			Message: message,
+		},
+		metav1.Condition{
+			Type:    srov1beta1.ConditionDegraded,
+			Status:  metav1.ConditionFalse,
+			Reason:  reason,
+			Message: message,
+		},
+	)
+}
+
+// conditionsConflicts reports the fields taken over from other field
+// managers, the condition is only set once a conflict occurred.
+func conditionsConflicts(sr *srov1beta1.SpecialResource, conflicts []string) {
+
+	if len(conflicts) > 0 {
+		message := strings.Join(conflicts, "; ")
+		// Conditions are limited to 32768 characters
+		if len(conflicts) > 10 {
+			message = strings.Join(conflicts[:10], "; ") + "; and " + strconv.Itoa(len(conflicts)-10) + " more"
+		}
+		if len(message) > 32000 {
+			message = message[:32000] + "..."
+		}
+		setStatusCondition(sr, metav1.Condition{
+			Type:    srov1beta1.ConditionFieldConflicts,
+			Status:  metav1.ConditionTrue,
+			Reason:  "FieldsTakenOver",
+			Message: message,
+		})
+		return
+	}
+
+	if meta.FindStatusCondition(sr.Status.Conditions, srov1beta1.ConditionFieldConflicts) != nil {
+		setStatusCondition(sr, metav1.Condition{
+			Type:    srov1beta1.ConditionFieldConflicts,
+			Status:  metav1.ConditionFalse,
+			Reason:  "NoConflicts",
+			Message: "No fields of other field managers were changed",
+		})
+	}
+}
+
+// conditionsVulnerabilityScan reports whether the vulnerability scans of the
+// driver containers blocked the rollout, other errors leave the condition
+func conditionsVulnerabilityScan(sr *srov1beta1.SpecialResource, err error) {
+
+	if resource.ImageScanner == nil {
+		return
+	}
+
+	var vulnerable *scan.VulnerabilityError
+	if errors.As(err, &vulnerable) {
+		setStatusCondition(sr, metav1.Condition{
+			Type:    srov1beta1.ConditionVulnerabilityScanPassed,
+			Status:  metav1.ConditionFalse,
+			Reason:  "VulnerabilitiesFound",
+			Message: vulnerable.Error(),
+		})
+		return
+	}
+
+	if err == nil {
+		setStatusCondition(sr, metav1.Condition{
+			Type:    srov1beta1.ConditionVulnerabilityScanPassed,
+			Status:  metav1.ConditionTrue,
+			Reason:  "NoVulnerabilities",
+			Message: "No driver container image has vulnerabilities of severity " + resource.ImageScanner.Severity + " or above",
+		})
+	}
+}
+
+// failedReason returns the reason of the Degraded condition for err
+func failedReason(err error) string {
+	var invalid *resource.ValidationError
+	if errors.As(err, &invalid) {
+		return "ValidationFailed"
+	}
+	var vulnerable *scan.VulnerabilityError
+	if errors.As(err, &vulnerable) {
+		return "VulnerabilitiesFound"
+	}
+	var schema1 *registry.Schema1Error
+	if errors.As(err, &schema1) {
+		return "UnsupportedManifestSchema1"
+	}
+	return "ReconcileFailed"
+}
+
+// conditionsFailed marks sr as Degraded and not Ready, the reconcile is
+// retried so it stays Progressing.
+func conditionsFailed(sr *srov1beta1.SpecialResource, reason string, err error) {
+	setStatusConditions(sr,
+		metav1.Condition{
+			Type:    srov1beta1.ConditionReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  reason,
+			Message: err.Error(),
+		},
+		metav1.Condition{
+			Type:    srov1beta1.ConditionDegraded,
+			Status:  metav1.ConditionTrue,
+			Reason:  reason,
+			Message: err.Error(),
+		},
+	)
+}
+
+// conditionsState sets BuildSucceeded or DriverLoaded if the state of the
+// chart builds or runs the driver container
+func conditionsState(sr *srov1beta1.SpecialResource, stateName string, stateYAML []byte, err error) {
+
+	conditionType := ""
+
+	switch {
+	case isBuildState(stateYAML):
+		conditionType = srov1beta1.ConditionBuildSucceeded
+	case isDriverContainerState(stateName):
+		conditionType = srov1beta1.ConditionDriverLoaded
+	default:
+		return
+	}
+
+	if err != nil {
+		setStatusCondition(sr, metav1.Condition{
+			Type:    conditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "StateFailed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	setStatusCondition(sr, metav1.Condition{
+		Type:    conditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "StateReady",
+		Message: "State " + stateName + " ready",
+	})
+}
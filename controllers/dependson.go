@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"strings"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// dependsOnWaiting returns why sr cannot be reconciled yet, an empty string
+// if all SpecialResources in DependsOn are Ready. An error is returned if
+// the dependencies contain a cycle.
+func dependsOnWaiting(sr srov1beta1.SpecialResource, list *srov1beta1.SpecialResourceList) (string, error) {
+
+	byName := make(map[string]srov1beta1.SpecialResource)
+	for _, item := range list.Items {
+		byName[item.Name] = item
+	}
+
+	if err := dependsOnCycle(sr.Name, byName, map[string]bool{}, []string{}); err != nil {
+		return "", err
+	}
+
+	waiting := []string{}
+
+	for _, name := range sr.Spec.DependsOn {
+		dep, found := byName[name]
+		if !found {
+			waiting = append(waiting, name+" (not found)")
+			continue
+		}
+		ready := meta.FindStatusCondition(dep.Status.Conditions, srov1beta1.ConditionReady)
+		if ready == nil {
+			waiting = append(waiting, name+" (not reconciled)")
+		} else if ready.Status != metav1.ConditionTrue {
+			waiting = append(waiting, name+" ("+ready.Reason+")")
+		} else if ready.ObservedGeneration != dep.Generation {
+			waiting = append(waiting, name+" (outdated)")
+		}
+	}
+
+	if len(waiting) > 0 {
+		return "Waiting for " + strings.Join(waiting, ", "), nil
+	}
+	return "", nil
+}
+
+// dependsOnCycle walks the DependsOn edges depth first, a SpecialResource
+// that is visited again on the current path closes a cycle.
+func dependsOnCycle(name string, byName map[string]srov1beta1.SpecialResource, done map[string]bool, path []string) error {
+
+	for _, visited := range path {
+		if visited == name {
+			return errors.New("Dependency cycle " + strings.Join(append(path, name), " -> "))
+		}
+	}
+
+	if done[name] {
+		return nil
+	}
+
+	// Missing SpecialResources have no edges, they are reported as waiting
+	for _, dep := range byName[name].Spec.DependsOn {
+		if err := dependsOnCycle(dep, byName, done, append(path, name)); err != nil {
+			return err
+		}
+	}
+
+	done[name] = true
+	return nil
+}
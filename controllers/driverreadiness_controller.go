@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/color"
+	"github.com/openshift-psap/special-resource-operator/pkg/readiness"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlevent "sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var (
+	drlog logr.Logger
+)
+
+// DriverReadinessReconciler keeps the driver-ready label of the nodes in
+// sync with the pods of the driver container DaemonSets, between the
+// reconciles of the SpecialResource
+type DriverReadinessReconciler struct {
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// Options of the work queue
+	Options ReconcileOptions
+}
+
+// Reconcile labels the nodes of all driver container DaemonSets of the
+// SpecialResource that released the DaemonSet of the request
+func (r *DriverReadinessReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+
+	ds := &appsv1.DaemonSet{}
+
+	if err := clients.Workload().Get(ctx, req.NamespacedName, ds); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, errors.Wrap(err, "Cannot get DaemonSet "+req.String())
+	}
+
+	if !readiness.IsDriverContainer(ds.GetAnnotations()) {
+		return reconcile.Result{}, nil
+	}
+
+	sr := readiness.Release(ds.GetAnnotations())
+
+	if err := readiness.UpdateNodes(ds.GetNamespace(), sr); err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "Cannot label nodes of SpecialResource "+sr)
+	}
+
+	drlog.Info("RECONCILE SUCCESS: Driver readiness labeled", "SpecialResource", sr, "DaemonSet", req.String())
+	return reconcile.Result{}, nil
+}
+
+// driverDaemonSet filters the driver container DaemonSets
+func driverDaemonSet() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return readiness.IsDriverContainer(obj.GetAnnotations())
+	})
+}
+
+// driverPodReadiness filters the pods of DaemonSets whose readiness changed
+func driverPodReadiness() predicate.Predicate {
+
+	ofDaemonSet := func(obj client.Object) bool {
+		owner := metav1.GetControllerOf(obj)
+		return owner != nil && owner.Kind == "DaemonSet"
+	}
+
+	return predicate.Funcs{
+		CreateFunc: func(e ctrlevent.CreateEvent) bool {
+			return ofDaemonSet(e.Object)
+		},
+		DeleteFunc: func(e ctrlevent.DeleteEvent) bool {
+			return ofDaemonSet(e.Object)
+		},
+		UpdateFunc: func(e ctrlevent.UpdateEvent) bool {
+			old, ok := e.ObjectOld.(*v1.Pod)
+			if !ok {
+				return false
+			}
+			pod, ok := e.ObjectNew.(*v1.Pod)
+			if !ok {
+				return false
+			}
+			return ofDaemonSet(pod) && (podReady(old) != podReady(pod) || pod.GetDeletionTimestamp() != nil)
+		},
+		GenericFunc: func(e ctrlevent.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+func podReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// SetupWithManager main initalization for manager
+func (r *DriverReadinessReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	drlog = r.Log.WithName(color.Print("readiness", color.Green))
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("driverreadiness").
+		For(&appsv1.DaemonSet{}, builder.WithPredicates(driverDaemonSet())).
+		Watches(&source.Kind{Type: &v1.Pod{}},
+			&handler.EnqueueRequestForOwner{OwnerType: &appsv1.DaemonSet{}, IsController: true},
+			builder.WithPredicates(driverPodReadiness())).
+		WithOptions(r.Options.controllerOptions()).
+		Complete(r)
+}
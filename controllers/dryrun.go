@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"context"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/assets"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/helmer"
+	"github.com/openshift-psap/special-resource-operator/pkg/state"
+	"github.com/openshift-psap/special-resource-operator/pkg/upgrade"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+)
+
+// DryRunAnnotation renders the chart of a SpecialResource into a ConfigMap
+// instead of applying it, for troubleshooting the templates
+const DryRunAnnotation = "specialresource.openshift.io/dry-run"
+
+// maxConfigMapSize the API server rejects larger ConfigMaps
+const maxConfigMapSize = 1 << 20
+
+// isDryRun is true if the operator runs with --dry-run or sr is annotated
+func isDryRun(r *SpecialResourceReconciler, sr *srov1beta1.SpecialResource) bool {
+	return r.DryRun || sr.GetAnnotations()[DryRunAnnotation] == "true"
+}
+
+// dryRunConfigMap the rendered manifests of the SpecialResource name are
+// written to
+func dryRunConfigMap(name string) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + "-dry-run",
+			Namespace: os.Getenv("OPERATOR_NAMESPACE"),
+		},
+	}
+}
+
+// renderChart renders the chart with the resolved RunInfo into the dry-run
+// ConfigMap, nothing is applied.
+func renderChart(r *SpecialResourceReconciler) error {
+
+	manifests, err := renderManifests(r, nil)
+	if err != nil {
+		return err
+	}
+
+	size := 0
+	for key, manifest := range manifests {
+		size += len(key) + len(manifest)
+	}
+	if size > maxConfigMapSize {
+		return errors.Errorf("Rendered manifests have %d bytes, more than a ConfigMap can store", size)
+	}
+
+	cm := dryRunConfigMap(r.specialresource.Name)
+
+	res, err := controllerutil.CreateOrUpdate(context.TODO(), clients.Interface, cm, func() error {
+		cm.Data = manifests
+		return controllerutil.SetOwnerReference(&r.specialresource, cm, r.Scheme)
+	})
+	if err != nil {
+		return errors.Wrap(err, "Cannot write rendered manifests to ConfigMap "+cm.GetName())
+	}
+
+	log.Info("Rendered chart", "ConfigMap", cm.GetNamespace()+"/"+cm.GetName(), "operation", res)
+
+	setStatusConditions(&r.specialresource,
+		metav1.Condition{
+			Type:    srov1beta1.ConditionDryRun,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Rendered",
+			Message: "Rendered manifests written to ConfigMap " + cm.GetNamespace() + "/" + cm.GetName(),
+		},
+		metav1.Condition{
+			Type:    srov1beta1.ConditionProgressing,
+			Status:  metav1.ConditionFalse,
+			Reason:  "DryRun",
+			Message: "Chart rendered, nothing applied",
+		})
+
+	return nil
+}
+
+// renderManifests renders the states, once per kernel for kernel affine
+// ones, and the templates without a state with the resolved RunInfo. With
+// facts the chart is rendered without a cluster.
+func renderManifests(r *SpecialResourceReconciler, facts *Facts) (map[string]string, error) {
+
+	if len(RunInfo.ClusterUpgradeInfo) == 0 {
+		return nil, errors.New("No KernelVersion detected, something is wrong")
+	}
+
+	manifests := make(map[string]string)
+
+	info, err := yaml.Marshal(RunInfo)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot marshal RunInfo")
+	}
+	manifests["runinfo.yaml"] = string(info)
+
+	nostate := r.chart
+	nostate.Templates = []*chart.File{}
+
+	stateYAMLS := []*chart.File{}
+
+	for _, template := range r.chart.Templates {
+		if assets.ValidStateName(template.Name) {
+			stateYAMLS = append(stateYAMLS, template)
+		} else {
+			nostate.Templates = append(nostate.Templates, template)
+		}
+	}
+
+	sort.Slice(stateYAMLS, func(i, j int) bool {
+		return stateYAMLS[i].Name < stateYAMLS[j].Name
+	})
+
+	for _, stateYAML := range stateYAMLS {
+
+		log.Info("Rendering", "State", stateYAML.Name)
+
+		state.GenerateName(stateYAML, r.specialresource.Name)
+
+		step := nostate
+		step.Templates = append(append([]*chart.File{}, nostate.Templates...), stateYAML)
+
+		kernelAffine := strings.Contains(string(stateYAML.Data), ".Values.kernelFullVersion")
+
+		var version upgrade.NodeVersion
+
+		for RunInfo.KernelFullVersion, version = range RunInfo.ClusterUpgradeInfo {
+
+			if facts != nil {
+				applyNodeVersion(r, version)
+			} else if err := setNodeVersion(r, version); err != nil {
+				return nil, err
+			}
+
+			key := path.Base(stateYAML.Name)
+			if kernelAffine {
+				ext := path.Ext(key)
+				key = strings.TrimSuffix(key, ext) + "-" + RunInfo.KernelFullVersion + ext
+			}
+
+			if manifests[key], err = renderStep(r, step, facts); err != nil {
+				return nil, errors.Wrap(err, "Cannot render state "+stateYAML.Name)
+			}
+
+			if !kernelAffine {
+				break
+			}
+		}
+	}
+
+	if manifests["nostate.yaml"], err = renderStep(r, nostate, facts); err != nil {
+		return nil, errors.Wrap(err, "Cannot render templates without state")
+	}
+
+	return manifests, nil
+}
+
+// renderStep coalesces the values of ch like the reconcile of a state does
+func renderStep(r *SpecialResourceReconciler, ch chart.Chart, facts *Facts) (string, error) {
+
+	var err error
+
+	ch.Values, err = chartutil.CoalesceValues(&ch, r.values.Object)
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot coalesce values")
+	}
+
+	rinfo, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&RunInfo)
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot convert RunInfo")
+	}
+
+	ch.Values, err = chartutil.CoalesceValues(&ch, rinfo)
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot coalesce RunInfo")
+	}
+
+	if facts != nil {
+		return helmer.RenderOffline(ch, ch.Values, r.postRenderer, r.specialresource.Spec.Namespace, facts.KubeVersion)
+	}
+	return helmer.Render(ch, ch.Values, r.postRenderer, r.specialresource.Spec.Namespace)
+}
+
+// resumeFromDryRun clears the DryRun condition and removes the rendered
+// manifests once the chart of sr is applied again
+func resumeFromDryRun(sr *srov1beta1.SpecialResource) {
+
+	if !meta.IsStatusConditionTrue(sr.Status.Conditions, srov1beta1.ConditionDryRun) {
+		return
+	}
+
+	err := clients.Interface.Delete(context.TODO(), dryRunConfigMap(sr.Name))
+	warn.OnError(errors.Wrap(client.IgnoreNotFound(err), "Cannot delete dry-run ConfigMap"))
+
+	setStatusCondition(sr, metav1.Condition{
+		Type:    srov1beta1.ConditionDryRun,
+		Status:  metav1.ConditionFalse,
+		Reason:  "Applied",
+		Message: "Dry run disabled, the chart is applied",
+	})
+}
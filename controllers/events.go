@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/helmer"
+	"github.com/openshift-psap/special-resource-operator/pkg/resource"
+	"github.com/openshift-psap/special-resource-operator/pkg/upgrade"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Event reasons emitted on the SpecialResource
+const (
+	EventBuildStarted          = "BuildStarted"
+	EventBuildSucceeded        = "BuildSucceeded"
+	EventBuildFailed           = "BuildFailed"
+	EventDriverToolkitResolved = "DriverToolkitResolved"
+	EventHookFailed            = "HookFailed"
+	EventRolloutComplete       = "RolloutComplete"
+	EventRolloutFailed         = "RolloutFailed"
+	EventUpgradeRebuild        = "UpgradeRebuild"
+)
+
+// DTK images per kernel of the last reconcile of every SpecialResource,
+// used to only report changes and to detect kernels added by an upgrade
+var (
+	kernelsMutex      sync.Mutex
+	reconciledKernels = make(map[string]map[string]string)
+)
+
+func event(sr *srov1beta1.SpecialResource, eventtype string, reason string, message string) {
+	if clients.Interface == nil || clients.Interface.EventRecorder == nil {
+		return
+	}
+	clients.Interface.Event(sr, eventtype, reason, message)
+}
+
+// kernelEvents reports resolved DTK images and kernels that appeared since
+// the last reconcile, the first reconcile after a restart has no history.
+func kernelEvents(sr *srov1beta1.SpecialResource, info map[string]upgrade.NodeVersion) {
+
+	kernelsMutex.Lock()
+	defer kernelsMutex.Unlock()
+
+	previous, seen := reconciledKernels[sr.Name]
+	current := make(map[string]string)
+
+	for kernel, version := range info {
+		image := version.DriverToolkit.ImageURL
+		current[kernel] = image
+
+		if _, found := previous[kernel]; seen && !found {
+			event(sr, v1.EventTypeNormal, EventUpgradeRebuild, "New kernel "+kernel+" in cluster, rebuilding driver container")
+		}
+		if image != "" && previous[kernel] != image {
+			event(sr, v1.EventTypeNormal, EventDriverToolkitResolved, "Kernel "+kernel+" uses DTK "+image)
+		}
+	}
+
+	reconciledKernels[sr.Name] = current
+}
+
+// isBuildState returns true if the state builds the driver container,
+// prebuilt driver containers are never built
+func isBuildState(stateYAML []byte) bool {
+	return !resource.Prebuilt && bytes.Contains(stateYAML, []byte("kind: BuildConfig"))
+}
+
+// isDriverContainerState returns true if the state rolls out the driver
+func isDriverContainerState(stateName string) bool {
+	return strings.Contains(stateName, "driver-container")
+}
+
+// stateEvents reports the outcome of a build or driver container state and
+// failed hooks of any state
+func stateEvents(sr *srov1beta1.SpecialResource, stateName string, stateYAML []byte, err error) {
+
+	hook := &helmer.HookFailedError{}
+
+	switch {
+	case errors.As(err, &hook):
+		event(sr, v1.EventTypeWarning, EventHookFailed, "Hook of state "+stateName+" failed: "+hook.Message())
+	case isBuildState(stateYAML) && err != nil:
+		event(sr, v1.EventTypeWarning, EventBuildFailed, "Build of state "+stateName+" failed: "+err.Error())
+	case isBuildState(stateYAML):
+		event(sr, v1.EventTypeNormal, EventBuildSucceeded, "Build of state "+stateName+" completed")
+	case isDriverContainerState(stateName) && err != nil:
+		event(sr, v1.EventTypeWarning, EventRolloutFailed, "Driver container rollout of state "+stateName+" failed: "+err.Error())
+	case isDriverContainerState(stateName):
+		event(sr, v1.EventTypeNormal, EventRolloutComplete, "Driver container of state "+stateName+" running on all nodes")
+	}
+}
@@ -5,12 +5,18 @@ import (
 	"fmt"
 	"strings"
 
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
 	"github.com/openshift-psap/special-resource-operator/pkg/cache"
 	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/helmer"
 	"github.com/openshift-psap/special-resource-operator/pkg/poll"
+	"github.com/openshift-psap/special-resource-operator/pkg/readiness"
+	"github.com/openshift-psap/special-resource-operator/pkg/resource"
 	"github.com/openshift-psap/special-resource-operator/pkg/state"
 	"github.com/openshift-psap/special-resource-operator/pkg/warn"
 	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/release"
+	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -59,7 +65,7 @@ func finalizeNodes(r *SpecialResourceReconciler, remove string) error {
 		}
 
 		node.SetLabels(update)
-		err := clients.Interface.Update(context.TODO(), &node)
+		err := clients.Workload().Update(context.TODO(), &node)
 		if apierrors.IsForbidden(err) {
 			return errors.Wrap(err, "forbidden check Role, ClusterRole and Bindings for operator %s")
 		}
@@ -83,41 +89,117 @@ func finalizeSpecialResource(r *SpecialResourceReconciler) error {
 	// of finalizers include performing backups and deleting
 	// resources that are not owned by this CR, like a PVC.
 
+	if r.specialresource.Spec.CleanupPolicy == srov1beta1.CleanupPolicyOrphan {
+		// The garbage collector orphans the dependents, the node labels
+		// and the namespace are kept as well, delete hooks do not run.
+		log.Info("Orphaning resources", "SpecialResource:", r.specialresource.Name)
+		return nil
+	}
+
+	// Delete hooks run as the service account the chart was applied with
+	restore, err := impersonate(&r.specialresource)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	deleteHooks(r, release.HookPreDelete)
+
+	if r.specialresource.Spec.CleanupPolicy == srov1beta1.CleanupPolicyDeleteAndWait {
+		if err := unloadDrivers(r); err != nil {
+			return err
+		}
+	}
+
 	// If this special resources is deleted we're going to remove all
 	// specialresource labels from the nodes.
 	if r.specialresource.Name == "special-resource-preamble" {
 		err := finalizeNodes(r, "specialresource.openshift.io")
 		warn.OnError(err)
 	}
-	err := finalizeNodes(r, "specialresource.openshift.io/state-"+r.specialresource.Name)
+	err = finalizeNodes(r, "specialresource.openshift.io/state-"+r.specialresource.Name)
 	warn.OnError(err)
+	err = finalizeNodes(r, readiness.Label(r.specialresource.Name))
+	warn.OnError(err)
+
+	// The namespace is deleted last, post-delete hooks run in it
+	deleteHooks(r, release.HookPostDelete)
 
 	if r.specialresource.Name != "special-resource-preamble" {
+		finalizeNamespace(r, r.specialresource.Spec.Namespace)
+		for _, target := range r.specialresource.Spec.Targets.Namespaces {
+			finalizeNamespace(r, target.Name)
+		}
+	}
 
-		ns.SetName(r.specialresource.Spec.Namespace)
-		key := client.ObjectKeyFromObject(&ns)
+	log.Info("Successfully finalized", "SpecialResource:", r.specialresource.Name)
+	return nil
+}
 
-		err := clients.Interface.Get(context.TODO(), key, &ns)
-		if apierrors.IsNotFound(err) {
-			log.Info("Successfully finalized (IsNotFound)", "SpecialResource:", r.specialresource.Name)
-			return nil
-		}
+// finalizeNamespace deletes the namespace if the SpecialResource owns it,
+// namespaces of spec.namespaceManagement Ignore are kept
+func finalizeNamespace(r *SpecialResourceReconciler, name string) {
 
-		for _, owner := range ns.GetOwnerReferences() {
-			if owner.Kind == "SpecialResource" {
-				log.Info("Namespaces is owned by SpecialResource deleting")
-				err = clients.Interface.Delete(context.TODO(), &ns)
-				if !apierrors.IsNotFound(err) {
-					warn.OnError(err)
-				}
-				err = poll.ForResourceUnavailability(&ns)
-				warn.OnError(err)
-			}
+	if r.specialresource.Spec.NamespaceManagement == srov1beta1.NamespaceManagementIgnore {
+		log.Info("Namespace is not managed, keeping it", "namespace", name)
+		return
+	}
+
+	ns.SetName(name)
+	key := client.ObjectKeyFromObject(&ns)
+
+	err := clients.Workload().Get(context.TODO(), key, &ns)
+	if apierrors.IsNotFound(err) {
+		log.Info("Namespace already deleted (IsNotFound)", "namespace", name)
+		return
+	}
+
+	owned := ns.GetAnnotations()[resource.OwnerAnnotation] == r.specialresource.Name
+	for _, owner := range ns.GetOwnerReferences() {
+		owned = owned || owner.Kind == "SpecialResource"
+	}
+
+	if owned {
+		log.Info("Namespaces is owned by SpecialResource deleting", "namespace", name)
+		err = clients.Workload().Delete(context.TODO(), &ns)
+		if !apierrors.IsNotFound(err) {
+			warn.OnError(err)
 		}
+		err = poll.ForResourceUnavailability(&ns)
+		warn.OnError(err)
 	}
+}
 
-	log.Info("Successfully finalized", "SpecialResource:", r.specialresource.Name)
-	return nil
+// deleteHooks runs the delete hooks recorded for the SpecialResource, a
+// failed hook is reported and does not block the deletion
+func deleteHooks(r *SpecialResourceReconciler, hook release.HookEvent) {
+
+	sr := &r.specialresource
+	if sr.Spec.Namespace == "" {
+		return
+	}
+
+	namespaces := []string{sr.Spec.Namespace}
+	targets, err := targetNamespaces(sr)
+	warn.OnError(err)
+	for _, target := range targets {
+		namespaces = append(namespaces, target.Name)
+	}
+
+	for _, namespace := range namespaces {
+		err := helmer.ExecDeleteHooks(hook, sr, sr.Name, namespace)
+		if err == nil {
+			continue
+		}
+		warn.OnError(err)
+
+		message := err.Error()
+		failed := &helmer.HookFailedError{}
+		if errors.As(err, &failed) {
+			message = failed.Message()
+		}
+		event(sr, v1.EventTypeWarning, EventHookFailed, message)
+	}
 }
 
 func addFinalizer(r *SpecialResourceReconciler) error {
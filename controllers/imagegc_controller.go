@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/color"
+	"github.com/openshift-psap/special-resource-operator/pkg/imagegc"
+	"github.com/openshift-psap/special-resource-operator/pkg/kernel"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// EventImagesPruned reports the ImageStreamTags removed by the image GC
+const EventImagesPruned = "ImagesPruned"
+
+// imageGCInterval between two runs of the image GC of a SpecialResource,
+// kernel upgrades are not watched
+const imageGCInterval = time.Hour
+
+var (
+	gclog logr.Logger
+)
+
+// ImageGCReconciler prunes the driver container images of kernels no longer
+// running in the cluster
+type ImageGCReconciler struct {
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// Options of the work queue
+	Options ReconcileOptions
+}
+
+// Reconcile prunes the obsolete ImageStreamTags of a SpecialResource
+func (r *ImageGCReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+
+	sr := srov1beta1.SpecialResource{}
+
+	if err := clients.Interface.Get(ctx, req.NamespacedName, &sr); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, errors.Wrap(err, "Cannot get SpecialResource "+req.Name)
+	}
+
+	if !sr.Spec.ImageGC.Enabled || sr.GetDeletionTimestamp() != nil || sr.Spec.Namespace == "" {
+		return reconcile.Result{}, nil
+	}
+
+	if sr.GetAnnotations()[PausedAnnotation] == "true" {
+		return reconcile.Result{RequeueAfter: imageGCInterval}, nil
+	}
+
+	kernels, err := clusterKernels(ctx)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	// Images built for watched releases are kept until they are dropped
+	for _, release := range sr.Status.WatchedReleases {
+		kernels[release.KernelVersion] = true
+	}
+
+	pruned, err := imagegc.Prune(sr.Spec.Namespace, sr.Name, kernels, int(sr.Spec.ImageGC.Retention))
+	if len(pruned) > 0 {
+		event(&sr, v1.EventTypeNormal, EventImagesPruned, "Pruned ImageStreamTags "+strings.Join(pruned, ", "))
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	gclog.Info("RECONCILE SUCCESS: Images pruned", "sr", sr.Name, "pruned", len(pruned))
+	return reconcile.Result{RequeueAfter: imageGCInterval}, nil
+}
+
+// clusterKernels returns the kernels of all nodes and the target kernels of
+// PreflightValidations, images prebuilt for an upgrade are kept.
+func clusterKernels(ctx context.Context) (map[string]bool, error) {
+
+	kernels := make(map[string]bool)
+
+	inventory, err := kernel.LoadInventory()
+	if err != nil {
+		return nil, err
+	}
+	for version := range inventory {
+		kernels[version] = true
+	}
+
+	pvs := &srov1beta1.PreflightValidationList{}
+	if err := clients.Interface.List(ctx, pvs); err != nil {
+		return nil, errors.Wrap(err, "Cannot list PreflightValidations")
+	}
+	for _, pv := range pvs.Items {
+		if pv.Status.KernelVersion != "" {
+			kernels[pv.Status.KernelVersion] = true
+		}
+	}
+
+	return kernels, nil
+}
+
+// SetupWithManager main initalization for manager
+func (r *ImageGCReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	gclog = r.Log.WithName(color.Print("imagegc", color.Purple))
+
+	if clients.GetPlatform() != "OCP" {
+		r.Log.Info("Warning: assuming vanilla K8s. No ImageStreams to prune.")
+		return nil
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("imagegc").
+		For(&srov1beta1.SpecialResource{}).
+		WithOptions(r.Options.concurrentOptions()).
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(r)
+}
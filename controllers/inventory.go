@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/inventory"
+	"github.com/openshift-psap/special-resource-operator/pkg/resource"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// EventObjectsPruned reports objects the chart does not render anymore
+const EventObjectsPruned = "ObjectsPruned"
+
+// pruneInventory deletes the objects applied by the previous reconcile that
+// the chart did not render this time, e.g. renamed ones, and stores the
+// current inventory.
+func pruneInventory(r *SpecialResourceReconciler, current *inventory.Inventory) error {
+
+	previous, err := loadInventory(r.specialresource.Name)
+	if err != nil {
+		return err
+	}
+
+	objects := current.Objects()
+
+	if current.Partial() {
+		// Keep the previous objects until a complete reconcile decides
+		for _, o := range previous {
+			if !current.Contains(o) {
+				objects = append(objects, o)
+			}
+		}
+		log.Info("Manifests skipped, not pruning")
+	} else if previous != nil {
+		pruned, err := inventory.Prune(r.specialresource.Name, previous, current)
+		resource.Changes += len(pruned)
+		if len(pruned) > 0 {
+			names := []string{}
+			for _, o := range pruned {
+				names = append(names, o.String())
+			}
+			event(&r.specialresource, v1.EventTypeNormal, EventObjectsPruned, "Pruned "+strings.Join(names, ", "))
+		}
+		if err != nil {
+			return errors.Wrap(err, "Cannot prune objects")
+		}
+	}
+
+	return storeInventory(r, objects)
+}
+
+// loadInventory returns the objects of the SpecialResourceInventory of the
+// SpecialResource name, falls back to the inventory ConfigMap of older
+// operators and is nil if no reconcile stored an inventory yet
+func loadInventory(name string) ([]inventory.Object, error) {
+
+	sri := &srov1beta1.SpecialResourceInventory{}
+
+	err := clients.Interface.Get(context.TODO(), types.NamespacedName{Name: name}, sri)
+	if apierrors.IsNotFound(err) {
+		return inventory.LoadConfigMap(name)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot get SpecialResourceInventory "+name)
+	}
+
+	objects := []inventory.Object{}
+	for _, o := range sri.Spec.Objects {
+		objects = append(objects, inventory.Object{
+			APIVersion: o.APIVersion,
+			Kind:       o.Kind,
+			Namespace:  o.Namespace,
+			Name:       o.Name,
+			Hash:       o.Hash,
+		})
+	}
+
+	return objects, nil
+}
+
+// storeInventory writes objects to the SpecialResourceInventory of the
+// SpecialResource, it is garbage collected with it
+func storeInventory(r *SpecialResourceReconciler, objects []inventory.Object) error {
+
+	sri := &srov1beta1.SpecialResourceInventory{}
+	sri.SetName(r.specialresource.Name)
+
+	_, err := controllerutil.CreateOrUpdate(context.TODO(), clients.Interface, sri, func() error {
+		sri.Spec.SpecialResource = r.specialresource.Name
+		sri.Spec.Objects = []srov1beta1.SpecialResourceInventoryObject{}
+		for _, o := range objects {
+			sri.Spec.Objects = append(sri.Spec.Objects, srov1beta1.SpecialResourceInventoryObject{
+				APIVersion: o.APIVersion,
+				Kind:       o.Kind,
+				Namespace:  o.Namespace,
+				Name:       o.Name,
+				Hash:       o.Hash,
+			})
+		}
+		return controllerutil.SetOwnerReference(&r.specialresource, sri, r.Scheme)
+	})
+	if err != nil {
+		return errors.Wrap(err, "Cannot write SpecialResourceInventory "+sri.GetName())
+	}
+
+	return inventory.DeleteConfigMap(r.specialresource.Name)
+}
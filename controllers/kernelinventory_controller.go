@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/color"
+	"github.com/openshift-psap/special-resource-operator/pkg/kernel"
+	"github.com/openshift-psap/special-resource-operator/pkg/metrics"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlevent "sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var (
+	kilog logr.Logger
+)
+
+// KernelInventoryReconciler publishes the nodes per kernel version in the
+// kernel.InventoryConfigMap and as metrics
+type KernelInventoryReconciler struct {
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// Options of the work queue
+	Options ReconcileOptions
+}
+
+// Reconcile rebuilds the inventory from all nodes, the request only tells
+// which node changed
+func (r *KernelInventoryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+
+	nodes, err := clients.ListNodes(labels.Everything())
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "Cannot list nodes")
+	}
+
+	inventory := kernel.NewInventory(nodes)
+
+	counts := make(map[string]int)
+	for version, entry := range inventory {
+		counts[version] = len(entry.Nodes)
+	}
+	metrics.SetKernelNodes(counts)
+
+	changed, err := kernel.PublishInventory(inventory)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if changed {
+		kilog.Info("RECONCILE SUCCESS: Kernel inventory published", "node", req.Name, "kernels", inventory.Kernels())
+	}
+	return reconcile.Result{}, nil
+}
+
+// kernelChanged filters the node updates that do not change the inventory
+func kernelChanged() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e ctrlevent.UpdateEvent) bool {
+			old, ok := e.ObjectOld.(*v1.Node)
+			if !ok {
+				return false
+			}
+			node, ok := e.ObjectNew.(*v1.Node)
+			if !ok {
+				return false
+			}
+			return old.Status.NodeInfo.KernelVersion != node.Status.NodeInfo.KernelVersion ||
+				old.Status.NodeInfo.OSImage != node.Status.NodeInfo.OSImage ||
+				old.GetLabels()[kernel.OSVersionLabel] != node.GetLabels()[kernel.OSVersionLabel]
+		},
+	}
+}
+
+// SetupWithManager main initalization for manager
+func (r *KernelInventoryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	kilog = r.Log.WithName(color.Print("kernels", color.Green))
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("kernelinventory").
+		For(&v1.Node{}).
+		WithOptions(r.Options.controllerOptions()).
+		WithEventFilter(kernelChanged()).
+		Complete(r)
+}
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/cache"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/kernel"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// updateKernelStatus summarizes the rollout of the kernel affine driver
+// container DaemonSets of sr per kernel version in status.kernels. A kernel
+// that is only built, e.g. of a watched release, is listed without nodes.
+func updateKernelStatus(sr *srov1beta1.SpecialResource) {
+
+	kernels, err := kernelStatus(sr)
+	if err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot summarize the rollout per kernel"))
+		return
+	}
+
+	if reflect.DeepEqual(kernels, sr.Status.Kernels) {
+		return
+	}
+
+	update := srov1beta1.SpecialResource{}
+
+	objectKey := types.NamespacedName{Name: sr.GetName(), Namespace: sr.GetNamespace()}
+	if err := clients.Interface.Get(context.TODO(), objectKey, &update); err != nil {
+		warn.OnError(errors.Wrap(err, "Is SR being deleted? Cannot get current instance"))
+		return
+	}
+
+	update.Status.Kernels = kernels
+
+	if err := clients.Interface.Status().Update(context.TODO(), &update); err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot update SpecialResource kernel status"))
+		return
+	}
+
+	update.Status.DeepCopyInto(&sr.Status)
+	sr.SetResourceVersion(update.GetResourceVersion())
+}
+
+func kernelStatus(sr *srov1beta1.SpecialResource) ([]srov1beta1.SpecialResourceKernelStatus, error) {
+
+	namespace := sr.Spec.Namespace
+	status := make(map[string]*srov1beta1.SpecialResourceKernelStatus)
+
+	entry := func(kernelFullVersion string) *srov1beta1.SpecialResourceKernelStatus {
+		if _, found := status[kernelFullVersion]; !found {
+			status[kernelFullVersion] = &srov1beta1.SpecialResourceKernelStatus{KernelFullVersion: kernelFullVersion}
+		}
+		return status[kernelFullVersion]
+	}
+
+	daemonSets := &unstructured.UnstructuredList{}
+	daemonSets.SetAPIVersion("apps/v1")
+	daemonSets.SetKind("DaemonSetList")
+
+	if err := clients.Workload().List(context.TODO(), daemonSets, client.InNamespace(namespace)); err != nil {
+		return nil, errors.Wrap(err, "Cannot list DaemonSets in "+namespace)
+	}
+
+	for idx, ds := range daemonSets.Items {
+
+		annotations := ds.GetAnnotations()
+		if annotations["specialresource.openshift.io/state"] != "driver-container" ||
+			annotations["meta.helm.sh/release-name"] != sr.Name {
+			continue
+		}
+
+		kernelFullVersion, _, _ := unstructured.NestedString(ds.Object, "spec", "template", "spec", "nodeSelector", kernel.FullVersionLabel)
+		if kernelFullVersion == "" {
+			continue
+		}
+
+		kernelStatus := entry(kernelFullVersion)
+
+		desired, _, _ := unstructured.NestedInt64(ds.Object, "status", "desiredNumberScheduled")
+		ready, _, _ := unstructured.NestedInt64(ds.Object, "status", "numberReady")
+		updated, _, _ := unstructured.NestedInt64(ds.Object, "status", "updatedNumberScheduled")
+		kernelStatus.DesiredNodes += int32(desired)
+		kernelStatus.ReadyNodes += int32(ready)
+		kernelStatus.UpdatedNodes += int32(updated)
+
+		containers, _, _ := unstructured.NestedSlice(ds.Object, "spec", "template", "spec", "containers")
+		if len(containers) > 0 {
+			image, _, _ := unstructured.NestedString(containers[0].(map[string]interface{}), "image")
+			if image != "" && !contains(kernelStatus.Images, image) {
+				kernelStatus.Images = append(kernelStatus.Images, image)
+			}
+		}
+
+		pending, err := pendingNodes(&daemonSets.Items[idx], kernelFullVersion)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range pending {
+			if !contains(kernelStatus.PendingNodes, node) {
+				kernelStatus.PendingNodes = append(kernelStatus.PendingNodes, node)
+			}
+		}
+	}
+
+	phases, err := buildPhases(namespace)
+	if err != nil {
+		return nil, err
+	}
+	for kernelFullVersion, phase := range phases {
+		entry(kernelFullVersion).BuildState = phase
+	}
+
+	kernels := []srov1beta1.SpecialResourceKernelStatus{}
+	for _, kernelStatus := range status {
+		sort.Strings(kernelStatus.Images)
+		sort.Strings(kernelStatus.PendingNodes)
+		kernels = append(kernels, *kernelStatus)
+	}
+	sort.Slice(kernels, func(i, j int) bool {
+		return kernels[i].KernelFullVersion < kernels[j].KernelFullVersion
+	})
+
+	if len(kernels) == 0 {
+		return nil, nil
+	}
+	return kernels, nil
+}
+
+// pendingNodes returns the nodes running kernelFullVersion that do not run a
+// ready pod of the current template of ds
+func pendingNodes(ds *unstructured.Unstructured, kernelFullVersion string) ([]string, error) {
+
+	matchLabels, _, err := unstructured.NestedStringMap(ds.Object, "spec", "selector", "matchLabels")
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid selector of DaemonSet "+ds.GetName())
+	}
+
+	pods := &v1.PodList{}
+	if err := clients.Workload().List(context.TODO(), pods, client.InNamespace(ds.GetNamespace()), client.MatchingLabels(matchLabels)); err != nil {
+		return nil, errors.Wrap(err, "Cannot list pods of DaemonSet "+ds.GetName())
+	}
+
+	generation := strconv.FormatInt(ds.GetGeneration(), 10)
+
+	current := make(map[string]bool)
+	for _, pod := range pods.Items {
+		if !metav1.IsControlledBy(&pod, ds) || pod.Spec.NodeName == "" {
+			continue
+		}
+		if pod.GetLabels()["pod-template-generation"] != generation {
+			continue
+		}
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == v1.PodReady && cond.Status == v1.ConditionTrue {
+				current[pod.Spec.NodeName] = true
+			}
+		}
+	}
+
+	pending := []string{}
+	for _, node := range cache.Node.List.Items {
+		if version, _ := kernel.NodeFullVersion(node); version != kernelFullVersion {
+			continue
+		}
+		if !current[node.GetName()] {
+			pending = append(pending, node.GetName())
+		}
+	}
+
+	return pending, nil
+}
+
+// buildPhases returns the phase of the most recent build per kernel version
+// in namespace, empty without BuildConfigs
+func buildPhases(namespace string) (map[string]string, error) {
+
+	phases := make(map[string]string)
+
+	available, err := clients.HasWorkloadResource(schema.GroupVersionResource{Group: "build.openshift.io", Version: "v1", Resource: "builds"})
+	if err != nil || !available {
+		return phases, errors.Wrap(err, "Cannot discover builds")
+	}
+
+	builds := &unstructured.UnstructuredList{}
+	builds.SetAPIVersion("build.openshift.io/v1")
+	builds.SetKind("BuildList")
+
+	if err := clients.Workload().List(context.TODO(), builds, client.InNamespace(namespace)); err != nil {
+		return nil, errors.Wrap(err, "Cannot list builds in "+namespace)
+	}
+
+	latest := make(map[string]metav1.Time)
+	for _, build := range builds.Items {
+
+		kernelFullVersion, _, _ := unstructured.NestedString(build.Object, "spec", "nodeSelector", kernel.FullVersionLabel)
+		if kernelFullVersion == "" {
+			continue
+		}
+
+		created := build.GetCreationTimestamp()
+		if last, found := latest[kernelFullVersion]; found && created.Before(&last) {
+			continue
+		}
+		latest[kernelFullVersion] = created
+
+		phase, _, _ := unstructured.NestedString(build.Object, "status", "phase")
+		phases[kernelFullVersion] = phase
+	}
+
+	return phases, nil
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"sort"
+	"strings"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/cache"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/nfd"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// reconcileNodeFeatures adds the NFD labels of spec.nodeFeatures to the
+// nodeSelector of the in-memory SpecialResource, every object of the chart
+// is then only scheduled to nodes with the features. NFD is not required
+// to be installed, the SpecialResource waits until the labels show up.
+func reconcileNodeFeatures(r *SpecialResourceReconciler) error {
+
+	sr := &r.specialresource
+	features := nfd.Features{
+		Required: sr.Spec.NodeFeatures.Required,
+		PCI:      sr.Spec.NodeFeatures.PCI,
+		USB:      sr.Spec.NodeFeatures.USB,
+	}
+
+	if features.Empty() {
+		return nil
+	}
+
+	list, err := clients.ListNodes(labels.SelectorFromSet(sr.Spec.NodeSelector))
+	if err != nil {
+		return errors.Wrap(err, "Client cannot get NodeList")
+	}
+
+	nodes := []map[string]string{}
+	for _, node := range list {
+		if cache.Excluded(node.GetLabels(), node.Status.NodeInfo.OSImage, sr.Spec.NodeExclusionSelector) {
+			continue
+		}
+		nodes = append(nodes, node.GetLabels())
+	}
+
+	selector, err := nfd.Selector(features, nodes)
+	if err != nil {
+		setStatusCondition(sr, metav1.Condition{
+			Type:    srov1beta1.ConditionNodeFeaturesDiscovered,
+			Status:  metav1.ConditionFalse,
+			Reason:  "NodeFeaturesMissing",
+			Message: err.Error() + ", is Node Feature Discovery running?",
+		})
+		return err
+	}
+
+	// The spec may be shared with the cached SpecialResource
+	nodeSelector := make(map[string]string)
+	for key, value := range sr.Spec.NodeSelector {
+		nodeSelector[key] = value
+	}
+
+	terms := []string{}
+	for key, value := range selector {
+		nodeSelector[key] = value
+		terms = append(terms, key+"="+value)
+	}
+	sort.Strings(terms)
+
+	sr.Spec.NodeSelector = nodeSelector
+
+	log.Info("Node features", "nodeSelector", nodeSelector)
+
+	setStatusCondition(sr, metav1.Condition{
+		Type:    srov1beta1.ConditionNodeFeaturesDiscovered,
+		Status:  metav1.ConditionTrue,
+		Reason:  "NodeFeaturesFound",
+		Message: "Selecting nodes with " + strings.Join(terms, ", "),
+	})
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+)
+
+// ReconcileOptions tune the work queue of a controller, the zero value
+// keeps the controller-runtime defaults
+type ReconcileOptions struct {
+	// MaxConcurrentReconciles of different objects of the controllers whose
+	// reconciles share no state, see concurrentOptions
+	MaxConcurrentReconciles int
+	// BaseDelay and MaxDelay bound the exponential backoff of an object
+	// whose reconcile fails or is requeued, other objects are not delayed
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// controllerOptions returns the controller.Options of o reconciling one
+// object at a time, a SpecialResource reconcile shares RunInfo, the fields
+// of the reconciler and the state of pkg/resource
+func (o ReconcileOptions) controllerOptions() controller.Options {
+
+	opts := controller.Options{MaxConcurrentReconciles: 1}
+
+	if o.BaseDelay > 0 && o.MaxDelay >= o.BaseDelay {
+		opts.RateLimiter = workqueue.NewItemExponentialFailureRateLimiter(o.BaseDelay, o.MaxDelay)
+	}
+
+	return opts
+}
+
+// concurrentOptions returns the controller.Options of o with
+// MaxConcurrentReconciles, an object is never reconciled twice at the same
+// time
+func (o ReconcileOptions) concurrentOptions() controller.Options {
+
+	opts := o.controllerOptions()
+
+	if o.MaxConcurrentReconciles > 1 {
+		opts.MaxConcurrentReconciles = o.MaxConcurrentReconciles
+	}
+
+	return opts
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconcileOptions(t *testing.T) {
+
+	tests := []struct {
+		concurrent int
+		shared     int
+		parallel   int
+	}{
+		{0, 1, 1},
+		{1, 1, 1},
+		{4, 1, 4},
+	}
+
+	for _, tt := range tests {
+		o := ReconcileOptions{MaxConcurrentReconciles: tt.concurrent, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+
+		// SpecialResources share RunInfo and pkg/resource, never in parallel
+		if got := o.controllerOptions(); got.MaxConcurrentReconciles != tt.shared || got.RateLimiter == nil {
+			t.Errorf("controllerOptions(%d) = %d, %v, want %d with rate limiter", tt.concurrent, got.MaxConcurrentReconciles, got.RateLimiter, tt.shared)
+		}
+		if got := o.concurrentOptions(); got.MaxConcurrentReconciles != tt.parallel || got.RateLimiter == nil {
+			t.Errorf("concurrentOptions(%d) = %d, %v, want %d with rate limiter", tt.concurrent, got.MaxConcurrentReconciles, got.RateLimiter, tt.parallel)
+		}
+	}
+}
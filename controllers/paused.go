@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PausedAnnotation stops the reconciliation of a SpecialResource so the
+// generated resources can be debugged or tweaked manually
+const PausedAnnotation = "specialresource.openshift.io/paused"
+
+// isPaused records the Paused condition of sr and returns true if no
+// changes must be applied
+func isPaused(sr *srov1beta1.SpecialResource) bool {
+
+	if sr.GetAnnotations()[PausedAnnotation] == "true" {
+		setStatusCondition(sr, metav1.Condition{
+			Type:    srov1beta1.ConditionPaused,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Paused",
+			Message: "Reconciliation paused by annotation " + PausedAnnotation,
+		})
+		return true
+	}
+
+	for _, condition := range sr.Status.Conditions {
+		if condition.Type == srov1beta1.ConditionPaused && condition.Status == metav1.ConditionTrue {
+			setStatusCondition(sr, metav1.Condition{
+				Type:    srov1beta1.ConditionPaused,
+				Status:  metav1.ConditionFalse,
+				Reason:  "Resumed",
+				Message: "Reconciliation resumed",
+			})
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"sort"
+	"strings"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/openshift-psap/special-resource-operator/pkg/resource"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KernelVersionVariable is replaced with the node kernel in the image of
+// prebuilt driver containers
+const KernelVersionVariable = "${KERNEL_VERSION}"
+
+func prebuiltImage(sr *srov1beta1.SpecialResource, kernelVersion string) string {
+	if !sr.Spec.DriverContainer.Prebuilt {
+		return ""
+	}
+	return strings.ReplaceAll(sr.Spec.DriverContainer.Image, KernelVersionVariable, kernelVersion)
+}
+
+// verifyPrebuilt checks that the prebuilt driver container exists for the
+// kernel of every node, builds of the chart are skipped in prebuilt mode.
+func verifyPrebuilt(r *SpecialResourceReconciler) error {
+
+	resource.Prebuilt = r.specialresource.Spec.DriverContainer.Prebuilt
+	if !resource.Prebuilt {
+		return nil
+	}
+
+	if r.specialresource.Spec.DriverContainer.Image == "" {
+		return errors.New("spec.driverContainer.image is required for prebuilt driver containers")
+	}
+
+	kernels := []string{}
+	for kernelVersion := range RunInfo.ClusterUpgradeInfo {
+		kernels = append(kernels, kernelVersion)
+	}
+	sort.Strings(kernels)
+
+	missing := []string{}
+	for _, kernelVersion := range kernels {
+		image := prebuiltImage(&r.specialresource, kernelVersion)
+		if _, err := registry.ResolveDigest(image); err != nil {
+			log.Info("Prebuilt driver container not found", "kernel", kernelVersion, "image", image)
+			missing = append(missing, image)
+		}
+	}
+
+	if len(missing) > 0 {
+		err := errors.New("Prebuilt driver container not found: " + strings.Join(missing, ", "))
+		setStatusCondition(&r.specialresource, metav1.Condition{
+			Type:    srov1beta1.ConditionPrebuiltImagesAvailable,
+			Status:  metav1.ConditionFalse,
+			Reason:  "PrebuiltImageMissing",
+			Message: err.Error(),
+		})
+		return err
+	}
+
+	setStatusCondition(&r.specialresource, metav1.Condition{
+		Type:    srov1beta1.ConditionPrebuiltImagesAvailable,
+		Status:  metav1.ConditionTrue,
+		Reason:  "PrebuiltImagesFound",
+		Message: "Prebuilt driver container found for kernels " + strings.Join(kernels, ", "),
+	})
+
+	return nil
+}
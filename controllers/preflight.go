@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	goruntime "runtime"
+	"strings"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/cache"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// preflightTargetKernel returns the kernel of the DTK shipped with the
+// release payload the cluster is going to be upgraded to.
+func preflightTargetKernel(pv *srov1beta1.PreflightValidation) (string, string, error) {
+
+	version, dtkImage, err := releaseDriverToolkit(pv.Spec.UpdateImage)
+	if err != nil {
+		return "", "", err
+	}
+	pvlog.Info("Release", "version", version, "dtk", dtkImage)
+
+	if pv.Spec.KernelVersion != "" {
+		return pv.Spec.KernelVersion, dtkImage, nil
+	}
+
+	dtk, err := registry.ToolkitRelease(dtkImage)
+	if err != nil {
+		return "", "", err
+	}
+
+	return kernelWithArch(dtk.KernelFullVersion, goruntime.GOARCH), dtkImage, nil
+}
+
+// kernelWithArch appends the architecture to kernelVersion, NFD labels carry
+// the architecture, the DTK release does not
+func kernelWithArch(kernelVersion string, arch string) string {
+
+	if arch == "amd64" {
+		arch = "x86_64"
+	} else if arch == "arm64" {
+		arch = "aarch64"
+	}
+	if !strings.Contains(kernelVersion, arch) {
+		kernelVersion = kernelVersion + "." + arch
+	}
+
+	return kernelVersion
+}
+
+// preflightVerify checks that sr has a driver container for kernelVersion,
+// either a prebuilt image or an in-cluster build with the target DTK.
+func preflightVerify(sr *srov1beta1.SpecialResource, kernelVersion string, dtkImage string) srov1beta1.PreflightValidationSRStatus {
+
+	running, err := runningKernels(sr.Spec.NodeSelector, sr.Spec.NodeExclusionSelector)
+	if err != nil {
+		return newPreflightStatus(sr.Name, srov1beta1.VerificationFalse, err.Error())
+	}
+
+	// Prebuilt driver containers are tagged with the kernel they were built
+	// for, the images of the last reconcile tell us the naming scheme.
+	missing := []string{}
+	for _, image := range sr.Status.Images {
+		if strings.Contains(image.Image, "@") {
+			continue
+		}
+		for _, kernel := range running {
+			if !strings.Contains(image.Image, kernel) {
+				continue
+			}
+			target := strings.ReplaceAll(image.Image, kernel, kernelVersion)
+			if _, err := registry.ResolveDigest(target); err != nil {
+				missing = append(missing, target)
+				continue
+			}
+			return newPreflightStatus(sr.Name, srov1beta1.VerificationTrue, "Driver container "+target+" exists")
+		}
+	}
+
+	if sr.Spec.DriverContainer.Source.Git.Uri != "" || len(missing) > 0 {
+
+		if _, err := registry.ResolveDigest(dtkImage); err == nil {
+			return newPreflightStatus(sr.Name, srov1beta1.VerificationTrue, "Driver container can be built with DTK "+dtkImage)
+		}
+		if sr.Spec.DriverToolkit.FallbackToEntitled {
+			return newPreflightStatus(sr.Name, srov1beta1.VerificationTrue, "Driver container can be built with the RHEL entitlement")
+		}
+		if len(missing) > 0 {
+			return newPreflightStatus(sr.Name, srov1beta1.VerificationFalse, "Driver container "+strings.Join(missing, ", ")+" not found and DTK "+dtkImage+" not available")
+		}
+		return newPreflightStatus(sr.Name, srov1beta1.VerificationFalse, "DTK "+dtkImage+" not available for build")
+	}
+
+	return newPreflightStatus(sr.Name, srov1beta1.VerificationTrue, "No kernel specific driver container")
+}
+
+// runningKernels returns the kernels of the nodes matching nodeSelector
+// that are not excluded, the node cache is not used since it belongs to the
+// SpecialResource being reconciled.
+func runningKernels(nodeSelector map[string]string, exclusion map[string]string) ([]string, error) {
+
+	nodes, err := clients.ListNodes(labels.SelectorFromSet(nodeSelector))
+	if err != nil {
+		return nil, errors.Wrap(err, "Client cannot get NodeList")
+	}
+
+	seen := make(map[string]bool)
+	kernels := []string{}
+
+	for _, node := range nodes {
+		if cache.Excluded(node.GetLabels(), node.Status.NodeInfo.OSImage, exclusion) {
+			continue
+		}
+		kernel, found := node.GetLabels()["feature.node.kubernetes.io/kernel-version.full"]
+		if !found || seen[kernel] {
+			continue
+		}
+		seen[kernel] = true
+		kernels = append(kernels, kernel)
+	}
+
+	return kernels, nil
+}
+
+// PreflightReport the result of PreflightRelease
+type PreflightReport struct {
+	Release            string            `json:"release"`
+	Version            string            `json:"version"`
+	KernelFullVersion  string            `json:"kernelFullVersion"`
+	DriverToolkitImage string            `json:"driverToolkitImage"`
+	SpecialResources   []PreflightResult `json:"specialResources"`
+}
+
+// PreflightResult tells whether a SpecialResource is ready for the release,
+// either its driver container exists or it can be built
+type PreflightResult struct {
+	Name          string `json:"name"`
+	Verified      bool   `json:"verified"`
+	BuildRequired bool   `json:"buildRequired"`
+	Image         string `json:"image,omitempty"`
+	Message       string `json:"message"`
+}
+
+// PreflightRelease checks the SpecialResources against the release payload
+// image without a cluster, only the registries are contacted. Prebuilt
+// driver containers have to exist for the kernel of the release, otherwise
+// a build from a git source needs the DTK of the release or the RHEL
+// entitlement.
+func PreflightRelease(image string, arch string, srs []srov1beta1.SpecialResource) (PreflightReport, error) {
+
+	report := PreflightReport{Release: image, SpecialResources: []PreflightResult{}}
+
+	version, dtkImage, err := releaseDriverToolkit(image)
+	if err != nil {
+		return report, err
+	}
+
+	dtk, err := registry.ToolkitRelease(dtkImage)
+	if err != nil {
+		return report, err
+	}
+
+	report.Version = version
+	report.DriverToolkitImage = dtkImage
+	report.KernelFullVersion = kernelWithArch(dtk.KernelFullVersion, arch)
+
+	_, dtkErr := registry.ResolveDigest(dtkImage)
+
+	for idx := range srs {
+
+		sr := &srs[idx]
+		result := PreflightResult{Name: sr.Name, Verified: true}
+
+		switch {
+		case sr.Spec.DriverContainer.Prebuilt:
+			result.Image = prebuiltImage(sr, report.KernelFullVersion)
+			if _, err := registry.ResolveDigest(result.Image); err != nil {
+				result.Verified = false
+				result.Message = "Driver container " + result.Image + " not found"
+			} else {
+				result.Message = "Driver container " + result.Image + " exists"
+			}
+		case sr.Spec.DriverContainer.Source.Git.Uri != "":
+			result.BuildRequired = true
+			if dtkErr == nil {
+				result.Message = "Driver container can be built with DTK " + dtkImage
+			} else if sr.Spec.DriverToolkit.FallbackToEntitled {
+				result.Message = "Driver container can be built with the RHEL entitlement"
+			} else {
+				result.Verified = false
+				result.Message = "DTK " + dtkImage + " not available for build"
+			}
+		default:
+			result.Message = "No kernel specific driver container"
+		}
+
+		report.SpecialResources = append(report.SpecialResources, result)
+	}
+
+	return report, nil
+}
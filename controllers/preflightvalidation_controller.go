@@ -0,0 +1,129 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/color"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var (
+	pvlog logr.Logger
+)
+
+// PreflightValidationReconciler reconciles a PreflightValidation object
+type PreflightValidationReconciler struct {
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// Options of the work queue
+	Options ReconcileOptions
+}
+
+// Reconcile verifies all SpecialResources against the target kernel
+func (r *PreflightValidationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+
+	pvlog.Info("Controller Request", "Name", req.Name)
+
+	pv := srov1beta1.PreflightValidation{}
+
+	if err := clients.Interface.Get(ctx, req.NamespacedName, &pv); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, errors.Wrap(err, "Cannot get PreflightValidation "+req.Name)
+	}
+
+	kernelVersion, dtkImage, err := preflightTargetKernel(&pv)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "Cannot get target kernel of "+pv.Spec.UpdateImage)
+	}
+	pvlog.Info("Target", "kernel", kernelVersion, "dtk", dtkImage)
+
+	srs := srov1beta1.SpecialResourceList{}
+	if err := clients.Interface.List(ctx, &srs); err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "Cannot list SpecialResources")
+	}
+
+	verified := true
+	statuses := []srov1beta1.PreflightValidationSRStatus{}
+
+	for _, sr := range srs.Items {
+		status := preflightVerify(&sr, kernelVersion, dtkImage)
+		pvlog.Info("Verification", "sr", sr.Name, "status", status.VerificationStatus, "reason", status.StatusReason)
+
+		// Keep the transition time if the result did not change
+		for _, prev := range pv.Status.SRStatuses {
+			if prev.Name == status.Name && prev.VerificationStatus == status.VerificationStatus {
+				status.LastTransitionTime = prev.LastTransitionTime
+			}
+		}
+
+		verified = verified && status.VerificationStatus == srov1beta1.VerificationTrue
+		statuses = append(statuses, status)
+	}
+
+	pv.Status.KernelVersion = kernelVersion
+	pv.Status.DriverToolkitImage = dtkImage
+	pv.Status.SRStatuses = statuses
+
+	if err := clients.Interface.Status().Update(ctx, &pv); err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "Cannot update PreflightValidation status")
+	}
+
+	// Driver containers may be pushed after the validation was created,
+	// check again until every SpecialResource is verified.
+	if !verified {
+		pvlog.Info("RECONCILE REQUEUE: Not all SpecialResources verified")
+		return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+	}
+
+	pvlog.Info("RECONCILE SUCCESS: All SpecialResources verified")
+	return reconcile.Result{}, nil
+}
+
+// SetupWithManager main initalization for manager
+func (r *PreflightValidationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	pvlog = r.Log.WithName(color.Print("preflight", color.Purple))
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&srov1beta1.PreflightValidation{}).
+		WithOptions(r.Options.concurrentOptions()).
+		// Status updates must not retrigger the validation
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(r)
+}
+
+func newPreflightStatus(name string, status string, reason string) srov1beta1.PreflightValidationSRStatus {
+	return srov1beta1.PreflightValidationSRStatus{
+		Name:               name,
+		VerificationStatus: status,
+		StatusReason:       reason,
+		LastTransitionTime: metav1.Now(),
+	}
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/cluster"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/openshift-psap/special-resource-operator/pkg/slice"
+	"github.com/openshift-psap/special-resource-operator/pkg/upgrade"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// watchedRelease a resolved release payload
+type watchedRelease struct {
+	status srov1beta1.SpecialResourceWatchedRelease
+	dtk    registry.DriverToolkitEntry
+}
+
+// The DTK of a release payload never changes, payloads are only pulled
+// once
+var (
+	watchedReleasesMutex sync.Mutex
+	watchedReleases      = make(map[string]watchedRelease)
+)
+
+// releaseDriverToolkit returns the OCP version and the DTK image of the
+// release payload image
+func releaseDriverToolkit(image string) (string, string, error) {
+
+	version, dtkImage, err := registry.ReleaseManifests(image)
+	if err != nil {
+		return "", "", errors.Wrap(err, "Cannot get release payload "+image)
+	}
+	if dtkImage == "" {
+		return "", "", errors.New("No driver-toolkit in release payload " + image)
+	}
+
+	return version, dtkImage, nil
+}
+
+// resolveWatchedRelease returns the version and DTK of the release payload
+func resolveWatchedRelease(image string) (watchedRelease, error) {
+
+	watchedReleasesMutex.Lock()
+	defer watchedReleasesMutex.Unlock()
+
+	if release, found := watchedReleases[image]; found {
+		return release, nil
+	}
+
+	version, dtkImage, err := releaseDriverToolkit(image)
+	if err != nil {
+		return watchedRelease{}, err
+	}
+
+	dtk, err := registry.ToolkitRelease(dtkImage)
+	if err != nil {
+		return watchedRelease{}, err
+	}
+
+	release := watchedRelease{
+		status: srov1beta1.SpecialResourceWatchedRelease{
+			Image:              image,
+			Version:            version,
+			DriverToolkitImage: dtkImage,
+		},
+		dtk: dtk,
+	}
+	watchedReleases[image] = release
+
+	return release, nil
+}
+
+// watchReleases adds the kernels of the watched releases to the runtime
+// information, kernel affine states are rendered for every kernel so each
+// watched release gets its own build. Dropped releases are not rendered
+// anymore and their builds are pruned. The release the cluster is upgrading
+// to is watched until the upgrade completes, its driver containers are built
+// while the nodes are updated.
+func watchReleases(r *SpecialResourceReconciler) error {
+
+	images := append([]string{}, r.specialresource.Spec.DriverToolkit.WatchedReleases...)
+
+	// Prebuilt driver containers cannot be built ahead of the upgrade
+	target := ""
+	if !r.specialresource.Spec.DriverContainer.Prebuilt {
+		image, version, err := cluster.UpgradeTarget()
+		if err != nil {
+			return errors.Wrap(err, "Cannot get upgrade target")
+		}
+		if image != "" && !slice.Contains(images, image) {
+			log.Info("Cluster upgrade in progress, building for target release", "version", version)
+			target = image
+			images = append(images, target)
+		}
+	}
+
+	if len(images) == 0 && len(r.specialresource.Status.WatchedReleases) == 0 {
+		return nil
+	}
+
+	// Do not modify the map of the DTK cache
+	info := make(map[string]upgrade.NodeVersion)
+	for kernel, version := range RunInfo.ClusterUpgradeInfo {
+		info[kernel] = version
+	}
+
+	watched := []srov1beta1.SpecialResourceWatchedRelease{}
+
+	for _, image := range images {
+
+		release, err := resolveWatchedRelease(image)
+		if err != nil && image == target {
+			// The nodes get the new kernel anyway, build after the reboot
+			warn.OnError(errors.Wrap(err, "Cannot resolve upgrade target "+image))
+			continue
+		}
+		if err != nil {
+			return errors.Wrap(err, "Cannot resolve watched release "+image)
+		}
+
+		// The OCP version is the one of the release, e.g. 4.9 of 4.9.0-rc.1
+		clusterVersion := release.status.Version
+		if parts := strings.SplitN(clusterVersion, ".", 3); len(parts) == 3 {
+			clusterVersion = parts[0] + "." + parts[1]
+		}
+		release.status.KernelVersion = upgrade.AddRelease(info, release.dtk, release.status.DriverToolkitImage, clusterVersion)
+
+		log.Info("Watching release", "version", release.status.Version, "kernel", release.status.KernelVersion)
+		watched = append(watched, release.status)
+	}
+
+	RunInfo.ClusterUpgradeInfo = info
+
+	if !reflect.DeepEqual(watched, r.specialresource.Status.WatchedReleases) {
+		updateWatchedReleaseStatus(&r.specialresource, watched)
+	}
+
+	return nil
+}
+
+// updateWatchedReleaseStatus stores the resolved releases in the status
+func updateWatchedReleaseStatus(sr *srov1beta1.SpecialResource, watched []srov1beta1.SpecialResourceWatchedRelease) {
+
+	update := srov1beta1.SpecialResource{}
+
+	objectKey := types.NamespacedName{Name: sr.GetName(), Namespace: sr.GetNamespace()}
+	if err := clients.Interface.Get(context.TODO(), objectKey, &update); err != nil {
+		warn.OnError(errors.Wrap(err, "Is SR being deleted? Cannot get current instance"))
+		return
+	}
+
+	update.Status.WatchedReleases = watched
+
+	if err := clients.Interface.Status().Update(context.TODO(), &update); err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot update SpecialResource watched releases"))
+		return
+	}
+
+	sr.Status.WatchedReleases = watched
+}
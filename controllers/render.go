@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"strings"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/color"
+	"github.com/openshift-psap/special-resource-operator/pkg/firmware"
+	"github.com/openshift-psap/special-resource-operator/pkg/kernel"
+	"github.com/openshift-psap/special-resource-operator/pkg/osversion"
+	"github.com/openshift-psap/special-resource-operator/pkg/readiness"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/openshift-psap/special-resource-operator/pkg/upgrade"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// Facts the cluster a chart is rendered for by RenderOffline, the operator
+// discovers them from the nodes and the cluster
+type Facts struct {
+	// KernelFullVersion of the nodes, e.g. 4.18.0-305.19.1.el8_4.x86_64
+	KernelFullVersion string `json:"kernelFullVersion"`
+	KernelRealTime    bool   `json:"kernelRealTime,omitempty"`
+	// ClusterVersion the OpenShift version, e.g. 4.8.12
+	ClusterVersion string `json:"clusterVersion"`
+	// OSRelease and OSVersion the ID and VERSION_ID of the os-release of
+	// the nodes like NFD labels them, e.g. rhcos and 4.8
+	OSRelease          string `json:"osRelease"`
+	OSVersion          string `json:"osVersion"`
+	DriverToolkitImage string `json:"driverToolkitImage,omitempty"`
+	// KubeVersion .Capabilities.KubeVersion of the templates, defaults to
+	// the one of helm
+	KubeVersion    string `json:"kubeVersion,omitempty"`
+	Platform       string `json:"platform,omitempty"`
+	FIPS           bool   `json:"fips,omitempty"`
+	PushSecretName string `json:"pushSecretName,omitempty"`
+	// CombineDevicePlugin renders the chart like on single-node clusters
+	CombineDevicePlugin bool `json:"combineDevicePlugin,omitempty"`
+}
+
+// RenderOffline renders ch for sr like the dry-run of the operator does but
+// with facts instead of a cluster. The manifests are keyed like in the
+// dry-run ConfigMap. Dependencies of sr are not rendered.
+func RenderOffline(sr srov1beta1.SpecialResource, ch *chart.Chart, facts Facts) (map[string]string, error) {
+
+	if facts.KernelFullVersion == "" || facts.ClusterVersion == "" {
+		return nil, errors.New("kernelFullVersion and clusterVersion are required facts")
+	}
+	if len(strings.Split(facts.ClusterVersion, ".")) < 2 {
+		return nil, errors.New("Invalid clusterVersion " + facts.ClusterVersion)
+	}
+	if len(strings.Split(facts.KernelFullVersion, ".")) < 3 {
+		return nil, errors.New("Invalid kernelFullVersion " + facts.KernelFullVersion)
+	}
+
+	log = zap.New(zap.UseDevMode(true)).WithName(color.Print(sr.Name, color.Green))
+
+	var err error
+
+	version := strings.SplitN(facts.OSVersion, ".", 2)
+	if len(version) < 2 {
+		version = append(version, "")
+	}
+	RunInfo.OperatingSystemMajor, RunInfo.OperatingSystemMajorMinor, RunInfo.OperatingSystemDecimal, err =
+		osversion.RenderOperatingSystem(facts.OSRelease, version[0], version[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid osRelease or osVersion")
+	}
+
+	RunInfo.KernelFullVersion = facts.KernelFullVersion
+	if RunInfo.KernelPatchVersion, err = kernel.PatchVersion(facts.KernelFullVersion); err != nil {
+		return nil, err
+	}
+
+	ocp := strings.Split(facts.ClusterVersion, ".")
+	RunInfo.ClusterVersion = facts.ClusterVersion
+	RunInfo.ClusterVersionMajorMinor = ocp[0] + "." + ocp[1]
+
+	RunInfo.Platform = facts.Platform
+	if RunInfo.Platform == "" {
+		RunInfo.Platform = "OCP"
+	}
+	RunInfo.FIPS = facts.FIPS
+	RunInfo.PushSecretName = facts.PushSecretName
+	RunInfo.CombineDevicePlugin = facts.CombineDevicePlugin
+
+	RunInfo.ClusterUpgradeInfo = map[string]upgrade.NodeVersion{
+		facts.KernelFullVersion: {
+			OSVersion:      RunInfo.OperatingSystemDecimal,
+			ClusterVersion: RunInfo.ClusterVersionMajorMinor,
+			DriverToolkit: registry.DriverToolkitEntry{
+				ImageURL:          facts.DriverToolkitImage,
+				KernelFullVersion: facts.KernelFullVersion,
+				OSVersion:         RunInfo.OperatingSystemDecimal,
+			},
+			RealTime: facts.KernelRealTime,
+		},
+	}
+
+	r := &SpecialResourceReconciler{
+		specialresource: sr,
+		chart:           *ch,
+		values:          sr.Spec.Set,
+	}
+	r.chart.Templates = readiness.AddHelpers(firmware.AddHelpers(ch.Templates))
+
+	for _, values := range []*unstructured.Unstructured{&r.specialresource.Spec.Set, &r.values} {
+		if values.Object == nil {
+			values.Object = make(map[string]interface{})
+		}
+		values.SetKind("Values")
+		values.SetAPIVersion("sro.openshift.io/v1beta1")
+	}
+
+	TemplateFragmentOrDie(&r.specialresource)
+	r.specialresource.DeepCopyInto(&RunInfo.SpecialResource)
+	TemplateFragmentOrDie(&r.values)
+
+	return renderManifests(r, &facts)
+}
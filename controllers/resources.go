@@ -4,13 +4,18 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
 	"github.com/openshift-psap/special-resource-operator/pkg/assets"
 	"github.com/openshift-psap/special-resource-operator/pkg/clients"
 	"github.com/openshift-psap/special-resource-operator/pkg/exit"
 	"github.com/openshift-psap/special-resource-operator/pkg/helmer"
+	"github.com/openshift-psap/special-resource-operator/pkg/inventory"
+	"github.com/openshift-psap/special-resource-operator/pkg/kernel"
 	"github.com/openshift-psap/special-resource-operator/pkg/metrics"
+	"github.com/openshift-psap/special-resource-operator/pkg/proxy"
 	"github.com/openshift-psap/special-resource-operator/pkg/resource"
 	"github.com/openshift-psap/special-resource-operator/pkg/slice"
 	"github.com/openshift-psap/special-resource-operator/pkg/state"
@@ -19,12 +24,52 @@ import (
 	"gopkg.in/yaml.v2"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
+	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 )
 
+// entitledFallback switches the build of a kernel without DTK to an
+// entitlement-based build if the SpecialResource allows it.
+func entitledFallback(r *SpecialResourceReconciler, version upgrade.NodeVersion) error {
+
+	RunInfo.Entitled = false
+	RunInfo.EntitlementSecret = ""
+
+	if version.DriverToolkit.ImageURL != "" {
+		return nil
+	}
+
+	dtk := r.specialresource.Spec.DriverToolkit
+	if !dtk.FallbackToEntitled {
+		log.Info("No DTK found for kernel, fallbackToEntitled not set", "kernel", RunInfo.KernelFullVersion)
+		return nil
+	}
+
+	secret := dtk.EntitlementSecret
+	if secret == "" {
+		secret = "etc-pki-entitlement"
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("Secret")
+
+	err := clients.Workload().Get(context.TODO(), types.NamespacedName{Namespace: r.specialresource.Spec.Namespace, Name: secret}, obj)
+	if err != nil {
+		return errors.Wrap(err, "Cannot get entitlement Secret "+r.specialresource.Spec.Namespace+"/"+secret)
+	}
+
+	log.Info("No DTK found for kernel, falling back to entitled build", "kernel", RunInfo.KernelFullVersion, "secret", secret)
+
+	RunInfo.Entitled = true
+	RunInfo.EntitlementSecret = secret
+
+	return nil
+}
+
 func createImagePullerRoleBinding(r *SpecialResourceReconciler) error {
 
 	if found := slice.Contains(r.dependency.Tags, "image-puller"); !found {
@@ -37,7 +82,7 @@ func createImagePullerRoleBinding(r *SpecialResourceReconciler) error {
 	rb.SetKind("RoleBinding")
 
 	namespacedName := types.NamespacedName{Namespace: r.specialresource.Spec.Namespace, Name: "system:image-pullers"}
-	err := clients.Interface.Get(context.TODO(), namespacedName, rb)
+	err := clients.Workload().Get(context.TODO(), namespacedName, rb)
 	if apierrors.IsNotFound(err) {
 		log.Info("Warning: RoleBinding system:image-pullers not found. Can be ignored on vanilla k8s or when namespace is being created.")
 		return nil
@@ -69,7 +114,7 @@ func createImagePullerRoleBinding(r *SpecialResourceReconciler) error {
 		err = unstructured.SetNestedSlice(rb.Object, newSubjects, "subjects")
 		exit.OnError(err)
 
-		if err := clients.Interface.Create(context.TODO(), rb); err != nil {
+		if err := clients.Workload().Create(context.TODO(), rb); err != nil {
 			return errors.Wrap(err, "Couldn't Create Resource")
 		}
 
@@ -109,13 +154,45 @@ func createImagePullerRoleBinding(r *SpecialResourceReconciler) error {
 	err = unstructured.SetNestedSlice(rb.Object, oldSubjects, "subjects")
 	exit.OnError(err)
 
-	if err := clients.Interface.Update(context.TODO(), rb); err != nil {
+	if err := clients.Workload().Update(context.TODO(), rb); err != nil {
 		return errors.Wrap(err, "Couldn't Update Resource")
 	}
 
 	return nil
 }
 
+// setNodeVersion sets the RunInfo of RunInfo.KernelFullVersion for the
+// next replica of a kernel affine state
+func setNodeVersion(r *SpecialResourceReconciler, version upgrade.NodeVersion) error {
+
+	applyNodeVersion(r, version)
+
+	if err := entitledFallback(r, version); err != nil {
+		return errors.Wrap(err, "No DTK for kernel "+RunInfo.KernelFullVersion)
+	}
+
+	return nil
+}
+
+// applyNodeVersion sets the RunInfo of the kernel of version
+func applyNodeVersion(r *SpecialResourceReconciler, version upgrade.NodeVersion) {
+
+	var err error
+
+	RunInfo.ClusterVersionMajorMinor = version.ClusterVersion
+	RunInfo.OperatingSystemDecimal = version.OSVersion
+	RunInfo.DriverToolkitImage = version.DriverToolkit.ImageURL
+	resource.DriverToolkitImage = version.DriverToolkit.ImageURL
+	RunInfo.DriverContainerImage = prebuiltImage(&r.specialresource, RunInfo.KernelFullVersion)
+	// RT kernels need the kernel-rt headers and their own DaemonSet
+	// that is pinned to the RT nodes by the kernel version
+	RunInfo.KernelRealTime = version.RealTime
+	RunInfo.KernelPatchVersion, err = kernel.PatchVersion(RunInfo.KernelFullVersion)
+	exit.OnError(err)
+
+	setRuntimeValues()
+}
+
 // ReconcileChartStates Reconcile Hardware States
 func ReconcileChartStates(r *SpecialResourceReconciler, templates *unstructured.Unstructured) error {
 
@@ -155,6 +232,10 @@ func ReconcileChartStates(r *SpecialResourceReconciler, templates *unstructured.
 		step := nostate
 		step.Templates = append(nostate.Templates, stateYAML)
 
+		if isBuildState(stateYAML.Data) {
+			event(&r.specialresource, v1.EventTypeNormal, EventBuildStarted, "Building driver container in state "+stateYAML.Name)
+		}
+
 		// We are kernel-affine if the yamlSpec uses {{.Values.kernelFullVersion}}
 		// then we need to replicate the object and set a name + os + kernel version
 		kernelAffine := strings.Contains(string(stateYAML.Data), ".Values.kernelFullVersion")
@@ -169,23 +250,33 @@ func ReconcileChartStates(r *SpecialResourceReconciler, templates *unstructured.
 			exit.OnError(errors.New("No KernelVersion detected, something is wrong"))
 		}
 
+		// The builds of all kernels run in parallel up to
+		// spec.build.maxConcurrent, their outcome is collected before the
+		// next state rolls out the driver
+		parallel := kernelAffine && isBuildState(stateYAML.Data)
+		if parallel {
+			resource.StartBuilds(r.ctx)
+		}
+
 		//var replicas is to keep track of the number of replicas
 		// and either to break or continue the for looop
 		for RunInfo.KernelFullVersion, version = range RunInfo.ClusterUpgradeInfo {
 
-			RunInfo.ClusterVersionMajorMinor = version.ClusterVersion
-			RunInfo.OperatingSystemDecimal = version.OSVersion
-			RunInfo.DriverToolkitImage = version.DriverToolkit.ImageURL
+			var err error
+
+			if err = setNodeVersion(r, version); err != nil {
+				return err
+			}
 
 			if kernelAffine {
 				log.Info("KernelAffine: ClusterUpgradeInfo",
 					"kernel", RunInfo.KernelFullVersion,
+					"realTime", RunInfo.KernelRealTime,
 					"os", RunInfo.OperatingSystemDecimal,
 					"cluster", RunInfo.ClusterVersionMajorMinor,
 					"driverToolkitImage", RunInfo.DriverToolkitImage)
 			}
 
-			var err error
 			step.Values, err = chartutil.CoalesceValues(&step, r.values.Object)
 			exit.OnError(err)
 
@@ -200,14 +291,17 @@ func ReconcileChartStates(r *SpecialResourceReconciler, templates *unstructured.
 				fmt.Printf("STEP VALUES --------------------------------------------------\n%s\n\n", d)
 			}
 
-			err = helmer.Run(step, step.Values,
-				&r.specialresource,
-				r.specialresource.Name,
-				r.specialresource.Spec.Namespace,
-				r.specialresource.Spec.NodeSelector,
-				RunInfo.KernelFullVersion,
-				RunInfo.OperatingSystemDecimal,
-				r.specialresource.Spec.Debug)
+			err = traced(r, "state", func() error {
+				return helmer.Run(r.ctx, step, step.Values, r.postRenderer, r.imageVerifier,
+					&r.specialresource,
+					r.specialresource.Name,
+					r.specialresource.Spec.Namespace,
+					r.specialresource.Spec.NodeSelector,
+					RunInfo.KernelFullVersion,
+					RunInfo.OperatingSystemDecimal,
+					r.specialresource.Spec.Debug)
+			}, "state", stateYAML.Name, "namespace", r.specialresource.Spec.Namespace,
+				"kernel", RunInfo.KernelFullVersion, "build", strconv.FormatBool(isBuildState(stateYAML.Data)))
 			//exit.OnError(err)
 
 			replicas += 1
@@ -215,8 +309,17 @@ func ReconcileChartStates(r *SpecialResourceReconciler, templates *unstructured.
 			// If the first replica fails we want to create all remaining
 			// ones for parallel startup, otherwise we would wait for the first
 			// then for the second etc.
+			if parallel && replicas == len(RunInfo.ClusterUpgradeInfo) {
+				if builds := resource.WaitForBuilds(); err == nil {
+					err = builds
+				}
+			}
+
 			if err != nil && replicas == len(RunInfo.ClusterUpgradeInfo) {
+				err = retryBuild(&r.specialresource, stateYAML.Name, err)
 				metrics.SetCompletedState(r.specialresource.Name, stateYAML.Name, 0)
+				conditionsState(&r.specialresource, stateYAML.Name, stateYAML.Data, err)
+				stateEvents(&r.specialresource, stateYAML.Name, stateYAML.Data, err)
 				return errors.Wrap(err, "Failed to create state: "+stateYAML.Name)
 			}
 
@@ -231,6 +334,9 @@ func ReconcileChartStates(r *SpecialResourceReconciler, templates *unstructured.
 		// If resource available, label the nodes according to the current state
 		// if e.g driver-container ready -> specialresource.openshift.io/driver-container:ready
 		operatorStatusUpdate(&r.specialresource, state.CurrentName)
+		conditionsState(&r.specialresource, stateYAML.Name, stateYAML.Data, nil)
+		clearBuildStatus(&r.specialresource, stateYAML.Name)
+		stateEvents(&r.specialresource, stateYAML.Name, stateYAML.Data, nil)
 		err := labelNodesAccordingToState(r.specialresource.Spec.NodeSelector)
 		exit.OnError(err)
 
@@ -248,7 +354,7 @@ func ReconcileChartStates(r *SpecialResourceReconciler, templates *unstructured.
 	nostate.Values, err = chartutil.CoalesceValues(&nostate, rinfo)
 	exit.OnError(err)
 
-	return helmer.Run(nostate, nostate.Values,
+	return helmer.Run(r.ctx, nostate, nostate.Values, r.postRenderer, r.imageVerifier,
 		&r.specialresource,
 		r.specialresource.Name,
 		r.specialresource.Spec.Namespace,
@@ -258,28 +364,111 @@ func ReconcileChartStates(r *SpecialResourceReconciler, templates *unstructured.
 		false)
 }
 
-func createSpecialResourceNamespace(r *SpecialResourceReconciler) {
+// impersonate applies, deletes and looks up the objects of the chart as the
+// service account of sr until restore is called
+func impersonate(sr *srov1beta1.SpecialResource) (restore func(), err error) {
 
-	ns := []byte(`apiVersion: v1
-kind: Namespace
-metadata:
-  annotations:
-    specialresource.openshift.io/wait: "true"
-    openshift.io/cluster-monitoring: "true"
-  name: `)
-
-	if r.specialresource.Spec.Namespace != "" {
-		add := []byte(r.specialresource.Spec.Namespace)
-		ns = append(ns, add...)
-	} else {
+	serviceAccount := sr.Spec.ServiceAccount
+	if serviceAccount == "" {
+		return func() {}, nil
+	}
+
+	impersonated, err := clients.Impersonate(sr.Spec.Namespace, serviceAccount)
+	if err != nil {
+		return nil, err
+	}
+	resource.Impersonated = impersonated
+	helmer.LookupConfig = clients.ImpersonateConfig(sr.Spec.Namespace, serviceAccount)
+
+	return func() { resource.Impersonated, helmer.LookupConfig = nil, nil }, nil
+}
+
+// asOperator runs create with the permissions of the operator even if the
+// chart is applied as the service account of the SpecialResource
+func asOperator(create func() error) error {
+	impersonated := resource.Impersonated
+	resource.Impersonated = nil
+	defer func() { resource.Impersonated = impersonated }()
+
+	return create()
+}
+
+// createSpecialResourceNamespace creates or updates spec.namespace with the
+// labels and annotations of the SpecialResource according to
+// spec.namespaceManagement
+func createSpecialResourceNamespace(r *SpecialResourceReconciler) error {
+
+	if r.specialresource.Spec.Namespace == "" {
 		r.specialresource.Spec.Namespace = r.specialresource.Name
-		add := []byte(r.specialresource.Spec.Namespace)
-		ns = append(ns, add...)
 	}
-	if err := resource.CreateFromYAML(ns, false, &r.specialresource, "", "", nil, "", ""); err != nil {
-		log.Info("Cannot reconcile specialresource namespace, something went horribly wrong")
-		exit.OnError(err)
+	name := r.specialresource.Spec.Namespace
+
+	if r.specialresource.Spec.NamespaceManagement == srov1beta1.NamespaceManagementIgnore {
+		ns := &v1.Namespace{}
+		err := clients.Workload().Get(context.TODO(), types.NamespacedName{Name: name}, ns)
+		if apierrors.IsNotFound(err) {
+			return errors.New("Namespace " + name + " does not exist and spec.namespaceManagement is Ignore")
+		}
+		return errors.Wrap(err, "Cannot get namespace "+name)
+	}
+
+	labels := map[string]string{}
+	for key, value := range r.specialresource.Spec.NamespaceLabels {
+		labels[key] = value
+	}
+
+	annotations := map[string]string{
+		"specialresource.openshift.io/wait": "true",
+		"openshift.io/cluster-monitoring":   "true",
+	}
+	for key, value := range r.specialresource.Spec.NamespaceAnnotations {
+		annotations[key] = value
+	}
+	// An adopted namespace is deleted with the SpecialResource like the
+	// ones it created, see finalizeNamespace
+	if r.specialresource.Spec.NamespaceManagement == srov1beta1.NamespaceManagementAdopt {
+		annotations[resource.OwnerAnnotation] = r.specialresource.Name
+	}
+
+	ns := unstructured.Unstructured{Object: map[string]interface{}{}}
+	ns.SetAPIVersion("v1")
+	ns.SetKind("Namespace")
+	ns.SetName(name)
+	if len(labels) > 0 {
+		ns.SetLabels(labels)
+	}
+	ns.SetAnnotations(annotations)
+
+	manifest, err := yaml.Marshal(ns.Object)
+	if err != nil {
+		return errors.Wrap(err, "Cannot marshal namespace "+name)
+	}
+
+	// Target namespaces are created by the operator, not the service account
+	return asOperator(func() error {
+		return resource.CreateFromYAML(manifest, false, &r.specialresource, "", "", nil, "", "", nil)
+	})
+}
+
+// createTrustedCABundle creates the ConfigMap the trusted CA bundle of the
+// cluster proxy is injected into, the injected pods mount it
+func createTrustedCABundle(r *SpecialResourceReconciler) error {
+
+	if !resource.ProxyInjection || proxy.ProxyConfiguration.TrustedCA == "" {
+		return nil
 	}
+
+	cm := []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  labels:
+    config.openshift.io/inject-trusted-cabundle: "true"
+  name: ` + proxy.TrustedCAConfigMap)
+
+	return asOperator(func() error {
+		return resource.CreateFromYAML(cm, false, &r.specialresource, r.specialresource.Name,
+			r.specialresource.Spec.Namespace, nil, "", "", nil)
+	})
 }
 
 // ReconcileChart Reconcile Hardware Configurations
@@ -290,14 +479,48 @@ func ReconcileChart(r *SpecialResourceReconciler) error {
 	// Leave this here, this is crucial for all following work
 	// Creating and setting the working namespace for the specialresource
 	// specialresource name == namespace if not metadata.namespace is set
-	createSpecialResourceNamespace(r)
+	if err := createSpecialResourceNamespace(r); err != nil {
+		return errors.Wrap(err, "Cannot reconcile namespace of the SpecialResource")
+	}
 	if err := createImagePullerRoleBinding(r); err != nil {
 		return errors.Wrap(err, "Could not create ImagePuller RoleBinding")
 	}
 
+	restore, err := impersonate(&r.specialresource)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	// Lookups of the charts of targets may read the SpecialResource namespace
+	helmer.LookupNamespaces = []string{r.specialresource.Spec.Namespace}
+	defer func() { helmer.LookupNamespaces = nil }()
+
+	// Record the objects of the chart to prune those it stops rendering
+	applied := inventory.New()
+	resource.Applied = applied
+	defer func() { resource.Applied = nil }()
+
+	if err := createTrustedCABundle(r); err != nil {
+		return errors.Wrap(err, "Could not create trusted CA bundle")
+	}
+
+	resource.Conflicts = nil
+	resource.Changes = 0
+	resource.Diffs = nil
+	defer func() { recordChanges(&r.specialresource, resource.Diffs, r.DiffHistory) }()
+	helmer.RenderCacheHits = 0
+
 	if err := ReconcileChartStates(r, templates); err != nil {
 		return errors.Wrap(err, "Cannot reconcile hardware states")
 	}
 
-	return nil
+	if err := reconcileTargets(r, templates); err != nil {
+		return err
+	}
+
+	conditionsConflicts(&r.specialresource, resource.Conflicts)
+	log.Info("Chart applied", "changes", resource.Changes, "renderCacheHits", helmer.RenderCacheHits)
+
+	return pruneInventory(r, applied)
 }
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"time"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/rollout"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// rolloutOptions returns the options of an operator driven rollout of the
+// driver DaemonSets, nil for RollingUpdate.
+func rolloutOptions(spec srov1beta1.SpecialResourceRollout) *rollout.Options {
+
+	if spec.Strategy != rollout.StrategyOrdered && spec.Strategy != rollout.StrategyCanary {
+		return nil
+	}
+
+	opts := &rollout.Options{MaxUnavailable: intstr.FromInt(1)}
+	if spec.MaxUnavailable != nil {
+		opts.MaxUnavailable = *spec.MaxUnavailable
+	}
+
+	if spec.Strategy == rollout.StrategyCanary {
+		opts.Canary = &rollout.Canary{
+			Nodes:        int(spec.CanaryNodes),
+			NodeSelector: spec.CanaryNodeSelector,
+		}
+	}
+
+	if spec.Drain.Enabled {
+		opts.Drain = &rollout.Drain{
+			Resources:   spec.Drain.Resources,
+			PodSelector: spec.Drain.PodSelector,
+			Timeout:     time.Duration(spec.Drain.TimeoutSeconds) * time.Second,
+		}
+	}
+
+	return opts
+}
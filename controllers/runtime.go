@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	goruntime "runtime"
 	"strings"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/openshift-psap/special-resource-operator/pkg/exit"
 	"github.com/openshift-psap/special-resource-operator/pkg/kernel"
 	"github.com/openshift-psap/special-resource-operator/pkg/proxy"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
 	"github.com/openshift-psap/special-resource-operator/pkg/upgrade"
 	"github.com/openshift-psap/special-resource-operator/pkg/warn"
 
@@ -40,7 +42,12 @@ type RuntimeInformation struct {
 	OperatingSystemDecimal    string                         `json:"operatingSystemDecimal"`
 	KernelFullVersion         string                         `json:"kernelFullVersion"`
 	KernelPatchVersion        string                         `json:"kernelPatchVersion"`
+	KernelRealTime            bool                           `json:"kernelRealTime"`
+	FIPS                      bool                           `json:"fips"`
+	Entitled                  bool                           `json:"entitled"`
+	EntitlementSecret         string                         `json:"entitlementSecret"`
 	DriverToolkitImage        string                         `json:"driverToolkitImage"`
+	DriverContainerImage      string                         `json:"driverContainerImage"`
 	Platform                  string                         `json:"platform"`
 	ClusterVersion            string                         `json:"clusterVersion"`
 	ClusterVersionMajorMinor  string                         `json:"clusterVersionMajorMinor"`
@@ -49,6 +56,9 @@ type RuntimeInformation struct {
 	OSImageURL                string                         `json:"osImageURL"`
 	Proxy                     proxy.Configuration            `json:"proxy"`
 	GroupName                 ResourceGroupName              `json:"groupName"`
+	CombineDevicePlugin       bool                           `json:"combineDevicePlugin"`
+	CgroupVersion             string                         `json:"cgroupVersion"`
+	Runtime                   RuntimeValues                  `json:"runtime"`
 	SpecialResource           srov1beta1.SpecialResource     `json:"specialresource"`
 }
 
@@ -59,7 +69,12 @@ var RunInfo = RuntimeInformation{
 	OperatingSystemDecimal:    "",
 	KernelFullVersion:         "",
 	KernelPatchVersion:        "",
+	KernelRealTime:            false,
+	FIPS:                      false,
+	Entitled:                  false,
+	EntitlementSecret:         "",
 	DriverToolkitImage:        "",
+	DriverContainerImage:      "",
 	Platform:                  "",
 	ClusterVersion:            "",
 	ClusterVersionMajorMinor:  "",
@@ -68,6 +83,9 @@ var RunInfo = RuntimeInformation{
 	OSImageURL:                "",
 	Proxy:                     proxy.Configuration{},
 	GroupName:                 ResourceGroupName{DriverBuild: "driver-build", DriverContainer: "driver-container", RuntimeEnablement: "runtime-enablement", DevicePlugin: "device-plugin", DeviceMonitoring: "device-monitoring", DeviceDashboard: "device-dashboard", DeviceFeatureDiscovery: "device-feature-discovery", CSIDriver: "csi-driver"},
+	CombineDevicePlugin:       false,
+	CgroupVersion:             "",
+	Runtime:                   RuntimeValues{},
 	SpecialResource:           srov1beta1.SpecialResource{},
 }
 
@@ -77,6 +95,7 @@ func logRuntimeInformation() {
 	log.Info("Runtime Information", "OperatingSystemDecimal", RunInfo.OperatingSystemDecimal)
 	log.Info("Runtime Information", "KernelFullVersion", RunInfo.KernelFullVersion)
 	log.Info("Runtime Information", "KernelPatchVersion", RunInfo.KernelPatchVersion)
+	log.Info("Runtime Information", "KernelRealTime", RunInfo.KernelRealTime)
 	log.Info("Runtime Information", "DriverToolkitImage", RunInfo.DriverToolkitImage)
 	log.Info("Runtime Information", "Platform", RunInfo.Platform)
 	log.Info("Runtime Information", "ClusterVersion", RunInfo.ClusterVersion)
@@ -85,12 +104,15 @@ func logRuntimeInformation() {
 	log.Info("Runtime Information", "PushSecretName", RunInfo.PushSecretName)
 	log.Info("Runtime Information", "OSImageURL", RunInfo.OSImageURL)
 	log.Info("Runtime Information", "Proxy", RunInfo.Proxy)
+	log.Info("Runtime Information", "CombineDevicePlugin", RunInfo.CombineDevicePlugin)
+	log.Info("Runtime Information", "CgroupVersion", RunInfo.CgroupVersion)
 }
 
 func getRuntimeInformation(r *SpecialResourceReconciler) {
 
 	var err error
 
+	cache.ExclusionSelector = r.specialresource.Spec.NodeExclusionSelector
 	err = cache.Nodes(r.specialresource.Spec.NodeSelector, false)
 	exit.OnError(errors.Wrap(err, "Failed to cache nodes"))
 
@@ -111,6 +133,28 @@ func getRuntimeInformation(r *SpecialResourceReconciler) {
 	RunInfo.ClusterVersion, RunInfo.ClusterVersionMajorMinor, err = cluster.Version()
 	exit.OnError(errors.Wrap(err, "Failed to get cluster version"))
 
+	RunInfo.FIPS, err = cluster.FIPS()
+	exit.OnError(errors.Wrap(err, "Failed to get FIPS mode"))
+
+	RunInfo.CgroupVersion, err = cluster.CgroupVersion()
+	exit.OnError(errors.Wrap(err, "Failed to get cgroup version"))
+
+	// Pull secrets of the SpecialResource take precedence over the
+	// operator and global pull secrets
+	registry.Providers = []registry.KeychainProvider{
+		registry.PullSecrets{
+			Namespace:      r.specialresource.Spec.Namespace,
+			ServiceAccount: "default",
+			Secrets:        r.specialresource.Spec.ImagePullSecrets,
+		},
+	}
+
+	// Manifest lists are resolved for the nodes we are targeting not for
+	// the node the operator is running on
+	registry.Architecture = nodeArchitecture(r.specialresource.Spec.NodeSelector)
+
+	registry.LocalSource = r.specialresource.GetAnnotations()[registry.ImageSourceAnnotation]
+
 	RunInfo.ClusterUpgradeInfo, err = upgrade.ClusterInfo()
 	exit.OnError(errors.Wrap(err, "Failed to get upgrade info"))
 
@@ -124,6 +168,36 @@ func getRuntimeInformation(r *SpecialResourceReconciler) {
 	exit.OnError(errors.Wrap(err, "Failed to get Proxy Configuration"))
 
 	r.specialresource.DeepCopyInto(&RunInfo.SpecialResource)
+
+	setRuntimeValues()
+}
+
+// nodeArchitecture returns the architecture requested by the nodeSelector or
+// the one of the selected nodes, falls back to the operators architecture.
+func nodeArchitecture(nodeSelector map[string]string) string {
+
+	if arch, found := nodeSelector["kubernetes.io/arch"]; found {
+		return arch
+	}
+
+	arch := ""
+	for _, node := range cache.Node.List.Items {
+		nodeArch, _, err := unstructured.NestedString(node.Object, "status", "nodeInfo", "architecture")
+		warn.OnError(err)
+		if nodeArch == "" {
+			continue
+		}
+		if arch != "" && arch != nodeArch {
+			log.Info("Nodes with mixed architectures selected, use a kubernetes.io/arch nodeSelector", "using", arch, "found", nodeArch)
+			continue
+		}
+		arch = nodeArch
+	}
+
+	if arch == "" {
+		return goruntime.GOARCH
+	}
+	return arch
 }
 
 func retryGetPushSecretName(r *SpecialResourceReconciler) (string, error) {
@@ -158,7 +232,7 @@ func getPushSecretName(r *SpecialResourceReconciler) (string, error) {
 	opts := []client.ListOption{
 		client.InNamespace(r.specialresource.Spec.Namespace),
 	}
-	err := clients.Interface.List(context.TODO(), secrets, opts...)
+	err := clients.Workload().List(context.TODO(), secrets, opts...)
 	if err != nil {
 		return "", errors.Wrap(err, "Client cannot get SecretList")
 	}
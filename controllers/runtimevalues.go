@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/cache"
+	"github.com/openshift-psap/special-resource-operator/pkg/kernel"
+	"github.com/openshift-psap/special-resource-operator/pkg/proxy"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// RuntimeValuesVersion of the .Values.runtime schema published as
+// docs/runtime-values.schema.json. Facts are only added within a version,
+// removing, renaming or retyping one starts a new version.
+const RuntimeValuesVersion = "v1"
+
+// RuntimeValues the facts SRO injects as .Values.runtime before a chart is
+// templated, the facts of the kernel are the ones of the current replica
+type RuntimeValues struct {
+	SchemaVersion             string               `json:"schemaVersion"`
+	KernelFullVersion         string               `json:"kernelFullVersion"`
+	KernelPatchVersion        string               `json:"kernelPatchVersion"`
+	KernelRealTime            bool                 `json:"kernelRealTime"`
+	OperatingSystemMajor      string               `json:"operatingSystemMajor"`
+	OperatingSystemMajorMinor string               `json:"operatingSystemMajorMinor"`
+	OperatingSystemDecimal    string               `json:"operatingSystemDecimal"`
+	ClusterVersion            string               `json:"clusterVersion"`
+	ClusterVersionMajorMinor  string               `json:"clusterVersionMajorMinor"`
+	DriverToolkitImage        string               `json:"driverToolkitImage"`
+	DriverToolkitImageConfig  registry.ImageConfig `json:"driverToolkitImageConfig"`
+	Platform                  string               `json:"platform"`
+	Proxy                     proxy.Configuration  `json:"proxy"`
+	FIPS                      bool                 `json:"fips"`
+	CgroupVersion             string               `json:"cgroupVersion"`
+	ContainerRuntime          string               `json:"containerRuntime"`
+	ContainerRuntimeVersion   string               `json:"containerRuntimeVersion"`
+	Arch                      string               `json:"arch"`
+}
+
+// setRuntimeValues copies the RunInfo of the current kernel into
+// .Values.runtime, the node facts are the ones of the nodes running it
+func setRuntimeValues() {
+
+	containerRuntime, containerRuntimeVersion, arch := kernelNodeFacts(RunInfo.KernelFullVersion)
+
+	// Charts read e.g. the version labels of the DTK, without a DTK or if
+	// the config cannot be read the fact is empty
+	dtkConfig := registry.ImageConfig{}
+	if RunInfo.DriverToolkitImage != "" {
+		config, err := registry.GetImageConfig(RunInfo.DriverToolkitImage)
+		warn.OnError(errors.Wrap(err, "Cannot get image config of "+RunInfo.DriverToolkitImage))
+		if err == nil {
+			dtkConfig = config
+		}
+	}
+
+	RunInfo.Runtime = RuntimeValues{
+		SchemaVersion:             RuntimeValuesVersion,
+		KernelFullVersion:         RunInfo.KernelFullVersion,
+		KernelPatchVersion:        RunInfo.KernelPatchVersion,
+		KernelRealTime:            RunInfo.KernelRealTime,
+		OperatingSystemMajor:      RunInfo.OperatingSystemMajor,
+		OperatingSystemMajorMinor: RunInfo.OperatingSystemMajorMinor,
+		OperatingSystemDecimal:    RunInfo.OperatingSystemDecimal,
+		ClusterVersion:            RunInfo.ClusterVersion,
+		ClusterVersionMajorMinor:  RunInfo.ClusterVersionMajorMinor,
+		DriverToolkitImage:        RunInfo.DriverToolkitImage,
+		DriverToolkitImageConfig:  dtkConfig,
+		Platform:                  RunInfo.Platform,
+		Proxy:                     RunInfo.Proxy,
+		FIPS:                      RunInfo.FIPS,
+		CgroupVersion:             RunInfo.CgroupVersion,
+		ContainerRuntime:          containerRuntime,
+		ContainerRuntimeVersion:   containerRuntimeVersion,
+		Arch:                      arch,
+	}
+}
+
+// kernelNodeFacts returns the CRI runtime, e.g. cri-o, its version and the
+// GOARCH of the first cached node running kernelFullVersion
+func kernelNodeFacts(kernelFullVersion string) (string, string, string) {
+
+	for _, node := range cache.Node.List.Items {
+		if version, _ := kernel.NodeFullVersion(node); version != kernelFullVersion {
+			continue
+		}
+
+		arch, _, _ := unstructured.NestedString(node.Object, "status", "nodeInfo", "architecture")
+		cri, _, _ := unstructured.NestedString(node.Object, "status", "nodeInfo", "containerRuntimeVersion")
+
+		// The kubelet reports the runtime as <name>://<version>
+		name, version := cri, ""
+		if parts := strings.SplitN(cri, "://", 2); len(parts) == 2 {
+			name, version = parts[0], parts[1]
+		}
+
+		return name, version, arch
+	}
+
+	return "", "", ""
+}
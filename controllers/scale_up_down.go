@@ -33,7 +33,7 @@ func labelNodesAccordingToState(nodeSelector map[string]string) error {
 
 		updated.SetLabels(labels)
 
-		err := clients.Interface.Update(context.TODO(), updated)
+		err := clients.Workload().Update(context.TODO(), updated)
 		if apierrors.IsForbidden(err) {
 			return errors.Wrap(err, "forbidden check Role, ClusterRole and Bindings for operator %s")
 		}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/blacklist"
+	"github.com/openshift-psap/special-resource-operator/pkg/build"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/resource"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// reconcileMOKEnrollment creates a MachineConfig enrolling the signing
+// certificate as Machine Owner Key for every pool of the driver nodes, the
+// ones of pools without driver nodes anymore are deleted.
+func reconcileMOKEnrollment(r *SpecialResourceReconciler) error {
+
+	sr := &r.specialresource
+	signing := sr.Spec.DriverContainer.Signing
+
+	available, err := blacklist.Available()
+	if err != nil {
+		return errors.Wrap(err, "Error discovering machineconfigs API resource")
+	}
+	if !available {
+		if signing.Enroll {
+			return errors.New("spec.driverContainer.signing.enroll needs MachineConfigs, the cluster has no machineconfigs API resource")
+		}
+		return nil
+	}
+
+	pools := []string{}
+	if signing.Enroll {
+		if signing.KeySecret == "" {
+			return errors.New("spec.driverContainer.signing.enroll is set but no keySecret")
+		}
+		pools = machineConfigPools(nil)
+	}
+
+	names := []string{}
+	for _, pool := range pools {
+		names = append(names, blacklist.MOKName(sr.Name, pool))
+	}
+
+	if err := blacklist.PruneMOK(sr.Name, names); err != nil {
+		return err
+	}
+
+	if len(pools) == 0 {
+		return nil
+	}
+
+	secret := &v1.Secret{}
+	key := types.NamespacedName{Namespace: sr.Spec.Namespace, Name: signing.KeySecret}
+	if err := clients.Workload().Get(context.TODO(), key, secret); err != nil {
+		return errors.Wrap(err, "Cannot get signing Secret "+key.String())
+	}
+
+	for _, pool := range pools {
+		labels, err := blacklist.PoolLabels(pool)
+		if err != nil {
+			return err
+		}
+		mc, err := blacklist.MOKMachineConfig(sr.Name, pool, labels, secret.Data[build.SigningCert], secret.Data[blacklist.MOKPasswordHash])
+		if err != nil {
+			return err
+		}
+		if err := resource.CRUD(mc, false, sr, sr.Name, sr.Spec.Namespace); err != nil {
+			return errors.Wrap(err, "Cannot reconcile MachineConfig "+mc.GetName())
+		}
+	}
+
+	return nil
+}
@@ -6,21 +6,31 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"text/template"
+	"time"
 
 	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/build"
 	"github.com/openshift-psap/special-resource-operator/pkg/clients"
 	"github.com/openshift-psap/special-resource-operator/pkg/color"
 	"github.com/openshift-psap/special-resource-operator/pkg/exit"
 	"github.com/openshift-psap/special-resource-operator/pkg/filter"
+	"github.com/openshift-psap/special-resource-operator/pkg/firmware"
 	"github.com/openshift-psap/special-resource-operator/pkg/helmer"
 	helmerv1beta1 "github.com/openshift-psap/special-resource-operator/pkg/helmer/api/v1beta1"
 	"github.com/openshift-psap/special-resource-operator/pkg/metrics"
+	"github.com/openshift-psap/special-resource-operator/pkg/poll"
+	"github.com/openshift-psap/special-resource-operator/pkg/readiness"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
 	"github.com/openshift-psap/special-resource-operator/pkg/resource"
+	"github.com/openshift-psap/special-resource-operator/pkg/scan"
 	"github.com/openshift-psap/special-resource-operator/pkg/slice"
 	"github.com/openshift-psap/special-resource-operator/pkg/storage"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
 	"github.com/pkg/errors"
 	"helm.sh/helm/v3/pkg/chart"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	controllerruntime "sigs.k8s.io/controller-runtime"
@@ -96,9 +106,62 @@ func SpecialResourcesReconcile(r *SpecialResourceReconciler, req ctrl.Request) (
 		return reconcile.Result{}, nil
 	}
 
+	if isPaused(&r.parent) {
+		log.Info("RECONCILE SKIPPED: Paused by annotation", "annotation", PausedAnnotation)
+		return reconcile.Result{}, nil
+	}
+
+	// SpecialResources in DependsOn have to be Ready first, dependents are
+	// polled since the SR of the request is the only one reconciled.
+	if reason, err := dependsOnWaiting(r.parent, specialresources); err != nil {
+		setStatusCondition(&r.parent, metav1.Condition{
+			Type:    srov1beta1.ConditionDependenciesReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "DependencyCycle",
+			Message: err.Error(),
+		})
+		log.Info("RECONCILE ERROR: Cannot resolve dependsOn", "error", err.Error())
+		return reconcile.Result{}, nil
+	} else if reason != "" {
+		setStatusConditions(&r.parent,
+			metav1.Condition{
+				Type:    srov1beta1.ConditionDependenciesReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  "WaitingForDependencies",
+				Message: reason,
+			},
+			metav1.Condition{
+				Type:    srov1beta1.ConditionReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  "WaitingForDependencies",
+				Message: reason,
+			})
+		log.Info("RECONCILE REQUEUE: Waiting for dependsOn", "reason", reason)
+		return reconcile.Result{Requeue: true, RequeueAfter: 30 * time.Second}, nil
+	} else if len(r.parent.Spec.DependsOn) > 0 {
+		setStatusCondition(&r.parent, metav1.Condition{
+			Type:    srov1beta1.ConditionDependenciesReady,
+			Status:  metav1.ConditionTrue,
+			Reason:  "DependenciesReady",
+			Message: "All SpecialResources in dependsOn are Ready",
+		})
+	}
+
+	conditionsReconciling(&r.parent)
+
 	log.Info("Resolving Dependencies")
 
-	pchart, err := helmer.Load(r.parent.Spec.Chart)
+	verifier, err := chartVerifier(&r.parent)
+	if err != nil {
+		operatorStatusUpdate(&r.parent, fmt.Sprintf("%v", err))
+		return reconcile.Result{}, err
+	}
+
+	var pchart *chart.Chart
+	err = traced(r, "chart fetch", func() error {
+		pchart, err = helmer.LoadVerified(r.parent.Spec.Chart, verifier)
+		return err
+	}, "chart", r.parent.Spec.Chart.Name)
 	if err != nil {
 		operatorStatusUpdate(&r.parent, fmt.Sprintf("%v", err))
 		return reconcile.Result{}, err
@@ -110,8 +173,15 @@ func SpecialResourcesReconcile(r *SpecialResourceReconciler, req ctrl.Request) (
 		log = r.Log.WithName(color.Print(r.dependency.Name, color.Purple))
 		log.Info("Getting Dependency")
 
-		cchart, err := helmer.Load(r.dependency.HelmChart)
-		exit.OnError(err)
+		var cchart *chart.Chart
+		err = traced(r, "chart fetch", func() error {
+			cchart, err = helmer.LoadVerified(r.dependency.HelmChart, verifier)
+			return err
+		}, "chart", r.dependency.HelmChart.Name)
+		if err != nil {
+			operatorStatusUpdate(&r.parent, fmt.Sprintf("%v", err))
+			return reconcile.Result{}, err
+		}
 
 		// We save the dependency chain so we can restore specialresources
 		// if one is deleted that is a dependency of another
@@ -128,7 +198,10 @@ func SpecialResourcesReconcile(r *SpecialResourceReconciler, req ctrl.Request) (
 
 		var child srov1beta1.SpecialResource
 		// Assign the specialresource to the reconciler object
-		if child, err = getDependencyFrom(specialresources, r.dependency.Name); err != nil {
+		if child, err = getDependencyFrom(specialresources, r.dependency.Name); err == nil && isPaused(&child) {
+			log.Info("Dependency paused by annotation, skipping", "annotation", PausedAnnotation)
+			continue
+		} else if err != nil {
 			log.Info("Could not get SpecialResource dependency", "error", fmt.Sprintf("%v", err))
 			if err = createSpecialResourceFrom(r, cchart, r.dependency.HelmChart); err != nil {
 				log.Info("RECONCILE REQUEUE: Dependency creation failed ", "error", fmt.Sprintf("%v", err))
@@ -137,29 +210,57 @@ func SpecialResourcesReconcile(r *SpecialResourceReconciler, req ctrl.Request) (
 			// We need to fetch the newly created SpecialResources, reconciling
 			return reconcile.Result{}, nil
 		}
-		if err := ReconcileSpecialResourceChart(r, child, cchart, r.dependency.Set); err != nil {
+		conditionsReconciling(&child)
+		err = traced(r, "chart", func() error {
+			return ReconcileSpecialResourceChart(r, child, cchart, r.dependency.Set)
+		}, "specialresource", child.Name)
+		if err != nil {
 			// We do not want a stacktrace here, errors.Wrap already created
 			// breadcrumb of errors to follow. Just sprintf with %v rather than %+v
 			operatorStatusUpdate(&child, fmt.Sprintf("%v", err))
+			conditionsFailed(&child, failedReason(err), err)
 			log.Info("RECONCILE REQUEUE: Could not reconcile chart", "error", fmt.Sprintf("%v", err))
 			//return reconcile.Result{}, errors.New("Reconciling failed")
 			return reconcile.Result{Requeue: true}, nil
 		}
+		if isDryRun(r, &child) {
+			continue
+		}
+		operatorStatusUpdate(&child, srov1beta1.StateReady)
+		conditionsReconciled(&child)
+		metrics.SetLastSuccessfulReconcile(child.Name)
 
 	}
 
 	log.Info("Reconciling Parent")
-	if err := ReconcileSpecialResourceChart(r, r.parent, pchart, r.parent.Spec.Set); err != nil {
+	err = traced(r, "chart", func() error {
+		return ReconcileSpecialResourceChart(r, r.parent, pchart, r.parent.Spec.Set)
+	}, "specialresource", r.parent.Name)
+	// A failed reconcile may leave kernels behind, summarize either way
+	if !isDryRun(r, &r.parent) {
+		updateKernelStatus(&r.parent)
+	}
+	if err != nil {
 		// We do not want a stacktrace here, errors.Wrap already created
 		// breadcrumb of errors to follow. Just sprintf with %v rather than %+v
 		operatorStatusUpdate(&r.parent, fmt.Sprintf("%v", err))
+		conditionsFailed(&r.parent, failedReason(err), err)
 		log.Info("RECONCILE REQUEUE: Could not reconcile chart", "error", fmt.Sprintf("%v", err))
 		//return reconcile.Result{}, errors.New("Reconciling failed")
 		return reconcile.Result{Requeue: true}, nil
 	}
 
+	if isDryRun(r, &r.parent) {
+		log.Info("RECONCILE SUCCESS: Chart rendered, nothing applied")
+		return reconcile.Result{}, nil
+	}
+
+	operatorStatusUpdate(&r.parent, srov1beta1.StateReady)
+	conditionsReconciled(&r.parent)
+	metrics.SetLastSuccessfulReconcile(r.parent.Name)
+
 	log.Info("RECONCILE SUCCESS: All resources done")
-	return reconcile.Result{}, nil
+	return reconcile.Result{RequeueAfter: r.parent.Spec.ResyncPeriod.Duration}, nil
 }
 
 func TemplateFragmentOrDie(sr interface{}) {
@@ -194,13 +295,108 @@ func ReconcileSpecialResourceChart(r *SpecialResourceReconciler, sr srov1beta1.S
 
 	r.specialresource = sr
 	r.chart = *chart
+	r.chart.Templates = readiness.AddHelpers(firmware.AddHelpers(chart.Templates))
 	r.values = values
 
 	log = r.Log.WithName(color.Print(r.specialresource.Name, color.Green))
 	log.Info("Reconciling Chart")
 
-	getRuntimeInformation(r)
+	start := time.Now()
+	defer func() { metrics.ObserveReconcile(sr.Name, time.Since(start)) }()
+
+	if err := validateValues(r); err != nil {
+		return err
+	}
+
+	postRenderer, err := loadPostRenderer(&sr)
+	if err != nil {
+		return err
+	}
+	r.postRenderer = postRenderer
+
+	if err := reconcileNodeFeatures(r); err != nil {
+		return errors.Wrap(err, "Node features not discovered")
+	}
+
+	_ = traced(r, "runtime information", func() error {
+		getRuntimeInformation(r)
+		return nil
+	})
 	logRuntimeInformation()
+	kernelEvents(&sr, RunInfo.ClusterUpgradeInfo)
+
+	if err := traced(r, "watched releases", func() error { return watchReleases(r) }); err != nil {
+		return err
+	}
+
+	if err := traced(r, "image verification", func() error { return verifyImages(r) }); err != nil {
+		return errors.Wrap(err, "Image signature verification failed")
+	}
+
+	if err := setupImageScanner(r); err != nil {
+		return errors.Wrap(err, "Cannot setup vulnerability scan")
+	}
+
+	if err := traced(r, "prebuilt verification", func() error { return verifyPrebuilt(r) }); err != nil {
+		return errors.Wrap(err, "Prebuilt driver container verification failed")
+	}
+
+	if r.specialresource.Spec.DriverContainer.Firmware.Source != "" {
+		if err := firmware.Validate(r.chart.Templates); err != nil {
+			return errors.Wrap(err, "Invalid firmware configuration")
+		}
+	}
+
+	dryRun := isDryRun(r, &sr)
+
+	if !dryRun {
+		if err := reconcileModuleBlacklist(r); err != nil {
+			return errors.Wrap(err, "Module blacklist not applied")
+		}
+		if err := reconcileMOKEnrollment(r); err != nil {
+			return errors.Wrap(err, "Signing certificate not enrolled")
+		}
+	}
+
+	// Record the digest of every image used so reconciles can be audited
+	registry.Digests = registry.NewDigestRecorder()
+	for _, nodeVersion := range RunInfo.ClusterUpgradeInfo {
+		if nodeVersion.DriverToolkit.ImageURL == "" {
+			continue
+		}
+		_, err := registry.Digests.Record(nodeVersion.DriverToolkit.ImageURL)
+		warn.OnError(errors.Wrap(err, "Cannot resolve DTK digest"))
+	}
+
+	resource.PromoteRepository = r.specialresource.Spec.DriverContainer.Promote.Repository
+	resource.PromotePushSecret = r.specialresource.Spec.DriverContainer.Promote.PushSecret
+	resource.SBOMFormat = r.specialresource.Spec.DriverContainer.SBOM.Format
+
+	backend, err := build.Get(r.specialresource.Spec.Build.Backend)
+	if err != nil {
+		return errors.Wrap(err, "Cannot get build backend")
+	}
+	resource.BuildBackend = backend
+
+	build.Cache = nil
+	if cache := r.specialresource.Spec.Build.Cache; cache.Enabled {
+		build.Cache = &build.CacheOptions{Size: cache.Size, StorageClassName: cache.StorageClassName}
+	}
+
+	resource.MaxConcurrentBuilds = int(r.specialresource.Spec.Build.MaxConcurrent)
+
+	poll.BuildLogLimit = 4096
+	if limit := r.specialresource.Spec.Build.Logs.LimitBytes; limit > 0 {
+		poll.BuildLogLimit = int(limit)
+	}
+
+	resource.Rollout = rolloutOptions(r.specialresource.Spec.Rollout)
+	resource.ProxyInjection = !r.specialresource.Spec.Proxy.Disabled
+	resource.FIPS, resource.FIPSStrict = RunInfo.FIPS, r.specialresource.Spec.FIPS.Strict
+	resource.NodeExclusion = r.specialresource.Spec.NodeExclusionSelector
+	resource.VerifyModules = r.specialresource.Spec.DriverContainer.VerifyModules
+	resource.SigningKeySecret = r.specialresource.Spec.DriverContainer.Signing.KeySecret
+	resource.SigningImage = r.specialresource.Spec.DriverContainer.Signing.Image
 
 	for idx, dep := range r.specialresource.Spec.Dependencies {
 		if dep.Set.Object == nil {
@@ -218,7 +414,7 @@ func ReconcileSpecialResourceChart(r *SpecialResourceReconciler, sr srov1beta1.S
 		r.specialresource.Spec.Set.Object = make(map[string]interface{})
 	}
 
-	err := unstructured.SetNestedField(r.specialresource.Spec.Set.Object, "Values", "kind")
+	err = unstructured.SetNestedField(r.specialresource.Spec.Set.Object, "Values", "kind")
 	exit.OnError(err)
 	err = unstructured.SetNestedField(r.specialresource.Spec.Set.Object, "sro.openshift.io/v1beta1", "apiVersion")
 	exit.OnError(err)
@@ -236,6 +432,16 @@ func ReconcileSpecialResourceChart(r *SpecialResourceReconciler, sr srov1beta1.S
 
 	TemplateFragmentOrDie(&r.values)
 
+	// The rendered manifests of a dry-run are stored in a ConfigMap
+	if r.values.Object, err = resolveValueReferences(r.specialresource.Spec.Namespace, r.values.Object, !dryRun); err != nil {
+		return err
+	}
+
+	if dryRun {
+		return renderChart(r)
+	}
+	resumeFromDryRun(&r.specialresource)
+
 	// Add a finalizer to CR if it does not already have one
 	if !contains(r.specialresource.GetFinalizers(), specialresourceFinalizer) {
 		if err := addFinalizer(r); err != nil {
@@ -244,8 +450,143 @@ func ReconcileSpecialResourceChart(r *SpecialResourceReconciler, sr srov1beta1.S
 		}
 	}
 
+	if err := reconcileCleanupPolicy(r); err != nil {
+		return err
+	}
+
 	// Reconcile the special resource chart
-	return ReconcileChart(r)
+	reconciled := ReconcileChart(r)
+	conditionsVulnerabilityScan(&r.specialresource, reconciled)
+
+	return reconciled
+}
+
+// verifyImages checks the cosign signatures of the DTK images before they
+// are used, prebuilt driver containers are verified before their CRUD.
+func verifyImages(r *SpecialResourceReconciler) error {
+
+	r.imageVerifier = nil
+
+	verification := r.specialresource.Spec.Verification
+	if !verification.Enabled() {
+		return nil
+	}
+
+	verifier, err := registry.NewVerifier(r.specialresource.Spec.Namespace, verification.PublicKeySecret,
+		verification.KeylessRootsConfigMap, verification.KeylessIdentity, verification.KeylessIssuer)
+	if err != nil {
+		return err
+	}
+
+	verifier.Skip = verification.SkipRepositories
+
+	// The DTK images are used by their verified digest from here on
+	for kernelVersion, nodeVersion := range RunInfo.ClusterUpgradeInfo {
+		if nodeVersion.DriverToolkit.ImageURL == "" {
+			continue
+		}
+		pinned, err := verifier.Verify(nodeVersion.DriverToolkit.ImageURL)
+		if err != nil {
+			return errors.Wrap(err, "DTK for kernel "+kernelVersion)
+		}
+		nodeVersion.DriverToolkit.ImageURL = pinned
+		RunInfo.ClusterUpgradeInfo[kernelVersion] = nodeVersion
+	}
+
+	r.imageVerifier = verifier
+
+	return nil
+}
+
+// setupImageScanner gates the driver containers on their vulnerability scan
+// if the SpecialResource configures one
+func setupImageScanner(r *SpecialResourceReconciler) error {
+
+	resource.ImageScanner = nil
+
+	vulnerabilityScan := r.specialresource.Spec.VulnerabilityScan
+	if !vulnerabilityScan.Enabled() {
+		return nil
+	}
+
+	scanner, err := scan.NewScanner(r.specialresource.Spec.Namespace, vulnerabilityScan.Quay,
+		vulnerabilityScan.Webhook, vulnerabilityScan.TokenSecret, vulnerabilityScan.Severity)
+	if err != nil {
+		return err
+	}
+
+	resource.ImageScanner = scanner
+
+	return nil
+}
+
+// validateValues checks the values of the SpecialResource against the
+// values.schema.json of the chart before anything is rendered
+func validateValues(r *SpecialResourceReconciler) error {
+
+	if !helmer.HasSchema(&r.chart) {
+		return nil
+	}
+
+	violations, err := helmer.SchemaViolations(&r.chart, r.values.Object)
+	if err != nil {
+		return err
+	}
+
+	if len(violations) > 0 {
+		msg := strings.Join(violations, "; ")
+		if len(msg) > 32000 {
+			msg = msg[:32000] + "..."
+		}
+		setStatusCondition(&r.specialresource, metav1.Condition{
+			Type:    srov1beta1.ConditionValuesValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  "SchemaViolation",
+			Message: msg,
+		})
+		return errors.New("Values violate the chart schema: " + msg)
+	}
+
+	setStatusCondition(&r.specialresource, metav1.Condition{
+		Type:    srov1beta1.ConditionValuesValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  "SchemaSatisfied",
+		Message: "Values satisfy the values.schema.json of the chart",
+	})
+
+	return nil
+}
+
+// chartVerifier returns the verifier of the charts of sr and its
+// dependencies, nil if chart verification is not configured
+func chartVerifier(sr *srov1beta1.SpecialResource) (*helmer.ChartVerifier, error) {
+
+	verification := sr.Spec.ChartVerification
+	if !verification.Enabled() {
+		return nil, nil
+	}
+
+	return helmer.NewChartVerifier(sr.Spec.Namespace, verification.KeyringSecret, verification.PublicKeySecret)
+}
+
+// loadPostRenderer returns the patches of the kustomize ConfigMap of sr,
+// nil if the rendered chart is applied as is. The patches are applied with
+// the rights of the operator, so they are only read from its namespace.
+func loadPostRenderer(sr *srov1beta1.SpecialResource) (*helmer.PostRenderer, error) {
+
+	name := sr.Spec.PostRender.KustomizeConfigMap
+	if name == "" {
+		return nil, nil
+	}
+	namespace := os.Getenv("OPERATOR_NAMESPACE")
+
+	cm, err := clients.GetConfigMap(namespace, name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot get post-render ConfigMap "+namespace+"/"+name)
+	}
+
+	postRenderer, err := helmer.NewKustomizePostRenderer(cm.Data)
+	return postRenderer, errors.Wrap(err, "Invalid post-render ConfigMap "+namespace+"/"+name)
 }
 
 func FindSR(a []srov1beta1.SpecialResource, x string, by string) (int, bool) {
@@ -306,7 +647,7 @@ func createSpecialResourceFrom(r *SpecialResourceReconciler, ch *chart.Chart, dp
 		r.specialresource.Name,
 		r.specialresource.Namespace,
 		r.specialresource.Spec.NodeSelector,
-		"", ""); err != nil {
+		"", "", nil); err != nil {
 		log.Info("Cannot create, something went horribly wrong")
 		exit.OnError(err)
 	}
@@ -22,10 +22,14 @@ import (
 
 	"github.com/go-logr/logr"
 	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/blacklist"
 	"github.com/openshift-psap/special-resource-operator/pkg/clients"
 	"github.com/openshift-psap/special-resource-operator/pkg/color"
 	"github.com/openshift-psap/special-resource-operator/pkg/conditions"
 	"github.com/openshift-psap/special-resource-operator/pkg/filter"
+	"github.com/openshift-psap/special-resource-operator/pkg/helmer"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/openshift-psap/special-resource-operator/pkg/trace"
 	buildv1 "github.com/openshift/api/build/v1"
 	secv1 "github.com/openshift/api/security/v1"
 
@@ -42,8 +46,9 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 var (
@@ -54,13 +59,26 @@ var (
 type SpecialResourceReconciler struct {
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	// DryRun renders the charts of all SpecialResources instead of
+	// applying them, see DryRunAnnotation
+	DryRun bool
+	// DiffHistory number of updated objects and their changed fields kept
+	// in the status of a SpecialResource, 0 only logs them
+	DiffHistory int
+	// Options of the work queue, SpecialResources are reconciled one at a
+	// time, see controllerOptions
+	Options ReconcileOptions
 
 	specialresource srov1beta1.SpecialResource
 	parent          srov1beta1.SpecialResource
 	chart           chart.Chart
 	values          unstructured.Unstructured
+	postRenderer    *helmer.PostRenderer
+	imageVerifier   *registry.Verifier
 	dependency      srov1beta1.SpecialResourceDependency
 	clusterOperator configv1.ClusterOperator
+	// ctx carries the span of the running phase of the reconcile
+	ctx context.Context
 }
 
 // Reconcile Reconiliation entry point
@@ -72,6 +90,10 @@ func (r *SpecialResourceReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	log = r.Log.WithName(color.Print("preamble", color.Brown))
 	log.Info("Controller Request", "Name", req.Name, "Namespace", req.Namespace)
 
+	ctx, span := trace.Start(ctx, "reconcile", "specialresource", req.Name)
+	defer func() { span.End(err) }()
+	r.ctx = ctx
+
 	conds := conditions.NotAvailableProgressingNotDegraded(
 		"Reconciling "+req.Name,
 		"Reconciling "+req.Name,
@@ -79,7 +101,11 @@ func (r *SpecialResourceReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	)
 
 	// Do some preflight checks and get the cluster upgrade info
-	if res, err = SpecialResourceUpgrade(r, req); err != nil {
+	err = traced(r, "dtk resolution", func() error {
+		res, err = SpecialResourceUpgrade(r, req)
+		return err
+	})
+	if err != nil {
 		return res, errors.Wrap(err, "RECONCILE ERROR: Cannot upgrade special resource")
 	}
 	// A resource is being reconciled set status to not available and only
@@ -93,6 +119,8 @@ func (r *SpecialResourceReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	} else {
 		return res, errors.Wrap(err, "RECONCILE ERROR: Cannot reconcile special resource")
 	}
+	// spec.resyncPeriod of the reconciled SpecialResource
+	resync := res.RequeueAfter
 
 	// Only if we're successfull we're going to update the status to
 	// Available otherwise return the reconcile error
@@ -101,7 +129,7 @@ func (r *SpecialResourceReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		return res, nil
 	}
 	log.Info("RECONCILE SUCCESS: Reconcile")
-	return reconcile.Result{}, nil
+	return reconcile.Result{RequeueAfter: resync}, nil
 }
 
 // SetupWithManager main initalization for manager
@@ -109,7 +137,7 @@ func (r *SpecialResourceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	log = r.Log.WithName(color.Print("setup", color.Brown))
 
 	if clients.GetPlatform() == "OCP" {
-		return ctrl.NewControllerManagedBy(mgr).
+		b := ctrl.NewControllerManagedBy(mgr).
 			For(&srov1beta1.SpecialResource{}).
 			Owns(&v1.Pod{}).
 			Owns(&appsv1.DaemonSet{}).
@@ -125,9 +153,21 @@ func (r *SpecialResourceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			Owns(&rbacv1.ClusterRoleBinding{}).
 			Owns(&secv1.SecurityContextConstraints{}).
 			Owns(&v1.Secret{}).
-			WithOptions(controller.Options{
-				MaxConcurrentReconciles: 1,
-			}).
+			Watches(&source.Kind{Type: &configv1.ClusterVersion{}}, handler.EnqueueRequestsFromMapFunc(upgradeRequests)).
+			Watches(&source.Kind{Type: &v1.Node{}}, handler.EnqueueRequestsFromMapFunc(nodeRequests)).
+			Watches(&source.Kind{Type: &v1.Secret{}}, handler.EnqueueRequestsFromMapFunc(secretRequests)).
+			Watches(&source.Kind{Type: &v1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(configMapRequests))
+
+		if available, err := blacklist.Available(); err != nil {
+			return errors.Wrap(err, "Cannot discover MachineConfigPools")
+		} else if available {
+			pool := &unstructured.Unstructured{}
+			pool.SetAPIVersion("machineconfiguration.openshift.io/v1")
+			pool.SetKind("MachineConfigPool")
+			b = b.Watches(&source.Kind{Type: pool}, handler.EnqueueRequestsFromMapFunc(machineConfigPoolRequests))
+		}
+
+		return b.WithOptions(r.Options.controllerOptions()).
 			WithEventFilter(filter.Predicate()).
 			Complete(r)
 	} else {
@@ -145,9 +185,10 @@ func (r *SpecialResourceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			Owns(&rbacv1.ClusterRole{}).
 			Owns(&rbacv1.ClusterRoleBinding{}).
 			Owns(&v1.Secret{}).
-			WithOptions(controller.Options{
-				MaxConcurrentReconciles: 1,
-			}).
+			Watches(&source.Kind{Type: &v1.Node{}}, handler.EnqueueRequestsFromMapFunc(nodeRequests)).
+			Watches(&source.Kind{Type: &v1.Secret{}}, handler.EnqueueRequestsFromMapFunc(secretRequests)).
+			Watches(&source.Kind{Type: &v1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(configMapRequests)).
+			WithOptions(r.Options.controllerOptions()).
 			WithEventFilter(filter.Predicate()).
 			Complete(r)
 	}
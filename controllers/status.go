@@ -8,6 +8,7 @@ import (
 	"github.com/openshift-psap/special-resource-operator/pkg/clients"
 	"github.com/openshift-psap/special-resource-operator/pkg/color"
 	"github.com/openshift-psap/special-resource-operator/pkg/exit"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
 	"github.com/openshift-psap/special-resource-operator/pkg/warn"
 	configv1 "github.com/openshift/api/config/v1"
 	operatorv1helpers "github.com/openshift/library-go/pkg/operator/v1helpers"
@@ -34,6 +35,13 @@ func operatorStatusUpdate(sr *srov1beta1.SpecialResource, state string) {
 	}
 
 	update.Status.State = state
+
+	if images, digests := registry.Digests.Images(); len(images) > 0 {
+		update.Status.Images = []srov1beta1.SpecialResourceImageDigest{}
+		for _, image := range images {
+			update.Status.Images = append(update.Status.Images, srov1beta1.SpecialResourceImageDigest{Image: image, Digest: digests[image]})
+		}
+	}
 	update.DeepCopyInto(sr)
 
 	err = clients.Interface.Status().Update(context.TODO(), sr)
@@ -152,6 +160,9 @@ func (r *SpecialResourceReconciler) clusterOperatorUpdateRelatedObjects() error
 			log.Info("Adding to relatedObjects", "namespace", sr.Spec.Namespace)
 			relatedObjects = append(relatedObjects, configv1.ObjectReference{Group: "", Resource: "namespaces", Name: sr.Spec.Namespace})
 		}
+		for _, target := range sr.Spec.Targets.Namespaces {
+			relatedObjects = append(relatedObjects, configv1.ObjectReference{Group: "", Resource: "namespaces", Name: target.Name})
+		}
 	}
 
 	r.clusterOperator.Status.RelatedObjects = relatedObjects
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chartutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// targetNamespaces returns the listed target namespaces of sr followed by
+// the selected ones that are not listed, spec.namespace is never a target
+func targetNamespaces(sr *srov1beta1.SpecialResource) ([]srov1beta1.SpecialResourceTargetNamespace, error) {
+
+	targets := []srov1beta1.SpecialResourceTargetNamespace{}
+	seen := map[string]bool{sr.Spec.Namespace: true}
+
+	for _, target := range sr.Spec.Targets.Namespaces {
+		if !seen[target.Name] {
+			targets = append(targets, target)
+			seen[target.Name] = true
+		}
+	}
+
+	if sr.Spec.Targets.NamespaceSelector == nil {
+		return targets, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(sr.Spec.Targets.NamespaceSelector)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid namespaceSelector")
+	}
+
+	namespaces := &v1.NamespaceList{}
+	if err := clients.Workload().List(context.TODO(), namespaces, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, errors.Wrap(err, "Cannot list target namespaces")
+	}
+
+	for _, namespace := range namespaces.Items {
+		if !seen[namespace.Name] {
+			targets = append(targets, srov1beta1.SpecialResourceTargetNamespace{Name: namespace.Name})
+			seen[namespace.Name] = true
+		}
+	}
+
+	return targets, nil
+}
+
+// listedTarget returns true if the namespace is listed in the targets of
+// sr, only listed namespaces are created and deleted by the SpecialResource
+func listedTarget(sr *srov1beta1.SpecialResource, namespace string) bool {
+	for _, target := range sr.Spec.Targets.Namespaces {
+		if target.Name == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// targetValues overlays the set values of target on values
+func targetValues(values unstructured.Unstructured, target srov1beta1.SpecialResourceTargetNamespace) unstructured.Unstructured {
+
+	if target.Set.Object == nil {
+		return values
+	}
+
+	overlay := target.Set.DeepCopy()
+	TemplateFragmentOrDie(overlay)
+
+	return unstructured.Unstructured{
+		Object: chartutil.CoalesceTables(overlay.Object, values.DeepCopy().Object),
+	}
+}
+
+// reconcileTargets reconciles the chart states in every target namespace as
+// if it was spec.namespace, with the values of the target overlaid
+func reconcileTargets(r *SpecialResourceReconciler, templates *unstructured.Unstructured) error {
+
+	if !r.specialresource.Spec.Targets.Enabled() {
+		return nil
+	}
+
+	targets, err := targetNamespaces(&r.specialresource)
+	if err != nil {
+		return err
+	}
+
+	namespace, values := r.specialresource.Spec.Namespace, r.values
+	defer func() {
+		r.specialresource.Spec.Namespace = namespace
+		RunInfo.SpecialResource.Spec.Namespace = namespace
+		r.values = values
+	}()
+
+	for _, target := range targets {
+
+		log.Info("Reconciling target", "namespace", target.Name)
+
+		r.specialresource.Spec.Namespace = target.Name
+		RunInfo.SpecialResource.Spec.Namespace = target.Name
+		r.values = targetValues(values, target)
+
+		// Selected namespaces exist and are not owned by the SpecialResource
+		if listedTarget(&r.specialresource, target.Name) {
+			if err := createSpecialResourceNamespace(r); err != nil {
+				return errors.Wrap(err, "Cannot reconcile target namespace "+target.Name)
+			}
+		}
+		if err := createImagePullerRoleBinding(r); err != nil {
+			return errors.Wrap(err, "Could not create ImagePuller RoleBinding in "+target.Name)
+		}
+		if err := createTrustedCABundle(r); err != nil {
+			return errors.Wrap(err, "Could not create trusted CA bundle in "+target.Name)
+		}
+
+		if err := ReconcileChartStates(r, templates); err != nil {
+			return errors.Wrap(err, "Cannot reconcile target namespace "+target.Name)
+		}
+	}
+
+	return nil
+}
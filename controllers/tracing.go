@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"github.com/openshift-psap/special-resource-operator/pkg/trace"
+)
+
+// traced runs phase in the span name of the reconcile of r, spans started
+// by phase with r.ctx are its children
+func traced(r *SpecialResourceReconciler, name string, phase func() error, attributes ...string) error {
+
+	parent := r.ctx
+	defer func() { r.ctx = parent }()
+
+	ctx, span := trace.Start(parent, name, attributes...)
+	r.ctx = ctx
+
+	err := phase()
+	span.End(err)
+
+	return err
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+	"os"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// triggerRequests reconciles the SpecialResources for which triggered is
+// true, see spec.triggers
+func triggerRequests(triggered func(sr *srov1beta1.SpecialResource) bool) []reconcile.Request {
+
+	specialresources := &srov1beta1.SpecialResourceList{}
+	if err := clients.Interface.List(context.TODO(), specialresources); err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot list SpecialResources for triggers"))
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for i := range specialresources.Items {
+		sr := &specialresources.Items[i]
+		if !triggered(sr) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: sr.GetName(), Namespace: sr.GetNamespace()},
+		})
+	}
+
+	return requests
+}
+
+// nodeRequests reconciles the SpecialResources that opted into nodes and
+// whose nodeSelector matches the node
+func nodeRequests(obj client.Object) []reconcile.Request {
+	return triggerRequests(func(sr *srov1beta1.SpecialResource) bool {
+		if !sr.Spec.Triggers.Nodes {
+			return false
+		}
+		return labels.SelectorFromSet(sr.Spec.NodeSelector).Matches(labels.Set(obj.GetLabels()))
+	})
+}
+
+// machineConfigPoolRequests reconciles the SpecialResources that opted into
+// MachineConfigPools
+func machineConfigPoolRequests(obj client.Object) []reconcile.Request {
+	return triggerRequests(func(sr *srov1beta1.SpecialResource) bool {
+		return sr.Spec.Triggers.MachineConfigPools
+	})
+}
+
+// secretRequests reconciles the SpecialResources that list the Secret in
+// their namespace or reference it in spec.set
+func secretRequests(obj client.Object) []reconcile.Request {
+	return triggerRequests(func(sr *srov1beta1.SpecialResource) bool {
+		if sr.Spec.Namespace != obj.GetNamespace() {
+			return false
+		}
+		if referencesValue(sr.Spec.Set.Object, "secret", obj.GetName()) {
+			return true
+		}
+		for _, name := range sr.Spec.Triggers.Secrets {
+			if name == obj.GetName() {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// configMapRequests reconciles the SpecialResources whose post-render
+// patches are in the ConfigMap or that reference it in spec.set
+func configMapRequests(obj client.Object) []reconcile.Request {
+	return triggerRequests(func(sr *srov1beta1.SpecialResource) bool {
+		if obj.GetNamespace() == os.Getenv("OPERATOR_NAMESPACE") && sr.Spec.PostRender.KustomizeConfigMap == obj.GetName() {
+			return true
+		}
+		return sr.Spec.Namespace == obj.GetNamespace() &&
+			referencesValue(sr.Spec.Set.Object, "configMap", obj.GetName())
+	})
+}
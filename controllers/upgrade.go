@@ -1,12 +1,20 @@
 package controllers
 
 import (
+	"context"
+
+	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
 	"github.com/openshift-psap/special-resource-operator/pkg/cache"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
 	"github.com/openshift-psap/special-resource-operator/pkg/color"
 	"github.com/openshift-psap/special-resource-operator/pkg/exit"
 	"github.com/openshift-psap/special-resource-operator/pkg/upgrade"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 // SpecialResourceUpgrade upgrade special resources
@@ -14,6 +22,7 @@ func SpecialResourceUpgrade(r *SpecialResourceReconciler, req ctrl.Request) (ctr
 
 	log = r.Log.WithName(color.Print("upgrade", color.Blue))
 
+	cache.ExclusionSelector = r.specialresource.Spec.NodeExclusionSelector
 	err := cache.Nodes(r.specialresource.Spec.NodeSelector, false)
 	exit.OnError(errors.Wrap(err, "Failed to cache nodes"))
 
@@ -24,3 +33,24 @@ func SpecialResourceUpgrade(r *SpecialResourceReconciler, req ctrl.Request) (ctr
 
 	return ctrl.Result{Requeue: false}, nil
 }
+
+// upgradeRequests reconciles all SpecialResources once the ClusterVersion
+// targets a new release, driver containers for its kernel are built while
+// the nodes are updated, see watchReleases
+func upgradeRequests(obj client.Object) []reconcile.Request {
+
+	specialresources := &srov1beta1.SpecialResourceList{}
+	if err := clients.Interface.List(context.TODO(), specialresources); err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot list SpecialResources for cluster upgrade"))
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, sr := range specialresources.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: sr.GetName(), Namespace: sr.GetNamespace()},
+		})
+	}
+
+	return requests
+}
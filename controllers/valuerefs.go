@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"regexp"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/pkg/errors"
+)
+
+// valueReferencePattern matches $(configMap:<name>/<key>) and
+// $(secret:<name>/<key>) in the strings of spec.set. They do not collide
+// with the {{ .Values }} templates of the SpecialResource.
+var valueReferencePattern = regexp.MustCompile(`\$\((configMap|secret):([a-z0-9][-a-z0-9.]*)/([-._a-zA-Z0-9]+)\)`)
+
+// valueReference a key of a ConfigMap or Secret in spec.namespace
+type valueReference struct {
+	kind string
+	name string
+	key  string
+}
+
+func parseValueReference(match []string) valueReference {
+	return valueReference{kind: match[1], name: match[2], key: match[3]}
+}
+
+// resolve returns the data of the key, missing objects or keys are errors
+func (ref valueReference) resolve(namespace string) (string, error) {
+
+	what := ref.kind + " " + namespace + "/" + ref.name
+
+	if ref.kind == "secret" {
+		secret, err := clients.GetSecret(namespace, ref.name)
+		if err != nil {
+			return "", errors.Wrap(err, "Cannot get "+what)
+		}
+		data, found := secret.Data[ref.key]
+		if !found {
+			return "", errors.New("Key " + ref.key + " not found in " + what)
+		}
+		return string(data), nil
+	}
+
+	cm, err := clients.GetConfigMap(namespace, ref.name)
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot get "+what)
+	}
+	data, found := cm.Data[ref.key]
+	if !found {
+		return "", errors.New("Key " + ref.key + " not found in " + what)
+	}
+	return data, nil
+}
+
+// walkStrings returns a copy of value with fn applied to all strings
+func walkStrings(value interface{}, fn func(string) (string, error)) (interface{}, error) {
+
+	switch v := value.(type) {
+	case string:
+		return fn(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			walked, err := walkStrings(elem, fn)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = walked
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for idx, elem := range v {
+			walked, err := walkStrings(elem, fn)
+			if err != nil {
+				return nil, err
+			}
+			out[idx] = walked
+		}
+		return out, nil
+	}
+	return value, nil
+}
+
+// resolveValueReferences returns a copy of values with the value references
+// replaced by the data they reference in namespace. The values of the
+// chart change with the data, which re-renders it. Without secrets the
+// Secret references are kept, e.g. for the dry-run ConfigMap.
+func resolveValueReferences(namespace string, values map[string]interface{}, secrets bool) (map[string]interface{}, error) {
+
+	resolved, err := walkStrings(values, func(s string) (string, error) {
+		var failed error
+		out := valueReferencePattern.ReplaceAllStringFunc(s, func(match string) string {
+			ref := parseValueReference(valueReferencePattern.FindStringSubmatch(match))
+			if failed != nil || (ref.kind == "secret" && !secrets) {
+				return match
+			}
+			data, err := ref.resolve(namespace)
+			if err != nil {
+				failed = err
+				return match
+			}
+			return data
+		})
+		return out, failed
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot resolve value references of spec.set")
+	}
+
+	return resolved.(map[string]interface{}), nil
+}
+
+// valueReferences returns the references in values, the triggers of a
+// SpecialResource whose values reference a ConfigMap or Secret
+func valueReferences(values map[string]interface{}) []valueReference {
+
+	refs := []valueReference{}
+	_, _ = walkStrings(values, func(s string) (string, error) {
+		for _, match := range valueReferencePattern.FindAllStringSubmatch(s, -1) {
+			refs = append(refs, parseValueReference(match))
+		}
+		return s, nil
+	})
+
+	return refs
+}
+
+// referencesValue is true if values reference the object of kind
+func referencesValue(values map[string]interface{}, kind string, name string) bool {
+	for _, ref := range valueReferences(values) {
+		if ref.kind == kind && ref.name == name {
+			return true
+		}
+	}
+	return false
+}
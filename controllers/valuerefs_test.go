@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveValueReferencesWithoutSecrets(t *testing.T) {
+
+	values := map[string]interface{}{
+		"license": map[string]interface{}{
+			"key":   "$(secret:simple-kmod-license/key)",
+			"token": "Bearer $(secret:simple-kmod-license/token)",
+		},
+		"keys":  []interface{}{"$(secret:simple-kmod-keys/signing)", "plain"},
+		"debug": true,
+	}
+
+	// Without secrets no Secret is read, the references end up in the
+	// dry-run ConfigMap instead of their data
+	resolved, err := resolveValueReferences("simple-kmod", values, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resolved, values) {
+		t.Errorf("resolveValueReferences(secrets false) = %v, want %v", resolved, values)
+	}
+}
+
+func TestValueReferences(t *testing.T) {
+
+	values := map[string]interface{}{
+		"license": map[string]interface{}{
+			"key":    "$(secret:simple-kmod-license/key)",
+			"server": "https://$(configMap:simple-kmod-config/license-server)/v1",
+		},
+		"ignored": "$(secret:Invalid_Name/key) $(pod:simple-kmod/key)",
+	}
+
+	refs := valueReferences(values)
+	want := map[valueReference]bool{
+		{kind: "secret", name: "simple-kmod-license", key: "key"}:              true,
+		{kind: "configMap", name: "simple-kmod-config", key: "license-server"}: true,
+	}
+	if len(refs) != len(want) {
+		t.Fatalf("valueReferences() = %v, want %v", refs, want)
+	}
+	for _, ref := range refs {
+		if !want[ref] {
+			t.Errorf("valueReferences() = %v, want %v", refs, want)
+		}
+	}
+}
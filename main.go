@@ -19,19 +19,31 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	srov1beta1 "github.com/openshift-psap/special-resource-operator/api/v1beta1"
+	srov2 "github.com/openshift-psap/special-resource-operator/api/v2"
 	"github.com/openshift-psap/special-resource-operator/controllers"
+	"github.com/openshift-psap/special-resource-operator/pkg/build"
 	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/gather"
+	"github.com/openshift-psap/special-resource-operator/pkg/helmer"
+	"github.com/openshift-psap/special-resource-operator/pkg/poll"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
 	"github.com/openshift-psap/special-resource-operator/pkg/resource"
+	"github.com/openshift-psap/special-resource-operator/pkg/trace"
 
 	sroscheme "github.com/openshift-psap/special-resource-operator/pkg/scheme"
+	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -45,26 +57,183 @@ func init() {
 	utilruntime.Must(sroscheme.AddToScheme(scheme))
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(srov1beta1.AddToScheme(scheme))
+	utilruntime.Must(srov2.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var leaderElectionID string
+	var leaderElectionNamespace string
+	var leaseDuration time.Duration
+	var renewDeadline time.Duration
+	var retryPeriod time.Duration
+	var releaseOnCancel bool
+	var gracefulShutdownTimeout time.Duration
+	var layerCacheDir string
+	var layerCacheSize int64
+	var layerCacheTTL time.Duration
+	var registryRetries int
+	var registryBackoff time.Duration
+	var registryTimeout time.Duration
+	var waitTimeout time.Duration
+	var insecureRegistries string
+	var hostedKubeconfig string
+	var hostedPullSecret string
+	var registryAuthFile string
+	var enableWebhooks bool
+	var dryRun bool
+	var diffHistory int
+	var otlpEndpoint string
+	var offlineCharts bool
+	var informerSelector string
+	var informerMemoryLimit int64
+	var gatherDir string
+	var profile string
+	var syncPeriod time.Duration
+	var serializeBuilds bool
+	var combineDevicePlugin bool
+	var reconcileOptions controllers.ReconcileOptions
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&leaderElectionID, "leader-election-id", "b6ae617b.openshift.io",
+		"Name of the lease the replicas compete for, replicas with the same id and namespace elect one leader.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "",
+		"Namespace of the leader election lease, defaults to the namespace the operator runs in.")
+	flag.DurationVar(&leaseDuration, "leader-election-lease-duration", 15*time.Second,
+		"Time a standby replica waits before taking over a lease that was not renewed.")
+	flag.DurationVar(&renewDeadline, "leader-election-renew-deadline", 10*time.Second,
+		"Time the leader retries renewing the lease before it gives up leadership.")
+	flag.DurationVar(&retryPeriod, "leader-election-retry-period", 2*time.Second,
+		"Time between attempts to acquire or renew the lease.")
+	flag.BoolVar(&releaseOnCancel, "leader-election-release-on-cancel", true,
+		"Release the lease on shutdown so a standby replica takes over immediately instead of after the lease duration.")
+	flag.DurationVar(&gracefulShutdownTimeout, "graceful-shutdown-timeout", 30*time.Second,
+		"Time running reconciles get to finish on shutdown before the lease is released.")
+	flag.StringVar(&layerCacheDir, "layer-cache-dir", "/cache/layers",
+		"Directory where pulled image layers are cached, empty disables the cache.")
+	flag.Int64Var(&layerCacheSize, "layer-cache-size", 2<<30, "Maximum size in bytes of the layer cache.")
+	flag.DurationVar(&layerCacheTTL, "layer-cache-ttl", 24*time.Hour, "Time a cached layer is kept before it is pulled again.")
+	flag.IntVar(&registryRetries, "registry-retries", 5, "Number of attempts for registry requests failing with 429, 5xx or network errors.")
+	flag.DurationVar(&registryBackoff, "registry-backoff", time.Second, "Initial backoff between registry request attempts, doubled on every retry.")
+	flag.DurationVar(&registryTimeout, "registry-timeout", 10*time.Minute, "Timeout of a single registry request including the download.")
+	flag.DurationVar(&waitTimeout, "wait-timeout", poll.Timeout, "Timeout of a single wait for a resource, e.g. a DaemonSet to become available.")
+	flag.StringVar(&insecureRegistries, "insecure-registries", "",
+		"Comma separated registries (host, host:port or *.domain) accessed without TLS verification or via plain HTTP.")
+	flag.StringVar(&hostedKubeconfig, "hosted-kubeconfig", "",
+		"Kubeconfig of a HyperShift hosted cluster the node side resources are applied to, the DTK lookup stays on the management cluster.")
+	flag.StringVar(&hostedPullSecret, "hosted-pull-secret", "",
+		"namespace/name of the pull secret on the management cluster used instead of openshift-config/pull-secret.")
+	flag.StringVar(&registryAuthFile, "registry-auth-file", "",
+		"Path of a podman auth.json or docker config.json, e.g. a mounted Secret, whose credentials are used after the pull secrets of the SpecialResource.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Serve the SpecialResource validating and v2 conversion webhooks, needs the serving certificate in /tmp/k8s-webhook-server/serving-certs.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Render the charts of all SpecialResources into ConfigMaps in the operator namespace instead of applying them.")
+	flag.IntVar(&diffHistory, "diff-history", 0,
+		"Number of updated objects and their changed fields kept in status.changes of a SpecialResource, 0 only logs them.")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"OTLP/HTTP traces URL reconcile spans are exported to, e.g. http://otel-collector:4318/v1/traces, tracing is disabled if empty.")
+	flag.BoolVar(&offlineCharts, "offline-charts", false,
+		"Do not download chart dependencies, subcharts have to be vendored in the charts/ directory of the chart.")
+	flag.IntVar(&reconcileOptions.MaxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"Maximum number of PreflightValidations and image GC runs reconciled at the same time, SpecialResources, driver readiness and the kernel inventory are always reconciled one at a time.")
+	flag.DurationVar(&reconcileOptions.BaseDelay, "reconcile-base-delay", 5*time.Millisecond,
+		"Initial delay before a failed or requeued object is reconciled again, doubled on every retry of the object.")
+	flag.DurationVar(&reconcileOptions.MaxDelay, "reconcile-max-delay", 1000*time.Second,
+		"Maximum delay before a failed or requeued object is reconciled again.")
+	flag.StringVar(&informerSelector, "informer-selector", "",
+		"Label selector of the Secrets and ConfigMaps served from informers, others are read from the API server.")
+	flag.Int64Var(&informerMemoryLimit, "informer-memory-limit", 256<<20,
+		"Maximum size in bytes of the objects held by the informers, above it they are stopped, 0 means no limit.")
+	flag.StringVar(&gatherDir, "gather", "",
+		"Write the must-gather dump of SpecialResources, their objects, logs, DTK mapping and node kernels to the directory and exit.")
+	flag.StringVar(&profile, "profile", "auto",
+		"The footprint profile, default, single-node or auto to use single-node on SNO clusters.")
+	flag.DurationVar(&syncPeriod, "sync-period", 10*time.Hour, "Minimum interval at which watched objects are reconciled again.")
+	flag.BoolVar(&serializeBuilds, "serialize-builds", false, "Create a BuildConfig only if no other build of the cluster is running.")
+	flag.BoolVar(&combineDevicePlugin, "combine-device-plugin", false,
+		"Ask charts to run the device plugin in the driver-container pod, exposed as .Values.combineDevicePlugin.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
+	if gatherDir == "" {
+		singleNode, err := singleNodeProfile(profile)
+		if err != nil {
+			setupLog.Error(err, "unable to select profile", "profile", profile)
+			os.Exit(1)
+		}
+		if singleNode {
+			setupLog.Info("using the single-node profile")
+			// Flags set on the command line win over the profile
+			set := map[string]bool{}
+			flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+			defaults := map[string]func(){
+				"layer-cache-size":      func() { layerCacheSize = 512 << 20 },
+				"informer-memory-limit": func() { informerMemoryLimit = 64 << 20 },
+				"sync-period":           func() { syncPeriod = 24 * time.Hour },
+				"serialize-builds":      func() { serializeBuilds = true },
+				"combine-device-plugin": func() { combineDevicePlugin = true },
+			}
+			for name, apply := range defaults {
+				if !set[name] {
+					apply()
+				}
+			}
+		}
+	}
+
+	registry.LayerCache.Path = layerCacheDir
+	registry.LayerCache.MaxSize = layerCacheSize
+	registry.LayerCache.TTL = layerCacheTTL
+
+	registry.Retry.Steps = registryRetries
+	registry.Retry.Duration = registryBackoff
+	registry.Retry.Timeout = registryTimeout
+
+	registry.AuthFile = registryAuthFile
+
+	poll.Timeout = waitTimeout
+
+	resource.SerializeBuilds = serializeBuilds
+	controllers.RunInfo.CombineDevicePlugin = combineDevicePlugin
+
+	helmer.Offline = offlineCharts
+
+	trace.Endpoint = otlpEndpoint
+
+	clients.InformerSelector = informerSelector
+	clients.InformerMemoryLimit = informerMemoryLimit
+
+	if insecureRegistries != "" {
+		registry.InsecureRegistries = strings.Split(insecureRegistries, ",")
+	}
+
+	if gatherDir != "" {
+		if err := runGather(gatherDir); err != nil {
+			setupLog.Error(err, "unable to gather")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		Port:               9443,
-		LeaderElection:     enableLeaderElection,
-		LeaderElectionID:   "b6ae617b.openshift.io",
+		Scheme:                        scheme,
+		MetricsBindAddress:            metricsAddr,
+		Port:                          9443,
+		LeaderElection:                enableLeaderElection,
+		LeaderElectionID:              leaderElectionID,
+		LeaderElectionNamespace:       leaderElectionNamespace,
+		LeaseDuration:                 &leaseDuration,
+		RenewDeadline:                 &renewDeadline,
+		RetryPeriod:                   &retryPeriod,
+		LeaderElectionReleaseOnCancel: releaseOnCancel,
+		GracefulShutdownTimeout:       &gracefulShutdownTimeout,
+		SyncPeriod:                    &syncPeriod,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -78,22 +247,147 @@ func main() {
 		ConfigV1Client:           clients.GetConfigClientOrDie(),
 		CachedDiscoveryInterface: clients.GetCachedDiscoveryClientOrDie(),
 		EventRecorder:            mgr.GetEventRecorderFor("specialresource"),
+		Watcher:                  clients.GetWatchClientOrDie(mgr.GetScheme()),
+	}
+
+	if hostedKubeconfig != "" {
+		clients.Hosted, clients.HostedRestConfig, err = clients.NewHosted(hostedKubeconfig, mgr.GetScheme())
+		if err != nil {
+			setupLog.Error(err, "unable to create hosted cluster clients")
+			os.Exit(1)
+		}
+		clients.Hosted.EventRecorder = clients.Interface.EventRecorder
+		helmer.UseKubeConfig(hostedKubeconfig)
+	}
+
+	if hostedPullSecret != "" {
+		secret := strings.SplitN(hostedPullSecret, "/", 2)
+		if len(secret) != 2 {
+			setupLog.Error(errors.New("expected namespace/name"), "invalid --hosted-pull-secret", "value", hostedPullSecret)
+			os.Exit(1)
+		}
+		registry.GlobalPullSecret = registry.PullSecrets{
+			Namespace:      secret[0],
+			ServiceAccount: "default",
+			Secrets:        []string{secret[1]},
+		}
 	}
 
 	resource.RuntimeScheme = mgr.GetScheme()
 
+	capabilities, err := clients.DetectCapabilities()
+	if err != nil {
+		setupLog.Error(err, "unable to detect cluster capabilities")
+		os.Exit(1)
+	}
+	if !capabilities.BuildConfigs {
+		build.Default = build.BackendJob
+	}
+
+	if err := mgr.Add(manager.RunnableFunc(clients.RunInformers)); err != nil {
+		setupLog.Error(err, "unable to add informers")
+		os.Exit(1)
+	}
+	if err := mgr.Add(manager.RunnableFunc(trace.Run)); err != nil {
+		setupLog.Error(err, "unable to add trace exporter")
+		os.Exit(1)
+	}
+
 	if err = (&controllers.SpecialResourceReconciler{
-		Log:    ctrl.Log,
-		Scheme: mgr.GetScheme(),
+		Log:         ctrl.Log,
+		Scheme:      mgr.GetScheme(),
+		DryRun:      dryRun,
+		DiffHistory: diffHistory,
+		Options:     reconcileOptions,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SpecialResource")
 		os.Exit(1)
 	}
+	if enableWebhooks {
+		srov1beta1.ChartValidator = helmer.ValidateValues
+		srov1beta1.ChartLocator = helmer.LocateChart
+		if err = (&srov1beta1.SpecialResource{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "SpecialResource")
+			os.Exit(1)
+		}
+	}
+	if err = (&controllers.PreflightValidationReconciler{
+		Log:     ctrl.Log,
+		Scheme:  mgr.GetScheme(),
+		Options: reconcileOptions,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PreflightValidation")
+		os.Exit(1)
+	}
+	if err = (&controllers.ImageGCReconciler{
+		Log:     ctrl.Log,
+		Scheme:  mgr.GetScheme(),
+		Options: reconcileOptions,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ImageGC")
+		os.Exit(1)
+	}
+	if err = (&controllers.KernelInventoryReconciler{
+		Log:     ctrl.Log,
+		Scheme:  mgr.GetScheme(),
+		Options: reconcileOptions,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "KernelInventory")
+		os.Exit(1)
+	}
+	if err = (&controllers.DriverReadinessReconciler{
+		Log:     ctrl.Log,
+		Scheme:  mgr.GetScheme(),
+		Options: reconcileOptions,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DriverReadiness")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	ctx := ctrl.SetupSignalHandler()
+	poll.Context = ctx
+
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// singleNodeProfile returns true if the single-node profile is used, auto
+// selects it on SNO clusters
+func singleNodeProfile(profile string) (bool, error) {
+
+	switch profile {
+	case "default":
+		return false, nil
+	case "single-node":
+		return true, nil
+	case "auto":
+		return clients.SingleNode(ctrl.GetConfigOrDie())
+	}
+
+	return false, errors.New("unknown profile " + profile)
+}
+
+// runGather writes the must-gather dump with clients that read directly
+// from the API server, no manager is started
+func runGather(dir string) error {
+
+	clients.RestConfig = ctrl.GetConfigOrDie()
+
+	c, err := client.New(clients.RestConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	clients.Interface = &clients.ClientsInterface{
+		Client:                   c,
+		Clientset:                clients.GetKubeClientSetOrDie(),
+		ConfigV1Client:           clients.GetConfigClientOrDie(),
+		CachedDiscoveryInterface: clients.GetCachedDiscoveryClientOrDie(),
+	}
+
+	return gather.Run(dir)
+}
@@ -0,0 +1,211 @@
+package blacklist
+
+import (
+	"context"
+	"encoding/base64"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/color"
+	"github.com/openshift-psap/special-resource-operator/pkg/slice"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+const (
+	// OwnerLabel names the SpecialResource of a blacklist MachineConfig
+	OwnerLabel = "specialresource.openshift.io/blacklist"
+
+	machineConfigAPIVersion = "machineconfiguration.openshift.io/v1"
+	ignitionVersion         = "3.2.0"
+)
+
+var log logr.Logger
+
+func init() {
+	log = zap.New(zap.UseDevMode(true)).WithName(color.Print("blacklist", color.Brown))
+}
+
+// Available is true if the cluster manages its nodes with MachineConfigs
+func Available() (bool, error) {
+	return clients.HasWorkloadResource(schema.GroupVersionResource{Group: "machineconfiguration.openshift.io", Version: "v1", Resource: "machineconfigs"})
+}
+
+// Name of the MachineConfig blacklisting the modules of the SpecialResource
+// name on the nodes of pool
+func Name(name string, pool string) string {
+	return "99-" + pool + "-" + name + "-blacklist"
+}
+
+// MachineConfig blacklists the modules with a modprobe.d file and on the
+// kernel command line, the latter also covers modules in the initramfs.
+func MachineConfig(name string, pool string, labels map[string]string, modules []string) *unstructured.Unstructured {
+
+	modules = append([]string{}, modules...)
+	sort.Strings(modules)
+
+	conf := strings.Builder{}
+	for _, module := range modules {
+		conf.WriteString("blacklist " + module + "\n")
+	}
+
+	mc := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	mc.SetAPIVersion(machineConfigAPIVersion)
+	mc.SetKind("MachineConfig")
+	mc.SetName(Name(name, pool))
+
+	mcLabels := map[string]string{OwnerLabel: name}
+	for key, value := range labels {
+		mcLabels[key] = value
+	}
+	mc.SetLabels(mcLabels)
+
+	mc.Object["spec"] = map[string]interface{}{
+		"config": map[string]interface{}{
+			"ignition": map[string]interface{}{"version": ignitionVersion},
+			"storage": map[string]interface{}{
+				"files": []interface{}{
+					map[string]interface{}{
+						"path":      "/etc/modprobe.d/" + name + "-blacklist.conf",
+						"mode":      int64(420),
+						"overwrite": true,
+						"contents": map[string]interface{}{
+							"source": "data:text/plain;charset=utf-8;base64," + base64.StdEncoding.EncodeToString([]byte(conf.String())),
+						},
+					},
+				},
+			},
+		},
+		"kernelArguments": []interface{}{"modprobe.blacklist=" + strings.Join(modules, ",")},
+	}
+
+	return mc
+}
+
+// PoolLabels returns the labels a MachineConfig needs to be rendered into
+// pool, the matchLabels of its machineConfigSelector or the role of pool.
+func PoolLabels(pool string) (map[string]string, error) {
+
+	mcp, err := getPool(pool)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, found, err := unstructured.NestedStringMap(mcp.Object, "spec", "machineConfigSelector", "matchLabels")
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid machineConfigSelector of MachineConfigPool "+pool)
+	}
+
+	if !found || len(labels) == 0 {
+		labels = map[string]string{"machineconfiguration.openshift.io/role": pool}
+	}
+
+	return labels, nil
+}
+
+// PoolUpdated is true once all machines of pool run a rendered config that
+// includes the MachineConfig name, otherwise the reason is returned.
+func PoolUpdated(pool string, name string) (bool, string, error) {
+
+	mcp, err := getPool(pool)
+	if err != nil {
+		return false, "", err
+	}
+
+	sources, _, _ := unstructured.NestedSlice(mcp.Object, "status", "configuration", "source")
+
+	rendered := false
+	for _, source := range sources {
+		if source, ok := source.(map[string]interface{}); ok && source["name"] == name {
+			rendered = true
+		}
+	}
+
+	if !rendered {
+		return false, "MachineConfigPool " + pool + " has not rendered " + name + " yet", nil
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(mcp.Object, "status", "conditions")
+	for _, condition := range conditions {
+		condition, ok := condition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condition["type"] == "Degraded" && condition["status"] == "True" {
+			return false, "MachineConfigPool " + pool + " is degraded: " + toString(condition["message"]), nil
+		}
+		if condition["type"] == "Updated" && condition["status"] != "True" {
+			return false, "MachineConfigPool " + pool + " is updating", nil
+		}
+	}
+
+	machines, _, _ := unstructured.NestedInt64(mcp.Object, "status", "machineCount")
+	updated, _, _ := unstructured.NestedInt64(mcp.Object, "status", "updatedMachineCount")
+	if updated < machines {
+		return false, "MachineConfigPool " + pool + " updated " + toString(updated) + " of " + toString(machines) + " machines", nil
+	}
+
+	return true, "", nil
+}
+
+// Prune deletes the blacklist MachineConfigs of the SpecialResource name
+// that are not in keep.
+func Prune(name string, keep []string) error {
+	return prune(OwnerLabel, name, keep)
+}
+
+// prune deletes the MachineConfigs labeled ownerLabel=name that are not in
+// keep
+func prune(ownerLabel string, name string, keep []string) error {
+
+	list := &unstructured.UnstructuredList{}
+	list.SetAPIVersion(machineConfigAPIVersion)
+	list.SetKind("MachineConfigList")
+
+	if err := clients.Workload().List(context.TODO(), list, client.MatchingLabels{ownerLabel: name}); err != nil {
+		return errors.Wrap(err, "Cannot list MachineConfigs of "+ownerLabel)
+	}
+
+	for idx, mc := range list.Items {
+		if slice.Contains(keep, mc.GetName()) {
+			continue
+		}
+
+		log.Info("Deleting MachineConfig", "name", mc.GetName())
+		if err := clients.Workload().Delete(context.TODO(), &list.Items[idx]); client.IgnoreNotFound(err) != nil {
+			return errors.Wrap(err, "Cannot delete MachineConfig "+mc.GetName())
+		}
+	}
+
+	return nil
+}
+
+func getPool(pool string) (*unstructured.Unstructured, error) {
+
+	mcp := &unstructured.Unstructured{}
+	mcp.SetAPIVersion(machineConfigAPIVersion)
+	mcp.SetKind("MachineConfigPool")
+
+	if err := clients.Workload().Get(context.TODO(), types.NamespacedName{Name: pool}, mcp); err != nil {
+		return nil, errors.Wrap(err, "Cannot get MachineConfigPool "+pool)
+	}
+
+	return mcp, nil
+}
+
+func toString(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case int64:
+		return strconv.FormatInt(value, 10)
+	}
+	return ""
+}
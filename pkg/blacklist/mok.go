@@ -0,0 +1,105 @@
+package blacklist
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// MOKOwnerLabel names the SpecialResource of a MOK enrollment
+	// MachineConfig
+	MOKOwnerLabel = "specialresource.openshift.io/mok"
+	// MOKPasswordHash the mokutil password hash in the signing Secret, the
+	// password is asked by the MokManager on the console to confirm the
+	// enrollment
+	MOKPasswordHash = "mok.hash"
+)
+
+// MOKName of the MachineConfig enrolling the signing certificate of the
+// SpecialResource name on the nodes of pool
+func MOKName(name string, pool string) string {
+	return "99-" + pool + "-" + name + "-mok"
+}
+
+// MOKMachineConfig writes the DER encoded certificate and a unit that
+// requests its enrollment with mokutil on EFI nodes unless it is enrolled.
+// The MokManager completes it on the next reboot, confirmed on the console.
+func MOKMachineConfig(name string, pool string, labels map[string]string, cert []byte, passwordHash []byte) (*unstructured.Unstructured, error) {
+
+	block, _ := pem.Decode(cert)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("Signing certificate of " + name + " is not a PEM encoded certificate")
+	}
+	if len(passwordHash) == 0 {
+		return nil, errors.New("Signing Secret of " + name + " has no " + MOKPasswordHash + ", generate it with mokutil -g")
+	}
+
+	dir := "/etc/pki/" + name
+	unit := `[Unit]
+Description=Enroll the module signing certificate of ` + name + ` as Machine Owner Key
+ConditionPathExists=/sys/firmware/efi
+After=local-fs.target
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=/bin/sh -c 'mokutil --test-key ` + dir + `/mok.der | grep -q "is already enrolled" || mokutil --import ` + dir + `/mok.der --hash-file ` + dir + `/mok.hash'
+
+[Install]
+WantedBy=multi-user.target
+`
+
+	mc := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	mc.SetAPIVersion(machineConfigAPIVersion)
+	mc.SetKind("MachineConfig")
+	mc.SetName(MOKName(name, pool))
+
+	mcLabels := map[string]string{MOKOwnerLabel: name}
+	for key, value := range labels {
+		mcLabels[key] = value
+	}
+	mc.SetLabels(mcLabels)
+
+	mc.Object["spec"] = map[string]interface{}{
+		"config": map[string]interface{}{
+			"ignition": map[string]interface{}{"version": ignitionVersion},
+			"storage": map[string]interface{}{
+				"files": []interface{}{
+					file(dir+"/mok.der", "application/octet-stream", block.Bytes),
+					file(dir+"/mok.hash", "text/plain;charset=utf-8", passwordHash),
+				},
+			},
+			"systemd": map[string]interface{}{
+				"units": []interface{}{
+					map[string]interface{}{
+						"name":     name + "-mok-enroll.service",
+						"enabled":  true,
+						"contents": unit,
+					},
+				},
+			},
+		},
+	}
+
+	return mc, nil
+}
+
+// PruneMOK deletes the MOK enrollment MachineConfigs of the SpecialResource
+// name that are not in keep.
+func PruneMOK(name string, keep []string) error {
+	return prune(MOKOwnerLabel, name, keep)
+}
+
+func file(path string, mediaType string, contents []byte) map[string]interface{} {
+	return map[string]interface{}{
+		"path":      path,
+		"mode":      int64(384),
+		"overwrite": true,
+		"contents": map[string]interface{}{
+			"source": "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(contents),
+		},
+	}
+}
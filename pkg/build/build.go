@@ -0,0 +1,95 @@
+package build
+
+import (
+	"github.com/go-logr/logr"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/color"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var (
+	log      logr.Logger
+	backends = make(map[string]Backend)
+)
+
+const (
+	// BackendBuildConfig builds with OpenShift BuildConfigs, the default
+	BackendBuildConfig = "BuildConfig"
+	// BackendShipwright builds with Shipwright Builds and BuildRuns
+	BackendShipwright = "Shipwright"
+	// BackendJob builds with kaniko in a Job, for clusters without
+	// BuildConfigs
+	BackendJob = "Job"
+)
+
+// Default is the backend of SpecialResources that do not select one, the
+// operator switches it to BackendJob if the cluster has no BuildConfigs
+var Default = BackendBuildConfig
+
+// Backend builds the driver containers described by the BuildConfigs of a
+// chart, charts are written against BuildConfig and every other backend
+// translates them into its own objects.
+type Backend interface {
+	// Available returns true if the cluster serves the API of the backend
+	Available() (bool, error)
+	// Translate returns the objects that build the same image as the
+	// BuildConfig obj, in the order they have to be created
+	Translate(obj *unstructured.Unstructured) ([]*unstructured.Unstructured, error)
+}
+
+func init() {
+	log = zap.New(zap.UseDevMode(true)).WithName(color.Print("build", color.Purple))
+
+	backends[BackendBuildConfig] = buildConfig{}
+	backends[BackendShipwright] = shipwright{}
+	backends[BackendJob] = job{}
+}
+
+// Get returns the backend with name, the Default backend if name is empty.
+func Get(name string) (Backend, error) {
+
+	if name == "" {
+		name = Default
+	}
+
+	backend, found := backends[name]
+	if !found {
+		return nil, errors.New("Unknown build backend " + name)
+	}
+
+	return backend, nil
+}
+
+// Translate converts the BuildConfig obj with backend, other objects are
+// returned as is. Availability is only checked once a chart builds.
+func Translate(backend Backend, obj *unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+
+	if obj.GetKind() != "BuildConfig" || backend == nil {
+		return []*unstructured.Unstructured{obj}, nil
+	}
+
+	available, err := backend.Available()
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot check build backend")
+	}
+	if !available {
+		return nil, errors.New("Build backend is not available in the cluster")
+	}
+
+	return backend.Translate(obj)
+}
+
+type buildConfig struct{}
+
+func (buildConfig) Available() (bool, error) {
+	return clients.BuildConfigsAvailable()
+}
+
+func (buildConfig) Translate(obj *unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	if Cache != nil {
+		log.Info("BuildConfigs cannot mount the ccache claim, the build cache needs the Job backend", "BuildConfig", obj.GetName())
+	}
+	return []*unstructured.Unstructured{obj}, nil
+}
@@ -0,0 +1,62 @@
+package build
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// CacheDir the ccache volume is mounted at, passed as build arg CCACHE_DIR
+const CacheDir = "/ccache"
+
+// CacheOptions of the ccache claims of the builds
+type CacheOptions struct {
+	// Size of the claims, defaults to 5Gi
+	Size string
+	// StorageClassName of the claims, the default class if empty
+	StorageClassName string
+}
+
+// Cache is set if the SpecialResource enables the build cache, nil otherwise
+var Cache *CacheOptions
+
+// cacheClaim returns the PersistentVolumeClaim caching the builds of obj,
+// builds for kernels of the same major.minor share it
+func cacheClaim(obj *unstructured.Unstructured) *unstructured.Unstructured {
+
+	size := Cache.Size
+	if size == "" {
+		size = "5Gi"
+	}
+
+	spec := map[string]interface{}{
+		"accessModes": []interface{}{"ReadWriteOnce"},
+		"resources": map[string]interface{}{
+			"requests": map[string]interface{}{"storage": size},
+		},
+	}
+	if Cache.StorageClassName != "" {
+		spec["storageClassName"] = Cache.StorageClassName
+	}
+
+	claim := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	claim.SetAPIVersion("v1")
+	claim.SetKind("PersistentVolumeClaim")
+	claim.SetName("ccache-" + strings.ReplaceAll(majorMinor(buildArg(obj, "KVER")), ".", "-"))
+	claim.SetNamespace(obj.GetNamespace())
+	claim.SetLabels(obj.GetLabels())
+	claim.Object["spec"] = spec
+
+	return claim
+}
+
+// majorMinor returns the major.minor of the kernel version, default if it
+// cannot be parsed
+func majorMinor(kernelFullVersion string) string {
+
+	parts := strings.SplitN(kernelFullVersion, ".", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "default"
+	}
+	return parts[0] + "." + parts[1]
+}
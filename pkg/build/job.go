@@ -0,0 +1,200 @@
+package build
+
+import (
+	"encoding/json"
+	"path"
+	"strconv"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/hash"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var (
+	// KanikoImage builds and pushes the driver container in the Job backend
+	KanikoImage = "gcr.io/kaniko-project/executor:v1.7.0"
+	// GitImage clones the source of the BuildConfig in the Job backend
+	GitImage = "docker.io/alpine/git:v2.32.0"
+)
+
+type job struct{}
+
+// Available is always true, Jobs are served by every cluster
+func (job) Available() (bool, error) {
+	return true, nil
+}
+
+// Translate creates a Job that clones the git source and builds it with
+// kaniko, for clusters without BuildConfigs e.g. MicroShift or upstream
+// Kubernetes. Jobs are immutable, the name carries the hash of the
+// BuildConfig so a changed BuildConfig is built again. The ccache claim of
+// the kernel is created before the Job if the build cache is enabled.
+func (job) Translate(obj *unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+
+	kind, _, _ := unstructured.NestedString(obj.Object, "spec", "output", "to", "kind")
+	if kind != "DockerImage" {
+		return nil, errors.New("BuildConfig " + obj.GetName() + " output kind " + kind + " not supported by the Job backend, use a DockerImage")
+	}
+	image, err := outputImage(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	uri, _, _ := unstructured.NestedString(obj.Object, "spec", "source", "git", "uri")
+	ref, _, _ := unstructured.NestedString(obj.Object, "spec", "source", "git", "ref")
+	inline, _, _ := unstructured.NestedString(obj.Object, "spec", "source", "dockerfile")
+	if uri == "" && inline == "" {
+		return nil, errors.New("BuildConfig " + obj.GetName() + " has neither a git source nor a Dockerfile")
+	}
+
+	if _, found, _ := unstructured.NestedMap(obj.Object, "spec", "strategy", "dockerStrategy", "from"); found {
+		log.Info("The Job backend builds from the FROM of the Dockerfile, strategy.dockerStrategy.from is ignored", "BuildConfig", obj.GetName())
+	}
+
+	context := "/workspace"
+	if dir, _, _ := unstructured.NestedString(obj.Object, "spec", "source", "contextDir"); dir != "" {
+		context = path.Join(context, dir)
+	}
+
+	dockerfile, _, _ := unstructured.NestedString(obj.Object, "spec", "strategy", "dockerStrategy", "dockerfilePath")
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+
+	args := []interface{}{
+		"--context=dir://" + context,
+		"--dockerfile=" + path.Join(context, dockerfile),
+		"--destination=" + image,
+	}
+
+	buildArgs, _, _ := unstructured.NestedSlice(obj.Object, "spec", "strategy", "dockerStrategy", "buildArgs")
+	for _, arg := range buildArgs {
+		arg, ok := arg.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(arg, "name")
+		value, _, _ := unstructured.NestedFieldNoCopy(arg, "value")
+		args = append(args, "--build-arg="+name+"="+toString(value))
+	}
+
+	// The source is cloned and the inline Dockerfile written by the init
+	// container, the Dockerfile wins over the one of the repository
+	script := ""
+	if uri != "" {
+		script = "git clone \"$GIT_URI\" /workspace && cd /workspace"
+		if ref != "" {
+			script = script + " && git checkout \"$GIT_REF\""
+		}
+		script = script + " && "
+	}
+	script = script + "if [ -n \"$DOCKERFILE\" ]; then mkdir -p \"$CONTEXT\" && printf '%s' \"$DOCKERFILE\" > \"$CONTEXT/" + dockerfile + "\"; fi"
+
+	volumes := []interface{}{
+		map[string]interface{}{"name": "workspace", "emptyDir": map[string]interface{}{}},
+	}
+	mounts := []interface{}{
+		map[string]interface{}{"name": "workspace", "mountPath": "/workspace"},
+	}
+	sourceMounts := []interface{}{mounts[0]}
+
+	// Build secrets are copied into the context like the BuildConfig does,
+	// mounted secrets are symlinks kaniko would copy as such
+	secrets, _, _ := unstructured.NestedSlice(obj.Object, "spec", "source", "secrets")
+	for idx, secret := range secrets {
+		secret, ok := secret.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(secret, "secret", "name")
+		dir, _, _ := unstructured.NestedString(secret, "destinationDir")
+		volume := "build-secret-" + strconv.Itoa(idx)
+
+		volumes = append(volumes, map[string]interface{}{
+			"name":   volume,
+			"secret": map[string]interface{}{"secretName": name},
+		})
+		sourceMounts = append(sourceMounts, map[string]interface{}{"name": volume, "mountPath": "/" + volume})
+		script = script + " && mkdir -p \"$CONTEXT/" + dir + "\" && cp -L /" + volume + "/* \"$CONTEXT/" + dir + "/\""
+	}
+
+	objs := []*unstructured.Unstructured{}
+
+	// The cache volume is not snapshotted by kaniko, it is only visible to
+	// the RUN instructions of the Dockerfile
+	if Cache != nil {
+		claim := cacheClaim(obj)
+		objs = append(objs, claim)
+		volumes = append(volumes, map[string]interface{}{
+			"name":                  "ccache",
+			"persistentVolumeClaim": map[string]interface{}{"claimName": claim.GetName()},
+		})
+		mounts = append(mounts, map[string]interface{}{"name": "ccache", "mountPath": CacheDir})
+		args = append(args, "--build-arg=CCACHE_DIR="+CacheDir, "--ignore-path="+CacheDir)
+	}
+
+	if secret, _, _ := unstructured.NestedString(obj.Object, "spec", "output", "pushSecret", "name"); secret != "" {
+		volumes = append(volumes, map[string]interface{}{
+			"name": "push-secret",
+			"secret": map[string]interface{}{
+				"secretName": secret,
+				"items": []interface{}{
+					map[string]interface{}{"key": ".dockerconfigjson", "path": "config.json"},
+				},
+			},
+		})
+		mounts = append(mounts, map[string]interface{}{"name": "push-secret", "mountPath": "/kaniko/.docker"})
+	}
+
+	pod := map[string]interface{}{
+		"restartPolicy": "Never",
+		"initContainers": []interface{}{
+			map[string]interface{}{
+				"name":    "source",
+				"image":   GitImage,
+				"command": []interface{}{"/bin/sh", "-c", script},
+				"env": []interface{}{
+					map[string]interface{}{"name": "GIT_URI", "value": uri},
+					map[string]interface{}{"name": "GIT_REF", "value": ref},
+					map[string]interface{}{"name": "DOCKERFILE", "value": inline},
+					map[string]interface{}{"name": "CONTEXT", "value": context},
+				},
+				"volumeMounts": sourceMounts,
+			},
+		},
+		"containers": []interface{}{
+			map[string]interface{}{
+				"name":         "build",
+				"image":        KanikoImage,
+				"args":         args,
+				"volumeMounts": mounts,
+			},
+		},
+		"volumes": volumes,
+	}
+
+	if nodeSelector, found, _ := unstructured.NestedFieldCopy(obj.Object, "spec", "nodeSelector"); found {
+		pod["nodeSelector"] = nodeSelector
+	}
+
+	raw, err := json.Marshal(obj.Object["spec"])
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot hash BuildConfig "+obj.GetName())
+	}
+
+	labels := map[string]interface{}{}
+	for key, value := range obj.GetLabels() {
+		labels[key] = value
+	}
+
+	build := newObject("batch/v1", "Job", obj.GetName()+"-"+hash.FNV64a(string(raw))[:8], obj)
+	build.Object["spec"] = map[string]interface{}{
+		"backoffLimit": int64(0),
+		"template": map[string]interface{}{
+			"metadata": map[string]interface{}{"labels": labels},
+			"spec":     pod,
+		},
+	}
+
+	return append(objs, build), nil
+}
@@ -0,0 +1,172 @@
+package build
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/hash"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// StrategyAnnotation selects the ClusterBuildStrategy of a BuildConfig
+	// translated to Shipwright, defaults to buildah
+	StrategyAnnotation = "specialresource.openshift.io/build-strategy"
+
+	shipwrightAPIVersion = "shipwright.io/v1alpha1"
+	internalRegistry     = "image-registry.openshift-image-registry.svc:5000"
+)
+
+type shipwright struct{}
+
+func (shipwright) Available() (bool, error) {
+	return clients.HasWorkloadResource(schema.GroupVersionResource{Group: "shipwright.io", Version: "v1alpha1", Resource: "buildruns"})
+}
+
+// Translate creates a Build with the source, Dockerfile, build args and
+// output of the BuildConfig and a BuildRun of it. BuildRuns run once, the
+// name carries the hash of the Build so a changed Build is run again.
+func (shipwright) Translate(obj *unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+
+	spec := obj.Object["spec"]
+
+	uri, _, _ := unstructured.NestedString(obj.Object, "spec", "source", "git", "uri")
+	if uri == "" {
+		return nil, errors.New("BuildConfig " + obj.GetName() + " has no git source")
+	}
+
+	image, err := outputImage(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy := obj.GetAnnotations()[StrategyAnnotation]
+	if strategy == "" {
+		strategy = "buildah"
+	}
+
+	source := map[string]interface{}{"url": uri}
+	if ref, _, _ := unstructured.NestedString(obj.Object, "spec", "source", "git", "ref"); ref != "" {
+		source["revision"] = ref
+	}
+	if dir, _, _ := unstructured.NestedString(obj.Object, "spec", "source", "contextDir"); dir != "" {
+		source["contextDir"] = dir
+	}
+
+	dockerfile, _, _ := unstructured.NestedString(obj.Object, "spec", "strategy", "dockerStrategy", "dockerfilePath")
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+
+	output := map[string]interface{}{"image": image}
+	if secret, _, _ := unstructured.NestedString(obj.Object, "spec", "output", "pushSecret", "name"); secret != "" {
+		output["credentials"] = map[string]interface{}{"name": secret}
+	}
+
+	buildSpec := map[string]interface{}{
+		"source":     source,
+		"strategy":   map[string]interface{}{"name": strategy, "kind": "ClusterBuildStrategy"},
+		"dockerfile": dockerfile,
+		"output":     output,
+	}
+
+	buildArgs, _, _ := unstructured.NestedSlice(obj.Object, "spec", "strategy", "dockerStrategy", "buildArgs")
+	values := []interface{}{}
+	for _, arg := range buildArgs {
+		arg, ok := arg.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(arg, "name")
+		value, _, _ := unstructured.NestedFieldNoCopy(arg, "value")
+		values = append(values, map[string]interface{}{"value": name + "=" + toString(value)})
+	}
+	if len(values) > 0 {
+		buildSpec["paramValues"] = []interface{}{
+			map[string]interface{}{"name": "build-args", "values": values},
+		}
+	}
+
+	if Cache != nil {
+		log.Info("Shipwright v1alpha1 cannot mount the ccache claim, the build cache needs the Job backend", "BuildConfig", obj.GetName())
+	}
+
+	if _, found, _ := unstructured.NestedMap(obj.Object, "spec", "nodeSelector"); found {
+		log.Info("Shipwright v1alpha1 has no nodeSelector, the build may run on any node", "BuildConfig", obj.GetName())
+	}
+
+	build := newObject(shipwrightAPIVersion, "Build", obj.GetName(), obj)
+	build.Object["spec"] = buildSpec
+
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot hash BuildConfig "+obj.GetName())
+	}
+
+	run := newObject(shipwrightAPIVersion, "BuildRun", obj.GetName()+"-"+hash.FNV64a(string(raw))[:8], obj)
+	run.Object["spec"] = map[string]interface{}{
+		"buildRef":       map[string]interface{}{"name": build.GetName()},
+		"serviceAccount": map[string]interface{}{"name": "builder"},
+	}
+
+	// Only the BuildRun completes, waiting on the Build would not block
+	annotations := build.GetAnnotations()
+	delete(annotations, "specialresource.openshift.io/wait")
+	build.SetAnnotations(annotations)
+
+	return []*unstructured.Unstructured{build, run}, nil
+}
+
+// outputImage returns the pull spec the BuildConfig pushes to, ImageStreamTags
+// are pushed through the internal registry like the BuildConfig would.
+func outputImage(obj *unstructured.Unstructured) (string, error) {
+
+	kind, _, _ := unstructured.NestedString(obj.Object, "spec", "output", "to", "kind")
+	name, _, _ := unstructured.NestedString(obj.Object, "spec", "output", "to", "name")
+
+	switch kind {
+	case "DockerImage":
+		return name, nil
+	case "ImageStreamTag":
+		namespace, _, _ := unstructured.NestedString(obj.Object, "spec", "output", "to", "namespace")
+		if namespace == "" {
+			namespace = obj.GetNamespace()
+		}
+		return internalRegistry + "/" + namespace + "/" + name, nil
+	}
+
+	return "", errors.New("BuildConfig " + obj.GetName() + " output kind " + kind + " not supported")
+}
+
+// newObject returns an object of a backend with the labels and the SRO
+// annotations of the BuildConfig from
+func newObject(apiVersion string, kind string, name string, from *unstructured.Unstructured) *unstructured.Unstructured {
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetName(name)
+	obj.SetNamespace(from.GetNamespace())
+	obj.SetLabels(from.GetLabels())
+
+	annotations := make(map[string]string)
+	for key, value := range from.GetAnnotations() {
+		if strings.HasPrefix(key, "specialresource.openshift.io/") {
+			annotations[key] = value
+		}
+	}
+	obj.SetAnnotations(annotations)
+
+	return obj
+}
+
+func toString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	raw, _ := json.Marshal(value)
+	return string(raw)
+}
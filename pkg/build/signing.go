@@ -0,0 +1,151 @@
+package build
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// SigningKey the private key in the signing Secret
+	SigningKey = "key.pem"
+	// SigningCert the certificate in the signing Secret, enrolled as MOK
+	SigningCert = "cert.pem"
+
+	signingKeyDir = "signing-key"
+)
+
+// signingDockerfile copies the modules the driver container installed for
+// KVER into the signer image, signs them with sign-file of the kernel devel
+// tree and copies them back. The key never ends up in a layer of the image.
+const signingDockerfile = `ARG UNSIGNED
+ARG SIGNER
+FROM ${UNSIGNED} AS unsigned
+FROM ${SIGNER} AS signer
+ARG KVER
+COPY --from=unsigned /lib/modules/${KVER} /modules
+COPY ` + signingKeyDir + ` /` + signingKeyDir + `
+RUN find /modules -name '*.ko' -exec /usr/src/kernels/${KVER}/scripts/sign-file sha256 /` + signingKeyDir + `/` + SigningKey + ` /` + signingKeyDir + `/` + SigningCert + ` {} \;
+FROM ${UNSIGNED}
+ARG KVER
+COPY --from=signer /modules /lib/modules/${KVER}
+`
+
+// Sign splits the BuildConfig obj into a build of the unsigned image, tagged
+// with the suffix -unsigned, and a BuildConfig that signs its modules with
+// the key of secret and pushes them to the output of obj. The driver
+// container keeps pulling the same image. Other objects are returned as is.
+func Sign(obj *unstructured.Unstructured, secret string, image string, kernelFullVersion string) ([]*unstructured.Unstructured, error) {
+
+	if obj.GetKind() != "BuildConfig" || secret == "" {
+		return []*unstructured.Unstructured{obj}, nil
+	}
+
+	if image == "" {
+		image = buildArg(obj, "IMAGE")
+	}
+	if image == "" {
+		return nil, errors.New("BuildConfig " + obj.GetName() + " has no IMAGE build arg, spec.driverContainer.signing.image has to be set")
+	}
+
+	kver := buildArg(obj, "KVER")
+	if kver == "" {
+		kver = kernelFullVersion
+	}
+
+	output, found, err := unstructured.NestedMap(obj.Object, "spec", "output")
+	if err != nil || !found {
+		return nil, errors.New("BuildConfig " + obj.GetName() + " has no output")
+	}
+
+	unsigned := obj.DeepCopy()
+	name, _, _ := unstructured.NestedString(unsigned.Object, "spec", "output", "to", "name")
+	if err := unstructured.SetNestedField(unsigned.Object, unsignedTag(name), "spec", "output", "to", "name"); err != nil {
+		return nil, errors.Wrap(err, "Cannot set output of BuildConfig "+obj.GetName())
+	}
+
+	from, err := outputImage(unsigned)
+	if err != nil {
+		return nil, err
+	}
+
+	triggers := []interface{}{map[string]interface{}{"type": "ConfigChange"}}
+	if kind, _, _ := unstructured.NestedString(obj.Object, "spec", "output", "to", "kind"); kind == "ImageStreamTag" {
+		to, _, _ := unstructured.NestedMap(unsigned.Object, "spec", "output", "to")
+		triggers = append(triggers, map[string]interface{}{
+			"type":        "ImageChange",
+			"imageChange": map[string]interface{}{"from": to},
+		})
+	}
+
+	strategy := map[string]interface{}{
+		"buildArgs": []interface{}{
+			map[string]interface{}{"name": "UNSIGNED", "value": from},
+			map[string]interface{}{"name": "SIGNER", "value": image},
+			map[string]interface{}{"name": "KVER", "value": kver},
+		},
+	}
+	if pushSecret, found, _ := unstructured.NestedMap(obj.Object, "spec", "output", "pushSecret"); found {
+		strategy["pullSecret"] = pushSecret
+	}
+
+	spec := map[string]interface{}{
+		"runPolicy": "Serial",
+		"triggers":  triggers,
+		"source": map[string]interface{}{
+			"type":       "Dockerfile",
+			"dockerfile": signingDockerfile,
+			"secrets": []interface{}{
+				map[string]interface{}{
+					"secret":         map[string]interface{}{"name": secret},
+					"destinationDir": signingKeyDir,
+				},
+			},
+		},
+		"strategy": map[string]interface{}{
+			"type":           "Docker",
+			"dockerStrategy": strategy,
+		},
+		"output": output,
+	}
+	if nodeSelector, found, _ := unstructured.NestedFieldCopy(obj.Object, "spec", "nodeSelector"); found {
+		spec["nodeSelector"] = nodeSelector
+	}
+
+	sign := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	sign.SetAPIVersion(obj.GetAPIVersion())
+	sign.SetKind("BuildConfig")
+	sign.SetName(obj.GetName() + "-sign")
+	sign.SetNamespace(obj.GetNamespace())
+	sign.SetLabels(obj.GetLabels())
+	sign.SetAnnotations(obj.GetAnnotations())
+	sign.Object["spec"] = spec
+
+	log.Info("Signing kernel modules", "BuildConfig", sign.GetName(), "Secret", secret)
+
+	return []*unstructured.Unstructured{unsigned, sign}, nil
+}
+
+// unsignedTag appends -unsigned to the tag of the pull spec or ImageStreamTag
+// name, untagged names are tagged unsigned
+func unsignedTag(name string) string {
+
+	if idx := strings.LastIndex(name, ":"); idx > strings.LastIndex(name, "/") {
+		return name + "-unsigned"
+	}
+	return name + ":unsigned"
+}
+
+// buildArg returns the value of the docker strategy build arg name
+func buildArg(obj *unstructured.Unstructured, name string) string {
+
+	buildArgs, _, _ := unstructured.NestedSlice(obj.Object, "spec", "strategy", "dockerStrategy", "buildArgs")
+	for _, arg := range buildArgs {
+		if arg, ok := arg.(map[string]interface{}); ok && arg["name"] == name {
+			value, _, _ := unstructured.NestedFieldNoCopy(arg, "value")
+			return toString(value)
+		}
+	}
+	return ""
+}
@@ -51,6 +51,7 @@ func Nodes(matchingLabels map[string]string, force bool) error {
 
 	// First check if we have nodeSelectors set and only include those nodes
 	// Otherwise select all nodes without NoExecute and NoSchedule taint.
+	// Windows nodes and the ones of the ExclusionSelector are skipped.
 	opts := []client.ListOption{}
 	if len(matchingLabels) > 0 {
 		opts = append(opts, client.MatchingLabels(matchingLabels))
@@ -60,7 +61,7 @@ func Nodes(matchingLabels map[string]string, force bool) error {
 	list.SetAPIVersion("v1")
 	list.SetKind("NodeList")
 
-	err := clients.Interface.List(context.TODO(), &list, opts...)
+	err := clients.Workload().List(context.TODO(), &list, opts...)
 	if err != nil {
 		return errors.Wrap(err, "Client cannot get NodeList")
 	}
@@ -71,6 +72,16 @@ func Nodes(matchingLabels map[string]string, force bool) error {
 	// Filter all nodes out that have NoExecute or NoSchedule taint
 	for idx, node := range list.Items {
 
+		osImage, _, err := unstructured.NestedString(node.Object, "status", "nodeInfo", "osImage")
+		if err != nil {
+			warn.OnError(err)
+			return errors.Wrap(err, "Cannot extract osImage from Node object")
+		}
+		if Excluded(node.GetLabels(), osImage, ExclusionSelector) {
+			log.Info("Nodes excluded", "name", node.GetName(), "osImage", osImage)
+			continue
+		}
+
 		taints, ok, err := unstructured.NestedSlice(node.Object, "spec", "taints")
 		if err != nil {
 			warn.OnError(err)
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"strings"
+)
+
+const (
+	// OSLabel the well-known label of the kubelet with the GOOS of the node
+	OSLabel = "kubernetes.io/os"
+	// BetaOSLabel the deprecated OSLabel older kubelets still set
+	BetaOSLabel = "beta.kubernetes.io/os"
+)
+
+// ExclusionSelector the .spec.nodeExclusionSelector of the SpecialResource
+// that is reconciled, nodes with all of its labels are not cached
+var ExclusionSelector map[string]string
+
+// Unsupported returns true for nodes SRO cannot build or load kernel modules
+// for, Windows workers and other nodes that do not run Linux
+func Unsupported(labels map[string]string, osImage string) bool {
+
+	for _, label := range []string{OSLabel, BetaOSLabel} {
+		if os, ok := labels[label]; ok && os != "linux" {
+			return true
+		}
+	}
+
+	return strings.Contains(strings.ToLower(osImage), "windows")
+}
+
+// Excluded returns true for Unsupported nodes and for nodes that have all
+// labels of the exclusion selector
+func Excluded(labels map[string]string, osImage string, exclusion map[string]string) bool {
+
+	if Unsupported(labels, osImage) {
+		return true
+	}
+
+	if len(exclusion) == 0 {
+		return false
+	}
+
+	for key, value := range exclusion {
+		if labels[key] != value {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,70 @@
+package clients
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Capabilities the OpenShift APIs the cluster serves, on MicroShift and
+// upstream Kubernetes most of them are missing
+type Capabilities struct {
+	// BuildConfigs driver containers are built with a Job otherwise
+	BuildConfigs bool
+	// ImageStreams the DTK is read from the release payload otherwise
+	ImageStreams bool
+	// ClusterVersion the cluster version is read from the facts otherwise
+	ClusterVersion bool
+	// MachineConfigs modules cannot be blacklisted otherwise
+	MachineConfigs bool
+	// ClusterOperators the operator status is not reported otherwise
+	ClusterOperators bool
+}
+
+var capabilityResources = map[string]schema.GroupVersionResource{
+	"BuildConfigs":     {Group: "build.openshift.io", Version: "v1", Resource: "buildconfigs"},
+	"ImageStreams":     {Group: "image.openshift.io", Version: "v1", Resource: "imagestreams"},
+	"ClusterVersion":   {Group: "config.openshift.io", Version: "v1", Resource: "clusterversions"},
+	"MachineConfigs":   {Group: "machineconfiguration.openshift.io", Version: "v1", Resource: "machineconfigs"},
+	"ClusterOperators": {Group: "config.openshift.io", Version: "v1", Resource: "clusteroperators"},
+}
+
+// DetectCapabilities discovers the Capabilities of the cluster running the
+// nodes, missing ones are logged with the code path that replaces them
+func DetectCapabilities() (Capabilities, error) {
+
+	found := make(map[string]bool)
+
+	for name, resource := range capabilityResources {
+		available, err := HasWorkloadResource(resource)
+		if err != nil {
+			return Capabilities{}, errors.Wrap(err, "Cannot discover "+name)
+		}
+		found[name] = available
+	}
+
+	capabilities := Capabilities{
+		BuildConfigs:     found["BuildConfigs"],
+		ImageStreams:     found["ImageStreams"],
+		ClusterVersion:   found["ClusterVersion"],
+		MachineConfigs:   found["MachineConfigs"],
+		ClusterOperators: found["ClusterOperators"],
+	}
+
+	if !capabilities.BuildConfigs {
+		log.Info("Capability missing, driver containers are built with the Job backend", "api", "BuildConfigs")
+	}
+	if !capabilities.ImageStreams {
+		log.Info("Capability missing, the driver-toolkit ImageStream is not used", "api", "ImageStreams")
+	}
+	if !capabilities.ClusterVersion {
+		log.Info("Capability missing, the cluster version is read from the facts ConfigMap", "api", "ClusterVersion")
+	}
+	if !capabilities.MachineConfigs {
+		log.Info("Capability missing, kernel modules cannot be blacklisted", "api", "MachineConfigs")
+	}
+	if !capabilities.ClusterOperators {
+		log.Info("Capability missing, the operator status is not reported", "api", "ClusterOperators")
+	}
+
+	return capabilities, nil
+}
@@ -11,10 +11,12 @@ import (
 
 	"github.com/pkg/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -26,6 +28,11 @@ var (
 	RestConfig *rest.Config
 	Namespace  string
 	config     genericclioptions.ConfigFlags
+
+	// Hosted are the clients of the HyperShift hosted cluster the node side
+	// resources are applied to, nil if the operator manages its own cluster.
+	Hosted           *ClientsInterface
+	HostedRestConfig *rest.Config
 )
 
 type ClientsInterface struct {
@@ -35,6 +42,9 @@ type ClientsInterface struct {
 	record.EventRecorder
 	authn.Keychain
 	discovery.CachedDiscoveryInterface
+	// Watcher reads directly from the API server and watches single
+	// objects, nil if waits have to poll
+	Watcher client.WithWatch
 }
 
 func init() {
@@ -59,6 +69,14 @@ func GetConfigClientOrDie() clientconfigv1.ConfigV1Client {
 	return *client
 }
 
+// GetWatchClientOrDie Add a client watching the objects SRO waits for
+func GetWatchClientOrDie(scheme *runtime.Scheme) client.WithWatch {
+
+	c, err := client.NewWithWatch(RestConfig, client.Options{Scheme: scheme})
+	exit.OnError(err)
+	return c
+}
+
 func GetCachedDiscoveryClientOrDie() discovery.CachedDiscoveryInterface {
 
 	client, err := config.ToDiscoveryClient()
@@ -66,8 +84,110 @@ func GetCachedDiscoveryClientOrDie() discovery.CachedDiscoveryInterface {
 	return client
 }
 
+// Workload returns the clients of the cluster running the nodes, the hosted
+// cluster in HyperShift mode. Metadata like the ClusterVersion, the pull
+// secret or the DTK ImageStream is always read from Interface.
+func Workload() *ClientsInterface {
+	if Hosted != nil {
+		return Hosted
+	}
+	return Interface
+}
+
+// IsHosted returns true if node side resources go to a hosted cluster
+func IsHosted() bool {
+	return Hosted != nil
+}
+
+// NewHosted creates the clients of the hosted cluster from kubeconfig, the
+// EventRecorder and Keychain are not set since events and credentials
+// belong to the management side.
+func NewHosted(kubeconfig string, scheme *runtime.Scheme) (*ClientsInterface, *rest.Config, error) {
+
+	restConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Cannot load hosted cluster kubeconfig "+kubeconfig)
+	}
+
+	c, err := client.NewWithWatch(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Cannot create hosted cluster client")
+	}
+
+	clientSet, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Cannot create hosted cluster clientset")
+	}
+
+	configClient, err := clientconfigv1.NewForConfig(restConfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Cannot create hosted cluster config client")
+	}
+
+	hostedConfig := genericclioptions.NewConfigFlags(true)
+	hostedConfig.KubeConfig = &kubeconfig
+
+	discoveryClient, err := hostedConfig.ToDiscoveryClient()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Cannot create hosted cluster discovery client")
+	}
+
+	log.Info("Applying node side resources to hosted cluster", "host", restConfig.Host)
+
+	return &ClientsInterface{
+		Client:                   c,
+		Clientset:                *clientSet,
+		ConfigV1Client:           *configClient,
+		CachedDiscoveryInterface: discoveryClient,
+		Watcher:                  c,
+	}, restConfig, nil
+}
+
+// ImpersonateConfig returns the config of Workload acting as the service
+// account
+func ImpersonateConfig(namespace string, serviceAccount string) *rest.Config {
+
+	restConfig := rest.CopyConfig(RestConfig)
+	if HostedRestConfig != nil {
+		restConfig = rest.CopyConfig(HostedRestConfig)
+	}
+	restConfig.Impersonate = rest.ImpersonationConfig{
+		UserName: "system:serviceaccount:" + namespace + ":" + serviceAccount,
+	}
+
+	return restConfig
+}
+
+// Impersonate returns a client of Workload acting as the service account,
+// requests are authorized with the RBAC of the service account instead of
+// the one of the operator.
+func Impersonate(namespace string, serviceAccount string) (client.Client, error) {
+
+	c, err := client.New(ImpersonateConfig(namespace, serviceAccount), client.Options{
+		Scheme: Workload().Scheme(),
+		Mapper: Workload().RESTMapper(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot impersonate service account "+namespace+"/"+serviceAccount)
+	}
+
+	return c, nil
+}
+
 func HasResource(resource schema.GroupVersionResource) (bool, error) {
-	dclient, err := discovery.NewDiscoveryClientForConfig(RestConfig)
+	return hasResource(RestConfig, resource)
+}
+
+// HasWorkloadResource checks the cluster running the nodes, see Workload
+func HasWorkloadResource(resource schema.GroupVersionResource) (bool, error) {
+	if HostedRestConfig != nil {
+		return hasResource(HostedRestConfig, resource)
+	}
+	return hasResource(RestConfig, resource)
+}
+
+func hasResource(restConfig *rest.Config, resource schema.GroupVersionResource) (bool, error) {
+	dclient, err := discovery.NewDiscoveryClientForConfig(restConfig)
 	if err != nil {
 		return false, errors.Wrap(err, "Error: cannot retrieve a DiscoveryClient")
 	}
@@ -98,7 +218,7 @@ func HasResource(resource schema.GroupVersionResource) (bool, error) {
 }
 
 func BuildConfigsAvailable() (bool, error) {
-	return HasResource(buildv1.SchemeGroupVersion.WithResource("buildconfigs"))
+	return HasWorkloadResource(buildv1.SchemeGroupVersion.WithResource("buildconfigs"))
 }
 
 func GetPlatform() string {
@@ -0,0 +1,259 @@
+package clients
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	// InformerSelector restricts the cached Secrets and ConfigMaps to the
+	// ones matching the label selector, the others are read from the API
+	// server. Nodes are always cached.
+	InformerSelector string
+	// InformerMemoryLimit is the maximum size in bytes of the cached
+	// objects, above it the informers are stopped and reads go to the API
+	// server, 0 means no limit.
+	InformerMemoryLimit int64
+
+	cached informerCache
+
+	secretHandlers      []func(namespace string, name string)
+	secretHandlersMutex sync.RWMutex
+)
+
+// OnSecretChange registers handler to be called for every Secret the
+// informers see added, updated or deleted. Secrets outside of the
+// InformerSelector and changes after the memory limit was exceeded are not
+// seen.
+func OnSecretChange(handler func(namespace string, name string)) {
+	secretHandlersMutex.Lock()
+	defer secretHandlersMutex.Unlock()
+	secretHandlers = append(secretHandlers, handler)
+}
+
+func notifySecretChange(obj interface{}) {
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(keyOf(obj))
+	if err != nil {
+		return
+	}
+
+	secretHandlersMutex.RLock()
+	defer secretHandlersMutex.RUnlock()
+	for _, handler := range secretHandlers {
+		handler(namespace, name)
+	}
+}
+
+func keyOf(obj interface{}) string {
+	key, _ := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	return key
+}
+
+// informerCache the listers of the informers and the size of the objects
+// they hold
+type informerCache struct {
+	mutex      sync.RWMutex
+	synced     bool
+	exceeded   bool
+	size       int64
+	sizes      map[string]int64
+	stop       context.CancelFunc
+	secrets    corelisters.SecretLister
+	configMaps corelisters.ConfigMapLister
+	nodes      corelisters.NodeLister
+}
+
+// sized is implemented by the generated protobuf API types
+type sized interface {
+	Size() int
+}
+
+// listers returns the listers if reads can be served from the informers
+func (c *informerCache) listers() (corelisters.SecretLister, corelisters.ConfigMapLister, corelisters.NodeLister, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if !c.synced || c.exceeded {
+		return nil, nil, nil, false
+	}
+	return c.secrets, c.configMaps, c.nodes, true
+}
+
+// account updates the size of the cached object, a size of 0 removes it
+func (c *informerCache) account(kind string, obj interface{}, size int64) {
+
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	key = kind + "/" + key
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.exceeded {
+		return
+	}
+
+	c.size += size - c.sizes[key]
+	if size == 0 {
+		delete(c.sizes, key)
+	} else {
+		c.sizes[key] = size
+	}
+
+	if InformerMemoryLimit == 0 || c.size <= InformerMemoryLimit {
+		return
+	}
+
+	// Drop the stores, objects are read from the API server from now on
+	log.Info("Informer memory limit exceeded, stopping informers", "size", c.size, "limit", InformerMemoryLimit)
+	c.exceeded = true
+	c.sizes = nil
+	c.secrets, c.configMaps, c.nodes = nil, nil, nil
+	c.stop()
+}
+
+// accounting returns the handler keeping track of the size of kind objects
+func (c *informerCache) accounting(kind string) cache.ResourceEventHandler {
+
+	add := func(obj interface{}) {
+		if s, ok := obj.(sized); ok {
+			c.account(kind, obj, int64(s.Size()))
+		}
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    add,
+		UpdateFunc: func(_, obj interface{}) { add(obj) },
+		DeleteFunc: func(obj interface{}) { c.account(kind, obj, 0) },
+	}
+}
+
+// RunInformers starts the informers of Secrets and ConfigMaps of Interface
+// and of Nodes of Workload and blocks until ctx is done. Until the informers
+// are synced all reads go to the API server.
+func RunInformers(ctx context.Context) error {
+
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	metadata := informers.NewSharedInformerFactoryWithOptions(&Interface.Clientset, 0,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = InformerSelector
+		}))
+	nodes := informers.NewSharedInformerFactory(&Workload().Clientset, 0)
+
+	secrets := metadata.Core().V1().Secrets()
+	configMaps := metadata.Core().V1().ConfigMaps()
+	nodeInformer := nodes.Core().V1().Nodes()
+
+	cached.mutex.Lock()
+	cached.sizes = make(map[string]int64)
+	cached.stop = stop
+	cached.secrets = secrets.Lister()
+	cached.configMaps = configMaps.Lister()
+	cached.nodes = nodeInformer.Lister()
+	cached.mutex.Unlock()
+
+	secrets.Informer().AddEventHandler(cached.accounting("Secret"))
+	secrets.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    notifySecretChange,
+		UpdateFunc: func(_, obj interface{}) { notifySecretChange(obj) },
+		DeleteFunc: notifySecretChange,
+	})
+	configMaps.Informer().AddEventHandler(cached.accounting("ConfigMap"))
+	nodeInformer.Informer().AddEventHandler(cached.accounting("Node"))
+
+	metadata.Start(ctx.Done())
+	nodes.Start(ctx.Done())
+
+	synced := metadata.WaitForCacheSync(ctx.Done())
+	for informer, ok := range nodes.WaitForCacheSync(ctx.Done()) {
+		synced[informer] = ok
+	}
+
+	// Stopped while syncing, either shutdown or the limit was exceeded
+	if ctx.Err() != nil {
+		return nil
+	}
+	for informer, ok := range synced {
+		if !ok {
+			return errors.New("Cannot sync informer " + informer.String())
+		}
+	}
+
+	cached.mutex.Lock()
+	cached.synced = true
+	log.Info("Informers synced", "selector", InformerSelector, "size", cached.size)
+	cached.mutex.Unlock()
+
+	<-ctx.Done()
+	return nil
+}
+
+// authoritative returns true if an object missing from the informers does
+// not exist, with a selector it may only lack the labels
+func authoritative(err error) bool {
+	return InformerSelector == "" || !apierrors.IsNotFound(err)
+}
+
+// GetSecret returns the Secret of Interface, from the informers if
+// possible. The returned object is shared and must not be modified.
+func GetSecret(namespace string, name string) (*v1.Secret, error) {
+
+	if secrets, _, _, ok := cached.listers(); ok {
+		secret, err := secrets.Secrets(namespace).Get(name)
+		if err == nil || authoritative(err) {
+			return secret, err
+		}
+	}
+
+	return Interface.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// GetConfigMap returns the ConfigMap of Interface, from the informers if
+// possible. The returned object is shared and must not be modified.
+func GetConfigMap(namespace string, name string) (*v1.ConfigMap, error) {
+
+	if _, configMaps, _, ok := cached.listers(); ok {
+		cm, err := configMaps.ConfigMaps(namespace).Get(name)
+		if err == nil || authoritative(err) {
+			return cm, err
+		}
+	}
+
+	return Interface.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// ListNodes returns the Nodes of Workload matching selector, from the
+// informers if possible. The returned objects are shared and must not be
+// modified, nodes that are updated have to be read with a Get first.
+func ListNodes(selector labels.Selector) ([]*v1.Node, error) {
+
+	if _, _, nodes, ok := cached.listers(); ok {
+		return nodes.List(selector)
+	}
+
+	list, err := Workload().CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]*v1.Node, 0, len(list.Items))
+	for idx := range list.Items {
+		nodes = append(nodes, &list.Items[idx])
+	}
+	return nodes, nil
+}
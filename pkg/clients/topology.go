@@ -0,0 +1,33 @@
+package clients
+
+import (
+	"context"
+
+	configv1 "github.com/openshift/api/config/v1"
+	clientconfigv1 "github.com/openshift/client-go/config/clientset/versioned/typed/config/v1"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// SingleNode returns true if the control plane of the cluster runs on a
+// single node (SNO), it is read before the clients are set up. Clusters
+// without the Infrastructure config are never single node.
+func SingleNode(restConfig *rest.Config) (bool, error) {
+
+	configClient, err := clientconfigv1.NewForConfig(restConfig)
+	if err != nil {
+		return false, errors.Wrap(err, "Cannot create config client")
+	}
+
+	infra, err := configClient.Infrastructures().Get(context.TODO(), "cluster", metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "Cannot get Infrastructure cluster")
+	}
+
+	return infra.Status.ControlPlaneTopology == configv1.SingleReplicaTopologyMode, nil
+}
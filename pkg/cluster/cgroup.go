@@ -0,0 +1,43 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// CgroupVersion returns the cgroup version of the nodes, v1 or v2, from the
+// cgroupMode of the cluster node config. Clusters without it fall back to the
+// facts, the default of OpenShift is v1.
+func CgroupVersion() (string, error) {
+
+	config := &unstructured.Unstructured{}
+	config.SetAPIVersion("config.openshift.io/v1")
+	config.SetKind("Node")
+
+	err := clients.Workload().Get(context.TODO(), types.NamespacedName{Name: "cluster"}, config)
+	if err != nil && !meta.IsNoMatchError(err) && !apierrors.IsNotFound(err) {
+		return "", errors.Wrap(err, "Cannot get nodes.config.openshift.io cluster")
+	}
+
+	if err == nil {
+		if mode, _, _ := unstructured.NestedString(config.Object, "spec", "cgroupMode"); mode != "" {
+			return mode, nil
+		}
+	}
+
+	facts, err := GetFacts()
+	if err != nil {
+		return "", err
+	}
+	if facts.CgroupVersion != "" {
+		return facts.CgroupVersion, nil
+	}
+
+	return "v1", nil
+}
@@ -20,6 +20,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
 )
 
 var (
@@ -33,7 +34,15 @@ func init() {
 func Version() (string, string, error) {
 
 	if !ClusterVersionAvailable() {
-		return "", "", nil
+		facts, err := GetFacts()
+		if err != nil || facts.ClusterVersion == "" {
+			return "", "", err
+		}
+		s := strings.Split(facts.ClusterVersion, ".")
+		if len(s) < 2 {
+			return "", "", errors.New("Invalid clusterVersion " + facts.ClusterVersion + " in ConfigMap " + FactsConfigMap)
+		}
+		return facts.ClusterVersion, s[0] + "." + s[1], nil
 	}
 
 	version, err := clients.Interface.ClusterVersions().Get(context.TODO(), "version", metav1.GetOptions{})
@@ -61,6 +70,40 @@ func Version() (string, string, error) {
 	return "", "", errors.New("Undefined Cluster Version")
 }
 
+// UpgradeTarget returns the release image and version the cluster is being
+// upgraded to, empty if no upgrade is in progress
+func UpgradeTarget() (string, string, error) {
+
+	if !ClusterVersionAvailable() {
+		return "", "", nil
+	}
+
+	version, err := clients.Interface.ClusterVersions().Get(context.TODO(), "version", metav1.GetOptions{})
+	if err != nil {
+		return "", "", errors.Wrap(err, "ConfigClient unable to get ClusterVersions")
+	}
+
+	desired := version.Status.Desired
+	if desired.Image == "" {
+		return "", "", nil
+	}
+
+	// The last completed release is the one running, the desired one is
+	// still rolled out otherwise
+	for _, update := range version.Status.History {
+		if update.State != configv1.CompletedUpdate {
+			continue
+		}
+		if update.Image == desired.Image {
+			return "", "", nil
+		}
+		return desired.Image, desired.Version, nil
+	}
+
+	// Still installing
+	return "", "", nil
+}
+
 func VersionHistory() ([]string, error) {
 
 	stat := []string{}
@@ -128,13 +171,33 @@ func OperatingSystem() (string, string, string, error) {
 		nodeOSmin = labels[os+".VERSION_ID.minor"]
 
 		if len(nodeOSrel) == 0 || len(nodeOSmaj) == 0 {
-			return "", "", "", errors.New("Cannot extract " + os + ".*, is NFD running? Check node labels")
+			return operatingSystemFromFacts(errors.New("Cannot extract " + os + ".*, is NFD running? Check node labels"))
 		}
 	}
 
 	return osversion.RenderOperatingSystem(nodeOSrel, nodeOSmaj, nodeOSmin)
 }
 
+// operatingSystemFromFacts renders the OS of the FactsConfigMap for nodes
+// without NFD labels, err is returned if the facts have none
+func operatingSystemFromFacts(err error) (string, string, string, error) {
+
+	facts, factsErr := GetFacts()
+	if factsErr != nil {
+		return "", "", "", factsErr
+	}
+	if facts.OSRelease == "" || facts.OSVersion == "" {
+		return "", "", "", errors.Wrap(err, "No osRelease and osVersion in ConfigMap "+FactsConfigMap)
+	}
+
+	version := strings.SplitN(facts.OSVersion, ".", 2)
+	if len(version) < 2 {
+		version = append(version, "")
+	}
+
+	return osversion.RenderOperatingSystem(facts.OSRelease, version[0], version[1])
+}
+
 func ClusterVersionAvailable() bool {
 
 	clusterVersionAvailable, err := clients.HasResource(configv1.SchemeGroupVersion.WithResource("clusterversions"))
@@ -147,3 +210,27 @@ func ClusterVersionAvailable() bool {
 	}
 	return true
 }
+
+// FIPS returns true if the cluster was installed in FIPS mode, FIPS can only
+// be enabled at install time for all nodes. Clusters without the install-config
+// are assumed to not run in FIPS mode.
+func FIPS() (bool, error) {
+
+	cm, err := clients.GetConfigMap("kube-system", "cluster-config-v1")
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "Cannot get ConfigMap cluster-config-v1 -n kube-system")
+	}
+
+	installConfig := struct {
+		FIPS bool `json:"fips"`
+	}{}
+
+	if err := yaml.Unmarshal([]byte(cm.Data["install-config"]), &installConfig); err != nil {
+		return false, errors.Wrap(err, "Cannot parse install-config")
+	}
+
+	return installConfig.FIPS, nil
+}
@@ -0,0 +1,46 @@
+package cluster
+
+import (
+	"os"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// FactsConfigMap in the operator namespace holds the facts SRO cannot
+// discover on clusters without ClusterVersion or NFD, e.g. MicroShift or
+// upstream Kubernetes
+const FactsConfigMap = "special-resource-facts"
+
+// Facts of the cluster given by the admin, discovered values win
+type Facts struct {
+	// ClusterVersion e.g. 4.9.0
+	ClusterVersion string
+	// OSRelease and OSVersion the ID and VERSION_ID of the os-release of
+	// the nodes, e.g. rhel and 8.4
+	OSRelease string
+	OSVersion string
+	// CgroupVersion of the nodes, v1 or v2
+	CgroupVersion string
+}
+
+// GetFacts returns the facts of the FactsConfigMap, empty ones if it does
+// not exist
+func GetFacts() (Facts, error) {
+
+	cm, err := clients.GetConfigMap(os.Getenv("OPERATOR_NAMESPACE"), FactsConfigMap)
+	if apierrors.IsNotFound(err) {
+		return Facts{}, nil
+	}
+	if err != nil {
+		return Facts{}, errors.Wrap(err, "Cannot get ConfigMap "+FactsConfigMap)
+	}
+
+	return Facts{
+		ClusterVersion: cm.Data["clusterVersion"],
+		OSRelease:      cm.Data["osRelease"],
+		OSVersion:      cm.Data["osVersion"],
+		CgroupVersion:  cm.Data["cgroupVersion"],
+	}, nil
+}
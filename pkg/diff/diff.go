@@ -0,0 +1,110 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// maxValue is the length values are truncated to
+const maxValue = 80
+
+// ignored fields are set by the API server or SRO on every update
+var ignored = map[string]bool{
+	".metadata.resourceVersion":                                    true,
+	".metadata.managedFields":                                      true,
+	".metadata.annotations[\"specialresource.openshift.io/hash\"]": true,
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Objects returns the fields of desired that differ from live, one line per
+// field, e.g. `.spec.template.spec.containers[0].image: "a" -> "b"`. Fields
+// only live has are not reported since server-side apply leaves them alone.
+// The values of Secrets are redacted.
+func Objects(live *unstructured.Unstructured, desired *unstructured.Unstructured) []string {
+
+	changes := []string{}
+	secret := desired.GetKind() == "Secret"
+
+	walk("", live.Object, desired.Object, secret, &changes)
+
+	return changes
+}
+
+func walk(path string, live interface{}, desired interface{}, secret bool, changes *[]string) {
+
+	if ignored[path] {
+		return
+	}
+
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		if l, ok := live.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(d))
+			for key := range d {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				walk(field(path, key), l[key], d[key], secret, changes)
+			}
+			return
+		}
+	case []interface{}:
+		if l, ok := live.([]interface{}); ok {
+			if len(l) != len(d) {
+				*changes = append(*changes, path+": "+strconv.Itoa(len(l))+" items -> "+strconv.Itoa(len(d))+" items")
+			}
+			for idx := range d {
+				var item interface{}
+				if idx < len(l) {
+					item = l[idx]
+				}
+				walk(path+"["+strconv.Itoa(idx)+"]", item, d[idx], secret, changes)
+			}
+			return
+		}
+	}
+
+	if reflect.DeepEqual(live, desired) {
+		return
+	}
+
+	redact := secret && (strings.HasPrefix(path, ".data") || strings.HasPrefix(path, ".stringData"))
+
+	*changes = append(*changes, path+": "+value(live, redact)+" -> "+value(desired, redact))
+}
+
+// field returns the path of key in the object at path
+func field(path string, key string) string {
+	if identifier.MatchString(key) {
+		return path + "." + key
+	}
+	return path + "[" + strconv.Quote(key) + "]"
+}
+
+// value returns the JSON of v truncated to maxValue
+func value(v interface{}, redact bool) string {
+
+	if v == nil {
+		return "<none>"
+	}
+	if redact {
+		return "<redacted>"
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		return "<invalid>"
+	}
+	if len(out) > maxValue {
+		return string(out[:maxValue]) + "..."
+	}
+	return string(out)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/openshift-psap/special-resource-operator/pkg/lifecycle"
 	"github.com/openshift-psap/special-resource-operator/pkg/storage"
 	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	configv1 "github.com/openshift/api/config/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -154,6 +155,10 @@ func Predicate() predicate.Predicate {
 			/* want to recreate it so handle the delete event */
 			obj := e.Object
 
+			if pass, found := triggerCreate(obj); found {
+				return pass
+			}
+
 			if IsSpecialResource(obj) {
 				return true
 			}
@@ -176,6 +181,21 @@ func Predicate() predicate.Predicate {
 			}*/
 			Mode = "UPDATE"
 
+			// The cluster is upgraded to another release, the generation
+			// only changes with the spec
+			if cv, ok := e.ObjectNew.(*configv1.ClusterVersion); ok {
+				old, ok := e.ObjectOld.(*configv1.ClusterVersion)
+				if !ok || cv.Status.Desired.Image == old.Status.Desired.Image {
+					return false
+				}
+				log.Info(Mode+" ClusterVersion desired release changed", "Version", cv.Status.Desired.Version)
+				return true
+			}
+
+			if pass, found := triggerUpdate(e.ObjectOld, e.ObjectNew); found {
+				return pass
+			}
+
 			e.ObjectOld.GetGeneration()
 			e.ObjectOld.GetOwnerReferences()
 
@@ -0,0 +1,74 @@
+package filter
+
+import (
+	"reflect"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Triggers are objects SpecialResources opt into with spec.triggers, they
+// are neither SpecialResources nor owned. The map functions of the
+// controller select the SpecialResources of an event.
+
+// isMachineConfigPool is true for the unstructured MachineConfigPools
+func isMachineConfigPool(obj client.Object) bool {
+	u, ok := obj.(*unstructured.Unstructured)
+	return ok && u.GetKind() == "MachineConfigPool"
+}
+
+// triggerCreate returns true for nodes joining the cluster, found is false if
+// obj is no trigger
+func triggerCreate(obj client.Object) (bool, bool) {
+
+	if _, ok := obj.(*v1.Node); ok {
+		return true, true
+	}
+	if isMachineConfigPool(obj) {
+		return false, true
+	}
+	if _, ok := obj.(*v1.Secret); ok && !Owned(obj) {
+		return false, true
+	}
+	if _, ok := obj.(*v1.ConfigMap); ok && !Owned(obj) {
+		return false, true
+	}
+
+	return false, false
+}
+
+// triggerUpdate returns true for nodes whose labels changed, pools that
+// rendered a new config or updated machines and Secrets or ConfigMaps whose
+// data changed, found is false if the objects are no trigger
+func triggerUpdate(old client.Object, new client.Object) (bool, bool) {
+
+	if _, ok := new.(*v1.Node); ok {
+		return !reflect.DeepEqual(old.GetLabels(), new.GetLabels()), true
+	}
+
+	if isMachineConfigPool(new) {
+		o, _ := old.(*unstructured.Unstructured)
+		n, _ := new.(*unstructured.Unstructured)
+		if o == nil || n == nil {
+			return false, true
+		}
+		oldConfig, _, _ := unstructured.NestedString(o.Object, "status", "configuration", "name")
+		newConfig, _, _ := unstructured.NestedString(n.Object, "status", "configuration", "name")
+		oldUpdated, _, _ := unstructured.NestedInt64(o.Object, "status", "updatedMachineCount")
+		newUpdated, _, _ := unstructured.NestedInt64(n.Object, "status", "updatedMachineCount")
+		return oldConfig != newConfig || oldUpdated != newUpdated, true
+	}
+
+	if secret, ok := new.(*v1.Secret); ok && !Owned(new) {
+		oldSecret, ok := old.(*v1.Secret)
+		return !ok || !reflect.DeepEqual(oldSecret.Data, secret.Data), true
+	}
+
+	if cm, ok := new.(*v1.ConfigMap); ok && !Owned(new) {
+		oldCM, ok := old.(*v1.ConfigMap)
+		return !ok || !reflect.DeepEqual(oldCM.Data, cm.Data), true
+	}
+
+	return false, false
+}
@@ -0,0 +1,137 @@
+package firmware
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+const (
+	// HelperName of the template with the firmware helpers that is added to
+	// every chart
+	HelperName = "templates/_specialresource_firmware.tpl"
+	// DefaultHostPath firmware is copied to if the SpecialResource sets none
+	DefaultHostPath = "/var/lib/firmware"
+)
+
+// helpers copies the firmware of the driver container to the host with an
+// init container, a sidecar removes the copied files again when the driver
+// pod is deleted. The files copied are kept in a manifest on the host.
+var helpers = `{{/*
+Firmware helpers for driver container DaemonSets, the init container and
+container take a dict with the chart Values and the driver container image:
+
+      initContainers:
+      {{- include "specialresource.firmware.initContainer" (dict "Values" .Values "image" $image) | nindent 6 }}
+      containers:
+      {{- include "specialresource.firmware.container" (dict "Values" .Values "image" $image) | nindent 6 }}
+      volumes:
+      {{- include "specialresource.firmware.volume" . | nindent 6 }}
+
+The helpers render nothing if spec.driverContainer.firmware.source is empty.
+*/}}
+{{- define "specialresource.firmware.manifest" -}}
+.specialresource-{{ .Values.specialresource.metadata.name }}.files
+{{- end }}
+
+{{- define "specialresource.firmware.initContainer" -}}
+{{- $firmware := .Values.specialresource.spec.driverContainer.firmware | default dict }}
+{{- if $firmware.source -}}
+- name: firmware-install
+  image: {{ .image }}
+  command:
+  - /bin/sh
+  - -c
+  - |
+    set -e
+    cd {{ $firmware.source }}
+    find . ! -type d | sed 's|^\./||' > /host-firmware/{{ include "specialresource.firmware.manifest" . }}
+    cp -a . /host-firmware/
+  securityContext:
+    privileged: true
+  volumeMounts:
+  - name: firmware
+    mountPath: /host-firmware
+{{- end }}
+{{- end }}
+
+{{- define "specialresource.firmware.container" -}}
+{{- $firmware := .Values.specialresource.spec.driverContainer.firmware | default dict }}
+{{- if $firmware.source -}}
+- name: firmware-cleanup
+  image: {{ .image }}
+  command:
+  - /bin/sh
+  - -c
+  - trap 'exit 0' TERM; while true; do sleep 3600 & wait $!; done
+  lifecycle:
+    preStop:
+      exec:
+        command:
+        - /bin/sh
+        - -c
+        - |
+          cd /host-firmware || exit 0
+          [ -f {{ include "specialresource.firmware.manifest" . }} ] || exit 0
+          while IFS= read -r file; do rm -f "$file"; done < {{ include "specialresource.firmware.manifest" . }}
+          rm -f {{ include "specialresource.firmware.manifest" . }}
+  securityContext:
+    privileged: true
+  volumeMounts:
+  - name: firmware
+    mountPath: /host-firmware
+{{- end }}
+{{- end }}
+
+{{- define "specialresource.firmware.volume" -}}
+{{- $firmware := .Values.specialresource.spec.driverContainer.firmware | default dict }}
+{{- if $firmware.source -}}
+- name: firmware
+  hostPath:
+    path: {{ $firmware.hostPath | default "` + DefaultHostPath + `" }}
+    type: DirectoryOrCreate
+{{- end }}
+{{- end }}
+`
+
+// AddHelpers returns a copy of templates with the firmware helpers, the
+// templates of the loaded chart are left untouched.
+func AddHelpers(templates []*chart.File) []*chart.File {
+
+	files := []*chart.File{}
+	for _, template := range templates {
+		if template.Name != HelperName {
+			files = append(files, template)
+		}
+	}
+
+	return append(files, &chart.File{Name: HelperName, Data: []byte(helpers)})
+}
+
+// Validate checks that the chart of a SpecialResource managing firmware
+// installs it with the helpers, otherwise the firmware never reaches the host.
+func Validate(templates []*chart.File) error {
+
+	missing := []string{}
+
+	for _, helper := range []string{"initContainer", "container", "volume"} {
+		name := "\"specialresource.firmware." + helper + "\""
+		found := false
+		for _, template := range templates {
+			if template.Name != HelperName && strings.Contains(string(template.Data), name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return errors.New("spec.driverContainer.firmware is set but the chart does not include " + strings.Join(missing, ", "))
+	}
+
+	return nil
+}
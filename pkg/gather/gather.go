@@ -0,0 +1,336 @@
+package gather
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/openshift-psap/special-resource-operator/pkg/cache"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/color"
+	"github.com/openshift-psap/special-resource-operator/pkg/upgrade"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
+)
+
+var log logr.Logger
+
+func init() {
+	log = zap.New(zap.UseDevMode(true)).WithName(color.Print("gather", color.Cyan))
+}
+
+// ownedLabel is set by SRO on every object it creates from a chart
+const ownedLabel = "specialresource.openshift.io/owned"
+
+// logLimit is the maximum size of the log of a single container
+var logLimit int64 = 10 << 20
+
+// custom resources of SRO, dumped completely
+var customResources = []schema.GroupVersionKind{
+	{Group: "sro.openshift.io", Version: "v1beta1", Kind: "SpecialResource"},
+	{Group: "sro.openshift.io", Version: "v1beta1", Kind: "PreflightValidation"},
+}
+
+// generated kinds that are dumped if they carry ownedLabel, Secrets are
+// left out on purpose
+var generated = []schema.GroupVersionKind{
+	{Group: "", Version: "v1", Kind: "Namespace"},
+	{Group: "", Version: "v1", Kind: "Pod"},
+	{Group: "", Version: "v1", Kind: "ConfigMap"},
+	{Group: "", Version: "v1", Kind: "Service"},
+	{Group: "", Version: "v1", Kind: "ServiceAccount"},
+	{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+	{Group: "apps", Version: "v1", Kind: "Deployment"},
+	{Group: "apps", Version: "v1", Kind: "StatefulSet"},
+	{Group: "batch", Version: "v1", Kind: "Job"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRoleBinding"},
+	{Group: "build.openshift.io", Version: "v1", Kind: "BuildConfig"},
+	{Group: "build.openshift.io", Version: "v1", Kind: "Build"},
+	{Group: "image.openshift.io", Version: "v1", Kind: "ImageStream"},
+	{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"},
+	{Group: "machineconfiguration.openshift.io", Version: "v1", Kind: "MachineConfig"},
+}
+
+// Run writes the SRO must-gather dump to dir:
+//
+//	<kind>/<name>.yaml                       SpecialResources, PreflightValidations
+//	objects/<namespace>/<kind>/<name>.yaml   objects created from charts
+//	logs/<namespace>/<pod>/<container>.log   builds, driver containers, operator
+//	dtk.json                                 kernels of the nodes and their DTK
+//	nodes.json                               kernel inventory of the nodes
+//
+// Failures of single steps are written to errors.txt, the others go on.
+func Run(dir string) error {
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "Cannot create "+dir)
+	}
+
+	failures := []string{}
+
+	namespaces := map[string]bool{}
+
+	for _, gvk := range customResources {
+		objs, err := list(gvk)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		for idx := range objs {
+			obj := &objs[idx]
+			if ns, _, _ := unstructured.NestedString(obj.Object, "spec", "namespace"); ns != "" {
+				namespaces[ns] = true
+			}
+			if err := writeYAML(filepath.Join(dir, strings.ToLower(gvk.Kind)+"s", obj.GetName()+".yaml"), obj); err != nil {
+				failures = append(failures, err.Error())
+			}
+		}
+	}
+
+	for _, gvk := range generated {
+		objs, err := list(gvk, client.MatchingLabels{ownedLabel: "true"})
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		for idx := range objs {
+			obj := &objs[idx]
+			scope := obj.GetNamespace()
+			if scope == "" {
+				scope = "cluster"
+			}
+			path := filepath.Join(dir, "objects", scope, strings.ToLower(gvk.Kind), obj.GetName()+".yaml")
+			if err := writeYAML(path, obj); err != nil {
+				failures = append(failures, err.Error())
+			}
+		}
+	}
+
+	if err := podLogs(dir, namespaces); err != nil {
+		failures = append(failures, err.Error())
+	}
+
+	if err := driverToolkits(dir); err != nil {
+		failures = append(failures, err.Error())
+	}
+
+	if err := nodes(dir); err != nil {
+		failures = append(failures, err.Error())
+	}
+
+	if len(failures) > 0 {
+		log.Info("Gathered with errors", "errors", len(failures))
+		return os.WriteFile(filepath.Join(dir, "errors.txt"), []byte(strings.Join(failures, "\n")+"\n"), 0644)
+	}
+
+	log.Info("Gathered", "dir", dir)
+	return nil
+}
+
+// list returns the objects of gvk in all namespaces, kinds the cluster does
+// not serve return no objects
+func list(gvk schema.GroupVersionKind, opts ...client.ListOption) ([]unstructured.Unstructured, error) {
+
+	objs := &unstructured.UnstructuredList{}
+	objs.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+
+	if err := clients.Interface.List(context.TODO(), objs, opts...); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "Cannot list "+gvk.String())
+	}
+
+	return objs.Items, nil
+}
+
+func writeYAML(path string, obj *unstructured.Unstructured) error {
+
+	obj.SetManagedFields(nil)
+
+	out, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return errors.Wrap(err, "Cannot marshal "+path)
+	}
+
+	return write(path, out)
+}
+
+func writeJSON(path string, v interface{}) error {
+
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Cannot marshal "+path)
+	}
+
+	return write(path, out)
+}
+
+func write(path string, content []byte) error {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "Cannot create "+filepath.Dir(path))
+	}
+
+	return errors.Wrap(os.WriteFile(path, content, 0644), "Cannot write "+path)
+}
+
+// podLogs writes the logs of the pods in the SpecialResource namespaces,
+// builds and driver containers, and of the operator, previous logs of
+// restarted containers included
+func podLogs(dir string, namespaces map[string]bool) error {
+
+	pods := []v1.Pod{}
+
+	for ns := range namespaces {
+		list, err := clients.Interface.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return errors.Wrap(err, "Cannot list pods in "+ns)
+		}
+		pods = append(pods, list.Items...)
+	}
+
+	operator, err := clients.Interface.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
+		LabelSelector: "control-plane=controller-manager",
+	})
+	if err != nil {
+		return errors.Wrap(err, "Cannot list operator pods")
+	}
+	for _, pod := range operator.Items {
+		if strings.Contains(pod.GetName(), "special-resource") {
+			pods = append(pods, pod)
+		}
+	}
+
+	for _, pod := range pods {
+
+		containers := append([]v1.Container{}, pod.Spec.InitContainers...)
+		containers = append(containers, pod.Spec.Containers...)
+
+		for _, container := range containers {
+			base := filepath.Join(dir, "logs", pod.GetNamespace(), pod.GetName(), container.Name)
+			if err := containerLog(base+".log", pod, container.Name, false); err != nil {
+				log.Info("Cannot get log", "pod", pod.GetName(), "container", container.Name, "error", err.Error())
+			}
+			for _, status := range pod.Status.ContainerStatuses {
+				if status.Name == container.Name && status.RestartCount > 0 {
+					if err := containerLog(base+".previous.log", pod, container.Name, true); err != nil {
+						log.Info("Cannot get previous log", "pod", pod.GetName(), "container", container.Name, "error", err.Error())
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func containerLog(path string, pod v1.Pod, container string, previous bool) error {
+
+	req := clients.Interface.CoreV1().Pods(pod.GetNamespace()).GetLogs(pod.GetName(), &v1.PodLogOptions{
+		Container:  container,
+		Previous:   previous,
+		LimitBytes: &logLimit,
+	})
+
+	stream, err := req.Stream(context.TODO())
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	content, err := io.ReadAll(stream)
+	if err != nil {
+		return err
+	}
+
+	return write(path, content)
+}
+
+// driverToolkits writes the kernels of the nodes with the DTK image of each
+// as SRO resolves them from the driver-toolkit ImageStream
+func driverToolkits(dir string) error {
+
+	if err := cache.Nodes(nil, true); err != nil {
+		return errors.Wrap(err, "Cannot list nodes")
+	}
+
+	info, err := upgrade.NodeVersionInfo()
+	if err != nil {
+		return errors.Wrap(err, "Cannot get node versions")
+	}
+
+	versions, found, err := upgrade.DriverToolkitFromImageStream(info)
+	if err != nil {
+		return errors.Wrap(err, "Cannot get DTK mapping")
+	}
+	if !found {
+		log.Info("No driver-toolkit ImageStream, writing kernels without DTK")
+	}
+
+	return writeJSON(filepath.Join(dir, "dtk.json"), versions)
+}
+
+// node the kernel inventory of a node
+type node struct {
+	Name             string            `json:"name"`
+	KernelVersion    string            `json:"kernelVersion"`
+	OSImage          string            `json:"osImage"`
+	Architecture     string            `json:"architecture"`
+	ContainerRuntime string            `json:"containerRuntime"`
+	KubeletVersion   string            `json:"kubeletVersion"`
+	Unschedulable    bool              `json:"unschedulable"`
+	Labels           map[string]string `json:"labels"`
+}
+
+// nodes writes the kernel and OS of every node and its NFD kernel, OS and
+// SRO labels
+func nodes(dir string) error {
+
+	list, err := clients.Workload().CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "Cannot list nodes")
+	}
+
+	inventory := []node{}
+
+	for _, n := range list.Items {
+		labels := map[string]string{}
+		for key, value := range n.GetLabels() {
+			if strings.HasPrefix(key, "feature.node.kubernetes.io/kernel-") ||
+				strings.HasPrefix(key, "feature.node.kubernetes.io/system-os_release") ||
+				strings.Contains(key, "specialresource.openshift.io") {
+				labels[key] = value
+			}
+		}
+		inventory = append(inventory, node{
+			Name:             n.GetName(),
+			KernelVersion:    n.Status.NodeInfo.KernelVersion,
+			OSImage:          n.Status.NodeInfo.OSImage,
+			Architecture:     n.Status.NodeInfo.Architecture,
+			ContainerRuntime: n.Status.NodeInfo.ContainerRuntimeVersion,
+			KubeletVersion:   n.Status.NodeInfo.KubeletVersion,
+			Unschedulable:    n.Spec.Unschedulable,
+			Labels:           labels,
+		})
+	}
+
+	sort.Slice(inventory, func(i, j int) bool { return inventory[i].Name < inventory[j].Name })
+
+	return writeJSON(filepath.Join(dir, "nodes.json"), inventory)
+}
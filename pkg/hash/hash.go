@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"hash/fnv"
 	"strconv"
+	"strings"
 
 	"github.com/mitchellh/hashstructure/v2"
 	"github.com/openshift-psap/special-resource-operator/pkg/exit"
@@ -20,24 +21,43 @@ func FNV64a(s string) string {
 	return fmt.Sprintf("%x", h.Sum64())
 }
 
-func Annotate(obj *unstructured.Unstructured) {
+// Object returns the hash Annotate sets for obj
+func Object(obj *unstructured.Unstructured) string {
 
 	hash, err := hashstructure.Hash(obj.Object, hashstructure.FormatV2, nil)
 	exit.OnError(err)
+	return strconv.FormatUint(hash, 10)
+}
+
+func Annotate(obj *unstructured.Unstructured) {
+
+	hash := Object(obj)
 	anno := obj.GetAnnotations()
 	if anno == nil {
 		anno = make(map[string]string)
 	}
-	anno["specialresource.openshift.io/hash"] = strconv.FormatUint(hash, 10)
+	anno["specialresource.openshift.io/hash"] = hash
 	obj.SetAnnotations(anno)
 
 }
 
 func AnnotationEqual(new *unstructured.Unstructured, old *unstructured.Unstructured) bool {
 
-	hash, err := hashstructure.Hash(old.Object, hashstructure.FormatV2, nil)
-	exit.OnError(err)
 	anno := new.GetAnnotations()
 
-	return anno["specialresource.openshift.io/hash"] == strconv.FormatUint(hash, 10)
+	return anno["specialresource.openshift.io/hash"] == Object(old)
+}
+
+// Truncate returns s if it has at most max characters, otherwise its prefix
+// and the FNV64a of s, e.g. for the name part of labels and taints
+func Truncate(s string, max int) string {
+
+	if len(s) <= max {
+		return s
+	}
+
+	sum := FNV64a(s)
+	prefix := strings.TrimRight(s[:max-len(sum)-1], "-.")
+
+	return prefix + "-" + sum
 }
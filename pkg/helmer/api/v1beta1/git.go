@@ -0,0 +1,91 @@
+package v1beta1
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GitScheme prefixes repository URLs of charts in a git repository, e.g.
+// git+https://github.com/org/driver.git?ref=v1.0&path=charts/driver
+const GitScheme = "git+"
+
+var (
+	// GitSchemes the schemes of git remotes, file:// would read the operator
+	// pod and ext:: or plain http remotes run commands or leak credentials
+	GitSchemes = []string{"https", "ssh"}
+
+	// CommitRef matches the full commit IDs a ref may be
+	CommitRef = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// ParseGitURL returns the remote, the ref, HEAD if not set, and the chart
+// path of a GitScheme URL, remote and ref are validated
+func ParseGitURL(raw string) (*url.URL, string, string, error) {
+
+	remote, err := url.Parse(strings.TrimPrefix(raw, GitScheme))
+	if err != nil {
+		return nil, "", "", errors.Wrap(err, "Cannot parse git URL "+raw)
+	}
+
+	query := remote.Query()
+	remote.RawQuery = ""
+
+	if err := validateGitRemote(remote); err != nil {
+		return nil, "", "", err
+	}
+
+	ref := query.Get("ref")
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if err := validateGitRef(ref); err != nil {
+		return nil, "", "", err
+	}
+
+	return remote, ref, query.Get("path"), nil
+}
+
+// validateGitRemote only accepts remotes of GitSchemes
+func validateGitRemote(remote *url.URL) error {
+
+	for _, scheme := range GitSchemes {
+		if remote.Scheme == scheme && remote.Host != "" {
+			return nil
+		}
+	}
+
+	return errors.New("Unsupported git remote " + remote.Redacted() + ", use one of " + strings.Join(GitSchemes, ", "))
+}
+
+// validateGitRef accepts HEAD, commits and the refs of git check-ref-format
+// --allow-onelevel, options like --upload-pack are never a ref
+func validateGitRef(ref string) error {
+
+	if ref == "HEAD" || CommitRef.MatchString(ref) {
+		return nil
+	}
+
+	invalid := errors.New("Invalid git ref " + strconv.Quote(ref))
+
+	if ref == "" || ref == "@" || strings.HasPrefix(ref, "-") || strings.HasSuffix(ref, "/") ||
+		strings.HasSuffix(ref, ".") || strings.Contains(ref, "..") || strings.Contains(ref, "@{") ||
+		strings.Contains(ref, "//") || strings.ContainsAny(ref, " ~^:?*[\\") {
+		return invalid
+	}
+	for _, r := range ref {
+		if r < 0x20 || r == 0x7f {
+			return invalid
+		}
+	}
+	for _, component := range strings.Split(ref, "/") {
+		if strings.HasPrefix(component, ".") || strings.HasSuffix(component, ".lock") {
+			return invalid
+		}
+	}
+
+	return nil
+}
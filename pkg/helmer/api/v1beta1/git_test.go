@@ -0,0 +1,118 @@
+package v1beta1
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestValidateGitRemote(t *testing.T) {
+
+	tests := []struct {
+		remote string
+		valid  bool
+	}{
+		{"https://github.com/org/driver.git", true},
+		{"ssh://git@github.com/org/driver.git", true},
+		{"http://github.com/org/driver.git", false},
+		{"file:///etc", false},
+		{"file://localhost/etc", false},
+		{"ext::sh -c touch% /tmp/pwned", false},
+		{"ext::ssh -p 22 host %S 'repo'", false},
+		{"https:///org/driver.git", false},
+		{"/var/run/secrets", false},
+		{"git@github.com:org/driver.git", false},
+	}
+
+	for _, tt := range tests {
+		remote, err := url.Parse(tt.remote)
+		if err != nil {
+			if tt.valid {
+				t.Errorf("url.Parse(%q) = %v", tt.remote, err)
+			}
+			continue
+		}
+		if err := validateGitRemote(remote); (err == nil) != tt.valid {
+			t.Errorf("validateGitRemote(%q) = %v, want valid %v", tt.remote, err, tt.valid)
+		}
+	}
+}
+
+func TestValidateGitRef(t *testing.T) {
+
+	tests := []struct {
+		ref   string
+		valid bool
+	}{
+		{"HEAD", true},
+		{"main", true},
+		{"v1.0.0", true},
+		{"refs/tags/v1.0.0", true},
+		{"release-4.9", true},
+		{strings.Repeat("a", 40), true},
+		{"", false},
+		{"@", false},
+		{"-", false},
+		{"--upload-pack=touch /tmp/pwned", false},
+		{"-uecho", false},
+		{"--output=/etc/passwd", false},
+		{"main/", false},
+		{"main.", false},
+		{"main..dev", false},
+		{"main@{1}", false},
+		{"refs//heads", false},
+		{"main dev", false},
+		{"main~1", false},
+		{"main^", false},
+		{"refs:heads", false},
+		{"ma?n", false},
+		{"ma*n", false},
+		{"ma[n", false},
+		{"ma\\n", false},
+		{"ma\nin", false},
+		{"ma\x7fin", false},
+		{".hidden", false},
+		{"refs/.hidden", false},
+		{"main.lock", false},
+		{"refs/main.lock/x", false},
+	}
+
+	for _, tt := range tests {
+		if err := validateGitRef(tt.ref); (err == nil) != tt.valid {
+			t.Errorf("validateGitRef(%q) = %v, want valid %v", tt.ref, err, tt.valid)
+		}
+	}
+}
+
+func TestParseGitURL(t *testing.T) {
+
+	tests := []struct {
+		url   string
+		ref   string
+		path  string
+		valid bool
+	}{
+		{"git+https://github.com/org/driver.git", "HEAD", "", true},
+		{"git+https://github.com/org/driver.git?ref=v1.0&path=charts/driver", "v1.0", "charts/driver", true},
+		{"git+ssh://git@github.com/org/driver.git?ref=" + strings.Repeat("0", 40), strings.Repeat("0", 40), "", true},
+		{"git+file:///etc?ref=main", "", "", false},
+		{"git+ext::sh -c touch% /tmp/pwned", "", "", false},
+		{"git+http://github.com/org/driver.git", "", "", false},
+		{"git+https://github.com/org/driver.git?ref=--upload-pack=touch%20/tmp/pwned", "", "", false},
+		{"git+https://github.com/org/driver.git?ref=-main", "", "", false},
+	}
+
+	for _, tt := range tests {
+		remote, ref, path, err := ParseGitURL(tt.url)
+		if (err == nil) != tt.valid {
+			t.Errorf("ParseGitURL(%q) = %v, want valid %v", tt.url, err, tt.valid)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if ref != tt.ref || path != tt.path || remote.RawQuery != "" {
+			t.Errorf("ParseGitURL(%q) = %s, %q, %q, want ref %q and path %q", tt.url, remote, ref, path, tt.ref, tt.path)
+		}
+	}
+}
@@ -16,6 +16,9 @@ limitations under the License.
 
 package v1beta1
 
+// OCIScheme prefixes repository URLs of charts stored in an OCI registry
+const OCIScheme = "oci://"
+
 type HelmRepo struct {
 	// +kubebuilder:validation:Required
 	Name string `json:"name"`
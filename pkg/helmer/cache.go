@@ -0,0 +1,77 @@
+package helmer
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// renderCacheSize releases are kept, the cache is emptied once it is full
+const renderCacheSize = 256
+
+var (
+	// renderCache maps the renderKey of a chart to its rendered release
+	renderCache = make(map[string]*release.Release)
+	// RenderCacheHits counts the releases served from the cache, the
+	// reconciler resets it
+	RenderCacheHits int
+)
+
+// renderKey digests everything the rendered manifests depend on, the
+// templates and files of the chart and its subcharts, the values with the
+// runtime information injected and the cluster facts passed to Run
+func renderKey(ch *chart.Chart, vals map[string]interface{}, facts ...string) (string, error) {
+
+	digest := sha256.New()
+
+	var write func(c *chart.Chart)
+	write = func(c *chart.Chart) {
+		fmt.Fprintf(digest, "chart %s %s\n", c.Name(), c.Metadata.Version)
+		for _, t := range c.Templates {
+			fmt.Fprintf(digest, "template %s %d\n", t.Name, len(t.Data))
+			digest.Write(t.Data)
+		}
+		for _, f := range c.Files {
+			fmt.Fprintf(digest, "file %s %d\n", f.Name, len(f.Data))
+			digest.Write(f.Data)
+		}
+		for _, sub := range c.Dependencies() {
+			write(sub)
+		}
+	}
+	write(ch)
+
+	// encoding/json sorts map keys, equal values give equal digests
+	values, err := json.Marshal(vals)
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot marshal values")
+	}
+	digest.Write(values)
+
+	for _, fact := range facts {
+		fmt.Fprintf(digest, "\nfact %s", fact)
+	}
+
+	return fmt.Sprintf("%x", digest.Sum(nil)), nil
+}
+
+// cachedRelease returns the release rendered for key, nil on a miss
+func cachedRelease(key string) *release.Release {
+	rel, found := renderCache[key]
+	if found {
+		RenderCacheHits++
+	}
+	return rel
+}
+
+// cacheRelease stores rel for key
+func cacheRelease(key string, rel *release.Release) {
+	if len(renderCache) >= renderCacheSize {
+		renderCache = make(map[string]*release.Release)
+	}
+	renderCache[key] = rel
+}
@@ -0,0 +1,150 @@
+package helmer
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	helmerv1beta1 "github.com/openshift-psap/special-resource-operator/pkg/helmer/api/v1beta1"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+var (
+	// Offline only subcharts vendored in charts/ are used, dependencies
+	// missing from a chart are an error instead of being downloaded
+	Offline = false
+	// DependencyCache downloaded subcharts of an exact version are kept in
+	DependencyCache = "/cache/helm/dependencies"
+
+	exactVersion = regexp.MustCompile(`^v?[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+)
+
+// resolveDependencies adds the subcharts listed in Chart.yaml that are not
+// vendored in charts/ of ch, Chart.lock pins the versions if present
+func resolveDependencies(ch *chart.Chart, verifier *ChartVerifier) error {
+
+	vendored := make(map[string]bool)
+	for _, sub := range ch.Dependencies() {
+		vendored[sub.Name()] = true
+	}
+
+	locked := make(map[string]string)
+	if ch.Lock != nil {
+		for _, dep := range ch.Lock.Dependencies {
+			locked[dep.Name] = dep.Version
+		}
+	}
+
+	for _, dep := range ch.Metadata.Dependencies {
+
+		if vendored[dep.Name] {
+			continue
+		}
+
+		if Offline {
+			return errors.Errorf("Dependency %s %s of chart %s is not vendored in charts/", dep.Name, dep.Version, ch.Name())
+		}
+
+		version := dep.Version
+		if lock, found := locked[dep.Name]; found {
+			version = lock
+		}
+
+		sub, err := loadDependency(dep, version, verifier)
+		if err != nil {
+			return errors.Wrap(err, "Cannot resolve dependency "+dep.Name+" of chart "+ch.Name())
+		}
+
+		ch.AddDependency(sub)
+		vendored[dep.Name] = true
+	}
+
+	return nil
+}
+
+// loadDependency loads the subchart dep, an exact version is served from
+// the DependencyCache once downloaded
+func loadDependency(dep *chart.Dependency, version string, verifier *ChartVerifier) (*chart.Chart, error) {
+
+	repo, err := dependencyRepository(dep.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	// Signatures are not cached, verified charts are always downloaded
+	cacheable := verifier == nil && exactVersion.MatchString(version)
+	dir := filepath.Join(DependencyCache, fmt.Sprintf("%x", sha256.Sum256([]byte(repo.URL)))[:16])
+	cached := filepath.Join(dir, dep.Name+"-"+strings.TrimPrefix(version, "v")+".tgz")
+
+	if cacheable {
+		if _, err := os.Stat(cached); err == nil {
+			log.Info("Using cached dependency", "chart", dep.Name, "version", version)
+			return loader.Load(cached)
+		}
+	}
+
+	log.Info("Resolving dependency", "chart", dep.Name, "version", version, "repository", repo.URL)
+
+	sub, err := LoadVerified(helmerv1beta1.HelmChart{
+		Name:       dep.Name,
+		Version:    version,
+		Repository: repo,
+	}, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheable {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, errors.Wrap(err, "Cannot create dependency cache "+dir)
+		}
+		if _, err := chartutil.Save(sub, dir); err != nil {
+			return nil, errors.Wrap(err, "Cannot cache dependency "+dep.Name)
+		}
+	}
+
+	return sub, nil
+}
+
+// dependencyRepository returns the repository of a dependency, @name and
+// alias:name reference a repository already added by a SpecialResource
+func dependencyRepository(url string) (helmerv1beta1.HelmRepo, error) {
+
+	if name := strings.TrimPrefix(strings.TrimPrefix(url, "@"), "alias:"); name != url {
+		entry := repoFile.Get(name)
+		if entry == nil {
+			return helmerv1beta1.HelmRepo{}, errors.New("Unknown repository " + url)
+		}
+		return helmerv1beta1.HelmRepo{
+			Name:                  entry.Name,
+			URL:                   entry.URL,
+			Username:              entry.Username,
+			Password:              entry.Password,
+			CertFile:              entry.CertFile,
+			KeyFile:               entry.KeyFile,
+			CAFile:                entry.CAFile,
+			InsecureSkipTLSverify: entry.InsecureSkipTLSverify,
+		}, nil
+	}
+
+	if url == "" || (strings.HasPrefix(url, "file://") && !strings.HasPrefix(url, "file:///")) {
+		return helmerv1beta1.HelmRepo{}, errors.New("Relative dependency " + url + " has to be vendored in charts/")
+	}
+
+	for _, entry := range repoFile.Repositories {
+		if strings.TrimSuffix(entry.URL, "/") == strings.TrimSuffix(url, "/") {
+			return dependencyRepository("@" + entry.Name)
+		}
+	}
+
+	return helmerv1beta1.HelmRepo{
+		Name: fmt.Sprintf("dependency-%x", sha256.Sum256([]byte(url)))[:19],
+		URL:  url,
+	}, nil
+}
@@ -0,0 +1,156 @@
+package helmer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	helmerv1beta1 "github.com/openshift-psap/special-resource-operator/pkg/helmer/api/v1beta1"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// gitScheme prefixes repository URLs of charts in a git repository
+const gitScheme = helmerv1beta1.GitScheme
+
+var (
+	// GitCache clones of chart repositories are kept in
+	GitCache = "/cache/helm/git"
+	// GitRefresh after which branches and tags are fetched again, commits
+	// never change
+	GitRefresh = 5 * time.Minute
+
+	gitMutex   sync.Mutex
+	gitFetched = make(map[string]time.Time)
+)
+
+// loadGit checks out ref of the git repository and loads the chart in
+// path, the repository root if not set. The checkout is cached in GitCache.
+func loadGit(spec helmerv1beta1.HelmChart) (*chart.Chart, error) {
+
+	remote, ref, chartPath, err := helmerv1beta1.ParseGitURL(spec.Repository.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	config := gitConfig(spec.Repository)
+
+	gitMutex.Lock()
+	defer gitMutex.Unlock()
+
+	dir := filepath.Join(GitCache, fmt.Sprintf("%x", sha256.Sum256([]byte(remote.String()+"#"+ref))))
+
+	commit, err := gitCheckout(dir, remote.String(), ref, config)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, filepath.Clean("/"+chartPath))
+	log.Info("Locating", "chart", spec.Name, "repository", remote.String(), "ref", ref, "commit", commit)
+
+	loaded, err := loader.LoadDir(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not load chart from "+remote.String()+" at "+chartPath)
+	}
+
+	if loaded.Name() != spec.Name {
+		return nil, errors.Errorf("Chart at %s is %s not %s", chartPath, loaded.Name(), spec.Name)
+	}
+	if spec.Version != "" && loaded.Metadata.Version != spec.Version {
+		return nil, errors.Errorf("Chart %s at %s has version %s not %s", spec.Name, ref, loaded.Metadata.Version, spec.Version)
+	}
+
+	return loaded, nil
+}
+
+// gitConfig returns the GIT_CONFIG_COUNT environment for the credentials
+// and TLS settings of repo, credentials are neither in argv nor in the clone
+func gitConfig(repo helmerv1beta1.HelmRepo) []string {
+
+	config := [][2]string{}
+
+	if repo.Username != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(repo.Username + ":" + repo.Password))
+		config = append(config, [2]string{"http.extraHeader", "Authorization: Basic " + auth})
+	}
+	if repo.CAFile != "" {
+		config = append(config, [2]string{"http.sslCAInfo", repo.CAFile})
+	}
+	if repo.CertFile != "" {
+		config = append(config, [2]string{"http.sslCert", repo.CertFile})
+	}
+	if repo.KeyFile != "" {
+		config = append(config, [2]string{"http.sslKey", repo.KeyFile})
+	}
+	if repo.InsecureSkipTLSverify {
+		config = append(config, [2]string{"http.sslVerify", "false"})
+	}
+
+	env := []string{"GIT_CONFIG_COUNT=" + strconv.Itoa(len(config))}
+	for i, kv := range config {
+		env = append(env, fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, kv[0]), fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, kv[1]))
+	}
+
+	return env
+}
+
+// gitCheckout fetches ref into dir unless it was fetched within GitRefresh
+// or is the commit already checked out, returns the commit checked out
+func gitCheckout(dir string, remote string, ref string, config []string) (string, error) {
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", errors.Wrap(err, "Cannot create git cache "+dir)
+		}
+		if _, err := git(dir, nil, "init", "--quiet"); err != nil {
+			return "", err
+		}
+	}
+
+	head, _ := git(dir, nil, "rev-parse", "--verify", "--quiet", "HEAD")
+
+	if head != "" && (head == ref || (!helmerv1beta1.CommitRef.MatchString(ref) && time.Since(gitFetched[dir]) < GitRefresh)) {
+		return head, nil
+	}
+
+	if _, err := git(dir, config, "fetch", "--quiet", "--depth", "1", "--end-of-options", remote, ref); err != nil {
+		return "", err
+	}
+	if _, err := git(dir, nil, "checkout", "--quiet", "--force", "FETCH_HEAD"); err != nil {
+		return "", err
+	}
+	if _, err := git(dir, nil, "clean", "--quiet", "--force", "-d", "-x"); err != nil {
+		return "", err
+	}
+
+	gitFetched[dir] = time.Now()
+
+	return git(dir, nil, "rev-parse", "HEAD")
+}
+
+// git runs a git command in dir with the config environment, never
+// prompting for credentials
+func git(dir string, config []string, args ...string) (string, error) {
+
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "HOME="+GitCache), config...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "git %s failed: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
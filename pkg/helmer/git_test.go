@@ -0,0 +1,52 @@
+package helmer
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	helmerv1beta1 "github.com/openshift-psap/special-resource-operator/pkg/helmer/api/v1beta1"
+)
+
+func TestGitConfig(t *testing.T) {
+
+	repo := helmerv1beta1.HelmRepo{
+		Username:              "user",
+		Password:              "secret",
+		CAFile:                "/ca.crt",
+		InsecureSkipTLSverify: true,
+	}
+
+	config := gitConfig(repo)
+
+	want := []string{
+		"GIT_CONFIG_COUNT=3",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic dXNlcjpzZWNyZXQ=",
+		"GIT_CONFIG_KEY_1=http.sslCAInfo",
+		"GIT_CONFIG_VALUE_1=/ca.crt",
+		"GIT_CONFIG_KEY_2=http.sslVerify",
+		"GIT_CONFIG_VALUE_2=false",
+	}
+	if strings.Join(config, "\n") != strings.Join(want, "\n") {
+		t.Errorf("gitConfig() = %q, want %q", config, want)
+	}
+
+	if got := gitConfig(helmerv1beta1.HelmRepo{}); len(got) != 1 || got[0] != "GIT_CONFIG_COUNT=0" {
+		t.Errorf("gitConfig(empty) = %q, want [GIT_CONFIG_COUNT=0]", got)
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	defer func(cache string) { GitCache = cache }(GitCache)
+	GitCache = t.TempDir()
+	header, err := git(GitCache, config, "config", "--get", "http.extraHeader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header != "Authorization: Basic dXNlcjpzZWNyZXQ=" {
+		t.Errorf("git config http.extraHeader = %q", header)
+	}
+}
@@ -6,31 +6,32 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/go-logr/logr"
 	"github.com/openshift-psap/special-resource-operator/pkg/clients"
 	"github.com/openshift-psap/special-resource-operator/pkg/color"
 	"github.com/openshift-psap/special-resource-operator/pkg/exit"
 	helmerv1beta1 "github.com/openshift-psap/special-resource-operator/pkg/helmer/api/v1beta1"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
 	"github.com/openshift-psap/special-resource-operator/pkg/resource"
 	"github.com/openshift-psap/special-resource-operator/pkg/slice"
+	"github.com/openshift-psap/special-resource-operator/pkg/trace"
 	"github.com/openshift-psap/special-resource-operator/pkg/warn"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/releaseutil"
 	"helm.sh/helm/v3/pkg/repo"
-	helmtime "helm.sh/helm/v3/pkg/time"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
@@ -107,19 +108,57 @@ func AddorUpdateRepo(entry *repo.Entry) error {
 	return nil
 }
 
+func repoEntry(repository helmerv1beta1.HelmRepo) *repo.Entry {
+	return &repo.Entry{
+		Name:                  repository.Name,
+		URL:                   repository.URL,
+		Username:              repository.Username,
+		Password:              repository.Password,
+		CertFile:              repository.CertFile,
+		KeyFile:               repository.KeyFile,
+		CAFile:                repository.CAFile,
+		InsecureSkipTLSverify: repository.InsecureSkipTLSverify,
+	}
+}
+
+// ociScheme prefixes repository URLs of charts stored in an OCI registry
+const ociScheme = helmerv1beta1.OCIScheme
+
 func Load(spec helmerv1beta1.HelmChart) (*chart.Chart, error) {
+	return LoadVerified(spec, nil)
+}
+
+// LoadVerified loads the chart of spec once its signature is verified by
+// verifier, a nil verifier skips the verification. Dependencies missing
+// from charts/ are resolved with the same verifier.
+func LoadVerified(spec helmerv1beta1.HelmChart, verifier *ChartVerifier) (*chart.Chart, error) {
+
+	loaded, err := loadChart(spec, verifier)
+	if err != nil {
+		return nil, err
+	}
 
-	entry := &repo.Entry{
-		Name:                  spec.Repository.Name,
-		URL:                   spec.Repository.URL,
-		Username:              spec.Repository.Username,
-		Password:              spec.Repository.Password,
-		CertFile:              spec.Repository.CertFile,
-		KeyFile:               spec.Repository.KeyFile,
-		CAFile:                spec.Repository.CAFile,
-		InsecureSkipTLSverify: spec.Repository.InsecureSkipTLSverify,
+	if err := resolveDependencies(loaded, verifier); err != nil {
+		return nil, err
 	}
 
+	return loaded, nil
+}
+
+func loadChart(spec helmerv1beta1.HelmChart, verifier *ChartVerifier) (*chart.Chart, error) {
+
+	if strings.HasPrefix(spec.Repository.URL, ociScheme) {
+		return loadOCI(spec, verifier)
+	}
+	if strings.HasPrefix(spec.Repository.URL, gitScheme) {
+		if verifier != nil {
+			return nil, errors.New("Signature verification is not supported for git repository " + spec.Repository.URL)
+		}
+		return loadGit(spec)
+	}
+
+	entry := repoEntry(spec.Repository)
+
 	if err := AddorUpdateRepo(entry); err != nil {
 		warn.OnError(err)
 		return nil, err
@@ -139,6 +178,14 @@ func Load(spec helmerv1beta1.HelmChart) (*chart.Chart, error) {
 	}
 	act.Verify = false
 
+	if verifier != nil {
+		if verifier.Keyring == "" {
+			return nil, errors.New("No keyring to verify the provenance of chart " + spec.Name)
+		}
+		act.Verify = true
+		act.Keyring = verifier.Keyring
+	}
+
 	repoChartName := entry.Name + "/" + spec.Name
 	log.Info("Locating", "chart", repoChartName)
 
@@ -155,6 +202,46 @@ func Load(spec helmerv1beta1.HelmChart) (*chart.Chart, error) {
 
 }
 
+// loadOCI pulls the chart from an OCI registry, the repository URL is the
+// registry namespace of the chart e.g. oci://quay.io/org/charts. The
+// repository credentials are tried before the pull secret chain.
+func loadOCI(spec helmerv1beta1.HelmChart, verifier *ChartVerifier) (*chart.Chart, error) {
+
+	repo := strings.TrimSuffix(strings.TrimPrefix(spec.Repository.URL, ociScheme), "/")
+
+	if spec.Version == "" {
+		return nil, errors.New("Chart version is required for OCI repository " + spec.Repository.URL)
+	}
+
+	// OCI tags cannot contain +, helm pushes semver build metadata with _
+	entry := repo + "/" + spec.Name + ":" + strings.ReplaceAll(spec.Version, "+", "_")
+	log.Info("Locating", "chart", entry)
+
+	providers := []registry.KeychainProvider{}
+	if spec.Repository.Username != "" {
+		providers = append(providers, registry.BasicAuth{
+			Registry: repo,
+			Username: spec.Repository.Username,
+			Password: spec.Repository.Password,
+		})
+	}
+
+	if verifier != nil {
+		if err := verifier.verifyOCI(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := registry.PullChart(entry, providers...)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded, err := loader.LoadArchive(bytes.NewReader(data))
+
+	return loaded, errors.Wrap(err, "Could not load chart: "+entry)
+}
+
 func OpenShiftInstallOrder() error {
 
 	idx := slice.Find(releaseutil.InstallOrder, "Service")
@@ -180,14 +267,150 @@ func InstallCRDs(crds []chart.CRD, owner v1.Object, name string, namespace strin
 		fmt.Fprintf(&manifests, "---\n# Source: %s\n%s\n", crd.Filename, crd.File.Data)
 	}
 	if err := resource.CreateFromYAML([]byte(manifests.Bytes()),
-		false, owner, name, namespace, nil, "", ""); err != nil {
+		false, owner, name, namespace, nil, "", "", nil); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func Run(ch chart.Chart, vals map[string]interface{},
+// ValidateValues loads the chart and validates values coalesced with the
+// chart defaults against the values.schema.json of the chart, if any.
+func ValidateValues(spec helmerv1beta1.HelmChart, values map[string]interface{}) error {
+
+	ch, err := Load(spec)
+	if err != nil {
+		return err
+	}
+
+	violations, err := SchemaViolations(ch, values)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return errors.New(strings.Join(violations, "; "))
+	}
+
+	return nil
+}
+
+// HasSchema is true if ch or one of its subcharts has a values.schema.json
+func HasSchema(ch *chart.Chart) bool {
+	if ch.Schema != nil {
+		return true
+	}
+	for _, sub := range ch.Dependencies() {
+		if HasSchema(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaViolations coalesces values with the chart defaults and returns the
+// violations of the values.schema.json of ch and its subcharts, every one
+// prefixed with the path of the failing value e.g. "driver.version: ..."
+func SchemaViolations(ch *chart.Chart, values map[string]interface{}) ([]string, error) {
+
+	// kind and apiVersion are added to the values by the reconciler
+	user := make(map[string]interface{})
+	for key, value := range values {
+		if key != "kind" && key != "apiVersion" {
+			user[key] = value
+		}
+	}
+
+	coalesced, err := chartutil.CoalesceValues(ch, user)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot coalesce values of chart "+ch.Name())
+	}
+
+	return schemaViolations(ch, coalesced, "")
+}
+
+func schemaViolations(ch *chart.Chart, values map[string]interface{}, prefix string) ([]string, error) {
+
+	violations := []string{}
+
+	if ch.Schema != nil {
+		err := chartutil.ValidateAgainstSingleSchema(values, ch.Schema)
+		// Every violation is one "- field: description" line, the root
+		// of the values is reported as (root)
+		for _, line := range strings.Split(fmt.Sprintf("%v", err), "\n") {
+			if !strings.HasPrefix(line, "- ") {
+				continue
+			}
+			violation := strings.TrimPrefix(line, "- ")
+			if strings.HasPrefix(violation, "(root)") && prefix != "" {
+				violation = strings.TrimSuffix(prefix, ".") + strings.TrimPrefix(violation, "(root)")
+			} else {
+				violation = prefix + violation
+			}
+			violations = append(violations, violation)
+		}
+		if err != nil && len(violations) == 0 {
+			return nil, errors.Wrap(err, "Cannot validate values of chart "+ch.Name())
+		}
+	}
+
+	for _, sub := range ch.Dependencies() {
+		subValues, _ := values[sub.Name()].(map[string]interface{})
+		subViolations, err := schemaViolations(sub, subValues, prefix+sub.Name()+".")
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, subViolations...)
+	}
+
+	return violations, nil
+}
+
+// ReleaseDriver the helm storage driver of the releases, a release records
+// the values with the data of $(secret:) references
+const ReleaseDriver = "secrets"
+
+// newActionConfig returns the helm config of the releases in namespace
+func newActionConfig(namespace string) (*action.Configuration, error) {
+
+	config := new(action.Configuration)
+
+	if err := config.Init(settings.RESTClientGetter(), namespace, ReleaseDriver, LogWrap); err != nil {
+		return nil, errors.Wrap(err, "Cannot initialize helm action config")
+	}
+
+	return config, nil
+}
+
+// deleteConfigMapReleases deletes the releases of name that were stored in
+// ConfigMaps before ReleaseDriver, their values are in plain text
+func deleteConfigMapReleases(name string, namespace string) error {
+
+	configmaps := clients.Workload().CoreV1().ConfigMaps(namespace)
+
+	found, err := configmaps.List(context.TODO(), v1.ListOptions{LabelSelector: "owner=helm,name=" + name})
+	if err != nil {
+		return errors.Wrap(err, "Cannot list ConfigMap releases of "+name)
+	}
+
+	for _, cm := range found.Items {
+		log.Info("Deleting release stored in ConfigMap", "release", name, "ConfigMap", cm.GetName())
+		if err := configmaps.Delete(context.TODO(), cm.GetName(), v1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "Cannot delete ConfigMap release "+cm.GetName())
+		}
+	}
+
+	return nil
+}
+
+// UseKubeConfig points the helm releases to the cluster of kubeconfig, in
+// HyperShift mode releases are stored next to the resources they install.
+func UseKubeConfig(kubeconfig string) {
+	settings.KubeConfig = kubeconfig
+}
+
+func Run(ctx context.Context, ch chart.Chart, vals map[string]interface{},
+	postRenderer *PostRenderer,
+	verifier *registry.Verifier,
 	owner v1.Object,
 	name string,
 	namespace string,
@@ -196,10 +419,9 @@ func Run(ch chart.Chart, vals map[string]interface{},
 	operatingSystemMajorMinor string,
 	debug bool) error {
 
-	ActionConfig = new(action.Configuration)
-
-	err := ActionConfig.Init(settings.RESTClientGetter(), namespace, "configmaps", LogWrap)
-	exit.OnError(errors.Wrap(err, "Cannot initialize helm action config"))
+	var err error
+	ActionConfig, err = newActionConfig(namespace)
+	exit.OnError(err)
 
 	resource.HelmClient = ActionConfig.KubeClient
 
@@ -214,7 +436,10 @@ func Run(ch chart.Chart, vals map[string]interface{},
 	install.Namespace = namespace
 	install.DisableHooks = false
 	install.IsUpgrade = false
-	install.Timeout = time.Second * 300
+	install.Timeout = HookTimeout
+	if postRenderer != nil {
+		install.PostRenderer = postRenderer
+	}
 
 	if install.Version == "" {
 		install.Version = ">0.0.0-0"
@@ -233,10 +458,39 @@ func Run(ch chart.Chart, vals map[string]interface{},
 		exit.OnError(errors.Wrap(err, "Cannot install CRDs"))
 	}
 
-	rel, err := install.Run(&ch, vals)
-	if err != nil {
+	// Lookups read the cluster, their output cannot be cached
+	var key string
+	if !LookupEnabled(&ch) {
+		selector, _ := json.Marshal(nodeSelector)
+		key, err = renderKey(&ch, vals, namespace, owner.GetName(), string(selector), kernelFullVersion, operatingSystemMajorMinor, postRenderer.Hash())
 		warn.OnError(err)
-		return err
+	}
+
+	rel := cachedRelease(key)
+
+	if rel != nil {
+		log.Info("Rendered manifests unchanged, using cache", "release", install.ReleaseName)
+	} else {
+		_, span := trace.Start(ctx, "render", "release", install.ReleaseName)
+
+		chrt := &ch
+		if LookupEnabled(chrt) {
+			if chrt, err = resolveLookups(ActionConfig, chrt, vals, namespace); err != nil {
+				span.End(err)
+				return err
+			}
+		}
+
+		rel, err = install.Run(chrt, vals)
+		span.End(err)
+		if err != nil {
+			warn.OnError(err)
+			return err
+		}
+
+		if key != "" {
+			cacheRelease(key, rel)
+		}
 	}
 
 	if debug {
@@ -269,18 +523,41 @@ func Run(ch chart.Chart, vals map[string]interface{},
 		warn.OnError(err)
 		//return err
 	}
+	warn.OnError(deleteConfigMapReleases(name, namespace))
+
+	// Report every invalid object at once instead of failing on the first
+	log.Info("Release validation")
+	_, span := trace.Start(ctx, "validate", "release", install.ReleaseName)
+	err = resource.ValidateFromYAML([]byte(rel.Manifest),
+		ReleaseInstalled(name),
+		owner,
+		name,
+		namespace,
+		nodeSelector,
+		kernelFullVersion,
+		operatingSystemMajorMinor)
+	span.End(err)
+
+	if err != nil {
+		_, err := install.FailRelease(rel, err)
+		return err
+	}
 
-	log.Info("Release pre-install hooks")
-	// pre-install hooks
+	log.Info("Release pre-install and pre-upgrade hooks")
 	if !install.DisableHooks {
-		if err := ExecHook(rel, release.HookPreInstall, install.Timeout, owner, name, namespace); err != nil {
-			_, err := install.FailRelease(rel, fmt.Errorf("failed pre-install: %s", err))
-			return err
+		for _, hook := range []release.HookEvent{release.HookPreInstall, release.HookPreUpgrade} {
+			_, span := trace.Start(ctx, "hook "+string(hook), "release", install.ReleaseName)
+			err := ExecHook(rel, hook, install.Timeout, owner, name, namespace, kernelFullVersion, operatingSystemMajorMinor, verifier)
+			span.End(err)
+			if err != nil {
+				_, err := install.FailRelease(rel, errors.Wrapf(err, "failed %s", hook))
+				return err
+			}
 		}
-
 	}
 
 	log.Info("Release manifests")
+	_, span = trace.Start(ctx, "apply", "release", install.ReleaseName)
 	err = resource.CreateFromYAML([]byte(rel.Manifest),
 		ReleaseInstalled(name),
 		owner,
@@ -288,7 +565,9 @@ func Run(ch chart.Chart, vals map[string]interface{},
 		namespace,
 		nodeSelector,
 		kernelFullVersion,
-		operatingSystemMajorMinor)
+		operatingSystemMajorMinor,
+		verifier)
+	span.End(err)
 
 	if err != nil {
 		_, err := install.FailRelease(rel, err)
@@ -296,11 +575,16 @@ func Run(ch chart.Chart, vals map[string]interface{},
 		return err
 	}
 
-	log.Info("Release post-install hooks")
+	log.Info("Release post-install and post-upgrade hooks, recording delete hooks")
 	if !install.DisableHooks {
-		if err := ExecHook(rel, release.HookPostInstall, install.Timeout, owner, name, namespace); err != nil {
-			_, err := install.FailRelease(rel, fmt.Errorf("failed post-install: %s", err))
-			return err
+		for _, hook := range []release.HookEvent{release.HookPostInstall, release.HookPostUpgrade, release.HookPreDelete, release.HookPostDelete} {
+			_, span := trace.Start(ctx, "hook "+string(hook), "release", install.ReleaseName)
+			err := ExecHook(rel, hook, install.Timeout, owner, name, namespace, kernelFullVersion, operatingSystemMajorMinor, verifier)
+			span.End(err)
+			if err != nil {
+				_, err := install.FailRelease(rel, errors.Wrapf(err, "failed %s", hook))
+				return err
+			}
 		}
 	}
 
@@ -317,115 +601,75 @@ func Run(ch chart.Chart, vals map[string]interface{},
 	return nil
 }
 
-// hookByWeight is a sorter for hooks
-type hookByWeight []*release.Hook
+// Render returns the manifests and hooks of ch rendered with vals, nothing
+// is installed and no release is stored.
+func Render(ch chart.Chart, vals map[string]interface{}, postRenderer *PostRenderer, namespace string) (string, error) {
 
-func (x hookByWeight) Len() int      { return len(x) }
-func (x hookByWeight) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
-func (x hookByWeight) Less(i, j int) bool {
-	if x[i].Weight == x[j].Weight {
-		return x[i].Name < x[j].Name
+	config, err := newActionConfig(namespace)
+	if err != nil {
+		return "", err
 	}
-	return x[i].Weight < x[j].Weight
-}
-
-func ExecHook(rl *release.Release, hook release.HookEvent, timeout time.Duration, owner v1.Object, name string, namespace string) error {
-
-	obj := unstructured.Unstructured{}
-	obj.SetKind("ConfigMap")
-	obj.SetAPIVersion("v1")
-	obj.SetName(string("sh.helm.hooks." + hook))
-	obj.SetNamespace(namespace)
 
-	found := obj.DeepCopy()
-
-	key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	return render(config, action.NewInstall(config), ch, vals, postRenderer, namespace)
+}
 
-	if err := clients.Interface.Get(context.TODO(), key, found); err != nil {
+// RenderOffline renders ch like Render without a cluster. Lookups return
+// empty objects and the capabilities are the defaults of helm with
+// kubeVersion, if set, as the Kubernetes version.
+func RenderOffline(ch chart.Chart, vals map[string]interface{}, postRenderer *PostRenderer, namespace string, kubeVersion string) (string, error) {
 
-		if apierrors.IsNotFound(err) {
-			log.Info("Hooks", string(hook), "NotReady (IsNotFound)")
-		} else {
-			return errors.Wrapf(err, "Unexpected error getting hook cm %s", hook)
-		}
-	} else {
-		log.Info("Hooks", string(hook), "Ready (Get)")
-		return nil
-	}
+	config := &action.Configuration{Log: LogWrap}
 
-	hooks := []*release.Hook{}
+	install := action.NewInstall(config)
+	install.ClientOnly = true
 
-	for _, h := range rl.Hooks {
-		for _, e := range h.Events {
-			if e == hook {
-				hooks = append(hooks, h)
-			}
+	if kubeVersion != "" {
+		version, err := chartutil.ParseKubeVersion(kubeVersion)
+		if err != nil {
+			return "", errors.Wrap(err, "Invalid Kubernetes version "+kubeVersion)
 		}
+		install.KubeVersion = version
 	}
 
-	// hooke are pre-ordered by kind, so keep order stable
-	sort.Stable(hookByWeight(hooks))
-
-	for _, h := range hooks {
-
-		if h.DeletePolicies == nil || len(h.DeletePolicies) == 0 {
-			h.DeletePolicies = []release.HookDeletePolicy{release.HookBeforeHookCreation}
-		}
-
-		if err := ActionConfig.DeleteHookByPolicy(h, release.HookBeforeHookCreation); err != nil {
-			return err
-		}
-
-		h.LastRun = release.HookExecution{
-			StartedAt: helmtime.Now(),
-			Phase:     release.HookPhaseRunning,
-		}
-		ActionConfig.RecordRelease(rl)
-
-		// As long as the implementation of WatchUntilReady does not panic, HookPhaseFailed or HookPhaseSucceeded
-		// should always be set by this function. If we fail to do that for any reason, then HookPhaseUnknown is
-		// the most appropriate value to surface.
-		h.LastRun.Phase = release.HookPhaseUnknown
+	return render(config, install, ch, vals, postRenderer, namespace)
+}
 
-		if err := resource.CreateFromYAML([]byte(h.Manifest), false, owner, name, namespace, nil, "", ""); err != nil {
+func render(config *action.Configuration, install *action.Install, ch chart.Chart, vals map[string]interface{}, postRenderer *PostRenderer, namespace string) (string, error) {
 
-			h.LastRun.CompletedAt = helmtime.Now()
-			h.LastRun.Phase = release.HookPhaseFailed
-			if err := ActionConfig.DeleteHookByPolicy(h, release.HookFailed); err != nil {
-				return errors.Wrapf(err, "failed to delete hook by policy %s %s", h.Name, h.Path)
-			}
-			return errors.Wrapf(err, "hook execution failed %s %s", h.Name, h.Path)
-		}
+	install.DryRun = true
+	if postRenderer != nil {
+		install.PostRenderer = postRenderer
+	}
+	install.ReleaseName = ch.Metadata.Name
+	install.Namespace = namespace
+	install.Version = ">0.0.0-0"
 
-		// Watch hook resources until they have completed
-		//err = ActionConfig.KubeClient.WatchUntilReady(resources, timeout)
-		// Note the time of success/failure
-		h.LastRun.CompletedAt = helmtime.Now()
-		h.LastRun.Phase = release.HookPhaseSucceeded
+	if ch.Metadata.Type != "" && ch.Metadata.Type != "application" {
+		return "", errors.New("Chart has an unsupported type and is not installable:" + ch.Metadata.Type)
 	}
-	// If all hooks are successful, check the annotation of each hook to determine whether the hook should be deleted
-	// under succeeded condition. If so, then clear the corresponding resource object in each hook
-	for _, h := range hooks {
-		if err := ActionConfig.DeleteHookByPolicy(h, release.HookSucceeded); err != nil {
-			return err
+
+	chrt := &ch
+	if LookupEnabled(chrt) && !install.ClientOnly {
+		var err error
+		if chrt, err = resolveLookups(config, chrt, vals, namespace); err != nil {
+			return "", err
 		}
 	}
 
-	if err := clients.Interface.Create(context.TODO(), &obj); err != nil {
-		log.Info(err.Error())
+	rel, err := install.Run(chrt, vals)
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot render chart "+ch.Metadata.Name)
+	}
 
-		if apierrors.IsAlreadyExists(err) {
-			log.Info("Hooks", string(hook), "Ready (IsAlreadyExists)")
-			return nil
-		}
+	var manifest bytes.Buffer
+	manifest.WriteString(rel.Manifest)
 
-		if apierrors.IsForbidden(err) {
-			return errors.Wrap(err, "API error is forbidden")
-		}
-		return errors.Wrapf(err, "Unexpected error creating hook cm %s", hook)
+	sort.Stable(hookByWeight(rel.Hooks))
+	for _, hook := range rel.Hooks {
+		fmt.Fprintf(&manifest, "\n---\n# Source: %s\n%s", hook.Path, hook.Manifest)
 	}
-	log.Info("Hooks", string(hook), "Ready (Created)")
-	return nil
+
+	return manifest.String(), nil
 }
 
 func ReleaseInstalled(releaseName string) bool {
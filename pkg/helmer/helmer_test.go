@@ -0,0 +1,20 @@
+package helmer
+
+import (
+	"testing"
+
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+func TestNewActionConfig(t *testing.T) {
+
+	// The values of a release include the data of $(secret:) references,
+	// releases must never be stored in ConfigMaps
+	config, err := newActionConfig("simple-kmod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := config.Releases.Driver.Name(); name != driver.SecretsDriverName {
+		t.Errorf("newActionConfig().Releases.Driver = %s, want %s", name, driver.SecretsDriverName)
+	}
+}
@@ -0,0 +1,376 @@
+package helmer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/kernel"
+	"github.com/openshift-psap/special-resource-operator/pkg/poll"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/openshift-psap/special-resource-operator/pkg/resource"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// hookEventLabel and hookOwnerLabel select the ConfigMaps recording
+	// the hooks of a SpecialResource
+	hookEventLabel = "specialresource.openshift.io/hook"
+	hookOwnerLabel = "specialresource.openshift.io/hook-owner"
+)
+
+// HookTimeout every hook has to complete within, failed Jobs are reported
+// right away
+var HookTimeout = 5 * time.Minute
+
+// HookFailedError is returned if a hook could not be created or did not
+// complete
+type HookFailedError struct {
+	Event release.HookEvent
+	Kind  string
+	Name  string
+	Path  string
+	Err   error
+	// LogTail of the pod of a failed Job
+	LogTail string
+}
+
+// Message describes the failure without the log tail
+func (e *HookFailedError) Message() string {
+	return fmt.Sprintf("%s hook %s %s (%s) failed: %v", e.Event, e.Kind, e.Name, e.Path, e.Err)
+}
+
+func (e *HookFailedError) Error() string {
+	if e.LogTail == "" {
+		return e.Message()
+	}
+	return e.Message() + ", log tail:\n" + e.LogTail
+}
+
+func (e *HookFailedError) Unwrap() error {
+	return e.Err
+}
+
+// hookByWeight is a sorter for hooks
+type hookByWeight []*release.Hook
+
+func (x hookByWeight) Len() int      { return len(x) }
+func (x hookByWeight) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x hookByWeight) Less(i, j int) bool {
+	if x[i].Weight == x[j].Weight {
+		return x[i].Name < x[j].Name
+	}
+	return x[i].Weight < x[j].Weight
+}
+
+// ExecHook runs the hooks of rl for the event hook and waits until they are
+// completed. Install hooks run once per namespace, upgrade hooks whenever
+// the rendered release changed since it was deployed. Delete hooks are only
+// recorded here, ExecDeleteHooks runs them.
+func ExecHook(rl *release.Release, hook release.HookEvent, timeout time.Duration, owner v1.Object, name string, namespace string, kernelFullVersion string, operatingSystemMajorMinor string, verifier *registry.Verifier) error {
+
+	hooks := hooksOf(rl, hook)
+
+	switch hook {
+	case release.HookPreInstall, release.HookPostInstall:
+		// One marker per namespace and event, kept from before upgrade
+		// hooks so deployed recipes do not run their install hooks again
+		marker := string("sh.helm.hooks." + hook)
+		found, err := getHookMarker(marker, namespace)
+		if err != nil {
+			return err
+		}
+		if found != nil {
+			log.Info("Hooks", string(hook), "Ready (Get)")
+			return nil
+		}
+		if err := runHooks(rl, hook, hooks, timeout, owner, name, namespace, kernelFullVersion, operatingSystemMajorMinor, verifier); err != nil {
+			return err
+		}
+		return saveHookMarker(marker, namespace, hook, name, nil)
+
+	case release.HookPreUpgrade, release.HookPostUpgrade:
+		if len(hooks) == 0 {
+			return nil
+		}
+		marker := hookMarkerName(hook, hooks, kernelFullVersion)
+		digest := releaseDigest(rl)
+		found, err := getHookMarker(marker, namespace)
+		if err != nil {
+			return err
+		}
+		if found != nil && found.Data["digest"] == digest {
+			log.Info("Hooks", string(hook), "Ready (unchanged)")
+			return nil
+		}
+		// The first deployment is an install, upgrade hooks only run for
+		// changes of a release SRO already deployed
+		if found != nil {
+			if err := runHooks(rl, hook, hooks, timeout, owner, name, namespace, kernelFullVersion, operatingSystemMajorMinor, verifier); err != nil {
+				return err
+			}
+		}
+		return saveHookMarker(marker, namespace, hook, name, map[string]string{"digest": digest})
+
+	case release.HookPreDelete, release.HookPostDelete:
+		if len(hooks) == 0 {
+			return nil
+		}
+		manifests, err := json.Marshal(hooks)
+		if err != nil {
+			return errors.Wrap(err, "Cannot marshal "+string(hook)+" hooks")
+		}
+		marker := hookMarkerName(hook, hooks, kernelFullVersion)
+		found, err := getHookMarker(marker, namespace)
+		if err != nil {
+			return err
+		}
+		if found != nil && found.Data["hooks"] == string(manifests) {
+			return nil
+		}
+		return saveHookMarker(marker, namespace, hook, name, map[string]string{
+			"hooks":  string(manifests),
+			"kernel": kernelFullVersion,
+			"os":     operatingSystemMajorMinor,
+		})
+	}
+
+	return errors.New("Unsupported hook event " + string(hook))
+}
+
+// ExecDeleteHooks runs the hooks of the event hook recorded for the
+// SpecialResource name in namespace
+func ExecDeleteHooks(hook release.HookEvent, owner v1.Object, name string, namespace string) error {
+
+	markers, err := clients.Workload().CoreV1().ConfigMaps(namespace).List(context.TODO(), v1.ListOptions{
+		LabelSelector: hookEventLabel + "=" + string(hook) + "," + hookOwnerLabel + "=" + name,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Cannot list %s hooks of %s", hook, name)
+	}
+
+	for _, marker := range markers.Items {
+		hooks := []*release.Hook{}
+		if err := json.Unmarshal([]byte(marker.Data["hooks"]), &hooks); err != nil {
+			return errors.Wrap(err, "Cannot unmarshal hooks of "+marker.GetName())
+		}
+		if err := runHooks(nil, hook, hooks, HookTimeout, owner, name, namespace, marker.Data["kernel"], marker.Data["os"], nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hooksOf returns the hooks of rl for the event hook
+func hooksOf(rl *release.Release, hook release.HookEvent) []*release.Hook {
+
+	hooks := []*release.Hook{}
+
+	for _, h := range rl.Hooks {
+		for _, e := range h.Events {
+			if e == hook {
+				hooks = append(hooks, h)
+			}
+		}
+	}
+
+	return hooks
+}
+
+// runHooks creates the hooks ordered by weight and waits for each of them,
+// the LastRun of the hooks is recorded in rl if set
+func runHooks(rl *release.Release, event release.HookEvent, hooks []*release.Hook, timeout time.Duration, owner v1.Object, name string, namespace string, kernelFullVersion string, operatingSystemMajorMinor string, verifier *registry.Verifier) error {
+
+	// hooks are pre-ordered by kind, so keep order stable
+	sort.Stable(hookByWeight(hooks))
+
+	defer func(timeout time.Duration) { poll.Timeout = timeout }(poll.Timeout)
+	poll.Timeout = timeout
+
+	record := func() {
+		if rl != nil && ActionConfig != nil {
+			ActionConfig.RecordRelease(rl)
+		}
+	}
+
+	for _, h := range hooks {
+
+		if len(h.DeletePolicies) == 0 {
+			h.DeletePolicies = []release.HookDeletePolicy{release.HookBeforeHookCreation}
+		}
+
+		if err := deleteHook(h, release.HookBeforeHookCreation, namespace, kernelFullVersion, operatingSystemMajorMinor); err != nil {
+			return err
+		}
+
+		h.LastRun = release.HookExecution{
+			StartedAt: helmtime.Now(),
+			Phase:     release.HookPhaseRunning,
+		}
+		record()
+
+		log.Info("Hooks", string(event), h.Path, "kind", h.Kind, "name", h.Name)
+
+		// CreateFromYAML waits for objects annotated as hook, Jobs until
+		// they are complete or failed
+		err := resource.CreateFromYAML([]byte(h.Manifest), false, owner, name, namespace, nil, kernelFullVersion, operatingSystemMajorMinor, verifier)
+
+		h.LastRun.CompletedAt = helmtime.Now()
+
+		if err != nil {
+			h.LastRun.Phase = release.HookPhaseFailed
+			record()
+			warn.OnError(deleteHook(h, release.HookFailed, namespace, kernelFullVersion, operatingSystemMajorMinor))
+			failed := &HookFailedError{Event: event, Kind: h.Kind, Name: h.Name, Path: h.Path, Err: err}
+			job := &poll.JobFailedError{}
+			if errors.As(err, &job) {
+				failed.LogTail = job.LogTail
+			}
+			return failed
+		}
+
+		h.LastRun.Phase = release.HookPhaseSucceeded
+	}
+	record()
+
+	// If all hooks are successful, delete the ones with the policy
+	// hook-succeeded
+	for _, h := range hooks {
+		if err := deleteHook(h, release.HookSucceeded, namespace, kernelFullVersion, operatingSystemMajorMinor); err != nil {
+			return err
+		}
+	}
+
+	log.Info("Hooks", string(event), "Ready (Completed)")
+	return nil
+}
+
+// deleteHook deletes the object of h if it has the delete policy and waits
+// until it is gone, a hook is created again only after its deletion
+func deleteHook(h *release.Hook, policy release.HookDeletePolicy, namespace string, kernelFullVersion string, operatingSystemMajorMinor string) error {
+
+	found := false
+	for _, p := range h.DeletePolicies {
+		found = found || p == policy
+	}
+	if !found {
+		return nil
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	if err := yaml.Unmarshal([]byte(h.Manifest), &obj.Object); err != nil {
+		return errors.Wrap(err, "Cannot unmarshal hook "+h.Path)
+	}
+
+	if resource.IsNamespaced(obj.GetKind()) && obj.GetNamespace() == "" {
+		obj.SetNamespace(namespace)
+	}
+	if kernel.IsObjectAffine(obj) {
+		if err := kernel.SetAffineAttributes(obj, kernelFullVersion, operatingSystemMajorMinor); err != nil {
+			return errors.Wrap(err, "Cannot set kernel affine attributes of hook "+h.Path)
+		}
+	}
+
+	err := resource.Writer().Delete(context.TODO(), obj, client.PropagationPolicy(v1.DeletePropagationBackground))
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Cannot delete hook %s %s by policy %s", h.Kind, h.Name, policy)
+	}
+
+	return poll.ForResourceUnavailability(obj)
+}
+
+// hookMarkerName of the ConfigMap recording the hooks of an event of one
+// state and kernel, states and kernels share the namespace
+func hookMarkerName(hook release.HookEvent, hooks []*release.Hook, kernelFullVersion string) string {
+
+	digest := sha256.New()
+	fmt.Fprintf(digest, "kernel %s\n", kernelFullVersion)
+	for _, h := range hooks {
+		fmt.Fprintf(digest, "%s %s %s\n", h.Kind, h.Name, h.Path)
+	}
+
+	return fmt.Sprintf("sh.helm.hooks.%s.%x", hook, digest.Sum(nil)[:8])
+}
+
+// releaseDigest of the manifests and hooks of rl
+func releaseDigest(rl *release.Release) string {
+
+	digest := sha256.New()
+	digest.Write([]byte(rl.Manifest))
+	for _, h := range rl.Hooks {
+		fmt.Fprintf(digest, "\n---\n%s\n", h.Path)
+		digest.Write([]byte(h.Manifest))
+	}
+
+	return fmt.Sprintf("%x", digest.Sum(nil))
+}
+
+// getHookMarker returns the marker ConfigMap, nil if not found
+func getHookMarker(marker string, namespace string) (*corev1.ConfigMap, error) {
+
+	found, err := clients.Workload().CoreV1().ConfigMaps(namespace).Get(context.TODO(), marker, v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unexpected error getting hook cm %s", marker)
+	}
+
+	return found, nil
+}
+
+// saveHookMarker creates or updates the marker ConfigMap with data
+func saveHookMarker(marker string, namespace string, hook release.HookEvent, name string, data map[string]string) error {
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      marker,
+			Namespace: namespace,
+			Labels: map[string]string{
+				hookEventLabel: string(hook),
+				hookOwnerLabel: name,
+			},
+		},
+		Data: data,
+	}
+
+	configmaps := clients.Workload().CoreV1().ConfigMaps(namespace)
+
+	_, err := configmaps.Create(context.TODO(), cm, v1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		found, err := configmaps.Get(context.TODO(), marker, v1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "Unexpected error getting hook cm %s", marker)
+		}
+		found.Labels = cm.Labels
+		found.Data = data
+		_, err = configmaps.Update(context.TODO(), found, v1.UpdateOptions{})
+		return errors.Wrapf(err, "Unexpected error updating hook cm %s", marker)
+	}
+	if apierrors.IsForbidden(err) {
+		return errors.Wrap(err, "API error is forbidden")
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Unexpected error creating hook cm %s", marker)
+	}
+
+	log.Info("Hooks", string(hook), "Ready (Created)")
+	return nil
+}
@@ -0,0 +1,61 @@
+package helmer
+
+import (
+	"strings"
+
+	helmerv1beta1 "github.com/openshift-psap/special-resource-operator/pkg/helmer/api/v1beta1"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// maxListedVersions caps the versions suggested for a missing chart version
+const maxListedVersions = 10
+
+// LocateChart checks that the index of the chart repository of spec lists
+// the chart and a version matching spec.Version, the error names the
+// versions that are available. Charts of OCI and git repositories have no
+// index and are checked once they are pulled.
+func LocateChart(spec helmerv1beta1.HelmChart) error {
+
+	if strings.HasPrefix(spec.Repository.URL, ociScheme) || strings.HasPrefix(spec.Repository.URL, gitScheme) {
+		return nil
+	}
+
+	chartRepo, err := repo.NewChartRepository(repoEntry(spec.Repository), getterProviders)
+	if err != nil {
+		return errors.Wrap(err, "new chart repository failed")
+	}
+	chartRepo.CachePath = settings.RepositoryCache
+
+	path, err := chartRepo.DownloadIndexFile()
+	if err != nil {
+		return errors.Wrap(err, "cannot find index.yaml for: "+spec.Repository.URL)
+	}
+
+	index, err := repo.LoadIndexFile(path)
+	if err != nil {
+		return errors.Wrap(err, "invalid index.yaml of: "+spec.Repository.URL)
+	}
+
+	versions, found := index.Entries[spec.Name]
+	if !found || len(versions) == 0 {
+		return errors.New("chart " + spec.Name + " not found in repository " + spec.Repository.URL)
+	}
+
+	if _, err := index.Get(spec.Name, spec.Version); err == nil {
+		return nil
+	}
+
+	// The index sorts the versions newest first
+	available := []string{}
+	for _, version := range versions {
+		if len(available) == maxListedVersions {
+			available = append(available, "...")
+			break
+		}
+		available = append(available, version.Version)
+	}
+
+	return errors.New("version " + spec.Version + " of chart " + spec.Name + " not found in repository " +
+		spec.Repository.URL + ", available versions: " + strings.Join(available, ", "))
+}
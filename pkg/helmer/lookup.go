@@ -0,0 +1,204 @@
+package helmer
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"k8s.io/client-go/rest"
+)
+
+// LookupAnnotation in Chart.yaml enables the helm lookup function for the
+// chart and its subcharts. Lookups are read-only and only see the objects
+// of the release namespace and LookupNamespaces.
+const LookupAnnotation = "specialresource.openshift.io/lookup"
+
+var (
+	// LookupConfig lookups authenticate with, the service account of the
+	// SpecialResource, the identity of the helm config if nil
+	LookupConfig *rest.Config
+	// LookupNamespaces lookups may read besides the release namespace
+	LookupNamespaces []string
+)
+
+// LookupEnabled is true if the chart opted in to lookup
+func LookupEnabled(ch *chart.Chart) bool {
+	return ch.Metadata != nil && ch.Metadata.Annotations[LookupAnnotation] == "true"
+}
+
+// resolveLookups renders the templates of ch with lookup backed by the
+// cluster and returns a copy of ch whose templates are the rendered output.
+// Helm only enables lookup when it installs a release itself, SRO renders
+// with a dry-run install that would return empty objects.
+func resolveLookups(config *action.Configuration, ch *chart.Chart, vals map[string]interface{}, namespace string) (*chart.Chart, error) {
+
+	if err := chartutil.ProcessDependencies(ch, vals); err != nil {
+		return nil, errors.Wrap(err, "Cannot process chart dependencies")
+	}
+
+	caps, err := capabilities(config)
+	if err != nil {
+		return nil, err
+	}
+
+	options := chartutil.ReleaseOptions{
+		Name:      ch.Metadata.Name,
+		Namespace: namespace,
+		Revision:  1,
+		IsInstall: true,
+	}
+
+	values, err := chartutil.ToRenderValues(ch, vals, options, caps)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot compose render values")
+	}
+
+	restConfig := LookupConfig
+	if restConfig == nil {
+		if restConfig, err = config.RESTClientGetter.ToRESTConfig(); err != nil {
+			return nil, errors.Wrap(err, "Cannot get REST config for lookup")
+		}
+	}
+
+	rendered, err := engine.RenderWithClient(ch, values, lookupConfig(restConfig, append([]string{namespace}, LookupNamespaces...)))
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot render chart "+ch.Metadata.Name+" with lookup")
+	}
+
+	return withRendered(ch, rendered), nil
+}
+
+// lookupConfig copies restConfig with a transport rejecting any request
+// but discovery and reads of objects in namespaces
+func lookupConfig(restConfig *rest.Config, namespaces []string) *rest.Config {
+
+	restConfig = rest.CopyConfig(restConfig)
+	restConfig.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return lookupGuard{next: rt, namespaces: namespaces}
+	})
+
+	return restConfig
+}
+
+type lookupGuard struct {
+	next       http.RoundTripper
+	namespaces []string
+}
+
+func (g lookupGuard) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	if err := lookupAllowed(req.Method, req.URL.Path, g.namespaces); err != nil {
+		return nil, err
+	}
+
+	return g.next.RoundTrip(req)
+}
+
+// lookupAllowed accepts GET requests of the discovery endpoints and of the
+// objects in one of namespaces, cluster scoped objects and lists across
+// namespaces are rejected
+func lookupAllowed(method string, urlPath string, namespaces []string) error {
+
+	if method != http.MethodGet {
+		return errors.New("Lookup cannot " + method + " " + urlPath)
+	}
+
+	denied := errors.New("Lookup of " + urlPath + " outside of namespaces " + strings.Join(namespaces, ", "))
+
+	if path.Clean(urlPath) != urlPath {
+		return denied
+	}
+
+	parts := strings.Split(strings.TrimPrefix(urlPath, "/"), "/")
+
+	// /api/<version> and /apis/<group>/<version> prefix the resources
+	var resource []string
+	switch {
+	case parts[0] == "api" && len(parts) > 2:
+		resource = parts[2:]
+	case parts[0] == "apis" && len(parts) > 3:
+		resource = parts[3:]
+	case parts[0] == "api" || parts[0] == "apis":
+		return nil
+	default:
+		return denied
+	}
+
+	if len(resource) < 2 || resource[0] != "namespaces" {
+		return denied
+	}
+	for _, namespace := range namespaces {
+		if resource[1] == namespace {
+			return nil
+		}
+	}
+
+	return denied
+}
+
+// withRendered copies ch and its subcharts replacing every template but the
+// partials with its rendered output, template actions in the output are
+// escaped so the second rendering by the install keeps them literally.
+func withRendered(ch *chart.Chart, rendered map[string]string) *chart.Chart {
+
+	out := *ch
+	out.Templates = []*chart.File{}
+
+	for _, t := range ch.Templates {
+		if strings.HasPrefix(path.Base(t.Name), "_") {
+			out.Templates = append(out.Templates, t)
+			continue
+		}
+		data := rendered[path.Join(ch.ChartFullPath(), t.Name)]
+		data = strings.ReplaceAll(data, "{{", `{{ "{{" }}`)
+		out.Templates = append(out.Templates, &chart.File{Name: t.Name, Data: []byte(data)})
+	}
+
+	deps := []*chart.Chart{}
+	for _, sub := range ch.Dependencies() {
+		deps = append(deps, withRendered(sub, rendered))
+	}
+	out.SetDependencies(deps...)
+
+	return &out
+}
+
+// capabilities of the cluster, stored in config so the install does not
+// discover them again
+func capabilities(config *action.Configuration) (*chartutil.Capabilities, error) {
+
+	if config.Capabilities != nil {
+		return config.Capabilities, nil
+	}
+
+	dc, err := config.RESTClientGetter.ToDiscoveryClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot get discovery client")
+	}
+
+	version, err := dc.ServerVersion()
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot get server version")
+	}
+
+	apiVersions, err := action.GetVersionSet(dc)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Capabilities = &chartutil.Capabilities{
+		APIVersions: apiVersions,
+		KubeVersion: chartutil.KubeVersion{
+			Version: version.GitVersion,
+			Major:   version.Major,
+			Minor:   version.Minor,
+		},
+	}
+
+	return config.Capabilities, nil
+}
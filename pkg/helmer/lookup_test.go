@@ -0,0 +1,85 @@
+package helmer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestLookupAllowed(t *testing.T) {
+
+	namespaces := []string{"driver", "driver-target"}
+
+	tests := []struct {
+		method string
+		path   string
+		valid  bool
+	}{
+		{"GET", "/api", true},
+		{"GET", "/api/v1", true},
+		{"GET", "/apis", true},
+		{"GET", "/apis/apps", true},
+		{"GET", "/apis/apps/v1", true},
+		{"GET", "/api/v1/namespaces/driver", true},
+		{"GET", "/api/v1/namespaces/driver/configmaps", true},
+		{"GET", "/api/v1/namespaces/driver/configmaps/ca", true},
+		{"GET", "/api/v1/namespaces/driver-target/secrets/pull", true},
+		{"GET", "/apis/apps/v1/namespaces/driver/daemonsets/driver", true},
+		{"POST", "/api/v1/namespaces/driver/configmaps", false},
+		{"DELETE", "/api/v1/namespaces/driver/configmaps/ca", false},
+		{"GET", "/api/v1/namespaces", false},
+		{"GET", "/api/v1/namespaces/openshift-config/configmaps/user-ca-bundle", false},
+		{"GET", "/api/v1/namespaces/kube-system/secrets", false},
+		{"GET", "/api/v1/secrets", false},
+		{"GET", "/api/v1/nodes", false},
+		{"GET", "/apis/apps/v1/daemonsets", false},
+		{"GET", "/apis/rbac.authorization.k8s.io/v1/clusterroles", false},
+		{"GET", "/api/v1/namespaces/driver/../kube-system/secrets", false},
+		{"GET", "/api/v1/namespaces/driver/secrets/../../kube-system/secrets", false},
+		{"GET", "/api/v1/namespaces//secrets", false},
+		{"GET", "/version", false},
+		{"GET", "/", false},
+	}
+
+	for _, tt := range tests {
+		if err := lookupAllowed(tt.method, tt.path, namespaces); (err == nil) != tt.valid {
+			t.Errorf("lookupAllowed(%q, %q) = %v, want valid %v", tt.method, tt.path, err, tt.valid)
+		}
+	}
+}
+
+func TestLookupConfig(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	restConfig := lookupConfig(&rest.Config{Host: server.URL}, []string{"driver"})
+
+	transport, err := rest.TransportFor(restConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: transport}
+
+	tests := []struct {
+		path  string
+		valid bool
+	}{
+		{"/api/v1/namespaces/driver/configmaps", true},
+		{"/api/v1/namespaces/kube-system/secrets", false},
+	}
+
+	for _, tt := range tests {
+		resp, err := client.Get(server.URL + tt.path)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if (err == nil) != tt.valid {
+			t.Errorf("GET %s = %v, want valid %v", tt.path, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package helmer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/resource"
+	"github.com/openshift-psap/special-resource-operator/pkg/yamlutil"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// KustomizationKey the optional kustomization.yaml of a patches ConfigMap
+const KustomizationKey = "kustomization.yaml"
+
+// PatchTarget selects the objects a patch applies to like the target of
+// the patches of a kustomization, name is a regular expression
+type PatchTarget struct {
+	Group              string `json:"group,omitempty"`
+	Version            string `json:"version,omitempty"`
+	Kind               string `json:"kind,omitempty"`
+	Name               string `json:"name,omitempty"`
+	Namespace          string `json:"namespace,omitempty"`
+	LabelSelector      string `json:"labelSelector,omitempty"`
+	AnnotationSelector string `json:"annotationSelector,omitempty"`
+}
+
+// kustomization the subset of a kustomization.yaml that patches the
+// rendered manifests, resources, generators and transformers are not
+// supported
+type kustomization struct {
+	PatchesStrategicMerge []string `json:"patchesStrategicMerge,omitempty"`
+	Patches               []struct {
+		Path   string       `json:"path,omitempty"`
+		Patch  string       `json:"patch,omitempty"`
+		Target *PatchTarget `json:"target,omitempty"`
+	} `json:"patches,omitempty"`
+	PatchesJSON6902 []interface{} `json:"patchesJson6902,omitempty"`
+}
+
+// ForbiddenPatchFields the fields patches must not set anywhere, they would
+// turn the privileged driver containers into arbitrary ones
+var ForbiddenPatchFields = []string{
+	"image", "securityContext", "volumes", "hostPID", "hostNetwork", "hostIPC",
+	"serviceAccountName", "serviceAccount",
+}
+
+// forbiddenField returns the path of the first forbidden field of value,
+// keys are compared case-insensitively like older API servers decode them
+func forbiddenField(value interface{}, path string) string {
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			for _, forbidden := range ForbiddenPatchFields {
+				if strings.EqualFold(key, forbidden) {
+					return path + "." + key
+				}
+			}
+			if found := forbiddenField(v[key], path+"."+key); found != "" {
+				return found
+			}
+		}
+	case []interface{}:
+		for idx, elem := range v {
+			if found := forbiddenField(elem, path+"["+strconv.Itoa(idx)+"]"); found != "" {
+				return found
+			}
+		}
+	}
+
+	return ""
+}
+
+type patch struct {
+	source string
+	object *unstructured.Unstructured
+	target *PatchTarget
+	name   *regexp.Regexp
+}
+
+// PostRenderer applies strategic merge patches to the manifests rendered by
+// helm, hooks are not patched. Objects without a built-in type, e.g. of
+// CRDs, are patched with a JSON merge patch. Patches must not set any of
+// ForbiddenPatchFields.
+type PostRenderer struct {
+	patches []patch
+	hash    string
+}
+
+// NewKustomizePostRenderer parses the patches of a ConfigMap. With a
+// kustomization.yaml its patchesStrategicMerge and patches reference the
+// other keys or are inline, without one every *.yaml key is a strategic
+// merge patch applied to the objects of its kind and name.
+func NewKustomizePostRenderer(data map[string]string) (*PostRenderer, error) {
+
+	pr := &PostRenderer{}
+
+	keys := []string{}
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, key := range keys {
+		h.Write([]byte(key + "\x00" + data[key] + "\x00"))
+	}
+	pr.hash = hex.EncodeToString(h.Sum(nil))
+
+	file := func(key string) (string, error) {
+		content, found := data[key]
+		if !found {
+			return "", errors.New("Patch " + key + " referenced by " + KustomizationKey + " not found")
+		}
+		return content, nil
+	}
+
+	k, found := data[KustomizationKey]
+	if !found {
+		for _, key := range keys {
+			if !strings.HasSuffix(key, ".yaml") && !strings.HasSuffix(key, ".yml") {
+				continue
+			}
+			if err := pr.add(key, data[key], nil); err != nil {
+				return nil, err
+			}
+		}
+		return pr, nil
+	}
+
+	kust := kustomization{}
+	if err := yaml.Unmarshal([]byte(k), &kust); err != nil {
+		return nil, errors.Wrap(err, "Cannot parse "+KustomizationKey)
+	}
+	if len(kust.PatchesJSON6902) > 0 {
+		return nil, errors.New("patchesJson6902 is not supported, use strategic merge patches")
+	}
+
+	for _, key := range kust.PatchesStrategicMerge {
+		content, err := file(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := pr.add(key, content, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	for idx, p := range kust.Patches {
+		source, content := "patches["+strconv.Itoa(idx)+"]", p.Patch
+		if p.Path != "" {
+			var err error
+			if content, err = file(p.Path); err != nil {
+				return nil, err
+			}
+			source = p.Path
+		}
+		if err := pr.add(source, content, p.Target); err != nil {
+			return nil, err
+		}
+	}
+
+	return pr, nil
+}
+
+// add parses the patches of content, one per YAML document
+func (pr *PostRenderer) add(source string, content string, target *PatchTarget) error {
+
+	var name *regexp.Regexp
+	if target != nil && target.Name != "" {
+		var err error
+		if name, err = regexp.Compile("^(?:" + target.Name + ")$"); err != nil {
+			return errors.Wrap(err, "Invalid target name of patch "+source)
+		}
+	}
+	if target != nil {
+		if _, err := labels.Parse(target.LabelSelector); err != nil {
+			return errors.Wrap(err, "Invalid target labelSelector of patch "+source)
+		}
+		if _, err := labels.Parse(target.AnnotationSelector); err != nil {
+			return errors.Wrap(err, "Invalid target annotationSelector of patch "+source)
+		}
+	}
+
+	scanner := yamlutil.NewYAMLScanner([]byte(content))
+	for scanner.Scan() {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(scanner.Bytes(), &obj.Object); err != nil {
+			return errors.Wrap(err, "Cannot parse patch "+source)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if field := forbiddenField(obj.Object, ""); field != "" {
+			return errors.New("Patch " + source + " must not set " + strings.TrimPrefix(field, "."))
+		}
+		if target == nil && (obj.GetKind() == "" || obj.GetName() == "") {
+			return errors.New("Patch " + source + " needs a kind and metadata.name or a target")
+		}
+		pr.patches = append(pr.patches, patch{source: source, object: obj, target: target, name: name})
+	}
+
+	return errors.Wrap(scanner.Err(), "Cannot read patch "+source)
+}
+
+// Hash of the patches, part of the render cache key
+func (pr *PostRenderer) Hash() string {
+	if pr == nil {
+		return ""
+	}
+	return pr.hash
+}
+
+// Run implements postrender.PostRenderer, documents that no patch applies
+// to are passed on as is
+func (pr *PostRenderer) Run(rendered *bytes.Buffer) (*bytes.Buffer, error) {
+
+	out := &bytes.Buffer{}
+
+	scanner := yamlutil.NewYAMLScanner(rendered.Bytes())
+	for scanner.Scan() {
+
+		doc := scanner.Bytes()
+
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(doc, &obj.Object); err != nil {
+			return nil, errors.Wrap(err, "Cannot parse rendered manifest")
+		}
+
+		patched := false
+		for _, p := range pr.patches {
+			if len(obj.Object) == 0 || !p.matches(obj) {
+				continue
+			}
+			if err := p.apply(obj); err != nil {
+				return nil, errors.Wrap(err, "Cannot apply patch "+p.source+" to "+obj.GetKind()+" "+obj.GetName())
+			}
+			log.Info("Patched rendered manifest", "patch", p.source, "kind", obj.GetKind(), "name", obj.GetName())
+			patched = true
+		}
+
+		out.WriteString("---\n")
+		if !patched {
+			out.Write(doc)
+			if !bytes.HasSuffix(doc, []byte("\n")) {
+				out.WriteString("\n")
+			}
+			continue
+		}
+
+		// Keep the # Source: comment of helm
+		for _, line := range strings.SplitAfter(string(doc), "\n") {
+			if !strings.HasPrefix(line, "#") {
+				break
+			}
+			out.WriteString(line)
+		}
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot marshal patched manifest")
+		}
+		out.Write(data)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Cannot read rendered manifests")
+	}
+
+	return out, nil
+}
+
+func (p *patch) matches(obj *unstructured.Unstructured) bool {
+
+	gvk := obj.GroupVersionKind()
+
+	if p.target == nil {
+		want := p.object.GroupVersionKind()
+		return gvk.Kind == want.Kind && (p.object.GetAPIVersion() == "" || gvk.Group == want.Group) &&
+			obj.GetName() == p.object.GetName() &&
+			(p.object.GetNamespace() == "" || obj.GetNamespace() == p.object.GetNamespace())
+	}
+
+	t := p.target
+	if (t.Group != "" && gvk.Group != t.Group) || (t.Version != "" && gvk.Version != t.Version) ||
+		(t.Kind != "" && gvk.Kind != t.Kind) || (t.Namespace != "" && obj.GetNamespace() != t.Namespace) {
+		return false
+	}
+	if p.name != nil && !p.name.MatchString(obj.GetName()) {
+		return false
+	}
+	if selector, _ := labels.Parse(t.LabelSelector); !selector.Matches(labels.Set(obj.GetLabels())) {
+		return false
+	}
+	if selector, _ := labels.Parse(t.AnnotationSelector); !selector.Matches(labels.Set(obj.GetAnnotations())) {
+		return false
+	}
+	return true
+}
+
+// apply patches obj in place, the identity of obj is kept
+func (p *patch) apply(obj *unstructured.Unstructured) error {
+
+	delta := p.object.DeepCopy().Object
+	// A targeted patch may name any object, only its content applies
+	for _, field := range []string{"apiVersion", "kind"} {
+		delete(delta, field)
+	}
+	unstructured.RemoveNestedField(delta, "metadata", "name")
+	unstructured.RemoveNestedField(delta, "metadata", "namespace")
+
+	gvk := obj.GroupVersionKind()
+
+	typed, err := typedObject(gvk)
+	if err != nil {
+		obj.Object = mergePatch(obj.Object, delta)
+		return nil
+	}
+
+	result, err := strategicpatch.StrategicMergeMapPatch(obj.Object, delta, typed)
+	if err != nil {
+		return err
+	}
+	obj.Object = result
+	return nil
+}
+
+func typedObject(gvk schema.GroupVersionKind) (interface{}, error) {
+	if resource.RuntimeScheme != nil {
+		if typed, err := resource.RuntimeScheme.New(gvk); err == nil {
+			return typed, nil
+		}
+	}
+	return scheme.Scheme.New(gvk)
+}
+
+// mergePatch applies a JSON merge patch (RFC 7386), null deletes a field
+func mergePatch(original map[string]interface{}, delta map[string]interface{}) map[string]interface{} {
+
+	for key, value := range delta {
+		if value == nil {
+			delete(original, key)
+			continue
+		}
+		patchMap, ok := value.(map[string]interface{})
+		originalMap, isMap := original[key].(map[string]interface{})
+		if ok && isMap {
+			original[key] = mergePatch(originalMap, patchMap)
+			continue
+		}
+		if ok {
+			original[key] = mergePatch(map[string]interface{}{}, patchMap)
+			continue
+		}
+		original[key] = value
+	}
+
+	return original
+}
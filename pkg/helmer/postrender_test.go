@@ -0,0 +1,215 @@
+package helmer
+
+import (
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestForbiddenField(t *testing.T) {
+
+	tests := []struct {
+		patch string
+		want  string
+	}{
+		{
+			patch: `
+kind: DaemonSet
+metadata:
+  name: driver
+  labels:
+    app: driver
+spec:
+  template:
+    spec:
+      nodeSelector:
+        feature: gpu
+      containers:
+      - name: driver
+        env:
+        - name: DEBUG
+          value: "true"
+        resources:
+          limits:
+            memory: 1Gi`,
+			want: "",
+		},
+		{
+			patch: `
+kind: DaemonSet
+spec:
+  template:
+    spec:
+      containers:
+      - name: driver
+        image: quay.io/evil/driver:latest`,
+			want: ".spec.template.spec.containers[0].image",
+		},
+		{
+			patch: `
+kind: DaemonSet
+spec:
+  template:
+    spec:
+      containers:
+      - name: driver
+      - name: sidecar
+        securityContext:
+          privileged: true`,
+			want: ".spec.template.spec.containers[1].securityContext",
+		},
+		{
+			patch: `
+kind: DaemonSet
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: quay.io/evil/init:latest`,
+			want: ".spec.template.spec.initContainers[0].image",
+		},
+		{
+			patch: `
+kind: Pod
+spec:
+  ephemeralContainers:
+  - name: debug
+    securityContext:
+      capabilities:
+        add: [SYS_ADMIN]`,
+			want: ".spec.ephemeralContainers[0].securityContext",
+		},
+		{
+			patch: `
+kind: DaemonSet
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsUser: 0`,
+			want: ".spec.template.spec.securityContext",
+		},
+		{
+			patch: `
+kind: DaemonSet
+spec:
+  template:
+    spec:
+      volumes:
+      - name: root
+        hostPath:
+          path: /`,
+			want: ".spec.template.spec.volumes",
+		},
+		{
+			patch: `
+kind: DaemonSet
+spec:
+  template:
+    spec:
+      hostPID: true`,
+			want: ".spec.template.spec.hostPID",
+		},
+		{
+			patch: `
+kind: DaemonSet
+spec:
+  template:
+    spec:
+      serviceAccountName: cluster-admin`,
+			want: ".spec.template.spec.serviceAccountName",
+		},
+		{
+			patch: `
+kind: Driver
+spec:
+  stages:
+  - - name: nested
+      image: quay.io/evil/driver:latest`,
+			want: ".spec.stages[0][0].image",
+		},
+		{
+			patch: `
+kind: DaemonSet
+spec:
+  template:
+    spec:
+      containers:
+      - name: driver
+        Image: quay.io/evil/driver:latest`,
+			want: ".spec.template.spec.containers[0].Image",
+		},
+		{
+			patch: `
+kind: DaemonSet
+spec:
+  template:
+    spec:
+      containers:
+      - name: driver
+        SECURITYCONTEXT:
+          privileged: true`,
+			want: ".spec.template.spec.containers[0].SECURITYCONTEXT",
+		},
+		{
+			patch: `
+kind: DaemonSet
+spec:
+  template:
+    spec:
+      containers:
+      - name: driver
+        imagePullPolicy: Always
+        env:
+        - name: image
+          value: securityContext`,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		obj := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(tt.patch), &obj); err != nil {
+			t.Fatal(err)
+		}
+		if got := forbiddenField(obj, ""); got != tt.want {
+			t.Errorf("forbiddenField(%s) = %q, want %q", tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestNewKustomizePostRendererForbidden(t *testing.T) {
+
+	tests := []struct {
+		data map[string]string
+		want string
+	}{
+		{
+			data: map[string]string{
+				"driver.yaml": "kind: DaemonSet\nmetadata:\n  name: driver\nspec:\n  template:\n    spec:\n      containers:\n      - name: driver\n        image: quay.io/evil/driver\n",
+			},
+			want: "Patch driver.yaml must not set spec.template.spec.containers[0].image",
+		},
+		{
+			data: map[string]string{
+				KustomizationKey: "patches:\n- target:\n    kind: DaemonSet\n  patch: |\n    spec:\n      template:\n        spec:\n          containers:\n          - name: driver\n            securityContext:\n              privileged: true\n",
+			},
+			want: "Patch patches[0] must not set spec.template.spec.containers[0].securityContext",
+		},
+		{
+			data: map[string]string{
+				"driver.yaml": "kind: DaemonSet\nmetadata:\n  name: driver\n---\nkind: DaemonSet\nmetadata:\n  name: other\nspec:\n  template:\n    spec:\n      hostNetwork: true\n",
+			},
+			want: "Patch driver.yaml must not set spec.template.spec.hostNetwork",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := NewKustomizePostRenderer(tt.data)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("NewKustomizePostRenderer(%v) = %v, want %q", tt.data, err, tt.want)
+		}
+	}
+}
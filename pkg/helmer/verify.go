@@ -0,0 +1,80 @@
+package helmer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/pkg/errors"
+)
+
+// KeyringCache the GPG keyrings for provenance verification are written to,
+// helm only reads keyrings from files
+var KeyringCache = "/cache/helm/keyrings"
+
+// ChartVerifier checks the signature of a chart before it is loaded
+type ChartVerifier struct {
+	// Keyring file with the GPG public keys .prov files are verified with
+	Keyring string
+	// Cosign verifies the signatures of charts in OCI repositories
+	Cosign *registry.Verifier
+}
+
+// NewChartVerifier loads the GPG keyrings of the Secret keyringSecret and
+// the cosign public keys of the Secret publicKeySecret in namespace
+func NewChartVerifier(namespace string, keyringSecret string, publicKeySecret string) (*ChartVerifier, error) {
+
+	verifier := &ChartVerifier{}
+
+	if keyringSecret != "" {
+		secret, err := clients.GetSecret(namespace, keyringSecret)
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot get keyring Secret "+namespace+"/"+keyringSecret)
+		}
+
+		keys := []string{}
+		for key := range secret.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		// Binary keyrings are a sequence of packets, concatenating them
+		// results in one keyring with all keys
+		var keyring bytes.Buffer
+		for _, key := range keys {
+			keyring.Write(secret.Data[key])
+		}
+
+		if err := os.MkdirAll(KeyringCache, 0755); err != nil {
+			return nil, errors.Wrap(err, "Cannot create keyring cache "+KeyringCache)
+		}
+
+		verifier.Keyring = filepath.Join(KeyringCache, namespace+"_"+keyringSecret+".gpg")
+		if err := ioutil.WriteFile(verifier.Keyring, keyring.Bytes(), 0644); err != nil {
+			return nil, errors.Wrap(err, "Cannot write keyring "+verifier.Keyring)
+		}
+	}
+
+	if publicKeySecret != "" {
+		cosign, err := registry.NewVerifier(namespace, publicKeySecret, "", "", "")
+		if err != nil {
+			return nil, err
+		}
+		verifier.Cosign = cosign
+	}
+
+	return verifier, nil
+}
+
+// verifyOCI checks the cosign signature of the chart entry
+func (v *ChartVerifier) verifyOCI(entry string) error {
+	if v.Cosign == nil {
+		return errors.New("No cosign public key to verify chart " + entry)
+	}
+	_, err := v.Cosign.Verify(entry)
+	return errors.Wrap(err, "Chart signature verification failed")
+}
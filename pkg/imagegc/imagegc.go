@@ -0,0 +1,111 @@
+package imagegc
+
+import (
+	"context"
+	"regexp"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/color"
+	imagev1 "github.com/openshift/api/image/v1"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var log logr.Logger
+
+func init() {
+	log = zap.New(zap.UseDevMode(true)).WithName(color.Print("imagegc", color.Brown))
+}
+
+// kernelTag matches tags named after a kernel full version, the charts tag
+// driver containers with v{{.Values.kernelFullVersion}}
+var kernelTag = regexp.MustCompile(`^v?([0-9]+\.[0-9]+\.[0-9]+-[A-Za-z0-9_.+-]+\.(x86_64|aarch64|ppc64le|s390x))$`)
+
+// KernelOf returns the kernel version of tag, false if tag is not named
+// after a kernel
+func KernelOf(tag string) (string, bool) {
+	match := kernelTag.FindStringSubmatch(tag)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// Obsolete returns the kernel tags of is whose kernel is not in kernels,
+// the retention most recently pushed of them are kept.
+func Obsolete(is *imagev1.ImageStream, kernels map[string]bool, retention int) []string {
+
+	type tagEvent struct {
+		tag     string
+		created metav1.Time
+	}
+
+	obsolete := []tagEvent{}
+
+	for _, tag := range is.Status.Tags {
+		kernel, ok := KernelOf(tag.Tag)
+		if !ok || kernels[kernel] {
+			continue
+		}
+		event := tagEvent{tag: tag.Tag}
+		if len(tag.Items) > 0 {
+			event.created = tag.Items[0].Created
+		}
+		obsolete = append(obsolete, event)
+	}
+
+	sort.SliceStable(obsolete, func(i, j int) bool {
+		return obsolete[j].created.Before(&obsolete[i].created)
+	})
+
+	tags := []string{}
+	for idx, event := range obsolete {
+		if idx >= retention {
+			tags = append(tags, event.tag)
+		}
+	}
+	sort.Strings(tags)
+
+	return tags
+}
+
+// Prune deletes the obsolete tags of the ImageStreams in namespace released
+// by the SpecialResource release and returns the deleted ImageStreamTags.
+// The registry only frees the images once the image pruner runs.
+func Prune(namespace string, release string, kernels map[string]bool, retention int) ([]string, error) {
+
+	streams := &imagev1.ImageStreamList{}
+	if err := clients.Workload().List(context.TODO(), streams, client.InNamespace(namespace)); err != nil {
+		return nil, errors.Wrap(err, "Cannot list ImageStreams in "+namespace)
+	}
+
+	pruned := []string{}
+
+	for idx, is := range streams.Items {
+
+		if is.GetAnnotations()["meta.helm.sh/release-name"] != release {
+			continue
+		}
+
+		for _, tag := range Obsolete(&streams.Items[idx], kernels, retention) {
+
+			name := is.GetName() + ":" + tag
+			log.Info("Pruning", "ImageStreamTag", name, "Namespace", namespace)
+
+			ist := &imagev1.ImageStreamTag{}
+			ist.SetName(name)
+			ist.SetNamespace(namespace)
+
+			if err := clients.Workload().Delete(context.TODO(), ist); client.IgnoreNotFound(err) != nil {
+				return pruned, errors.Wrap(err, "Cannot delete ImageStreamTag "+name)
+			}
+			pruned = append(pruned, name)
+		}
+	}
+
+	return pruned, nil
+}
@@ -0,0 +1,205 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/color"
+	"github.com/openshift-psap/special-resource-operator/pkg/hash"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+const (
+	dataKey     = "objects"
+	releaseName = "meta.helm.sh/release-name"
+)
+
+var log logr.Logger
+
+func init() {
+	log = zap.New(zap.UseDevMode(true)).WithName(color.Print("inventory", color.Brown))
+}
+
+// Object identifies an object of the chart manifests
+type Object struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	// Hash of the applied manifest, see hash.Annotate
+	Hash string `json:"hash,omitempty"`
+}
+
+// key identifies o regardless of its manifest
+func (o Object) key() Object {
+	o.Hash = ""
+	return o
+}
+
+func (o Object) String() string {
+	if o.Namespace == "" {
+		return o.Kind + ": " + o.Name
+	}
+	return o.Kind + ": " + o.Namespace + "/" + o.Name
+}
+
+// Inventory the objects rendered by one reconcile of a SpecialResource
+type Inventory struct {
+	// objects the hash of the manifest per object
+	objects map[Object]string
+	partial bool
+}
+
+// New returns an empty Inventory
+func New() *Inventory {
+	return &Inventory{objects: make(map[Object]string)}
+}
+
+// Add records obj and the hash of its manifest
+func (i *Inventory) Add(obj *unstructured.Unstructured) {
+	i.objects[Object{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}] = hash.Object(obj)
+}
+
+// SetPartial marks that manifests were skipped, objects missing from the
+// Inventory may still be produced by the chart and must not be pruned
+func (i *Inventory) SetPartial() {
+	i.partial = true
+}
+
+// Partial see SetPartial
+func (i *Inventory) Partial() bool {
+	return i.partial
+}
+
+// Contains is true if o was recorded, whatever its hash
+func (i *Inventory) Contains(o Object) bool {
+	_, found := i.objects[o.key()]
+	return found
+}
+
+// Objects returns the recorded objects sorted by kind, namespace and name
+func (i *Inventory) Objects() []Object {
+
+	objects := []Object{}
+	for o, hash := range i.objects {
+		o.Hash = hash
+		objects = append(objects, o)
+	}
+
+	sort.Slice(objects, func(a, b int) bool {
+		if objects[a].Kind != objects[b].Kind {
+			return objects[a].Kind < objects[b].Kind
+		}
+		if objects[a].Namespace != objects[b].Namespace {
+			return objects[a].Namespace < objects[b].Namespace
+		}
+		return objects[a].Name < objects[b].Name
+	})
+
+	return objects
+}
+
+func configMapKey(name string) types.NamespacedName {
+	return types.NamespacedName{Namespace: os.Getenv("OPERATOR_NAMESPACE"), Name: name + "-inventory"}
+}
+
+// LoadConfigMap returns the objects stored for the SpecialResource name by
+// operators before the SpecialResourceInventory, nil if there are none
+func LoadConfigMap(name string) ([]Object, error) {
+
+	cm := &v1.ConfigMap{}
+	key := configMapKey(name)
+
+	err := clients.Interface.Get(context.TODO(), key, cm)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot get inventory ConfigMap "+key.Name)
+	}
+
+	objects := []Object{}
+	if err := json.Unmarshal([]byte(cm.Data[dataKey]), &objects); err != nil {
+		return nil, errors.Wrap(err, "Invalid inventory ConfigMap "+key.Name)
+	}
+
+	return objects, nil
+}
+
+// DeleteConfigMap deletes the inventory ConfigMap of the SpecialResource
+// name once its objects are stored in the SpecialResourceInventory
+func DeleteConfigMap(name string) error {
+
+	key := configMapKey(name)
+
+	cm := &v1.ConfigMap{}
+	cm.SetName(key.Name)
+	cm.SetNamespace(key.Namespace)
+
+	err := clients.Interface.Delete(context.TODO(), cm)
+	if client.IgnoreNotFound(err) != nil {
+		return errors.Wrap(err, "Cannot delete inventory ConfigMap "+key.Name)
+	}
+
+	return nil
+}
+
+// Prune deletes the objects of previous that current does not contain,
+// objects no longer released by the SpecialResource name are left alone.
+func Prune(name string, previous []Object, current *Inventory) ([]Object, error) {
+
+	pruned := []Object{}
+
+	for _, o := range previous {
+
+		if current.Contains(o) {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(o.APIVersion)
+		obj.SetKind(o.Kind)
+
+		err := clients.Workload().Get(context.TODO(), types.NamespacedName{Namespace: o.Namespace, Name: o.Name}, obj)
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return pruned, errors.Wrap(err, "Cannot get "+o.String())
+		}
+
+		if obj.GetAnnotations()[releaseName] != name {
+			log.Info("Not released by SpecialResource anymore, skipping", "Object", o.String(), "SpecialResource", name)
+			continue
+		}
+
+		log.Info("Pruning", "Object", o.String(), "SpecialResource", name)
+
+		policy := metav1.DeletePropagationBackground
+		err = clients.Workload().Delete(context.TODO(), obj, &client.DeleteOptions{PropagationPolicy: &policy})
+		if client.IgnoreNotFound(err) != nil {
+			return pruned, errors.Wrap(err, "Cannot delete "+o.String())
+		}
+
+		pruned = append(pruned, o)
+	}
+
+	return pruned, nil
+}
@@ -0,0 +1,140 @@
+package kernel
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/cache"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// InventoryConfigMap the kernel inventory is published to in the
+	// operator namespace
+	InventoryConfigMap = "special-resource-kernels"
+	// InventoryKey of the ConfigMap holding the JSON of the Inventory
+	InventoryKey = "kernels.json"
+	// OSVersionLabel NFD label with the VERSION_ID of the os-release
+	OSVersionLabel = "feature.node.kubernetes.io/system-os_release.VERSION_ID"
+)
+
+// Nodes the nodes running a kernel
+type Nodes struct {
+	// OSImages of the nodes, e.g. Red Hat Enterprise Linux CoreOS 48.84...
+	OSImages []string `json:"osImages"`
+	// OSVersions NFD VERSION_ID of the nodes, e.g. 4.8
+	OSVersions    []string `json:"osVersions,omitempty"`
+	Architectures []string `json:"architectures"`
+	Nodes         []string `json:"nodes"`
+}
+
+// Inventory the nodes per kernel version
+type Inventory map[string]Nodes
+
+// NewInventory returns the Inventory of nodes, Windows and other non-Linux
+// nodes are skipped
+func NewInventory(nodes []*v1.Node) Inventory {
+
+	inventory := make(Inventory)
+
+	for _, node := range nodes {
+		info := node.Status.NodeInfo
+		if info.KernelVersion == "" || cache.Unsupported(node.GetLabels(), info.OSImage) {
+			continue
+		}
+		entry := inventory[info.KernelVersion]
+		entry.OSImages = appendUnique(entry.OSImages, info.OSImage)
+		entry.OSVersions = appendUnique(entry.OSVersions, node.GetLabels()[OSVersionLabel])
+		entry.Architectures = appendUnique(entry.Architectures, info.Architecture)
+		entry.Nodes = appendUnique(entry.Nodes, node.GetName())
+		inventory[info.KernelVersion] = entry
+	}
+
+	return inventory
+}
+
+// appendUnique inserts value into the sorted values, empty values are
+// skipped
+func appendUnique(values []string, value string) []string {
+
+	idx := sort.SearchStrings(values, value)
+	if value == "" || (idx < len(values) && values[idx] == value) {
+		return values
+	}
+
+	values = append(values, "")
+	copy(values[idx+1:], values[idx:])
+	values[idx] = value
+	return values
+}
+
+// Kernels returns the sorted kernel versions
+func (i Inventory) Kernels() []string {
+
+	kernels := make([]string, 0, len(i))
+	for kernel := range i {
+		kernels = append(kernels, kernel)
+	}
+	sort.Strings(kernels)
+
+	return kernels
+}
+
+// LoadInventory returns the published Inventory, if none was published yet
+// it is built from the nodes
+func LoadInventory() (Inventory, error) {
+
+	cm, err := clients.GetConfigMap(os.Getenv("OPERATOR_NAMESPACE"), InventoryConfigMap)
+	if err == nil {
+		inventory := make(Inventory)
+		if err := json.Unmarshal([]byte(cm.Data[InventoryKey]), &inventory); err != nil {
+			return nil, errors.Wrap(err, "Invalid kernel inventory ConfigMap")
+		}
+		return inventory, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, errors.Wrap(err, "Cannot get kernel inventory ConfigMap")
+	}
+
+	nodes, err := clients.ListNodes(labels.Everything())
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot list nodes")
+	}
+
+	return NewInventory(nodes), nil
+}
+
+// PublishInventory writes inventory to the ConfigMap, it returns true if
+// the ConfigMap changed
+func PublishInventory(inventory Inventory) (bool, error) {
+
+	data, err := json.MarshalIndent(inventory, "", "  ")
+	if err != nil {
+		return false, errors.Wrap(err, "Cannot marshal kernel inventory")
+	}
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      InventoryConfigMap,
+			Namespace: os.Getenv("OPERATOR_NAMESPACE"),
+		},
+	}
+
+	res, err := controllerutil.CreateOrUpdate(context.TODO(), clients.Interface, cm, func() error {
+		cm.Data = map[string]string{InventoryKey: string(data)}
+		return nil
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "Cannot write kernel inventory ConfigMap")
+	}
+
+	return res != controllerutil.OperationResultNone, nil
+}
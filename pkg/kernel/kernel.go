@@ -113,19 +113,35 @@ func FullVersion() (string, error) {
 	// Assuming all nodes are running the same kernel version,
 	// one could easily add driver-kernel-versions for each node.
 	for _, node := range cache.Node.List.Items {
-		labels := node.GetLabels()
-
-		// We only need to check for the key, the value
-		// is available if the key is there
-		short := "feature.node.kubernetes.io/kernel-version.full"
-		if kernelFullVersion, found = labels[short]; !found {
-			return "", errors.New("Label " + short + " not found is NFD running? Check node labels")
+		if kernelFullVersion, found = NodeFullVersion(node); !found {
+			return "", errors.New("Cannot get the kernel of node " + node.GetName() + ", label " + FullVersionLabel + " and nodeInfo are empty")
 		}
 	}
 
 	return kernelFullVersion, nil
 }
 
+// FullVersionLabel NFD label with the kernel release of the node
+const FullVersionLabel = "feature.node.kubernetes.io/kernel-version.full"
+
+// NodeFullVersion returns the kernel of the NFD label, without NFD e.g. on
+// upstream Kubernetes the one the kubelet reports
+func NodeFullVersion(node unstructured.Unstructured) (string, bool) {
+
+	if kernelFullVersion, found := node.GetLabels()[FullVersionLabel]; found {
+		return kernelFullVersion, true
+	}
+
+	kernelFullVersion, _, _ := unstructured.NestedString(node.Object, "status", "nodeInfo", "kernelVersion")
+	return kernelFullVersion, kernelFullVersion != ""
+}
+
+// IsRealTime returns true for PREEMPT_RT kernels, their release string
+// carries an rt marker e.g. 4.18.0-305.rt7.72.el8.x86_64
+func IsRealTime(kernelFullVersion string) bool {
+	return strings.Contains(kernelFullVersion, ".rt")
+}
+
 // Using w.xx.y-zzz and looking at the fourth file listed /boot/vmlinuz-4.4.0-45 we can say:
 // w = Kernel Version = 4
 // xx= Major Revision = 4
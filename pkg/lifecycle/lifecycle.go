@@ -35,7 +35,7 @@ func GetPodFromDaemonSet(key types.NamespacedName) unstructured.UnstructuredList
 	ds.SetAPIVersion("apps/v1")
 	ds.SetKind("DaemonSet")
 
-	err := clients.Interface.Get(context.TODO(), key, ds)
+	err := clients.Workload().Get(context.TODO(), key, ds)
 	if apierrors.IsNotFound(err) || err != nil {
 		warn.OnError(err)
 		return pl
@@ -57,7 +57,7 @@ func GetPodFromDaemonSet(key types.NamespacedName) unstructured.UnstructuredList
 		client.MatchingLabels(matchLabels),
 	}
 
-	err = clients.Interface.List(context.TODO(), &pl, opts...)
+	err = clients.Workload().List(context.TODO(), &pl, opts...)
 	if err != nil {
 		warn.OnError(err)
 		return pl
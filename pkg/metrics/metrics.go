@@ -1,6 +1,9 @@
 package metrics
 
 import (
+	"sync"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
@@ -9,6 +12,18 @@ import (
 const (
 	specialResourcesCreatedQuery = "sro_managed_resources_total"
 	completedStatesQuery         = "sro_states_completed_info"
+	registryRequestDurationQuery = "sro_registry_request_duration_seconds"
+	registryBytesQuery           = "sro_registry_downloaded_bytes_total"
+	registryErrorsQuery          = "sro_registry_errors_total"
+	registryAuthFailuresQuery    = "sro_registry_auth_failures_total"
+	layerCacheRequestsQuery      = "sro_registry_layer_cache_requests_total"
+	reconcileDurationQuery       = "sro_reconcile_duration_seconds"
+	lastSuccessfulReconcileQuery = "sro_last_successful_reconcile_timestamp_seconds"
+	buildAttemptsQuery           = "sro_build_attempts_total"
+	buildFailuresQuery           = "sro_build_failures_total"
+	driverNodesLoadedQuery       = "sro_driver_nodes_loaded"
+	driverNodesExpectedQuery     = "sro_driver_nodes_expected"
+	kernelNodesQuery             = "sro_kernel_nodes"
 )
 
 var (
@@ -26,6 +41,98 @@ var (
 		},
 		[]string{"specialresource", "state"},
 	)
+	registryRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    registryRequestDurationQuery,
+			Help:    "Latency of registry requests by host and kind (manifest, blob, other).",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 12),
+		},
+		[]string{"host", "kind"},
+	)
+	registryBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: registryBytesQuery,
+			Help: "Bytes downloaded from a registry host.",
+		},
+		[]string{"host"},
+	)
+	registryErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: registryErrorsQuery,
+			Help: "Failed registry requests (network errors, 4xx and 5xx) by host.",
+		},
+		[]string{"host"},
+	)
+	registryAuthFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: registryAuthFailuresQuery,
+			Help: "Registry requests with credentials rejected with 401 or 403 by host.",
+		},
+		[]string{"host"},
+	)
+	layerCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: layerCacheRequestsQuery,
+			Help: "Lookups in the on-disk layer cache, result is hit or miss.",
+		},
+		[]string{"result"},
+	)
+	reconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    reconcileDurationQuery,
+			Help:    "Duration of the chart reconciliation of a specialresource.",
+			Buckets: prometheus.ExponentialBuckets(0.5, 2, 12),
+		},
+		[]string{"specialresource"},
+	)
+	lastSuccessfulReconcile = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: lastSuccessfulReconcileQuery,
+			Help: "Unix time of the last reconcile of a specialresource that completed all states.",
+		},
+		[]string{"specialresource"},
+	)
+	buildAttempts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: buildAttemptsQuery,
+			Help: "Driver container builds started for a specialresource.",
+		},
+		[]string{"specialresource"},
+	)
+	buildFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: buildFailuresQuery,
+			Help: "Driver container builds of a specialresource that did not complete.",
+		},
+		[]string{"specialresource"},
+	)
+	driverNodesLoaded = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: driverNodesLoadedQuery,
+			Help: "Nodes with an available driver container pod, per specialresource and DaemonSet.",
+		},
+		[]string{"specialresource", "daemonset"},
+	)
+	driverNodesExpected = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: driverNodesExpectedQuery,
+			Help: "Nodes that should run the driver container, per specialresource and DaemonSet.",
+		},
+		[]string{"specialresource", "daemonset"},
+	)
+	kernelNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: kernelNodesQuery,
+			Help: "Nodes running a kernel version.",
+		},
+		[]string{"kernel"},
+	)
+)
+
+// builds are polled on every reconcile, only count each build once
+var (
+	buildsMutex   sync.Mutex
+	buildsCounted = make(map[string]bool)
 )
 
 // SetCompletedState set completed states
@@ -43,11 +150,102 @@ func SetSpecialResourcesCreated(value int) {
 	specialResourcesCreated.Set(float64(value))
 }
 
+// ObserveRegistryRequest records the latency of a registry request
+func ObserveRegistryRequest(host string, kind string, seconds float64) {
+	registryRequestDuration.WithLabelValues(host, kind).Observe(seconds)
+}
+
+// AddRegistryBytes adds downloaded bytes for a registry host
+func AddRegistryBytes(host string, bytes int) {
+	registryBytes.WithLabelValues(host).Add(float64(bytes))
+}
+
+// IncRegistryErrors counts a failed request to a registry host
+func IncRegistryErrors(host string) {
+	registryErrors.WithLabelValues(host).Inc()
+}
+
+// IncRegistryAuthFailures counts a request to a registry host whose
+// credentials were rejected
+func IncRegistryAuthFailures(host string) {
+	registryAuthFailures.WithLabelValues(host).Inc()
+}
+
+// IncLayerCache counts a layer cache lookup, hit or miss
+func IncLayerCache(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	layerCacheRequests.WithLabelValues(result).Inc()
+}
+
+// ObserveReconcile records how long the reconciliation of a specialresource took
+func ObserveReconcile(specialResource string, duration time.Duration) {
+	reconcileDuration.WithLabelValues(specialResource).Observe(duration.Seconds())
+}
+
+// SetLastSuccessfulReconcile sets the last success of a specialresource to now
+func SetLastSuccessfulReconcile(specialResource string) {
+	lastSuccessfulReconcile.WithLabelValues(specialResource).SetToCurrentTime()
+}
+
+// IncBuildAttempt counts build of a specialresource, a build is counted once
+func IncBuildAttempt(specialResource string, build string) {
+	if countBuild("attempt/" + build) {
+		buildAttempts.WithLabelValues(specialResource).Inc()
+	}
+}
+
+// IncBuildFailure counts a failed build of a specialresource, a build is counted once
+func IncBuildFailure(specialResource string, build string) {
+	if countBuild("failure/" + build) {
+		buildFailures.WithLabelValues(specialResource).Inc()
+	}
+}
+
+func countBuild(key string) bool {
+	buildsMutex.Lock()
+	defer buildsMutex.Unlock()
+
+	if buildsCounted[key] {
+		return false
+	}
+	buildsCounted[key] = true
+	return true
+}
+
+// SetDriverNodes sets the loaded and expected nodes of a driver container DaemonSet
+func SetDriverNodes(specialResource string, daemonSet string, loaded int64, expected int64) {
+	driverNodesLoaded.WithLabelValues(specialResource, daemonSet).Set(float64(loaded))
+	driverNodesExpected.WithLabelValues(specialResource, daemonSet).Set(float64(expected))
+}
+
+// SetKernelNodes sets the nodes per kernel, kernels not listed are removed
+func SetKernelNodes(nodes map[string]int) {
+	kernelNodes.Reset()
+	for kernel, count := range nodes {
+		kernelNodes.WithLabelValues(kernel).Set(float64(count))
+	}
+}
+
 func init() {
 	// Register custom metrics with the global prometheus registry
 	metrics.Registry.MustRegister(
 		specialResourcesCreated,
 		completedStates,
+		registryRequestDuration,
+		registryBytes,
+		registryErrors,
+		registryAuthFailures,
+		layerCacheRequests,
+		reconcileDuration,
+		lastSuccessfulReconcile,
+		buildAttempts,
+		buildFailures,
+		driverNodesLoaded,
+		driverNodesExpected,
+		kernelNodes,
 	)
 
 }
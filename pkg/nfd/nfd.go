@@ -0,0 +1,144 @@
+package nfd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/slice"
+	"github.com/pkg/errors"
+)
+
+// LabelPrefix of the node labels created by Node Feature Discovery
+const LabelPrefix = "feature.node.kubernetes.io/"
+
+// Features requested by a SpecialResource, see SpecialResourceNodeFeatures
+type Features struct {
+	Required []string
+	PCI      []string
+	USB      []string
+}
+
+// Empty is true if no features are requested
+func (f Features) Empty() bool {
+	return len(f.Required) == 0 && len(f.PCI) == 0 && len(f.USB) == 0
+}
+
+// Selector returns the node labels of the requested features, the labels
+// of the PCI and USB devices are looked up on nodes. A missing label is
+// returned as error, NFD may not have labeled the nodes yet.
+func Selector(features Features, nodes []map[string]string) (map[string]string, error) {
+
+	selector := make(map[string]string)
+
+	for _, required := range features.Required {
+		key, value := ParseRequired(required)
+		selector[key] = value
+	}
+
+	for _, device := range features.PCI {
+		key, err := deviceLabel("pci", device, nodes)
+		if err != nil {
+			return nil, err
+		}
+		selector[key] = "true"
+	}
+
+	for _, device := range features.USB {
+		key, err := deviceLabel("usb", device, nodes)
+		if err != nil {
+			return nil, err
+		}
+		selector[key] = "true"
+	}
+
+	for _, node := range nodes {
+		if matches(selector, node) {
+			return selector, nil
+		}
+	}
+
+	return nil, errors.New("No node has the labels " + format(selector))
+}
+
+// ParseRequired splits key=value, the value defaults to true and the NFD
+// prefix is added to keys without one.
+func ParseRequired(required string) (string, string) {
+
+	key, value := required, "true"
+	if idx := strings.Index(required, "="); idx >= 0 {
+		key, value = required[:idx], required[idx+1:]
+	}
+
+	if !strings.Contains(key, "/") {
+		key = LabelPrefix + key
+	}
+
+	return key, value
+}
+
+// deviceLabel finds the <bus>-<id>.present label of device, NFD builds the
+// id from the configured fields e.g. class_vendor, the fields of device all
+// have to be part of it.
+func deviceLabel(bus string, device string, nodes []map[string]string) (string, error) {
+
+	prefix := LabelPrefix + bus + "-"
+	fields := strings.Split(strings.ToLower(device), "_")
+
+	found := make(map[string]bool)
+
+	for _, node := range nodes {
+		for key, value := range node {
+			if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, ".present") || value != "true" {
+				continue
+			}
+			id := strings.Split(strings.TrimSuffix(strings.TrimPrefix(key, prefix), ".present"), "_")
+			if containsAll(id, fields) {
+				found[key] = true
+			}
+		}
+	}
+
+	keys := []string{}
+	for key := range found {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	switch len(keys) {
+	case 0:
+		return "", errors.New("No node has a " + bus + " device " + device)
+	case 1:
+		return keys[0], nil
+	}
+
+	return "", errors.New(bus + " device " + device + " matches several labels, use a more specific id or a required label: " + strings.Join(keys, ", "))
+}
+
+func containsAll(id []string, fields []string) bool {
+	for _, field := range fields {
+		if !slice.Contains(id, field) {
+			return false
+		}
+	}
+	return true
+}
+
+func matches(selector map[string]string, labels map[string]string) bool {
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func format(selector map[string]string) string {
+
+	terms := []string{}
+	for key, value := range selector {
+		terms = append(terms, key+"="+value)
+	}
+	sort.Strings(terms)
+
+	return strings.Join(terms, ", ")
+}
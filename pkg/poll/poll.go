@@ -17,12 +17,14 @@ import (
 	"github.com/openshift-psap/special-resource-operator/pkg/exit"
 	"github.com/openshift-psap/special-resource-operator/pkg/hash"
 	"github.com/openshift-psap/special-resource-operator/pkg/lifecycle"
+	"github.com/openshift-psap/special-resource-operator/pkg/metrics"
 	"github.com/openshift-psap/special-resource-operator/pkg/storage"
 	"github.com/openshift-psap/special-resource-operator/pkg/warn"
 
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -33,6 +35,8 @@ import (
 var (
 	RetryInterval = time.Second * 5
 	Timeout       = time.Second * 30
+	// BuildLogLimit bytes of the log tail kept of a failed build
+	BuildLogLimit = 4096
 	log           logr.Logger
 )
 
@@ -48,6 +52,7 @@ func init() {
 	waitFor["Pod"] = ForPod
 	waitFor["DaemonSet"] = ForDaemonSet
 	waitFor["BuildConfig"] = ForBuild
+	waitFor["BuildRun"] = ForBuildRun
 	waitFor["Secret"] = ForSecret
 	waitFor["CustomResourceDefinition"] = ForCRD
 	waitFor["Job"] = ForJob
@@ -63,37 +68,25 @@ type statusCallback func(obj *unstructured.Unstructured) bool
 
 func ForResourceAvailability(obj *unstructured.Unstructured) error {
 
-	found := obj.DeepCopy()
-	err := wait.Poll(RetryInterval, Timeout, func() (done bool, err error) {
-		err = clients.Interface.Get(context.TODO(), types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, found)
-		if err != nil {
-			if apierrors.IsNotFound(err) {
-				log.Info("Waiting for creation of ", "Namespace", obj.GetNamespace(), "Name", obj.GetName())
-				return false, nil
-			}
-			return false, err
+	return until(obj, func(found *unstructured.Unstructured) (bool, error) {
+		if found == nil {
+			log.Info("Waiting for creation of ", "Namespace", obj.GetNamespace(), "Name", obj.GetName())
+			return false, nil
 		}
 		return true, nil
 	})
-	return err
 }
 
 func ForResourceUnavailability(obj *unstructured.Unstructured) error {
 
-	found := obj.DeepCopy()
-	err := wait.Poll(RetryInterval, Timeout, func() (done bool, err error) {
-		err = clients.Interface.Get(context.TODO(), types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, found)
-		if err != nil {
-			if apierrors.IsNotFound(err) {
-				log.Info("Waiting done for deletion of ", "Namespace", obj.GetNamespace(), "Name", obj.GetName())
-				return true, nil
-			}
-			return true, err
+	return until(obj, func(found *unstructured.Unstructured) (bool, error) {
+		if found == nil {
+			log.Info("Waiting done for deletion of ", "Namespace", obj.GetNamespace(), "Name", obj.GetName())
+			return true, nil
 		}
 		log.Info("Waiting for deletion of ", "Namespace", obj.GetNamespace(), "Name", obj.GetName())
 		return false, nil
 	})
-	return err
 }
 
 // makeStatusCallback Closure capturing json path and expected status
@@ -162,13 +155,13 @@ func ForSecret(obj *unstructured.Unstructured) error {
 
 func ForCRD(obj *unstructured.Unstructured) error {
 
-	clients.Interface.Invalidate()
+	clients.Workload().Invalidate()
 	// Lets wait some time for the API server to register the new CRD
 	if err := ForResourceAvailability(obj); err != nil {
 		return err
 	}
 
-	_, err := clients.Interface.ServerGroups()
+	_, err := clients.Workload().ServerGroups()
 	warn.OnError(err)
 
 	return nil
@@ -208,7 +201,7 @@ func ForDeployment(obj *unstructured.Unstructured) error {
 		rss.SetKind("ReplicaSetList")
 		rss.SetAPIVersion("apps/v1")
 
-		err = clients.Interface.List(context.TODO(), &rss, opts...)
+		err = clients.Workload().List(context.TODO(), &rss, opts...)
 		if err != nil {
 			log.Info("Could not get ReplicaSet", "Deployment", obj.GetName(), "error", err)
 			return false
@@ -282,37 +275,75 @@ func ForStatefulSet(obj *unstructured.Unstructured) error {
 	})
 }
 
+// JobFailedError is returned if a Job failed, it reached its backoff limit
+// or active deadline
+type JobFailedError struct {
+	Namespace string
+	Name      string
+	Message   string
+	LogTail   string
+}
+
+func (e *JobFailedError) Error() string {
+	msg := "Job " + e.Namespace + "/" + e.Name + " failed"
+	if e.Message != "" {
+		msg = msg + ": " + e.Message
+	}
+	return msg
+}
+
+// ForJob waits for the Job to complete, failed Jobs are reported right away
+// with the tail of the log of the last pod.
 func ForJob(obj *unstructured.Unstructured) error {
 	if err := ForResourceAvailability(obj); err != nil {
 		return err
 	}
 
-	return ForResourceFullAvailability(obj, func(obj *unstructured.Unstructured) bool {
-
-		conditions, found, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
-		warn.OnError(err)
-
-		if !found {
-			return false
+	return until(obj, func(found *unstructured.Unstructured) (bool, error) {
+		if found == nil {
+			return false, apierrors.NewNotFound(v1.Resource("jobs"), obj.GetName())
 		}
 
-		for _, condition := range conditions {
-
-			status, found, err := unstructured.NestedString(condition.(map[string]interface{}), "status")
-			exit.OnErrorOrNotFound(found, err)
+		conditions, _, err := unstructured.NestedSlice(found.Object, "status", "conditions")
+		warn.OnError(err)
 
-			if status == "True" {
-				stype, found, err := unstructured.NestedString(condition.(map[string]interface{}), "type")
-				exit.OnErrorOrNotFound(found, err)
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok || condition["status"] != "True" {
+				continue
+			}
 
-				if stype == "Complete" {
-					return true
+			switch condition["type"] {
+			case "Complete":
+				log.Info("Resource available ", "Kind", "Job: "+obj.GetNamespace()+"/"+obj.GetName())
+				return true, nil
+			case "Failed":
+				message, _ := condition["message"].(string)
+				return false, &JobFailedError{
+					Namespace: obj.GetNamespace(),
+					Name:      obj.GetName(),
+					Message:   message,
+					LogTail:   jobLogTail(obj),
 				}
 			}
-
 		}
-		return false
+
+		log.Info("Waiting for availability of ", "Kind", "Job: "+obj.GetNamespace()+"/"+obj.GetName())
+		return false, nil
+	})
+}
+
+// jobLogTail returns the log tail of the last pod of the Job
+func jobLogTail(obj *unstructured.Unstructured) string {
+
+	pods, err := clients.Workload().CoreV1().Pods(obj.GetNamespace()).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: "job-name=" + obj.GetName(),
 	})
+	if err != nil || len(pods.Items) == 0 {
+		return ""
+	}
+
+	return PodLogTail(obj.GetNamespace(), pods.Items[len(pods.Items)-1].GetName())
 }
 
 func ForDaemonSetCallback(obj *unstructured.Unstructured) bool {
@@ -327,16 +358,27 @@ func ForDaemonSetCallback(obj *unstructured.Unstructured) bool {
 	cache.Node.Count, found, err = unstructured.NestedInt64(obj.Object, "status", "desiredNumberScheduled")
 	exit.OnErrorOrNotFound(found, err)
 
+	// No node selected, e.g. the kernel of a release no node runs yet
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if cache.Node.Count == 0 && observed >= obj.GetGeneration() {
+		return true
+	}
+
 	_, found, _ = unstructured.NestedInt64(obj.Object, "status", "numberUnavailable")
 	if found {
 		callback = makeStatusCallback(obj, 0, "status", "numberUnavailable")
 	}
 
-	_, found, _ = unstructured.NestedInt64(obj.Object, "status", "numberAvailable")
+	available, found, _ := unstructured.NestedInt64(obj.Object, "status", "numberAvailable")
 	if found {
 		callback = makeStatusCallback(obj, cache.Node.Count, "status", "numberAvailable")
 	}
 
+	if strings.Contains(obj.GetName(), "driver-container") {
+		sr := obj.GetAnnotations()["meta.helm.sh/release-name"]
+		metrics.SetDriverNodes(sr, obj.GetName(), available, cache.Node.Count)
+	}
+
 	return callback(obj)
 
 }
@@ -400,6 +442,26 @@ func ForDaemonSet(obj *unstructured.Unstructured) error {
 	return ForResourceFullAvailability(obj, ForDaemonSetCallback)
 }
 
+// BuildFailedError is returned if a build failed, Kind and Name identify
+// the object that has to be recreated to build again.
+type BuildFailedError struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+	Build      string
+	Message    string
+	LogTail    string
+}
+
+func (e *BuildFailedError) Error() string {
+	msg := e.Kind + " " + e.Namespace + "/" + e.Name + " build " + e.Build + " failed"
+	if e.Message != "" {
+		msg = msg + ": " + e.Message
+	}
+	return msg
+}
+
 func ForBuild(obj *unstructured.Unstructured) error {
 
 	if err := ForResourceAvailability(obj); err != nil {
@@ -412,14 +474,20 @@ func ForBuild(obj *unstructured.Unstructured) error {
 
 	opts := []client.ListOption{
 		client.InNamespace(clients.Namespace),
+		client.MatchingLabels{"openshift.io/build-config.name": obj.GetName()},
 	}
-	if err := clients.Interface.List(context.TODO(), builds, opts...); err != nil {
+	if err := clients.Workload().List(context.TODO(), builds, opts...); err != nil {
 		return errors.Wrap(err, "Could not get BuildList")
 	}
 
+	sr := obj.GetAnnotations()["meta.helm.sh/release-name"]
+
 	for _, build := range builds.Items {
-		callback := makeStatusCallback(&build, "Complete", "status", "phase")
-		if err := ForResourceFullAvailability(&build, callback); err != nil {
+		key := build.GetNamespace() + "/" + build.GetName()
+		metrics.IncBuildAttempt(sr, key)
+
+		if err := forBuildPhase(obj, &build); err != nil {
+			metrics.IncBuildFailure(sr, key)
 			return err
 		}
 	}
@@ -427,13 +495,171 @@ func ForBuild(obj *unstructured.Unstructured) error {
 	return nil
 }
 
-func ForResourceFullAvailability(obj *unstructured.Unstructured, callback statusCallback) error {
+// ActiveBuild returns namespace/name of a build of the cluster that is not
+// finished yet, empty if no build is running
+func ActiveBuild() (string, error) {
+
+	builds := &unstructured.UnstructuredList{}
+	builds.SetAPIVersion("build.openshift.io/v1")
+	builds.SetKind("BuildList")
+
+	if err := clients.Workload().List(context.TODO(), builds); err != nil {
+		return "", errors.Wrap(err, "Could not get BuildList")
+	}
+
+	for _, build := range builds.Items {
+		phase, _, _ := unstructured.NestedString(build.Object, "status", "phase")
+		switch phase {
+		case "", "New", "Pending", "Running":
+			return build.GetNamespace() + "/" + build.GetName(), nil
+		}
+	}
+
+	return "", nil
+}
 
-	found := obj.DeepCopy()
+// forBuildPhase waits for the build to complete, failed builds are reported
+// right away with the tail of the build pod log.
+func forBuildPhase(obj *unstructured.Unstructured, build *unstructured.Unstructured) error {
 
-	if err := wait.Poll(RetryInterval, Timeout, func() (done bool, err error) {
-		err = clients.Interface.Get(context.TODO(), types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, found)
+	return until(build, func(found *unstructured.Unstructured) (bool, error) {
+		if found == nil {
+			return false, apierrors.NewNotFound(v1.Resource("builds"), build.GetName())
+		}
+
+		phase, _, _ := unstructured.NestedString(found.Object, "status", "phase")
+		switch phase {
+		case "Complete":
+			log.Info("Resource available ", "Kind", "Build: "+build.GetNamespace()+"/"+build.GetName())
+			return true, nil
+		case "Failed", "Error", "Cancelled":
+			message, _, _ := unstructured.NestedString(found.Object, "status", "message")
+			pod := found.GetAnnotations()["openshift.io/build.pod-name"]
+			return false, &BuildFailedError{
+				APIVersion: obj.GetAPIVersion(),
+				Kind:       obj.GetKind(),
+				Namespace:  obj.GetNamespace(),
+				Name:       obj.GetName(),
+				Build:      build.GetName(),
+				Message:    message,
+				LogTail:    PodLogTail(build.GetNamespace(), pod),
+			}
+		}
+
+		log.Info("Waiting for availability of ", "Kind", "Build: "+build.GetNamespace()+"/"+build.GetName())
+		return false, nil
+	})
+}
+
+// PodLogTail returns the last lines of every container of pod, errors are
+// part of the returned text since the tail is only informational.
+func PodLogTail(namespace string, name string) string {
+
+	if name == "" {
+		return ""
+	}
+
+	pod, err := clients.Workload().CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return "Cannot get build pod " + namespace + "/" + name + ": " + err.Error()
+	}
+
+	lines := int64(500)
+	tail := ""
+
+	for _, container := range pod.Spec.Containers {
+		opts := v1.PodLogOptions{Container: container.Name, TailLines: &lines}
+		raw, err := clients.Workload().CoreV1().Pods(namespace).GetLogs(name, &opts).DoRaw(context.TODO())
 		if err != nil {
+			raw = []byte(err.Error())
+		}
+		tail = tail + "==> " + name + "/" + container.Name + " <==\n" + string(raw)
+	}
+
+	// Keep the status small, the beginning of the tail is the least useful
+	if cutoff := BuildLogLimit; len(tail) > cutoff {
+		tail = tail[len(tail)-cutoff:]
+	}
+
+	return tail
+}
+
+// ForBuildRun waits for the Succeeded condition of a Shipwright BuildRun, a
+// failed run is reported right away instead of waiting for the timeout.
+func ForBuildRun(obj *unstructured.Unstructured) error {
+
+	if err := ForResourceAvailability(obj); err != nil {
+		return err
+	}
+
+	sr := obj.GetAnnotations()["meta.helm.sh/release-name"]
+	key := obj.GetNamespace() + "/" + obj.GetName()
+	metrics.IncBuildAttempt(sr, key)
+
+	err := until(obj, func(found *unstructured.Unstructured) (bool, error) {
+		if found == nil {
+			return false, apierrors.NewNotFound(v1.Resource("buildruns"), obj.GetName())
+		}
+
+		conditions, _, _ := unstructured.NestedSlice(found.Object, "status", "conditions")
+		for _, condition := range conditions {
+			condition, ok := condition.(map[string]interface{})
+			if !ok || condition["type"] != "Succeeded" {
+				continue
+			}
+			switch condition["status"] {
+			case "True":
+				log.Info("Resource available ", "Kind", "BuildRun: "+key)
+				return true, nil
+			case "False":
+				message, _, _ := unstructured.NestedString(condition, "message")
+				// The BuildRun keeps its name when recreated, the TaskRun
+				// identifies the run
+				run, _, _ := unstructured.NestedString(found.Object, "status", "latestTaskRunRef")
+				if run == "" {
+					run = obj.GetName()
+				}
+				return false, &BuildFailedError{
+					APIVersion: obj.GetAPIVersion(),
+					Kind:       obj.GetKind(),
+					Namespace:  obj.GetNamespace(),
+					Name:       obj.GetName(),
+					Build:      run,
+					Message:    message,
+					LogTail:    buildRunLogTail(found),
+				}
+			}
+		}
+
+		log.Info("Waiting for availability of ", "Kind", "BuildRun: "+key)
+		return false, nil
+	})
+
+	if err != nil {
+		metrics.IncBuildFailure(sr, key)
+	}
+
+	return err
+}
+
+// buildRunLogTail returns the log tail of the pod the BuildRun ran in
+func buildRunLogTail(obj *unstructured.Unstructured) string {
+
+	pods, err := clients.Workload().CoreV1().Pods(obj.GetNamespace()).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: "buildrun.shipwright.io/name=" + obj.GetName(),
+	})
+	if err != nil || len(pods.Items) == 0 {
+		return ""
+	}
+
+	return PodLogTail(obj.GetNamespace(), pods.Items[len(pods.Items)-1].GetName())
+}
+
+func ForResourceFullAvailability(obj *unstructured.Unstructured, callback statusCallback) error {
+
+	return until(obj, func(found *unstructured.Unstructured) (bool, error) {
+		if found == nil {
+			err := apierrors.NewNotFound(v1.Resource(strings.ToLower(obj.GetKind())), obj.GetName())
 			log.Error(err, "")
 			return false, err
 		}
@@ -443,10 +669,7 @@ func ForResourceFullAvailability(obj *unstructured.Unstructured, callback status
 		}
 		log.Info("Waiting for availability of ", "Kind", obj.GetKind()+": "+obj.GetNamespace()+"/"+obj.GetName())
 		return false, nil
-	}); err != nil {
-		return err
-	}
-	return nil
+	})
 }
 
 func ForDaemonSetLogs(obj *unstructured.Unstructured, pattern string) error {
@@ -474,7 +697,7 @@ func ForDaemonSetLogs(obj *unstructured.Unstructured, pattern string) error {
 		client.MatchingLabels(label),
 	}
 
-	err := clients.Interface.List(context.TODO(), pods, opts...)
+	err := clients.Workload().List(context.TODO(), pods, opts...)
 	if err != nil {
 		return errors.Wrap(err, "Could not get PodList")
 	}
@@ -482,7 +705,7 @@ func ForDaemonSetLogs(obj *unstructured.Unstructured, pattern string) error {
 	for _, pod := range pods.Items {
 		log.Info("WaitForDaemonSetLogs", "Pod", pod.GetName())
 		podLogOpts := v1.PodLogOptions{}
-		req := clients.Interface.CoreV1().Pods(pod.GetNamespace()).GetLogs(pod.GetName(), &podLogOpts)
+		req := clients.Workload().CoreV1().Pods(pod.GetNamespace()).GetLogs(pod.GetName(), &podLogOpts)
 		podLogs, err := req.Stream(context.TODO())
 		if err != nil {
 			return errors.Wrap(err, "Error in opening stream")
@@ -0,0 +1,125 @@
+package poll
+
+import (
+	"context"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Context cancels all waits, the operator sets it to the context of the
+// manager so waits end on shutdown
+var Context = context.Background()
+
+// condition is called with the current state of the watched object, nil
+// if it does not exist, until it returns true or an error
+type condition func(found *unstructured.Unstructured) (bool, error)
+
+// until waits up to Timeout for done, the object is watched instead of
+// polled. Watches closed by the API server are resumed with a new list.
+func until(obj *unstructured.Unstructured, done condition) error {
+
+	ctx, cancel := context.WithTimeout(Context, Timeout)
+	defer cancel()
+
+	watcher := clients.Workload().Watcher
+	if watcher == nil {
+		return untilPolled(ctx, obj, done)
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetAPIVersion(obj.GetAPIVersion())
+	list.SetKind(obj.GetKind() + "List")
+
+	opts := []client.ListOption{
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{"metadata.name": obj.GetName()},
+	}
+
+	for {
+		if err := watcher.List(ctx, list, opts...); err != nil {
+			return errors.Wrap(err, "Cannot list "+obj.GetKind()+" "+obj.GetName())
+		}
+
+		var found *unstructured.Unstructured
+		if len(list.Items) > 0 {
+			found = &list.Items[0]
+		}
+
+		if ok, err := done(found); ok || err != nil {
+			return err
+		}
+
+		raw := &client.ListOptions{Raw: &metav1.ListOptions{ResourceVersion: list.GetResourceVersion()}}
+
+		w, err := watcher.Watch(ctx, list, append(opts, raw)...)
+		if err != nil {
+			return errors.Wrap(err, "Cannot watch "+obj.GetKind()+" "+obj.GetName())
+		}
+
+		ok, err := untilEvent(ctx, w, done)
+		w.Stop()
+		if ok || err != nil {
+			return err
+		}
+	}
+}
+
+// untilEvent calls done for every event of w, false without error if the
+// watch ended and has to be restarted
+func untilEvent(ctx context.Context, w watch.Interface, done condition) (bool, error) {
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false, errors.Wrap(ctx.Err(), "Waiting aborted")
+
+		case event, open := <-w.ResultChan():
+			if !open {
+				return false, nil
+			}
+
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				found, ok := event.Object.(*unstructured.Unstructured)
+				if !ok {
+					continue
+				}
+				if ok, err := done(found); ok || err != nil {
+					return ok, err
+				}
+			case watch.Deleted:
+				if ok, err := done(nil); ok || err != nil {
+					return ok, err
+				}
+			case watch.Error:
+				// e.g. the resourceVersion is too old, list again
+				return false, nil
+			}
+		}
+	}
+}
+
+// untilPolled is used if no watching client is configured
+func untilPolled(ctx context.Context, obj *unstructured.Unstructured, done condition) error {
+
+	found := obj.DeepCopy()
+
+	return wait.PollImmediateUntil(RetryInterval, func() (bool, error) {
+		err := clients.Workload().Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, found)
+		if apierrors.IsNotFound(err) {
+			return done(nil)
+		}
+		if err != nil {
+			return false, err
+		}
+		return done(found)
+	}, ctx.Done())
+}
@@ -2,12 +2,14 @@ package proxy
 
 import (
 	"context"
+	"net"
+	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/go-logr/logr"
 	"github.com/openshift-psap/special-resource-operator/pkg/clients"
 	"github.com/openshift-psap/special-resource-operator/pkg/color"
-	"github.com/openshift-psap/special-resource-operator/pkg/exit"
 	"github.com/openshift-psap/special-resource-operator/pkg/warn"
 	configv1 "github.com/openshift/api/config/v1"
 	"github.com/pkg/errors"
@@ -33,89 +35,240 @@ type Configuration struct {
 	TrustedCA  string
 }
 
+// TrustedCAConfigMap the ConfigMap the cluster network operator injects the
+// trusted CA bundle of the cluster proxy into, SRO creates it in the
+// namespaces the charts are applied to
+const TrustedCAConfigMap = "special-resource-trusted-ca"
+
+// trustedCADir is where RHEL based images look for the CA bundle
+const trustedCADir = "/etc/pki/ca-trust/extracted/pem"
+
+// Enabled returns true if the cluster has a proxy or trusted CA configured
+func (c Configuration) Enabled() bool {
+	return c.HttpProxy != "" || c.HttpsProxy != "" || c.NoProxy != "" || c.TrustedCA != ""
+}
+
+// Setup injects the cluster proxy into the containers of Pods, DaemonSets
+// and Jobs and into the builds of BuildConfigs. Settings the object already
+// has are left alone.
 func Setup(obj *unstructured.Unstructured) error {
 
-	if strings.Compare(obj.GetKind(), "Pod") == 0 {
+	if !ProxyConfiguration.Enabled() {
+		return nil
+	}
+
+	switch obj.GetKind() {
+	case "Pod":
 		if err := SetupPod(obj); err != nil {
 			return errors.Wrap(err, "Cannot setup Pod Proxy")
 		}
-	}
-	if strings.Compare(obj.GetKind(), "DaemonSet") == 0 {
+	case "DaemonSet":
 		if err := SetupDaemonSet(obj); err != nil {
 			return errors.Wrap(err, "Cannot setup DaemonSet Proxy")
 		}
-
+	case "Job":
+		if err := setupPodSpec(obj.Object, "spec", "template", "spec"); err != nil {
+			return errors.Wrap(err, "Cannot setup Job Proxy")
+		}
+	case "BuildConfig":
+		if err := SetupBuildConfig(obj); err != nil {
+			return errors.Wrap(err, "Cannot setup BuildConfig Proxy")
+		}
 	}
 
 	return nil
 }
 
-// We may generalize more depending on how many entities need proxy settings.
-// path... -> Pod, DaemonSet, BuildConfig, etc.
 func SetupDaemonSet(obj *unstructured.Unstructured) error {
-	containers, found, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
-	exit.OnErrorOrNotFound(found, err)
+	return setupPodSpec(obj.Object, "spec", "template", "spec")
+}
+
+func SetupPod(obj *unstructured.Unstructured) error {
+	return setupPodSpec(obj.Object, "spec")
+}
+
+// SetupBuildConfig passes the proxy to the build and the git clone, the
+// trusted CA is mounted by the build controller
+func SetupBuildConfig(obj *unstructured.Unstructured) error {
+
+	if _, found, _ := unstructured.NestedMap(obj.Object, "spec", "source", "git"); found {
+		for field, value := range map[string]string{
+			"httpProxy":  ProxyConfiguration.HttpProxy,
+			"httpsProxy": ProxyConfiguration.HttpsProxy,
+			"noProxy":    ProxyConfiguration.NoProxy,
+		} {
+			if err := setDefaultString(obj.Object, value, "spec", "source", "git", field); err != nil {
+				return err
+			}
+		}
+	}
 
-	if err := setupContainersProxy(containers); err != nil {
-		return errors.Wrap(err, "Cannot set proxy for Pod")
+	if _, found, _ := unstructured.NestedMap(obj.Object, "spec", "strategy", "dockerStrategy"); found {
+		// HTTP(S)_PROXY and NO_PROXY are predefined build args, RUN steps
+		// see them without an ARG in the Dockerfile
+		if err := setupEnv(obj.Object, "spec", "strategy", "dockerStrategy", "buildArgs"); err != nil {
+			return err
+		}
+	}
+
+	if _, found, _ := unstructured.NestedMap(obj.Object, "spec", "strategy", "sourceStrategy"); found {
+		if err := setupEnv(obj.Object, "spec", "strategy", "sourceStrategy", "env"); err != nil {
+			return err
+		}
+	}
+
+	if ProxyConfiguration.TrustedCA != "" {
+		if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "mountTrustedCA"); !found {
+			if err := unstructured.SetNestedField(obj.Object, true, "spec", "mountTrustedCA"); err != nil {
+				return errors.Wrap(err, "Cannot set mountTrustedCA")
+			}
+		}
 	}
 
 	return nil
 }
 
-func SetupPod(obj *unstructured.Unstructured) error {
+// setupPodSpec injects the proxy into all containers of the pod spec at path
+// and mounts the trusted CA bundle
+func setupPodSpec(obj map[string]interface{}, path ...string) error {
+
+	podSpec, found, err := unstructured.NestedMap(obj, path...)
+	if err != nil {
+		return errors.Wrap(err, "Cannot get pod spec")
+	}
+	if !found {
+		return errors.New("Pod spec not found: " + strings.Join(path, "."))
+	}
+
+	for _, field := range []string{"initContainers", "containers"} {
 
-	containers, found, err := unstructured.NestedSlice(obj.Object, "spec", "containers")
-	exit.OnErrorOrNotFound(found, err)
+		containers, found, err := unstructured.NestedSlice(podSpec, field)
+		if err != nil {
+			return errors.Wrap(err, "Cannot get "+field)
+		}
+		if !found {
+			continue
+		}
 
-	if err := setupContainersProxy(containers); err != nil {
-		return errors.Wrap(err, "Cannot set proxy for Pod")
+		for _, container := range containers {
+			container, ok := container.(map[string]interface{})
+			if !ok {
+				return errors.New("Unexpected type of container in " + field)
+			}
+			if err := setupEnv(container, "env"); err != nil {
+				return err
+			}
+			if err := mountTrustedCA(container); err != nil {
+				return err
+			}
+		}
+
+		if err := unstructured.SetNestedSlice(podSpec, containers, field); err != nil {
+			return errors.Wrap(err, "Cannot set "+field)
+		}
 	}
 
-	return nil
+	if ProxyConfiguration.TrustedCA != "" {
+		volume := map[string]interface{}{
+			"name": TrustedCAConfigMap,
+			"configMap": map[string]interface{}{
+				"name":     TrustedCAConfigMap,
+				"optional": true,
+				"items": []interface{}{
+					map[string]interface{}{"key": "ca-bundle.crt", "path": "tls-ca-bundle.pem"},
+				},
+			},
+		}
+		if err := appendNamed(podSpec, volume, "volumes"); err != nil {
+			return err
+		}
+	}
+
+	return unstructured.SetNestedMap(obj, podSpec, path...)
 }
 
-func setupContainersProxy(containers []interface{}) error {
+// setupEnv adds the proxy variables to the env list at path, variables
+// already set are not overridden
+func setupEnv(obj map[string]interface{}, path ...string) error {
 
-	for _, container := range containers {
-		switch container := container.(type) {
-		case map[string]interface{}:
-			env, found, err := unstructured.NestedSlice(container, "env")
-			exit.OnError(err)
+	env := [][2]string{
+		{"HTTP_PROXY", ProxyConfiguration.HttpProxy},
+		{"HTTPS_PROXY", ProxyConfiguration.HttpsProxy},
+		{"NO_PROXY", ProxyConfiguration.NoProxy},
+	}
 
-			// If env not found we are creating a new env slice
-			// otherwise we're appending it to the existing env slice
-			httpproxy := make(map[string]interface{})
-			httpsproxy := make(map[string]interface{})
-			noproxy := make(map[string]interface{})
+	for _, variable := range env {
+		if variable[1] == "" {
+			continue
+		}
+		if err := appendNamed(obj, map[string]interface{}{"name": variable[0], "value": variable[1]}, path...); err != nil {
+			return err
+		}
+	}
 
-			httpproxy["name"] = "HTTP_PROXY"
-			httpproxy["value"] = ProxyConfiguration.HttpProxy
+	return nil
+}
 
-			httpsproxy["name"] = "HTTPS_PROXY"
-			httpsproxy["value"] = ProxyConfiguration.HttpsProxy
+func mountTrustedCA(container map[string]interface{}) error {
 
-			noproxy["name"] = "NO_PROXY"
-			noproxy["value"] = ProxyConfiguration.NoProxy
+	if ProxyConfiguration.TrustedCA == "" {
+		return nil
+	}
 
-			if !found {
-				env = make([]interface{}, 0)
-			}
+	mount := map[string]interface{}{
+		"name":      TrustedCAConfigMap,
+		"mountPath": trustedCADir,
+		"readOnly":  true,
+	}
 
-			env = append(env, httpproxy)
-			env = append(env, httpsproxy)
-			env = append(env, noproxy)
+	// A chart mounting its own bundle keeps it
+	mounts, _, err := unstructured.NestedSlice(container, "volumeMounts")
+	if err != nil {
+		return errors.Wrap(err, "Cannot get volumeMounts")
+	}
+	for _, m := range mounts {
+		if m, ok := m.(map[string]interface{}); ok && m["mountPath"] == trustedCADir {
+			return nil
+		}
+	}
 
-			if err := unstructured.SetNestedSlice(container, env, "env"); err != nil {
-				return errors.Wrap(err, "Cannot set env for container")
-			}
+	return appendNamed(container, mount, "volumeMounts")
+}
+
+// appendNamed appends item to the list at path unless an item with the
+// same name is already in it
+func appendNamed(obj map[string]interface{}, item map[string]interface{}, path ...string) error {
+
+	list, _, err := unstructured.NestedSlice(obj, path...)
+	if err != nil {
+		return errors.Wrap(err, "Cannot get "+strings.Join(path, "."))
+	}
 
-		default:
-			log.Info("container", "DEFAULT NOT THE CORRECT TYPE", container)
+	for _, existing := range list {
+		if existing, ok := existing.(map[string]interface{}); ok && existing["name"] == item["name"] {
+			return nil
 		}
-		break
 	}
 
+	list = append(list, item)
+
+	if err := unstructured.SetNestedSlice(obj, list, path...); err != nil {
+		return errors.Wrap(err, "Cannot set "+strings.Join(path, "."))
+	}
+	return nil
+}
+
+func setDefaultString(obj map[string]interface{}, value string, path ...string) error {
+
+	if value == "" {
+		return nil
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(obj, path...); found {
+		return nil
+	}
+	if err := unstructured.SetNestedField(obj, value, path...); err != nil {
+		return errors.Wrap(err, "Cannot set "+strings.Join(path, "."))
+	}
 	return nil
 }
 
@@ -175,3 +328,54 @@ func ClusterConfiguration() (Configuration, error) {
 
 	return *proxy, nil
 }
+
+// ProxyFunc returns a function usable as http.Transport.Proxy that honors
+// the HTTP(S)_PROXY and NO_PROXY settings of the configuration.
+func (c Configuration) ProxyFunc() func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+
+		proxy := c.HttpProxy
+		if req.URL.Scheme == "https" {
+			proxy = c.HttpsProxy
+		}
+
+		if proxy == "" || c.excluded(req.URL.Hostname()) {
+			return nil, nil
+		}
+
+		if !strings.Contains(proxy, "://") {
+			proxy = "http://" + proxy
+		}
+
+		return url.Parse(proxy)
+	}
+}
+
+// excluded implements the usual NO_PROXY semantics: "*", exact hosts,
+// domain suffixes with or without leading dot, IPs and CIDRs.
+func (c Configuration) excluded(host string) bool {
+
+	ip := net.ParseIP(host)
+
+	for _, entry := range strings.Split(c.NoProxy, ",") {
+
+		entry = strings.TrimSpace(entry)
+
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		case ip != nil && strings.Contains(entry, "/"):
+			if _, cidr, err := net.ParseCIDR(entry); err == nil && cidr.Contains(ip) {
+				return true
+			}
+		case host == strings.TrimPrefix(entry, "."):
+			return true
+		case strings.HasSuffix(host, "."+strings.TrimPrefix(entry, ".")):
+			return true
+		}
+	}
+
+	return false
+}
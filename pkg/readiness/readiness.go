@@ -0,0 +1,230 @@
+package readiness
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/color"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+const (
+	// HelperName of the template with the readiness helpers that is added
+	// to every chart
+	HelperName = "templates/_specialresource_readiness.tpl"
+
+	labelPrefix = "specialresource.openshift.io/driver-ready."
+	releaseName = "meta.helm.sh/release-name"
+)
+
+var log logr.Logger
+
+func init() {
+	log = zap.New(zap.UseDevMode(true)).WithName(color.Print("readiness", color.Brown))
+}
+
+// helpers selects the nodes with a ready driver, e.g. in the device plugin
+// DaemonSet of a chart
+var helpers = `{{/*
+Selects the nodes on which the driver of the SpecialResource is loaded:
+
+      nodeSelector:
+        {{- include "specialresource.driverReady.nodeSelector" . | nindent 8 }}
+
+DaemonSets of the device-plugin state get the nodeSelector without the helper.
+*/}}
+{{- define "specialresource.driverReady.nodeSelector" -}}
+` + labelPrefix + `{{ .Values.specialresource.metadata.name }}: "true"
+{{- end }}
+`
+
+// Label of the nodes on which the driver of the SpecialResource sr is ready
+func Label(sr string) string {
+	return labelPrefix + sr
+}
+
+// AddHelpers returns a copy of templates with the readiness helpers
+func AddHelpers(templates []*chart.File) []*chart.File {
+
+	files := []*chart.File{}
+	for _, template := range templates {
+		if template.Name != HelperName {
+			files = append(files, template)
+		}
+	}
+
+	return append(files, &chart.File{Name: HelperName, Data: []byte(helpers)})
+}
+
+// IsDevicePlugin is true for DaemonSets that need a loaded driver
+func IsDevicePlugin(obj *unstructured.Unstructured) bool {
+	return obj.GetKind() == "DaemonSet" && obj.GetAnnotations()["specialresource.openshift.io/state"] == "device-plugin"
+}
+
+// SetNodeSelector restricts the DaemonSet obj to the nodes with a ready
+// driver of the SpecialResource it was released by.
+func SetNodeSelector(obj *unstructured.Unstructured) error {
+
+	sr, found := obj.GetAnnotations()[releaseName]
+	if !found {
+		return errors.New("DaemonSet " + obj.GetName() + " has no SpecialResource release")
+	}
+
+	fields := []string{"spec", "template", "spec", "nodeSelector"}
+
+	nodeSelector, _, err := unstructured.NestedStringMap(obj.Object, fields...)
+	if err != nil {
+		return errors.Wrap(err, "Invalid nodeSelector of "+obj.GetName())
+	}
+	if nodeSelector == nil {
+		nodeSelector = make(map[string]string)
+	}
+
+	nodeSelector[Label(sr)] = "true"
+
+	return unstructured.SetNestedStringMap(obj.Object, nodeSelector, fields...)
+}
+
+// Update labels the nodes running a ready pod of any driver container
+// DaemonSet of the SpecialResource that released obj, the label is removed
+// from nodes whose driver pod is gone or not ready.
+func Update(obj *unstructured.Unstructured) error {
+
+	sr, found := obj.GetAnnotations()[releaseName]
+	if !found {
+		return nil
+	}
+
+	return UpdateNodes(obj.GetNamespace(), sr)
+}
+
+// IsDriverContainer is true for the driver container DaemonSets the node
+// labels are derived from, annotations are the ones of the DaemonSet
+func IsDriverContainer(annotations map[string]string) bool {
+	_, released := annotations[releaseName]
+	return released && annotations["specialresource.openshift.io/state"] == "driver-container"
+}
+
+// Release returns the SpecialResource that released the object with
+// annotations
+func Release(annotations map[string]string) string {
+	return annotations[releaseName]
+}
+
+// UpdateNodes labels the nodes running a ready pod of any driver container
+// DaemonSet of sr in namespace and removes the label from the others
+func UpdateNodes(namespace string, sr string) error {
+
+	ready, err := readyNodes(namespace, sr)
+	if err != nil {
+		return err
+	}
+
+	label := Label(sr)
+
+	nodes := &v1.NodeList{}
+	if err := clients.Workload().List(context.TODO(), nodes, client.HasLabels{label}); err != nil {
+		return errors.Wrap(err, "Cannot list nodes labeled "+label)
+	}
+
+	for _, node := range nodes.Items {
+		if ready[node.GetName()] {
+			delete(ready, node.GetName())
+			continue
+		}
+		log.Info("Driver not ready", "Node", node.GetName(), "SpecialResource", sr)
+		if err := patchLabel(node.GetName(), label, nil); err != nil {
+			return err
+		}
+	}
+
+	names := []string{}
+	for name := range ready {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		log.Info("Driver ready", "Node", name, "SpecialResource", sr)
+		if err := patchLabel(name, label, "true"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readyNodes returns the nodes with a ready pod of a driver container
+// DaemonSet of sr, the readiness probe of the driver checks that the
+// module is loaded.
+func readyNodes(namespace string, sr string) (map[string]bool, error) {
+
+	ready := make(map[string]bool)
+
+	daemonSets := &unstructured.UnstructuredList{}
+	daemonSets.SetAPIVersion("apps/v1")
+	daemonSets.SetKind("DaemonSetList")
+
+	if err := clients.Workload().List(context.TODO(), daemonSets, client.InNamespace(namespace)); err != nil {
+		return nil, errors.Wrap(err, "Cannot list DaemonSets in "+namespace)
+	}
+
+	for _, ds := range daemonSets.Items {
+
+		annotations := ds.GetAnnotations()
+		if !IsDriverContainer(annotations) || annotations[releaseName] != sr {
+			continue
+		}
+
+		matchLabels, _, err := unstructured.NestedStringMap(ds.Object, "spec", "selector", "matchLabels")
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid selector of DaemonSet "+ds.GetName())
+		}
+
+		pods := &v1.PodList{}
+		if err := clients.Workload().List(context.TODO(), pods, client.InNamespace(namespace), client.MatchingLabels(matchLabels)); err != nil {
+			return nil, errors.Wrap(err, "Cannot list pods of DaemonSet "+ds.GetName())
+		}
+
+		for _, pod := range pods.Items {
+			if !metav1.IsControlledBy(&pod, &ds) || pod.GetDeletionTimestamp() != nil {
+				continue
+			}
+			for _, condition := range pod.Status.Conditions {
+				if condition.Type == v1.PodReady && condition.Status == v1.ConditionTrue {
+					ready[pod.Spec.NodeName] = true
+				}
+			}
+		}
+	}
+
+	return ready, nil
+}
+
+func patchLabel(node string, label string, value interface{}) error {
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{label: value},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "Cannot marshal node patch")
+	}
+
+	obj := &v1.Node{}
+	obj.SetName(node)
+
+	err = clients.Workload().Patch(context.TODO(), obj, client.RawPatch(types.MergePatchType, patch))
+	return errors.Wrap(err, "Cannot label node "+node)
+}
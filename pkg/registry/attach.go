@@ -0,0 +1,107 @@
+package registry
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/pkg/errors"
+)
+
+// Pull returns the image, manifest lists are resolved for Architecture.
+// Credentials from providers are tried before the default credential chain.
+func Pull(image string, providers ...KeychainProvider) (v1.Image, error) {
+
+	opts, err := optionsWith(append(providers, DefaultProviders()...))
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot setup registry transport")
+	}
+
+	opts = append(forRefs(opts, image), crane.WithPlatform(&v1.Platform{OS: "linux", Architecture: Architecture}))
+
+	img, err := crane.Pull(image, opts...)
+	if err != nil {
+		return nil, errors.Wrap(schema1Error(image, err), "Cannot pull "+image)
+	}
+
+	return img, nil
+}
+
+// Attachment returns the reference of the artifact attached to the image
+// with the given digest, tagged sha256-<hex>.<suffix> like cosign does, and
+// whether it exists
+func Attachment(image string, digest string, suffix string, providers ...KeychainProvider) (string, bool, error) {
+
+	repo, err := Repository(image)
+	if err != nil {
+		return "", false, err
+	}
+
+	ref := repo + ":" + strings.Replace(digest, ":", "-", 1) + "." + suffix
+
+	opts, err := optionsWith(append(providers, DefaultProviders()...))
+	if err != nil {
+		return "", false, errors.Wrap(err, "Cannot setup registry transport")
+	}
+
+	if _, err := crane.Digest(ref, forRefs(opts, ref)...); err != nil {
+		return ref, false, nil
+	}
+
+	return ref, true, nil
+}
+
+// Attach pushes payload as single layer OCI artifact to the reference
+// returned by Attachment
+func Attach(ref string, payload []byte, mediaType types.MediaType, providers ...KeychainProvider) error {
+
+	layer, err := newBlobLayer(payload, mediaType)
+	if err != nil {
+		return err
+	}
+
+	artifact, err := mutate.Append(empty.Image, mutate.Addendum{Layer: layer, MediaType: mediaType})
+	if err != nil {
+		return errors.Wrap(err, "Cannot create artifact for "+ref)
+	}
+
+	return Push(mutate.MediaType(artifact, types.OCIManifestSchema1), ref, providers...)
+}
+
+// blobLayer a layer that is stored as is, not as tar
+type blobLayer struct {
+	content   []byte
+	digest    v1.Hash
+	mediaType types.MediaType
+}
+
+func newBlobLayer(content []byte, mediaType types.MediaType) (*blobLayer, error) {
+
+	digest, _, err := v1.SHA256(bytes.NewReader(content))
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot hash layer")
+	}
+
+	return &blobLayer{content: content, digest: digest, mediaType: mediaType}, nil
+}
+
+func (l *blobLayer) Digest() (v1.Hash, error) { return l.digest, nil }
+
+func (l *blobLayer) DiffID() (v1.Hash, error) { return l.digest, nil }
+
+func (l *blobLayer) Compressed() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(l.content)), nil
+}
+
+func (l *blobLayer) Uncompressed() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(l.content)), nil
+}
+
+func (l *blobLayer) Size() (int64, error) { return int64(len(l.content)), nil }
+
+func (l *blobLayer) MediaType() (types.MediaType, error) { return l.mediaType, nil }
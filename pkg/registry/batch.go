@@ -0,0 +1,196 @@
+package registry
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/trace"
+	"github.com/pkg/errors"
+)
+
+// batchImage an image of ExtractBatch, its layers from bottom to top
+type batchImage struct {
+	entry   string
+	repo    string
+	digests []string
+	matcher *matcher
+}
+
+// ExtractBatch extracts the files matching the patterns of every image of
+// requests, see ExtractFiles. The images are walked from top to bottom in
+// lockstep: the layers of one step are read in parallel, each once for all
+// images that need it, and a layer that images share, e.g. a common base
+// image, is served to the deeper ones from memory. The parallel pulls are
+// bounded by the layer pull limit of the registry transport. Images that
+// fail are missing from the files and listed in the errors. The registry
+// API takes no context, the pulls of a batch are traced in a span of their
+// own.
+func ExtractBatch(requests map[string][]string) (map[string]map[string][]byte, map[string]error) {
+
+	files := make(map[string]map[string][]byte)
+	errs := make(map[string]error)
+
+	entries := []string{}
+	for entry := range requests {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+
+	ctx, span := trace.Start(context.Background(), "registry extract", "images", strings.Join(entries, ","))
+	defer func() { span.End(batchError(errs, len(requests))) }()
+
+	images := []*batchImage{}
+	for entry, patterns := range requests {
+
+		m, err := newMatcher(patterns)
+		if err != nil {
+			errs[entry] = err
+			continue
+		}
+
+		_, pull := trace.Start(ctx, "manifest pull", "image", entry)
+		repo, digests, err := imageLayers(entry)
+		pull.End(err)
+		if err != nil {
+			errs[entry] = err
+			continue
+		}
+
+		images = append(images, &batchImage{entry: entry, repo: repo, digests: digests, matcher: m})
+	}
+
+	// Complete indexes by digest, they serve the images whose patterns
+	// they were read for, partial ones only serve the images that were
+	// waiting for them
+	indexes := make(map[string]*batchIndex)
+
+	for depth := 1; ; depth++ {
+
+		pending := make(map[string][]*batchImage)
+		searching := false
+
+		for _, img := range images {
+			if errs[img.entry] != nil || img.matcher.done() || depth > len(img.digests) {
+				continue
+			}
+			searching = true
+
+			digest := img.digests[len(img.digests)-depth]
+			if index, found := indexes[digest]; found && index.covers(img.matcher) {
+				img.matcher.apply(index.layerIndex)
+				continue
+			}
+			pending[digest] = append(pending[digest], img)
+		}
+
+		if !searching {
+			break
+		}
+
+		read := readLayers(ctx, pending)
+
+		for digest, imgs := range pending {
+			result := read[digest]
+			for _, img := range imgs {
+				if result.err != nil {
+					errs[img.entry] = errors.Wrap(result.err, "Cannot search layer "+digest)
+					continue
+				}
+				img.matcher.apply(result.index)
+			}
+			if result.err == nil && !result.index.partial {
+				indexes[digest] = newBatchIndex(result.index, imgs)
+			}
+		}
+	}
+
+	for _, img := range images {
+		if errs[img.entry] != nil {
+			continue
+		}
+		for name := range img.matcher.files {
+			log.Info("Found file in image", "file", name, "image", img.entry)
+		}
+		files[img.entry] = img.matcher.files
+	}
+
+	return files, errs
+}
+
+// batchError the span error of a batch, nil if no image failed
+func batchError(errs map[string]error, images int) error {
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(strconv.Itoa(len(errs)) + " of " + strconv.Itoa(images) + " images failed")
+}
+
+// batchIndex a complete layerIndex and the patterns it was read for
+type batchIndex struct {
+	*layerIndex
+	patterns map[string]bool
+}
+
+func newBatchIndex(index *layerIndex, imgs []*batchImage) *batchIndex {
+
+	b := &batchIndex{layerIndex: index, patterns: make(map[string]bool)}
+	for _, img := range imgs {
+		for _, pattern := range img.matcher.patterns {
+			b.patterns[pattern] = true
+		}
+	}
+	return b
+}
+
+func (b *batchIndex) covers(m *matcher) bool {
+	for _, pattern := range m.patterns {
+		if !b.patterns[pattern] {
+			return false
+		}
+	}
+	return true
+}
+
+type layerResult struct {
+	index *layerIndex
+	err   error
+}
+
+// readLayers indexes the pending layers in parallel for the matchers of the
+// images that need them, each pull is a child span of ctx
+func readLayers(ctx context.Context, pending map[string][]*batchImage) map[string]layerResult {
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	results := make(map[string]layerResult)
+
+	for digest, imgs := range pending {
+
+		matchers := []*matcher{}
+		for _, img := range imgs {
+			matchers = append(matchers, img.matcher)
+		}
+
+		wg.Add(1)
+		go func(digest string, repo string, matchers []*matcher) {
+			defer wg.Done()
+
+			_, span := trace.Start(ctx, "layer pull", "repository", repo, "digest", digest)
+			index, err := indexDigest(repo, digest, matchers)
+			span.End(err)
+
+			mutex.Lock()
+			results[digest] = layerResult{index: index, err: err}
+			mutex.Unlock()
+		}(digest, imgs[0].repo, matchers)
+	}
+
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"io/ioutil"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/pkg/errors"
+)
+
+// Layer media types of a Helm chart pushed to an OCI registry, helm < 3.7
+// used the generic tar+gzip one
+const (
+	HelmChartContentLayer       types.MediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	HelmChartContentLayerLegacy types.MediaType = "application/tar+gzip"
+)
+
+// BasicAuth provides a username and password for the registries matching
+// Registry, a host or an auth file key such as quay.io/org, see matchAuthKey
+type BasicAuth struct {
+	Registry string
+	Username string
+	Password string
+}
+
+func (b BasicAuth) Name() string {
+	return "basic-auth " + b.Registry
+}
+
+func (b BasicAuth) Keychain() (authn.Keychain, error) {
+	return b, nil
+}
+
+// Resolve implements authn.Keychain
+func (b BasicAuth) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	if _, found := matchAuthKey([]string{b.Registry}, target); !found {
+		return authn.Anonymous, nil
+	}
+	return &authn.Basic{Username: b.Username, Password: b.Password}, nil
+}
+
+// PullChart returns the packaged chart (.tgz) stored in the OCI artifact
+// entry, credentials from providers are tried before the default
+// credential chain.
+func PullChart(entry string, providers ...KeychainProvider) ([]byte, error) {
+
+	opts, err := optionsWith(append(providers, DefaultProviders()...))
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot setup registry transport")
+	}
+
+	log.Info("Pulling chart", "entry", entry)
+
+	img, err := crane.Pull(entry, forRefs(opts, entry)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot pull chart "+entry)
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot get layers of chart "+entry)
+	}
+
+	for _, layer := range layers {
+
+		mediaType, err := layer.MediaType()
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot get media type of layer")
+		}
+		if mediaType != HelmChartContentLayer && mediaType != HelmChartContentLayerLegacy {
+			continue
+		}
+
+		rc, err := layer.Compressed()
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot fetch chart layer of "+entry)
+		}
+		defer rc.Close()
+
+		data, err := ioutil.ReadAll(rc)
+		return data, errors.Wrap(err, "Cannot read chart layer of "+entry)
+	}
+
+	return nil, errors.New("No Helm chart content layer in " + entry)
+}
@@ -0,0 +1,81 @@
+package registry
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/pkg/errors"
+)
+
+// ResolveDigest returns the sha256 digest image points to, digest
+// references are returned without contacting the registry.
+func ResolveDigest(image string) (string, error) {
+
+	if digest, found := referenceDigest(image); found {
+		return digest, nil
+	}
+
+	opts, err := options()
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot setup registry transport")
+	}
+
+	digest, err := crane.Digest(image, forRefs(opts, image)...)
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot resolve digest of "+image)
+	}
+
+	return digest, nil
+}
+
+// DigestRecorder collects the digests of all images used in a reconcile
+type DigestRecorder struct {
+	mutex   sync.Mutex
+	digests map[string]string
+}
+
+// Digests is reset by the reconciler for every SpecialResource
+var Digests = NewDigestRecorder()
+
+func NewDigestRecorder() *DigestRecorder {
+	return &DigestRecorder{digests: make(map[string]string)}
+}
+
+// Record resolves and remembers the digest of image, an image is only
+// resolved once per recorder.
+func (d *DigestRecorder) Record(image string) (string, error) {
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if digest, found := d.digests[image]; found {
+		return digest, nil
+	}
+
+	digest, err := ResolveDigest(image)
+	if err != nil {
+		return "", err
+	}
+
+	d.digests[image] = digest
+	return digest, nil
+}
+
+// Images returns the recorded images sorted by name with their digest
+func (d *DigestRecorder) Images() ([]string, map[string]string) {
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	images := []string{}
+	digests := make(map[string]string, len(d.digests))
+
+	for image, digest := range d.digests {
+		images = append(images, image)
+		digests[image] = digest
+	}
+	sort.Strings(images)
+
+	return images, digests
+}
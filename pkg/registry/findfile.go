@@ -0,0 +1,322 @@
+package registry
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	// whiteoutOpaque hides the content of the directory in lower layers
+	whiteoutOpaque = whiteoutPrefix + whiteoutPrefix + ".opq"
+)
+
+// FindFileInImage returns the content of file in image entry, layers are
+// walked from top to bottom and the search stops at the first layer that
+// contains or deletes (whiteout) the file.
+func FindFileInImage(entry string, file string) ([]byte, error) {
+
+	file = cleanTarPath(file)
+
+	files, err := ExtractFiles(entry, file)
+	if err != nil {
+		return nil, err
+	}
+
+	content, found := files[file]
+	if !found {
+		return nil, errors.New("File " + file + " not found in " + entry)
+	}
+
+	return content, nil
+}
+
+// ExtractFiles returns the content of the regular files of image entry
+// matching any of patterns, keyed by their path without leading slash. The
+// patterns are path.Match globs, e.g. usr/lib/modules/*/config, a * does not
+// match a /. The layers are walked once from top to bottom, files of upper
+// layers and whiteouts hide the files of lower layers. Files that do not
+// exist are missing from the result.
+func ExtractFiles(entry string, patterns ...string) (map[string][]byte, error) {
+
+	files, errs := ExtractBatch(map[string][]string{entry: patterns})
+
+	return files[entry], errs[entry]
+}
+
+// matcher collects the files matching its patterns over the layers of an
+// image, walked from top to bottom
+type matcher struct {
+	patterns []string
+	files    map[string][]byte
+	// shadowed paths of the upper layers, deleted or replaced by
+	// something else than a directory
+	shadowed map[string]bool
+	// opaque directories of the upper layers
+	opaque map[string]bool
+}
+
+func newMatcher(patterns []string) (*matcher, error) {
+
+	m := &matcher{
+		files:    make(map[string][]byte),
+		shadowed: make(map[string]bool),
+		opaque:   make(map[string]bool),
+	}
+
+	for _, pattern := range patterns {
+		pattern = cleanTarPath(pattern)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrap(err, "Invalid file pattern "+pattern)
+		}
+		m.patterns = append(m.patterns, pattern)
+	}
+
+	return m, nil
+}
+
+func (m *matcher) match(name string) bool {
+	for _, pattern := range m.patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// done is true once every pattern is a plain path that was found, globs may
+// still match files of lower layers
+func (m *matcher) done() bool {
+	return m.satisfiedBy(nil)
+}
+
+// satisfiedBy is true if m is done once files of the next layer are applied
+func (m *matcher) satisfiedBy(files map[string][]byte) bool {
+	for _, pattern := range m.patterns {
+		if isGlob(pattern) {
+			return false
+		}
+		if _, found := m.files[pattern]; found {
+			continue
+		}
+		if _, found := files[pattern]; !found || m.hidden(pattern) {
+			return false
+		}
+	}
+	return true
+}
+
+// hidden is true if name or one of its directories is shadowed by an upper
+// layer or a directory is opaque
+func (m *matcher) hidden(name string) bool {
+
+	if m.shadowed[name] {
+		return true
+	}
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if m.shadowed[dir] || m.opaque[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// apply adds the files of the next lower layer that are not hidden by the
+// upper ones
+func (m *matcher) apply(index *layerIndex) {
+
+	for name, content := range index.files {
+		if _, seen := m.files[name]; seen || m.hidden(name) || !m.match(name) {
+			continue
+		}
+		m.files[name] = content
+	}
+
+	// Whiteouts only hide the files of lower layers, a layer may list
+	// them after the files it adds
+	for _, name := range index.shadowed {
+		m.shadowed[name] = true
+	}
+	for _, dir := range index.opaque {
+		m.opaque[dir] = true
+	}
+}
+
+// layerIndex the files of a layer matching any of the matchers it was read
+// for and the paths it hides in lower layers
+type layerIndex struct {
+	files    map[string][]byte
+	shadowed []string
+	opaque   []string
+	// partial the layer was not read to the end since every matcher
+	// found its files, it cannot serve other matchers
+	partial bool
+}
+
+// indexLayer reads the tar stream of a layer once for all matchers
+func indexLayer(rc io.Reader, matchers []*matcher) (*layerIndex, error) {
+
+	index := &layerIndex{files: make(map[string][]byte)}
+
+	tr := tar.NewReader(rc)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return index, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := cleanTarPath(header.Name)
+		base := path.Base(name)
+
+		switch {
+		case base == whiteoutOpaque:
+			index.opaque = append(index.opaque, path.Dir(name))
+			continue
+		case strings.HasPrefix(base, whiteoutPrefix):
+			index.shadowed = append(index.shadowed, path.Join(path.Dir(name), strings.TrimPrefix(base, whiteoutPrefix)))
+			continue
+		}
+
+		// Links and files of upper layers hide the files of lower ones
+		if header.Typeflag != tar.TypeDir {
+			index.shadowed = append(index.shadowed, name)
+		}
+
+		if header.Typeflag != tar.TypeReg || !anyMatch(matchers, name) {
+			continue
+		}
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot read "+name)
+		}
+		index.files[name] = content
+
+		if allSatisfiedBy(matchers, index.files) {
+			index.partial = true
+			return index, nil
+		}
+	}
+}
+
+func anyMatch(matchers []*matcher, name string) bool {
+	for _, m := range matchers {
+		if m.match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func allSatisfiedBy(matchers []*matcher, files map[string][]byte) bool {
+	for _, m := range matchers {
+		if !m.satisfiedBy(files) {
+			return false
+		}
+	}
+	return true
+}
+
+// indexDigest reads the layer repo@digest for matchers. A layer that is
+// neither local nor cached is streamed from the registry, once all files are
+// found the download is cancelled. Layers read completely are added to the
+// LayerCache.
+func indexDigest(repo string, digest string, matchers []*matcher) (*layerIndex, error) {
+
+	if LocalSource != "" {
+		layer, err := localLayer(repo, digest)
+		if err != nil {
+			return nil, err
+		}
+		return indexCachedLayer(layer, matchers)
+	}
+
+	if layer, found := LayerCache.Get(digest); found {
+		return indexCachedLayer(layer, matchers)
+	}
+
+	opts, err := options()
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot setup registry transport")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	layer, err := crane.PullLayer(repo+"@"+digest, append(forRefs(opts, repo), crane.WithContext(ctx))...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot pull layer "+repo+"@"+digest)
+	}
+
+	compressed, err := layer.Compressed()
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot read layer")
+	}
+
+	cw := LayerCache.writer(digest)
+	if cw != nil {
+		compressed = &layerReader{Reader: io.TeeReader(compressed, cw), closers: []io.Closer{compressed}}
+	}
+
+	rc, err := uncompress(layer, compressed)
+	if err != nil {
+		if cw != nil {
+			cw.abort()
+		}
+		return nil, err
+	}
+	defer dclose(rc)
+
+	index, err := indexLayer(rc, matchers)
+	if err == nil && !index.partial && cw != nil {
+		// The tar stream ends before the compressed one, read the
+		// rest so the digest is verified
+		_, err = io.Copy(ioutil.Discard, rc)
+	}
+
+	if cw != nil {
+		if err == nil && !index.partial {
+			cw.commit()
+		} else {
+			cw.abort()
+		}
+	}
+	if err == nil && index.partial {
+		log.Info("Files found, stopped reading layer", "digest", digest)
+	}
+
+	return index, err
+}
+
+// indexCachedLayer reads a local or cached layer for matchers
+func indexCachedLayer(layer v1.Layer, matchers []*matcher) (*layerIndex, error) {
+
+	rc, err := uncompressedLayer(layer)
+	if err != nil {
+		return nil, err
+	}
+	defer dclose(rc)
+
+	return indexLayer(rc, matchers)
+}
+
+// cleanTarPath strips the leading ./ and / tar entries may have
+func cleanTarPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
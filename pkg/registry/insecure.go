@@ -0,0 +1,94 @@
+package registry
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// InsecureRegistries set by the operator flag, hosts of the cluster Image
+// config are added to it. Entries are host, host:port or *.domain.
+var InsecureRegistries = []string{}
+
+var (
+	// insecureHosts is the allowlist of the current transport, Transport
+	// replaces it while transports built before are in use
+	insecureHosts = []string{}
+	insecureMutex sync.RWMutex
+)
+
+// setInsecureHosts replaces the allowlist
+func setInsecureHosts(hosts []string) {
+	insecureMutex.Lock()
+	defer insecureMutex.Unlock()
+	insecureHosts = hosts
+}
+
+// isInsecure reports whether host, with or without port, is allowlisted
+func isInsecure(host string) bool {
+
+	insecureMutex.RLock()
+	hosts := insecureHosts
+	insecureMutex.RUnlock()
+
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+
+	for _, entry := range hosts {
+		switch {
+		case entry == host || entry == hostname:
+			return true
+		case strings.HasPrefix(entry, "*.") && strings.HasSuffix(hostname, entry[1:]):
+			return true
+		}
+	}
+
+	return false
+}
+
+// forRefs appends crane.Insecure if any of refs points to an allowlisted
+// registry, this allows plain HTTP for those registries only.
+func forRefs(opts []crane.Option, refs ...string) []crane.Option {
+
+	for _, ref := range refs {
+		parsed, err := name.ParseReference(ref)
+		if err != nil {
+			continue
+		}
+		if isInsecure(parsed.Context().RegistryStr()) {
+			return append(append([]crane.Option{}, opts...), crane.Insecure)
+		}
+	}
+
+	return opts
+}
+
+// insecureTransport skips TLS verification for allowlisted hosts only
+type insecureTransport struct {
+	secure   http.RoundTripper
+	insecure http.RoundTripper
+}
+
+func newInsecureTransport(transport *http.Transport) http.RoundTripper {
+
+	insecure := transport.Clone()
+	insecure.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} // #nosec G402 -- allowlisted registries only
+
+	return &insecureTransport{secure: transport, insecure: insecure}
+}
+
+func (t *insecureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	if isInsecure(req.URL.Host) {
+		return t.insecure.RoundTrip(req)
+	}
+
+	return t.secure.RoundTrip(req)
+}
@@ -0,0 +1,59 @@
+package registry
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestIsInsecure(t *testing.T) {
+
+	defer setInsecureHosts(nil)
+	setInsecureHosts([]string{"registry.local", "mirror.local:5000", "*.insecure.example.com"})
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"registry.local", true},
+		{"registry.local:5000", true},
+		{"mirror.local:5000", true},
+		{"mirror.local", false},
+		{"mirror.local:443", false},
+		{"a.insecure.example.com", true},
+		{"a.b.insecure.example.com:8443", true},
+		{"insecure.example.com", false},
+		{"quay.io", false},
+	}
+
+	for _, tt := range tests {
+		if got := isInsecure(tt.host); got != tt.want {
+			t.Errorf("isInsecure(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+// TestInsecureHostsConcurrent replaces the allowlist while transports read
+// it, run with -race
+func TestInsecureHostsConcurrent(t *testing.T) {
+
+	defer setInsecureHosts(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				setInsecureHosts([]string{"registry.local"})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				isInsecure("registry.local:5000")
+				forRefs(nil, "registry.local/driver:latest")
+			}
+		}()
+	}
+	wg.Wait()
+}
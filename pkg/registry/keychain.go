@@ -0,0 +1,386 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/authn/k8schain"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KeychainProvider is a source of registry credentials, providers are
+// chained and the first one that has credentials for a registry wins.
+type KeychainProvider interface {
+	Name() string
+	Keychain() (authn.Keychain, error)
+}
+
+// PullSecrets provides the credentials of the dockercfg secrets in a
+// namespace, either listed explicitly or attached to a ServiceAccount.
+type PullSecrets struct {
+	Namespace      string
+	ServiceAccount string
+	Secrets        []string
+}
+
+func (p PullSecrets) Name() string {
+	return "pull-secrets " + p.Namespace + "/" + p.ServiceAccount
+}
+
+func (p PullSecrets) Keychain() (authn.Keychain, error) {
+
+	if p.Namespace == "" {
+		return authn.NewMultiKeychain(), nil
+	}
+
+	if kc, found := Keychains.get(p); found {
+		return kc, nil
+	}
+
+	// A missing namespace is not cached, it may be created any time
+	_, err := clients.Interface.CoreV1().Namespaces().Get(context.TODO(), p.Namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		log.Info("Cannot find namespace for pull secrets, skipping", "namespace", p.Namespace)
+		return authn.NewMultiKeychain(), nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot get namespace "+p.Namespace)
+	}
+
+	kc, err := k8schain.New(context.TODO(), &clients.Interface.Clientset, k8schain.Options{
+		Namespace:          p.Namespace,
+		ServiceAccountName: p.ServiceAccount,
+		ImagePullSecrets:   p.Secrets,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	Keychains.put(p, kc)
+
+	return kc, nil
+}
+
+// GlobalPullSecret is openshift-config/pull-secret, the cluster wide
+// credentials that are used to pull the release payload.
+var GlobalPullSecret = PullSecrets{
+	Namespace:      "openshift-config",
+	ServiceAccount: "default",
+	Secrets:        []string{"pull-secret"},
+}
+
+// Providers are consulted before the operator and global pull secrets,
+// the reconciler sets them to the pull secrets of the SpecialResource.
+var Providers = []KeychainProvider{}
+
+// Standalone registries are accessed without a cluster, e.g. by the CLI.
+// Credentials are read from the docker config.json, the CAs and the proxy
+// come from the environment.
+var Standalone bool
+
+// DockerConfig provides the credentials of $DOCKER_CONFIG/config.json or
+// ~/.docker/config.json
+type DockerConfig struct{}
+
+func (DockerConfig) Name() string {
+	return "docker-config"
+}
+
+func (DockerConfig) Keychain() (authn.Keychain, error) {
+	return authn.DefaultKeychain, nil
+}
+
+// AuthFile is the path of a podman auth.json or docker config.json whose
+// credentials are used after the pull secrets of the SpecialResource
+var AuthFile string
+
+// AuthFileConfig provides the credentials of a podman auth.json or docker
+// config.json, the file is read on every lookup so a mounted Secret can be
+// updated in place.
+type AuthFileConfig struct {
+	Path string
+}
+
+func (a AuthFileConfig) Name() string {
+	return "auth-file " + a.Path
+}
+
+func (a AuthFileConfig) Keychain() (authn.Keychain, error) {
+	return a, nil
+}
+
+// Resolve implements authn.Keychain
+func (a AuthFileConfig) Resolve(target authn.Resource) (authn.Authenticator, error) {
+
+	data, err := ioutil.ReadFile(a.Path)
+	if os.IsNotExist(err) {
+		return authn.Anonymous, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot read auth file "+a.Path)
+	}
+
+	file := authFile{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrap(err, "Cannot parse auth file "+a.Path)
+	}
+
+	// Credential helpers are not available in the operator image, only
+	// the auths of the file itself are considered
+	key, found := matchAuthKey(authKeys(file.Auths), target)
+	if !found {
+		return authn.Anonymous, nil
+	}
+
+	cfg, err := decodeAuth(file.Auths[key])
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid credentials of "+key+" in auth file "+a.Path)
+	}
+
+	return authn.FromConfig(cfg), nil
+}
+
+// decodeAuth splits the base64 user:password of the auth field into the
+// username and password, explicit ones win. The token exchange of most
+// registries needs both, the auth field is only sent as is for basic auth.
+// An identitytoken, e.g. of a robot account or a docker login with a
+// credential store, is exchanged as OAuth refresh token.
+func decodeAuth(cfg authn.AuthConfig) (authn.AuthConfig, error) {
+
+	if cfg.Auth == "" {
+		return cfg, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(cfg.Auth))
+	if err != nil {
+		return cfg, errors.Wrap(err, "Cannot decode auth")
+	}
+
+	user := strings.SplitN(string(decoded), ":", 2)
+	if len(user) != 2 {
+		return cfg, errors.New("Expected user:password in auth")
+	}
+
+	if cfg.Username == "" {
+		cfg.Username = user[0]
+	}
+	if cfg.Password == "" {
+		cfg.Password = strings.Trim(user[1], "\x00")
+	}
+
+	return cfg, nil
+}
+
+func authKeys(auths map[string]authn.AuthConfig) []string {
+	keys := []string{}
+	for key, cfg := range auths {
+		if cfg != (authn.AuthConfig{}) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// authFile the auths of a podman auth.json or docker config.json, an entry
+// has a username and password, the base64 user:password as auth or an
+// identitytoken, the email of old docker versions is ignored
+type authFile struct {
+	Auths map[string]authn.AuthConfig `json:"auths"`
+}
+
+const (
+	// InternalRegistry the service of the OpenShift image registry
+	InternalRegistry        = "image-registry.openshift-image-registry.svc"
+	serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// ServiceAccountToken provides the token of the operator ServiceAccount for
+// the OpenShift image registry, which accepts it as password of any user.
+// The token is read on every lookup, bound tokens are rotated.
+type ServiceAccountToken struct {
+	Path string
+}
+
+func (ServiceAccountToken) Name() string {
+	return "service-account-token"
+}
+
+func (t ServiceAccountToken) Keychain() (authn.Keychain, error) {
+	return t, nil
+}
+
+// Resolve implements authn.Keychain
+func (t ServiceAccountToken) Resolve(target authn.Resource) (authn.Authenticator, error) {
+
+	if target.RegistryStr() != InternalRegistry && !strings.HasPrefix(target.RegistryStr(), InternalRegistry+":") {
+		return authn.Anonymous, nil
+	}
+
+	path := t.Path
+	if path == "" {
+		path = serviceAccountTokenFile
+	}
+
+	token, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		log.Info("No ServiceAccount token mounted, pulling anonymously", "registry", target.RegistryStr())
+		return authn.Anonymous, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot read ServiceAccount token "+path)
+	}
+
+	return &authn.Basic{Username: "serviceaccount", Password: strings.TrimSpace(string(token))}, nil
+}
+
+// DefaultProviders returns the credential chain in lookup order
+func DefaultProviders() []KeychainProvider {
+
+	providers := append([]KeychainProvider{}, Providers...)
+
+	if AuthFile != "" {
+		providers = append(providers, AuthFileConfig{Path: AuthFile})
+	}
+
+	if Standalone {
+		return append(providers, DockerConfig{})
+	}
+
+	providers = append(providers, ServiceAccountToken{})
+
+	if namespace := os.Getenv("OPERATOR_NAMESPACE"); namespace != "" {
+		providers = append(providers, PullSecrets{Namespace: namespace, ServiceAccount: "default"})
+	}
+
+	return append(providers, GlobalPullSecret)
+}
+
+func keychain(providers []KeychainProvider) (authn.Keychain, error) {
+
+	keychains := []authn.Keychain{}
+
+	for _, provider := range providers {
+		kc, err := provider.Keychain()
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot create keychain from "+provider.Name())
+		}
+		keychains = append(keychains, kc)
+	}
+
+	return authn.NewMultiKeychain(keychains...), nil
+}
+
+// matchAuthKey returns the key of an auth file or pull secret matching the
+// registry and repository of target, with the semantics of docker and the
+// kubelet: a key is a host, optionally with port, scheme and path prefix,
+// e.g. quay.io, https://registry:5000/v2/, quay.io/org or *.example.com:5000.
+// A * globs a single host component, the ports have to be equal and the path
+// has to be a prefix of the repository. The most specific key wins.
+func matchAuthKey(keys []string, target authn.Resource) (string, bool) {
+
+	registry := target.RegistryStr()
+	repository := ""
+	if repo, ok := target.(name.Repository); ok {
+		repository = repo.RepositoryStr()
+	}
+
+	// Docker Hub is keyed with its legacy URL by docker and with
+	// docker.io by podman
+	if registry == name.DefaultRegistry {
+		registry = "docker.io"
+	}
+
+	matches := []string{}
+	for _, key := range keys {
+		if authKeyMatches(key, registry, repository) {
+			matches = append(matches, key)
+		}
+	}
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	// A longer path or a host without wildcard is more specific
+	sort.Slice(matches, func(i, j int) bool {
+		pi, pj := authKeyPath(matches[i]), authKeyPath(matches[j])
+		if len(pi) != len(pj) {
+			return len(pi) > len(pj)
+		}
+		wi, wj := strings.Count(matches[i], "*"), strings.Count(matches[j], "*")
+		if wi != wj {
+			return wi < wj
+		}
+		return matches[i] < matches[j]
+	})
+
+	return matches[0], true
+}
+
+func parseAuthKey(key string) (*url.URL, error) {
+
+	if key == authn.DefaultAuthKey {
+		key = "docker.io"
+	}
+	if !strings.Contains(key, "://") {
+		key = "https://" + key
+	}
+	return url.Parse(key)
+}
+
+// authKeyPath the repository prefix of key, the /v1/ and /v2/ API paths of
+// legacy keys are ignored
+func authKeyPath(key string) string {
+
+	u, err := parseAuthKey(key)
+	if err != nil {
+		return ""
+	}
+	prefix := strings.Trim(u.Path, "/")
+	if prefix == "v1" || prefix == "v2" {
+		return ""
+	}
+	return prefix
+}
+
+func authKeyMatches(key string, registry string, repository string) bool {
+
+	u, err := parseAuthKey(key)
+	if err != nil {
+		return false
+	}
+
+	target, err := url.Parse("https://" + registry)
+	if err != nil {
+		return false
+	}
+
+	if u.Port() != target.Port() {
+		return false
+	}
+
+	globs := strings.Split(u.Hostname(), ".")
+	parts := strings.Split(target.Hostname(), ".")
+	if len(globs) != len(parts) {
+		return false
+	}
+	for idx := range globs {
+		if matched, err := path.Match(globs[idx], parts[idx]); err != nil || !matched {
+			return false
+		}
+	}
+
+	prefix := authKeyPath(key)
+	return prefix == "" || repository == prefix || strings.HasPrefix(repository, prefix+"/")
+}
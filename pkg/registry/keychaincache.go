@@ -0,0 +1,88 @@
+package registry
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+)
+
+// Keychains caches the keychains built from pull secrets, they are dropped
+// when a Secret of their namespace changes, a request with credentials
+// fails to authenticate or TTL expired. The TTL covers Secrets the
+// informers do not see, e.g. outside of the informer selector.
+var Keychains = &KeychainCache{TTL: 10 * time.Minute}
+
+func init() {
+	clients.OnSecretChange(func(namespace string, name string) {
+		Keychains.InvalidateNamespace(namespace)
+	})
+}
+
+// KeychainCache the keychains of PullSecrets by namespace, ServiceAccount
+// and Secrets
+type KeychainCache struct {
+	TTL time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]keychainEntry
+}
+
+type keychainEntry struct {
+	namespace string
+	keychain  authn.Keychain
+	created   time.Time
+}
+
+func (p PullSecrets) cacheKey() string {
+	return p.Namespace + "/" + p.ServiceAccount + "/" + strings.Join(p.Secrets, ",")
+}
+
+// get returns the keychain of p unless it expired
+func (c *KeychainCache) get(p PullSecrets) (authn.Keychain, bool) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, found := c.entries[p.cacheKey()]
+	if !found || (c.TTL > 0 && time.Since(entry.created) > c.TTL) {
+		return nil, false
+	}
+	return entry.keychain, true
+}
+
+func (c *KeychainCache) put(p PullSecrets, kc authn.Keychain) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]keychainEntry)
+	}
+	c.entries[p.cacheKey()] = keychainEntry{namespace: p.Namespace, keychain: kc, created: time.Now()}
+}
+
+// InvalidateNamespace drops the keychains of the pull secrets in namespace
+func (c *KeychainCache) InvalidateNamespace(namespace string) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key, entry := range c.entries {
+		if entry.namespace == namespace {
+			log.Info("Pull secrets changed, reloading credentials", "namespace", namespace)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Reset drops all keychains
+func (c *KeychainCache) Reset() {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = nil
+}
@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+)
+
+// ImageConfig the runtime configuration of an image from its OCI config
+type ImageConfig struct {
+	Labels     map[string]string `json:"labels"`
+	Env        []string          `json:"env"`
+	Entrypoint []string          `json:"entrypoint"`
+	Cmd        []string          `json:"cmd"`
+	WorkingDir string            `json:"workingDir"`
+	User       string            `json:"user"`
+}
+
+// imageConfigs of digest references, they cannot change
+var imageConfigs sync.Map
+
+// GetImageConfig returns the labels, environment and entrypoint of the
+// config of image, manifest lists are resolved for Architecture
+func GetImageConfig(image string) (ImageConfig, error) {
+
+	_, pinned := referenceDigest(image)
+	if pinned {
+		if config, found := imageConfigs.Load(image); found {
+			return config.(ImageConfig), nil
+		}
+	}
+
+	opts, err := options()
+	if err != nil {
+		return ImageConfig{}, errors.Wrap(err, "Cannot setup registry transport")
+	}
+
+	opts = append(forRefs(opts, image), crane.WithPlatform(&v1.Platform{OS: "linux", Architecture: Architecture}))
+
+	config, err := readImageConfig(image, opts)
+	if err != nil {
+		return ImageConfig{}, err
+	}
+
+	if pinned {
+		imageConfigs.Store(image, config)
+	}
+
+	return config, nil
+}
+
+func readImageConfig(image string, opts []crane.Option) (ImageConfig, error) {
+
+	raw, err := crane.Config(image, opts...)
+	if _, ok := schema1Error(image, err).(*Schema1Error); ok {
+		// Older registries only serve schema1, the config is part of
+		// the history of the manifest
+		return schema1Config(image, opts)
+	}
+	if err != nil {
+		return ImageConfig{}, errors.Wrap(err, "Cannot get config of "+image)
+	}
+
+	file, err := v1.ParseConfigFile(bytes.NewReader(raw))
+	if err != nil {
+		return ImageConfig{}, errors.Wrap(err, "Cannot parse config of "+image)
+	}
+
+	return ImageConfig{
+		Labels:     file.Config.Labels,
+		Env:        file.Config.Env,
+		Entrypoint: file.Config.Entrypoint,
+		Cmd:        file.Config.Cmd,
+		WorkingDir: file.Config.WorkingDir,
+		User:       file.Config.User,
+	}, nil
+}
+
+// ImageLabels returns the labels of the config of image, manifest lists are
+// resolved for Architecture
+func ImageLabels(image string) (map[string]string, error) {
+
+	config, err := GetImageConfig(image)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.Labels, nil
+}
@@ -0,0 +1,236 @@
+package registry
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/openshift-psap/special-resource-operator/pkg/metrics"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+)
+
+// LayerCache keeps compressed layers on disk keyed by their digest, the
+// release payload and DTK layers are hundreds of MB and never change for a
+// given digest.
+var LayerCache = &DiskLayerCache{
+	Path:    "/cache/layers",
+	MaxSize: 2 << 30,
+	TTL:     24 * time.Hour,
+}
+
+type DiskLayerCache struct {
+	Path    string
+	MaxSize int64
+	TTL     time.Duration
+
+	mutex sync.Mutex
+}
+
+func (c *DiskLayerCache) file(digest string) string {
+	return filepath.Join(c.Path, strings.ReplaceAll(digest, ":", "-"))
+}
+
+// tempFile creates the file a layer is downloaded to, downloads are kept
+// apart from the cache entries so that evict never sees them
+func (c *DiskLayerCache) tempFile() (*os.File, error) {
+
+	dir := filepath.Join(c.Path, ".download")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "Cannot create layer cache directory")
+	}
+
+	tmp, err := ioutil.TempFile(dir, "layer-")
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot create temporary layer file")
+	}
+
+	return tmp, nil
+}
+
+// Get returns the cached layer for digest, expired entries are treated as
+// a cache miss.
+func (c *DiskLayerCache) Get(digest string) (v1.Layer, bool) {
+
+	if c.Path == "" {
+		return nil, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	layer, found := c.get(digest)
+	metrics.IncLayerCache(found)
+
+	return layer, found
+}
+
+func (c *DiskLayerCache) get(digest string) (v1.Layer, bool) {
+
+	path := c.file(digest)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+
+	if c.TTL > 0 && time.Since(info.ModTime()) > c.TTL {
+		log.Info("Layer cache entry expired", "digest", digest)
+		warn.OnError(os.Remove(path))
+		return nil, false
+	}
+
+	layer, err := tarball.LayerFromFile(path)
+	if err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot read cached layer "+digest))
+		return nil, false
+	}
+
+	// The modification time is the last access, eviction removes the
+	// least recently used layers first.
+	now := time.Now()
+	warn.OnError(os.Chtimes(path, now, now))
+
+	log.Info("Layer cache hit", "digest", digest)
+	return layer, true
+}
+
+// Put writes the compressed layer to disk and evicts entries if the cache
+// grows beyond MaxSize.
+func (c *DiskLayerCache) Put(digest string, layer v1.Layer) (v1.Layer, error) {
+
+	if c.Path == "" {
+		return layer, nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	rc, err := layer.Compressed()
+	if err != nil {
+		return layer, errors.Wrap(err, "Cannot read compressed layer")
+	}
+	defer dclose(rc)
+
+	tmp, err := c.tempFile()
+	if err != nil {
+		return layer, err
+	}
+
+	if _, err = io.Copy(tmp, rc); err != nil {
+		dclose(tmp)
+		warn.OnError(os.Remove(tmp.Name()))
+		return layer, errors.Wrap(err, "Cannot write layer "+digest)
+	}
+	dclose(tmp)
+
+	if err := os.Rename(tmp.Name(), c.file(digest)); err != nil {
+		warn.OnError(os.Remove(tmp.Name()))
+		return layer, errors.Wrap(err, "Cannot move layer into cache")
+	}
+
+	c.evict()
+
+	cached, err := tarball.LayerFromFile(c.file(digest))
+	if err != nil {
+		return layer, errors.Wrap(err, "Cannot read cached layer "+digest)
+	}
+
+	return cached, nil
+}
+
+// cacheWriter receives the compressed layer while it is streamed, it is
+// added to the cache only if the stream was read completely
+type cacheWriter struct {
+	*os.File
+	cache  *DiskLayerCache
+	digest string
+}
+
+// writer returns a cacheWriter for digest, nil if the cache is disabled or
+// the temporary file cannot be created
+func (c *DiskLayerCache) writer(digest string) *cacheWriter {
+
+	if c.Path == "" {
+		return nil
+	}
+
+	tmp, err := c.tempFile()
+	if err != nil {
+		warn.OnError(err)
+		return nil
+	}
+
+	return &cacheWriter{File: tmp, cache: c, digest: digest}
+}
+
+// commit moves the complete layer into the cache
+func (w *cacheWriter) commit() {
+
+	dclose(w.File)
+
+	w.cache.mutex.Lock()
+	defer w.cache.mutex.Unlock()
+
+	if err := os.Rename(w.Name(), w.cache.file(w.digest)); err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot move layer into cache"))
+		warn.OnError(os.Remove(w.Name()))
+		return
+	}
+
+	w.cache.evict()
+}
+
+// abort drops a partially read layer
+func (w *cacheWriter) abort() {
+	dclose(w.File)
+	warn.OnError(os.Remove(w.Name()))
+}
+
+// evict removes expired entries and the least recently used ones until the
+// cache fits into MaxSize, callers hold the mutex. Downloads in progress are
+// not entries, they are written outside of the mutex.
+func (c *DiskLayerCache) evict() {
+
+	entries, err := ioutil.ReadDir(c.Path)
+	if err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot list layer cache"))
+		return
+	}
+
+	files := []os.FileInfo{}
+	for _, entry := range entries {
+		if entry.Mode().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			files = append(files, entry)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+
+	var size int64
+	for _, file := range files {
+		size += file.Size()
+	}
+
+	for _, file := range files {
+		expired := c.TTL > 0 && time.Since(file.ModTime()) > c.TTL
+		if !expired && (c.MaxSize <= 0 || size <= c.MaxSize) {
+			continue
+		}
+		log.Info("Evicting layer from cache", "file", file.Name(), "expired", expired)
+		if err := os.Remove(filepath.Join(c.Path, file.Name())); err != nil {
+			warn.OnError(err)
+			continue
+		}
+		size -= file.Size()
+	}
+}
@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEvict(t *testing.T) {
+
+	cache := &DiskLayerCache{Path: t.TempDir(), MaxSize: 20, TTL: time.Hour}
+
+	write := func(name string, size int, age time.Duration) {
+		path := filepath.Join(cache.Path, name)
+		if err := ioutil.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modified := time.Now().Add(-age)
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("sha256-expired", 1, 2*time.Hour)
+	write("sha256-old", 10, 30*time.Minute)
+	write("sha256-new", 10, time.Minute)
+
+	// A download in progress is neither expired nor counted
+	download, err := cache.tempFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer download.Close()
+	if _, err := download.Write(make([]byte, 100)); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(download.Name(), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	cache.evict()
+
+	for name, kept := range map[string]bool{
+		"sha256-expired": false,
+		"sha256-old":     true,
+		"sha256-new":     true,
+	} {
+		_, err := os.Stat(filepath.Join(cache.Path, name))
+		if kept != (err == nil) {
+			t.Errorf("%s kept = %v, want %v", name, err == nil, kept)
+		}
+	}
+
+	if _, err := os.Stat(download.Name()); err != nil {
+		t.Errorf("Download in progress was evicted: %v", err)
+	}
+
+	// Above MaxSize the least recently used entries go first
+	write("sha256-newest", 10, 0)
+	cache.evict()
+
+	if _, err := os.Stat(filepath.Join(cache.Path, "sha256-old")); err == nil {
+		t.Errorf("sha256-old was not evicted")
+	}
+	if _, err := os.Stat(filepath.Join(cache.Path, "sha256-newest")); err != nil {
+		t.Errorf("sha256-newest was evicted: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package registry
+
+import (
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/pkg/errors"
+)
+
+// ImageSourceAnnotation on a SpecialResource selects a local image source
+// mounted into the operator pod instead of the registries, the value is
+// oci-layout:<dir> or docker-archive:<file>.
+const ImageSourceAnnotation = "specialresource.openshift.io/image-source"
+
+// LocalSource is the image source of the current reconcile, empty means the
+// images are pulled from the registries.
+var LocalSource string
+
+const (
+	ociLayoutPrefix     = "oci-layout:"
+	dockerArchivePrefix = "docker-archive:"
+)
+
+func localImage(entry string) (v1.Image, error) {
+
+	switch {
+	case strings.HasPrefix(LocalSource, ociLayoutPrefix):
+		return ociLayoutImage(strings.TrimPrefix(LocalSource, ociLayoutPrefix), entry)
+	case strings.HasPrefix(LocalSource, dockerArchivePrefix):
+		return dockerArchiveImage(strings.TrimPrefix(LocalSource, dockerArchivePrefix), entry)
+	default:
+		return nil, errors.New("Unknown image source " + LocalSource + ", expected " + ociLayoutPrefix + " or " + dockerArchivePrefix)
+	}
+}
+
+// ociLayoutImage finds entry in the index of an oci-layout directory, either
+// by digest or by the org.opencontainers.image.ref.name annotation.
+func ociLayoutImage(path string, entry string) (v1.Image, error) {
+
+	index, err := layout.ImageIndexFromPath(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot read oci-layout "+path)
+	}
+
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot read index of oci-layout "+path)
+	}
+
+	for _, desc := range manifest.Manifests {
+
+		if digest, found := referenceDigest(entry); found {
+			if desc.Digest.String() != digest {
+				continue
+			}
+		} else {
+			ref := desc.Annotations["org.opencontainers.image.ref.name"]
+			if ref == "" || (ref != entry && !strings.HasSuffix(entry, ":"+ref)) {
+				continue
+			}
+		}
+
+		if !desc.MediaType.IsIndex() {
+			return index.Image(desc.Digest)
+		}
+
+		child, err := index.ImageIndex(desc.Digest)
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot read index "+desc.Digest.String())
+		}
+		platforms, err := child.IndexManifest()
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot read index "+desc.Digest.String())
+		}
+		for _, platform := range platforms.Manifests {
+			if platform.Platform != nil && platform.Platform.OS == "linux" && platform.Platform.Architecture == Architecture {
+				return child.Image(platform.Digest)
+			}
+		}
+		return nil, errors.New("No manifest for linux/" + Architecture + " in index of " + entry)
+	}
+
+	return nil, errors.New("Image " + entry + " not found in oci-layout " + path)
+}
+
+// dockerArchiveImage loads entry from a docker-archive, digest references
+// cannot be matched and need an archive with a single image.
+func dockerArchiveImage(path string, entry string) (v1.Image, error) {
+
+	var tag *name.Tag
+
+	if !strings.Contains(entry, "@") {
+		t, err := name.NewTag(entry)
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot parse tag "+entry)
+		}
+		tag = &t
+	}
+
+	img, err := tarball.ImageFromPath(path, tag)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot read "+entry+" from docker-archive "+path)
+	}
+
+	return img, nil
+}
+
+func localLayers(entry string) ([]string, error) {
+
+	img, err := localImage(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot read manifest of "+entry)
+	}
+
+	digests := []string{}
+	for _, layer := range manifest.Layers {
+		digests = append(digests, layer.Digest.String())
+	}
+
+	if len(digests) == 0 {
+		return nil, errors.New("No layers in manifest of " + entry)
+	}
+
+	return digests, nil
+}
+
+func localLayer(entry string, digest string) (v1.Layer, error) {
+
+	img, err := localImage(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := v1.NewHash(digest)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot parse digest "+digest)
+	}
+
+	return img.LayerByDigest(hash)
+}
@@ -0,0 +1,148 @@
+package registry
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"runtime"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/pkg/errors"
+)
+
+// zstd layer media types are not part of the vendored types package
+const (
+	OCILayerZstd           types.MediaType = "application/vnd.oci.image.layer.v1.tar+zstd"
+	OCIRestrictedLayerZstd types.MediaType = "application/vnd.oci.image.layer.nondistributable.v1.tar+zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+type manifestHeader struct {
+	MediaType types.MediaType `json:"mediaType"`
+	Manifests []struct {
+		MediaType types.MediaType `json:"mediaType"`
+		Digest    string          `json:"digest"`
+		Platform  *v1.Platform    `json:"platform"`
+	} `json:"manifests"`
+}
+
+// Architecture the manifest lists are resolved for, the nodes targeted by a
+// SpecialResource may differ from the node the operator runs on.
+var Architecture = runtime.GOARCH
+
+// ManifestForArch returns the image manifest of entry for arch, resolving
+// manifest lists and OCI image indexes.
+func ManifestForArch(entry string, arch string) ([]byte, error) {
+
+	opts, err := options()
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot setup registry transport")
+	}
+
+	ref, err := name.ParseReference(entry)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot parse image reference "+entry)
+	}
+
+	manifest, err := crane.Manifest(entry, forRefs(opts, entry)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot get manifest of "+entry)
+	}
+
+	return imageManifest(ref.Context().Name(), manifest, arch, opts)
+}
+
+// imageManifest resolves a Docker manifest list or OCI image index to the
+// image manifest of arch, image manifests are returned unmodified.
+func imageManifest(repo string, manifest []byte, arch string, opts []crane.Option) ([]byte, error) {
+
+	var header manifestHeader
+	if err := json.Unmarshal(manifest, &header); err != nil {
+		return nil, errors.Wrap(err, "Cannot unmarshal manifest")
+	}
+
+	// OCI manifests may omit the mediaType, an index is recognized by the
+	// manifests list.
+	if !header.MediaType.IsIndex() && header.Manifests == nil {
+		return manifest, nil
+	}
+
+	for _, desc := range header.Manifests {
+		if desc.Platform == nil || desc.Platform.OS != "linux" || desc.Platform.Architecture != arch {
+			continue
+		}
+		log.Info("Resolved index to platform manifest", "repo", repo, "arch", arch, "digest", desc.Digest)
+		return crane.Manifest(repo+"@"+desc.Digest, forRefs(opts, repo)...)
+	}
+
+	return nil, errors.New("No manifest for linux/" + arch + " in index of " + repo)
+}
+
+type layerReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (l *layerReader) Close() error {
+	var err error
+	for _, c := range l.closers {
+		if cerr := c.Close(); cerr != nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// uncompressedLayer returns the tar stream of layer, the compression is
+// detected from the content since registries do not always set the OCI
+// or Docker media type that matches the blob.
+func uncompressedLayer(layer v1.Layer) (io.ReadCloser, error) {
+
+	rc, err := layer.Compressed()
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot read layer")
+	}
+
+	return uncompress(layer, rc)
+}
+
+// uncompress returns the tar stream of rc, the compressed stream of layer,
+// rc is closed with it
+func uncompress(layer v1.Layer, rc io.ReadCloser) (io.ReadCloser, error) {
+
+	br := bufio.NewReader(rc)
+
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		dclose(rc)
+		return nil, errors.Wrap(err, "Cannot detect layer compression")
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			dclose(rc)
+			return nil, errors.Wrap(err, "Cannot create gzip reader")
+		}
+		return &layerReader{Reader: gr, closers: []io.Closer{gr, rc}}, nil
+
+	case bytes.HasPrefix(magic, zstdMagic):
+		dclose(rc)
+		mediaType, _ := layer.MediaType()
+		return nil, errors.New("zstd compressed layer (" + string(mediaType) + ") not supported, no zstd decoder available in this build")
+
+	default:
+		// Uncompressed tar, OCIUncompressedLayer or DockerUncompressedLayer
+		return &layerReader{Reader: br, closers: []io.Closer{rc}}, nil
+	}
+}
@@ -0,0 +1,69 @@
+package registry
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/metrics"
+)
+
+// metricsTransport records latency, errors and downloaded bytes of every
+// request per registry host.
+type metricsTransport struct {
+	inner http.RoundTripper
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	host := req.URL.Host
+	start := time.Now()
+
+	resp, err := t.inner.RoundTrip(req)
+
+	metrics.ObserveRegistryRequest(host, requestKind(req), time.Since(start).Seconds())
+
+	if err != nil {
+		metrics.IncRegistryErrors(host)
+		return resp, err
+	}
+	// 401 is the expected token challenge, not an error
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusUnauthorized {
+		metrics.IncRegistryErrors(host)
+	}
+
+	// Rejected credentials, e.g. of a rotated pull secret, are reloaded
+	// from the API on the next request
+	if req.Header.Get("Authorization") != "" &&
+		(resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+		metrics.IncRegistryAuthFailures(host)
+		Keychains.Reset()
+	}
+
+	resp.Body = &countingBody{ReadCloser: resp.Body, host: host}
+
+	return resp, nil
+}
+
+func requestKind(req *http.Request) string {
+	switch {
+	case strings.Contains(req.URL.Path, "/manifests/"):
+		return "manifest"
+	case strings.Contains(req.URL.Path, "/blobs/"):
+		return "blob"
+	default:
+		return "other"
+	}
+}
+
+type countingBody struct {
+	io.ReadCloser
+	host string
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	metrics.AddRegistryBytes(b.host, n)
+	return n, err
+}
@@ -0,0 +1,221 @@
+package registry
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	imageContentSourcePolicy = schema.GroupVersionResource{
+		Group: "operator.openshift.io", Version: "v1alpha1", Resource: "imagecontentsourcepolicies",
+	}
+	imageDigestMirrorSet = schema.GroupVersionResource{
+		Group: "config.openshift.io", Version: "v1", Resource: "imagedigestmirrorsets",
+	}
+
+	// MirrorsTTL is how long the mirrors of the cluster are reused before
+	// the policies are listed again
+	MirrorsTTL = 5 * time.Minute
+
+	clusterMirrors = &mirrorCache{}
+)
+
+// Mirror the mirrors configured for a source repository in the order they
+// were declared
+type Mirror struct {
+	Source  string
+	Mirrors []string
+}
+
+// Mirrors of all sources in the order the policies declare them
+type Mirrors []Mirror
+
+// add appends targets to the mirrors of source, a source declared by
+// several policies keeps the position of its first declaration
+func (m *Mirrors) add(source string, targets []string) {
+
+	for i := range *m {
+		if (*m)[i].Source != source {
+			continue
+		}
+		for _, target := range targets {
+			if !contains((*m)[i].Mirrors, target) {
+				(*m)[i].Mirrors = append((*m)[i].Mirrors, target)
+			}
+		}
+		return
+	}
+
+	*m = append(*m, Mirror{Source: source, Mirrors: append([]string{}, targets...)})
+}
+
+type mirrorCache struct {
+	mutex   sync.Mutex
+	mirrors Mirrors
+	created time.Time
+}
+
+// ClusterMirrors collects the digest mirrors from all ImageContentSourcePolicy
+// and ImageDigestMirrorSet objects in the cluster, they are cached for
+// MirrorsTTL.
+func ClusterMirrors() (Mirrors, error) {
+
+	if Standalone {
+		return Mirrors{}, nil
+	}
+
+	clusterMirrors.mutex.Lock()
+	defer clusterMirrors.mutex.Unlock()
+
+	if clusterMirrors.mirrors != nil && time.Since(clusterMirrors.created) < MirrorsTTL {
+		return clusterMirrors.mirrors, nil
+	}
+
+	mirrors := Mirrors{}
+
+	if err := collectMirrors(&mirrors, imageContentSourcePolicy, "ImageContentSourcePolicyList", "repositoryDigestMirrors"); err != nil {
+		return mirrors, err
+	}
+
+	if err := collectMirrors(&mirrors, imageDigestMirrorSet, "ImageDigestMirrorSetList", "imageDigestMirrors"); err != nil {
+		return mirrors, err
+	}
+
+	clusterMirrors.mirrors = mirrors
+	clusterMirrors.created = time.Now()
+
+	return mirrors, nil
+}
+
+func collectMirrors(mirrors *Mirrors, gvr schema.GroupVersionResource, kind string, field string) error {
+
+	available, err := clients.HasResource(gvr)
+	if err != nil {
+		return errors.Wrap(err, "Error discovering "+gvr.Resource+" API resource")
+	}
+	if !available {
+		log.Info("Warning: Could not find " + gvr.Resource + " API resource. Can be ignored on vanilla k8s.")
+		return nil
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetAPIVersion(gvr.GroupVersion().String())
+	list.SetKind(kind)
+
+	if err := clients.Interface.List(context.TODO(), list, []client.ListOption{}...); err != nil {
+		return errors.Wrap(err, "Client cannot get "+kind)
+	}
+
+	for _, policy := range list.Items {
+
+		entries, _, err := unstructured.NestedSlice(policy.Object, "spec", field)
+		if err != nil {
+			warn.OnError(errors.Wrap(err, "Cannot extract "+field+" from "+policy.GetName()))
+			continue
+		}
+
+		for _, entry := range entries {
+			entry, ok := entry.(map[string]interface{})
+			if !ok {
+				warn.OnError(errors.New("Unexpected " + field + " entry in " + policy.GetName()))
+				continue
+			}
+			source, _, _ := unstructured.NestedString(entry, "source")
+			targets, _, _ := unstructured.NestedStringSlice(entry, "mirrors")
+			if source == "" {
+				continue
+			}
+			mirrors.add(source, targets)
+		}
+	}
+
+	return nil
+}
+
+// Candidates returns the pull specs that should be tried for entry, mirrors
+// first and the original source last. Mirrors only apply to digest
+// references, that is how the cluster itself handles them. Like the
+// container runtime only the mirrors of the most specific matching source
+// are used, in the order they were declared.
+func (m Mirrors) Candidates(entry string) []string {
+
+	candidates := []string{}
+
+	ref, err := name.NewDigest(entry)
+	if err != nil {
+		return append(candidates, entry)
+	}
+
+	// Sources are compared normalized, docker.io/foo is
+	// index.docker.io/library/foo, a tag next to the digest is dropped
+	repo := ref.Context().Name()
+
+	matches := []Mirror{}
+	for _, mirror := range m {
+		prefix := normalizeSource(mirror.Source)
+		if repo != prefix && !strings.HasPrefix(repo, prefix+"/") {
+			continue
+		}
+		matches = append(matches, Mirror{Source: prefix, Mirrors: mirror.Mirrors})
+	}
+
+	if len(matches) == 0 {
+		return append(candidates, entry)
+	}
+
+	// Sources that normalize to the same prefix keep their declaration order
+	sort.SliceStable(matches, func(i, j int) bool {
+		return len(matches[i].Source) > len(matches[j].Source)
+	})
+
+	for _, match := range matches {
+		if match.Source != matches[0].Source {
+			break
+		}
+		for _, target := range match.Mirrors {
+			candidate := target + strings.TrimPrefix(repo, match.Source) + "@" + ref.DigestStr()
+			if !contains(candidates, candidate) {
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+
+	return append(candidates, entry)
+}
+
+// normalizeSource expands a Docker Hub source like repository does, a source
+// of a whole registry, e.g. quay.io, is not a repository and kept as is
+func normalizeSource(source string) string {
+
+	if source == "docker.io" {
+		return name.DefaultRegistry
+	}
+	if !strings.Contains(source, "/") {
+		return source
+	}
+	repo, err := name.NewRepository(source)
+	if err != nil {
+		return source
+	}
+	return repo.Name()
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestCandidates(t *testing.T) {
+
+	mirrors := Mirrors{
+		{"quay.io/openshift-release-dev", []string{"mirror:5000/ocp"}},
+		{"quay.io/openshift-release-dev/ocp-release", []string{"mirror:5000/ocp/release"}},
+		{"quay.io/openshift-release-dev/ocp-v4.0-art-dev", []string{"mirror:5000/ocp/art", "backup.example.com/art"}},
+		{"registry:5000/org", []string{"mirror:5000/org"}},
+		{"docker.io/library/busybox", []string{"mirror:5000/busybox"}},
+		{"index.docker.io/library/busybox", []string{"backup.example.com/busybox", "mirror:5000/busybox"}},
+		{"example.com", []string{"mirror:5000/example"}},
+	}
+
+	tests := []struct {
+		entry      string
+		candidates []string
+	}{
+		{
+			"quay.io/openshift-release-dev/ocp-release@" + testDigest,
+			[]string{"mirror:5000/ocp/release@" + testDigest},
+		},
+		{
+			"quay.io/openshift-release-dev/ocp-release:4.8.0-x86_64@" + testDigest,
+			[]string{"mirror:5000/ocp/release@" + testDigest},
+		},
+		{
+			"quay.io/openshift-release-dev/ocp-v4.0-art-dev@" + testDigest,
+			[]string{"mirror:5000/ocp/art@" + testDigest, "backup.example.com/art@" + testDigest},
+		},
+		{
+			"registry:5000/org/repo@" + testDigest,
+			[]string{"mirror:5000/org/repo@" + testDigest},
+		},
+		{
+			"registry:5000/org/repo:tag@" + testDigest,
+			[]string{"mirror:5000/org/repo@" + testDigest},
+		},
+		// Sources with the same prefix are tried in declaration order
+		{
+			"busybox@" + testDigest,
+			[]string{"mirror:5000/busybox@" + testDigest, "backup.example.com/busybox@" + testDigest},
+		},
+		{
+			"example.com/a/b@" + testDigest,
+			[]string{"mirror:5000/example/a/b@" + testDigest},
+		},
+		// Only the most specific source applies, a prefix has to end at a
+		// path component
+		{
+			"quay.io/openshift-release-dev/ocp-release-nightly@" + testDigest,
+			[]string{"mirror:5000/ocp/ocp-release-nightly@" + testDigest},
+		},
+		{
+			"registry:5001/org/repo@" + testDigest,
+			[]string{},
+		},
+	}
+
+	for _, test := range tests {
+		want := append(test.candidates, test.entry)
+		if got := mirrors.Candidates(test.entry); !reflect.DeepEqual(got, want) {
+			t.Errorf("Candidates(%q) = %v, want %v", test.entry, got, want)
+		}
+	}
+}
+
+func TestCandidatesOfTags(t *testing.T) {
+
+	// Mirrors only apply to digest references
+	mirrors := Mirrors{{"registry:5000/org", []string{"mirror:5000/org"}}}
+
+	for _, entry := range []string{"registry:5000/org/repo:tag", "registry:5000/org/repo"} {
+		candidates := mirrors.Candidates(entry)
+		if !reflect.DeepEqual(candidates, []string{entry}) {
+			t.Errorf("Candidates(%q) = %v, want %v", entry, candidates, []string{entry})
+		}
+	}
+}
+
+func TestMirrorsAdd(t *testing.T) {
+
+	// A source of several policies keeps its first position
+	mirrors := Mirrors{}
+	mirrors.add("quay.io/a", []string{"mirror:5000/a"})
+	mirrors.add("quay.io/b", []string{"mirror:5000/b"})
+	mirrors.add("quay.io/a", []string{"backup.example.com/a", "mirror:5000/a"})
+
+	want := Mirrors{
+		{"quay.io/a", []string{"mirror:5000/a", "backup.example.com/a"}},
+		{"quay.io/b", []string{"mirror:5000/b"}},
+	}
+	if !reflect.DeepEqual(mirrors, want) {
+		t.Errorf("add = %v, want %v", mirrors, want)
+	}
+}
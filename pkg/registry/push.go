@@ -0,0 +1,43 @@
+package registry
+
+import (
+	"github.com/google/go-containerregistry/pkg/crane"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+)
+
+// Copy copies the image src to dst, credentials from providers are tried
+// before the default credential chain.
+func Copy(src string, dst string, providers ...KeychainProvider) error {
+
+	opts, err := optionsWith(append(providers, DefaultProviders()...))
+	if err != nil {
+		return errors.Wrap(err, "Cannot setup registry transport")
+	}
+
+	log.Info("Copying image", "src", src, "dst", dst)
+
+	if err := crane.Copy(src, dst, forRefs(opts, src, dst)...); err != nil {
+		return errors.Wrap(err, "Cannot copy "+src+" to "+dst)
+	}
+
+	return nil
+}
+
+// Push pushes img to dst, credentials from providers are tried before the
+// default credential chain.
+func Push(img v1.Image, dst string, providers ...KeychainProvider) error {
+
+	opts, err := optionsWith(append(providers, DefaultProviders()...))
+	if err != nil {
+		return errors.Wrap(err, "Cannot setup registry transport")
+	}
+
+	log.Info("Pushing image", "dst", dst)
+
+	if err := crane.Push(img, dst, forRefs(opts, dst)...); err != nil {
+		return errors.Wrap(err, "Cannot push image to "+dst)
+	}
+
+	return nil
+}
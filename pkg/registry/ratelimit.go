@@ -0,0 +1,172 @@
+package registry
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// RegistryConfigMap optional ConfigMap in the operator namespace tuning the
+// registry access, keys are qps, burst and maxConcurrentLayerPulls.
+const RegistryConfigMap = "special-resource-registry-config"
+
+// Limits are shared by all SpecialResources reconciling in parallel
+var Limits = &RateLimits{
+	QPS:                     10,
+	Burst:                   20,
+	MaxConcurrentLayerPulls: 4,
+}
+
+// RateLimits a token bucket per registry host and a cap on the layer
+// downloads running at the same time.
+type RateLimits struct {
+	QPS                     float32
+	Burst                   int
+	MaxConcurrentLayerPulls int
+
+	mutex    sync.Mutex
+	limiters map[string]flowcontrol.RateLimiter
+	pulls    chan struct{}
+}
+
+// limiter returns the token bucket of host
+func (l *RateLimits) limiter(host string) flowcontrol.RateLimiter {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.limiters == nil {
+		l.limiters = make(map[string]flowcontrol.RateLimiter)
+	}
+
+	limiter, found := l.limiters[host]
+	if !found {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(l.QPS, l.Burst)
+		l.limiters[host] = limiter
+	}
+
+	return limiter
+}
+
+func (l *RateLimits) layerPulls() chan struct{} {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.pulls == nil || cap(l.pulls) != l.MaxConcurrentLayerPulls {
+		l.pulls = make(chan struct{}, l.MaxConcurrentLayerPulls)
+	}
+
+	return l.pulls
+}
+
+// update reads the RegistryConfigMap, missing or invalid keys keep the
+// current value.
+func (l *RateLimits) update() error {
+
+	namespace := os.Getenv("OPERATOR_NAMESPACE")
+	if namespace == "" {
+		return nil
+	}
+
+	cm, err := clients.GetConfigMap(namespace, RegistryConfigMap)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "Cannot get registry ConfigMap "+namespace+"/"+RegistryConfigMap)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	qps, burst := l.QPS, l.Burst
+
+	if value, found := cm.Data["qps"]; found {
+		qps, err := strconv.ParseFloat(value, 32)
+		warn.OnError(errors.Wrap(err, "Invalid qps "+value))
+		if err == nil && qps > 0 {
+			l.QPS = float32(qps)
+		}
+	}
+
+	if value, found := cm.Data["burst"]; found {
+		burst, err := strconv.Atoi(value)
+		warn.OnError(errors.Wrap(err, "Invalid burst "+value))
+		if err == nil && burst > 0 {
+			l.Burst = burst
+		}
+	}
+
+	if value, found := cm.Data["maxConcurrentLayerPulls"]; found {
+		pulls, err := strconv.Atoi(value)
+		warn.OnError(errors.Wrap(err, "Invalid maxConcurrentLayerPulls "+value))
+		if err == nil && pulls > 0 {
+			l.MaxConcurrentLayerPulls = pulls
+		}
+	}
+
+	// Changed limits take effect with new token buckets
+	if qps != l.QPS || burst != l.Burst {
+		log.Info("Registry rate limits changed", "qps", l.QPS, "burst", l.Burst)
+		l.limiters = nil
+	}
+
+	return nil
+}
+
+// limitTransport waits for a token of the registry host before every request
+// and holds a layer pull slot until a blob body was consumed.
+type limitTransport struct {
+	inner  http.RoundTripper
+	limits *RateLimits
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	if err := t.limits.limiter(req.URL.Host).Wait(req.Context()); err != nil {
+		return nil, errors.Wrap(err, "Rate limit wait for "+req.URL.Host)
+	}
+
+	if requestKind(req) != "blob" {
+		return t.inner.RoundTrip(req)
+	}
+
+	pulls := t.limits.layerPulls()
+
+	select {
+	case pulls <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil {
+		<-pulls
+		return nil, err
+	}
+
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: func() { <-pulls }}
+
+	return resp, nil
+}
+
+// releaseBody frees the layer pull slot once when the body is closed
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseBody) Close() error {
+	defer b.once.Do(b.release)
+	return b.ReadCloser.Close()
+}
@@ -1,24 +1,15 @@
 package registry
 
 import (
-	"archive/tar"
-	"compress/gzip"
-	"context"
 	"encoding/json"
 	"io"
-	"strings"
 
 	"github.com/go-logr/logr"
-	"github.com/google/go-containerregistry/pkg/authn"
-	"github.com/google/go-containerregistry/pkg/authn/k8schain"
 	"github.com/google/go-containerregistry/pkg/crane"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
-	"github.com/openshift-psap/special-resource-operator/pkg/clients"
 	"github.com/openshift-psap/special-resource-operator/pkg/color"
-	"github.com/openshift-psap/special-resource-operator/pkg/exit"
 	"github.com/openshift-psap/special-resource-operator/pkg/warn"
 	"github.com/pkg/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -39,160 +30,286 @@ type DriverToolkitEntry struct {
 	OSVersion           string `json:"OSVersion"`
 }
 
-func LastLayer(entry string) v1.Layer {
+// imageLayers returns the repository the manifest of entry was found in and
+// the layer digests from bottom to top.
+func imageLayers(entry string) (string, []string, error) {
 
-	var repo string
+	// Air-gapped clusters without a mirror can provide the images locally,
+	// the entry itself is used as repository for the layer lookup.
+	if LocalSource != "" {
+		digests, err := localLayers(entry)
+		return entry, digests, err
+	}
 
-	if hash := strings.Split(entry, "@"); len(hash) > 1 {
-		repo = hash[0]
-	} else if tag := strings.Split(entry, ":"); len(tag) > 1 {
-		repo = tag[0]
+	opts, err := options()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Cannot setup registry transport")
 	}
 
-	err := setAuthnKeychain()
-	exit.OnError(err)
+	mirrors, err := ClusterMirrors()
+	warn.OnError(errors.Wrap(err, "Cannot get mirrors, using source only"))
+
+	var manifest []byte
 
-	options := crane.NilOption
+	// Disconnected clusters mirror the release and DTK images, try the
+	// mirrors first and fall back to the source registry.
+	for _, candidate := range mirrors.Candidates(entry) {
+		if manifest, err = crane.Manifest(candidate, forRefs(opts, candidate)...); err == nil {
+			entry = candidate
+			break
+		}
+		warn.OnError(errors.Wrap(err, "Cannot extract manifest from "+candidate))
+	}
+
+	if manifest == nil {
+		return "", nil, errors.New("Cannot get manifest of " + entry)
+	}
+
+	repo, err := Repository(entry)
+	if err != nil {
+		return "", nil, err
+	}
 
-	manifest, err := crane.Manifest(entry, options)
+	manifest, err = imageManifest(repo, manifest, Architecture, opts)
 	if err != nil {
-		warn.OnError(errors.Wrap(err, "Cannot extract manifest"))
-		return nil
+		return "", nil, errors.Wrap(err, "Cannot resolve image manifest of "+entry)
+	}
+
+	// Older registries serve schema1 only, its layers are the same blobs
+	if schema1, ok := parseSchema1(manifest); ok {
+		log.Info("Reading layers of schema1 manifest", "image", entry)
+		digests, err := schema1.layerDigests()
+		if err != nil {
+			return "", nil, errors.Wrap(err, "Cannot read layers of "+entry)
+		}
+		if len(digests) == 0 {
+			return "", nil, errors.New("No layers in manifest of " + entry)
+		}
+		return repo, digests, nil
 	}
 
 	release := unstructured.Unstructured{}
-	err = json.Unmarshal(manifest, &release.Object)
-	exit.OnError(err)
+	if err = json.Unmarshal(manifest, &release.Object); err != nil {
+		return "", nil, errors.Wrap(err, "Cannot unmarshal manifest of "+entry)
+	}
 
 	layers, _, err := unstructured.NestedSlice(release.Object, "layers")
-	exit.OnError(err)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Cannot extract layers of "+entry)
+	}
 
-	last := layers[len(layers)-1]
+	digests := []string{}
+	for _, layer := range layers {
+		if digest, ok := layer.(map[string]interface{})["digest"].(string); ok {
+			digests = append(digests, digest)
+		}
+	}
 
-	digest := last.(map[string]interface{})["digest"].(string)
+	if len(digests) == 0 {
+		return "", nil, errors.New("No layers in manifest of " + entry)
+	}
 
-	layer, err := crane.PullLayer(repo+"@"+digest, options)
-	exit.OnError(err)
+	return repo, digests, nil
+}
+
+// GetLayerByDigest pulls the layer repo@digest, layers are served from the
+// on-disk LayerCache if already downloaded or from the LocalSource if set.
+func GetLayerByDigest(repo string, digest string) (v1.Layer, error) {
+
+	if LocalSource != "" {
+		return localLayer(repo, digest)
+	}
+
+	if layer, found := LayerCache.Get(digest); found {
+		return layer, nil
+	}
 
-	return layer
+	opts, err := options()
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot setup registry transport")
+	}
+
+	layer, err := crane.PullLayer(repo+"@"+digest, forRefs(opts, repo)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot pull layer "+repo+"@"+digest)
+	}
+
+	cached, err := LayerCache.Put(digest, layer)
+	if err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot cache layer, using remote layer"))
+		return layer, nil
+	}
+
+	return cached, nil
 }
 
-func ExtractToolkitRelease(layer v1.Layer) (DriverToolkitEntry, error) {
+const (
+	toolkitReleaseFile  = "etc/driver-toolkit-release.json"
+	imageReferencesFile = "release-manifests/image-references"
+	releaseMetadataFile = "release-manifests/release-metadata"
+)
+
+// ToolkitRelease reads /etc/driver-toolkit-release.json from the DTK image,
+// all layers are searched, not only the last one.
+func ToolkitRelease(imageURL string) (DriverToolkitEntry, error) {
 
-	targz, err := layer.Compressed()
-	defer dclose(targz)
-	exit.OnError(err)
+	dtks, errs := ToolkitReleases([]string{imageURL})
 
-	gr, err := gzip.NewReader(targz)
-	defer dclose(gr)
-	exit.OnError(err)
+	return dtks[imageURL], errs[imageURL]
+}
 
-	tr := tar.NewReader(gr)
+// ToolkitReleases reads the DTK release of several images in one
+// ExtractBatch, DTK images of different releases share most of their layers
+func ToolkitReleases(images []string) (map[string]DriverToolkitEntry, map[string]error) {
 
-	var dtk DriverToolkitEntry
+	requests := make(map[string][]string)
+	for _, image := range images {
+		requests[image] = []string{toolkitReleaseFile}
+	}
 
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
+	files, errs := ExtractBatch(requests)
+	for image, err := range errs {
+		errs[image] = errors.Wrap(err, "Missing driver toolkit entry: /"+toolkitReleaseFile)
+	}
+
+	dtks := make(map[string]DriverToolkitEntry)
+	for image, found := range files {
+		buff, ok := found[toolkitReleaseFile]
+		if !ok {
+			errs[image] = errors.New("Missing driver toolkit entry: /" + toolkitReleaseFile + " not found in " + image)
+			continue
+		}
+		dtk, err := parseToolkitRelease(buff)
+		if err != nil {
+			errs[image] = err
+			continue
 		}
+		dtks[image] = dtk
+	}
 
-		if header.Name == "etc/driver-toolkit-release.json" {
-			buff, err := io.ReadAll(tr)
-			exit.OnError(err)
+	return dtks, errs
+}
 
-			obj := unstructured.Unstructured{}
+// parseToolkitRelease a malformed release file is an error of its image
+// only, it must not stop the operator
+func parseToolkitRelease(buff []byte) (DriverToolkitEntry, error) {
 
-			err = json.Unmarshal(buff, &obj.Object)
-			exit.OnError(err)
+	var dtk DriverToolkitEntry
 
-			entry, _, err := unstructured.NestedString(obj.Object, "KERNEL_VERSION")
-			exit.OnError(err)
-			log.Info("DTK", "kernel-version", entry)
-			dtk.KernelFullVersion = entry
+	obj := unstructured.Unstructured{}
 
-			entry, _, err = unstructured.NestedString(obj.Object, "RT_KERNEL_VERSION")
-			exit.OnError(err)
-			log.Info("DTK", "rt-kernel-version", entry)
-			dtk.RTKernelFullVersion = entry
+	if err := json.Unmarshal(buff, &obj.Object); err != nil {
+		return dtk, errors.Wrap(err, "Cannot unmarshal /"+toolkitReleaseFile)
+	}
 
-			entry, _, err = unstructured.NestedString(obj.Object, "RHEL_VERSION")
-			exit.OnError(err)
-			log.Info("DTK", "rhel-version", entry)
-			dtk.OSVersion = entry
+	entry, _, err := unstructured.NestedString(obj.Object, "KERNEL_VERSION")
+	if err != nil {
+		return dtk, errors.Wrap(err, "Cannot read KERNEL_VERSION of /"+toolkitReleaseFile)
+	}
+	log.Info("DTK", "kernel-version", entry)
+	dtk.KernelFullVersion = entry
 
-			return dtk, err
-		}
+	entry, _, err = unstructured.NestedString(obj.Object, "RT_KERNEL_VERSION")
+	if err != nil {
+		return dtk, errors.Wrap(err, "Cannot read RT_KERNEL_VERSION of /"+toolkitReleaseFile)
+	}
+	log.Info("DTK", "rt-kernel-version", entry)
+	dtk.RTKernelFullVersion = entry
 
+	entry, _, err = unstructured.NestedString(obj.Object, "RHEL_VERSION")
+	if err != nil {
+		return dtk, errors.Wrap(err, "Cannot read RHEL_VERSION of /"+toolkitReleaseFile)
 	}
+	log.Info("DTK", "rhel-version", entry)
+	dtk.OSVersion = entry
+
+	return dtk, nil
+}
 
-	return dtk, errors.New("Missing driver toolkit entry: /etc/driver-toolkit-release.json")
+// Release the OCP version and the driver-toolkit image of a release payload
+type Release struct {
+	Version       string
+	DriverToolkit string
 }
 
-func ReleaseManifests(layer v1.Layer) (key string, value string) {
+// ReleaseManifests returns the OCP version and the driver-toolkit image of
+// the release payload image, empty if the payload does not list them
+func ReleaseManifests(image string) (string, string, error) {
 
-	targz, err := layer.Compressed()
-	defer dclose(targz)
-	exit.OnError(err)
+	releases, errs := Releases([]string{image})
 
-	gr, err := gzip.NewReader(targz)
-	defer dclose(gr)
-	exit.OnError(err)
+	return releases[image].Version, releases[image].DriverToolkit, errs[image]
+}
 
-	tr := tar.NewReader(gr)
+// Releases reads the release manifests of several release payloads in one
+// ExtractBatch
+func Releases(images []string) (map[string]Release, map[string]error) {
 
-	version := ""
-	imageURL := ""
+	requests := make(map[string][]string)
+	for _, image := range images {
+		requests[image] = []string{imageReferencesFile, releaseMetadataFile}
+	}
 
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
+	files, errs := ExtractBatch(requests)
 
-		if header.Name == "release-manifests/image-references" {
+	releases := make(map[string]Release)
+	for image, found := range files {
+		release, err := parseReleaseManifests(found)
+		if err != nil {
+			errs[image] = errors.Wrap(err, "Cannot read release manifests of "+image)
+			continue
+		}
+		releases[image] = release
+	}
 
-			buff, err := io.ReadAll(tr)
-			exit.OnError(err)
+	return releases, errs
+}
 
-			obj := unstructured.Unstructured{}
+func parseReleaseManifests(files map[string][]byte) (Release, error) {
 
-			err = json.Unmarshal(buff, &obj.Object)
-			exit.OnError(err)
+	release := Release{}
 
-			tags, _, err := unstructured.NestedSlice(obj.Object, "spec", "tags")
-			exit.OnError(err)
+	if buff, found := files[imageReferencesFile]; found {
 
-			for _, tag := range tags {
-				if tag.(map[string]interface{})["name"] == "driver-toolkit" {
-					from := tag.(map[string]interface{})["from"]
-					imageURL = from.(map[string]interface{})["name"].(string)
-				}
-			}
+		obj := unstructured.Unstructured{}
 
+		if err := json.Unmarshal(buff, &obj.Object); err != nil {
+			return release, errors.Wrap(err, "Cannot unmarshal /"+imageReferencesFile)
 		}
 
-		if header.Name == "release-manifests/release-metadata" {
+		tags, _, err := unstructured.NestedSlice(obj.Object, "spec", "tags")
+		if err != nil {
+			return release, errors.Wrap(err, "Cannot read tags of /"+imageReferencesFile)
+		}
 
-			buff, err := io.ReadAll(tr)
-			exit.OnError(err)
+		for _, tag := range tags {
+			tag, ok := tag.(map[string]interface{})
+			if !ok || tag["name"] != "driver-toolkit" {
+				continue
+			}
+			release.DriverToolkit, _, err = unstructured.NestedString(tag, "from", "name")
+			if err != nil {
+				return release, errors.Wrap(err, "Cannot read driver-toolkit of /"+imageReferencesFile)
+			}
+		}
+	}
 
-			obj := unstructured.Unstructured{}
+	if buff, found := files[releaseMetadataFile]; found {
 
-			err = json.Unmarshal(buff, &obj.Object)
-			exit.OnError(err)
+		obj := unstructured.Unstructured{}
 
-			version, _, err = unstructured.NestedString(obj.Object, "version")
-			exit.OnError(err)
+		if err := json.Unmarshal(buff, &obj.Object); err != nil {
+			return release, errors.Wrap(err, "Cannot unmarshal /"+releaseMetadataFile)
 		}
 
-		if version != "" && imageURL != "" {
-			break
+		version, _, err := unstructured.NestedString(obj.Object, "version")
+		if err != nil {
+			return release, errors.Wrap(err, "Cannot read version of /"+releaseMetadataFile)
 		}
-
+		release.Version = version
 	}
 
-	return version, imageURL
+	return release, nil
 }
 
 func dclose(c io.Closer) {
@@ -201,27 +318,3 @@ func dclose(c io.Closer) {
 		//log.Error(err)
 	}
 }
-
-func setAuthnKeychain() error {
-	var err error
-	pullSecretNamespace := "openshift-config"
-
-	_, err = clients.Interface.CoreV1().Namespaces().Get(context.TODO(), pullSecretNamespace, metav1.GetOptions{})
-
-	if err != nil {
-		log.Info("Cannot find namespace for pull-secret, assuming vanilla k8s")
-		return nil
-	} else {
-		authn.DefaultKeychain, err = k8schain.NewInCluster(context.TODO(), k8schain.Options{
-			Namespace:          "openshift-config",
-			ServiceAccountName: "default",
-			ImagePullSecrets: []string{
-				"pull-secret",
-			},
-		})
-		if err != nil {
-			return errors.Wrap(err, "Cannot set authn.DefaultKeychain")
-		}
-	}
-	return nil
-}
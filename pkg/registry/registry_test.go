@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"testing"
+)
+
+func TestParseToolkitRelease(t *testing.T) {
+
+	dtk, err := parseToolkitRelease([]byte(`{"KERNEL_VERSION": "4.18.0-305.el8.x86_64", "RT_KERNEL_VERSION": "4.18.0-305.rt7.72.el8.x86_64", "RHEL_VERSION": "8.4"}`))
+	if err != nil {
+		t.Fatalf("parseToolkitRelease failed: %v", err)
+	}
+
+	want := DriverToolkitEntry{
+		KernelFullVersion:   "4.18.0-305.el8.x86_64",
+		RTKernelFullVersion: "4.18.0-305.rt7.72.el8.x86_64",
+		OSVersion:           "8.4",
+	}
+	if dtk != want {
+		t.Errorf("parseToolkitRelease = %+v, want %+v", dtk, want)
+	}
+}
+
+func TestParseToolkitReleaseInvalid(t *testing.T) {
+
+	// A bad image is an error of that image only
+	for _, buff := range []string{
+		``,
+		`{"KERNEL_VERSION": `,
+		`["4.18.0-305.el8.x86_64"]`,
+		`{"KERNEL_VERSION": 4}`,
+		`{"KERNEL_VERSION": "4.18.0-305.el8.x86_64", "RHEL_VERSION": {"major": 8}}`,
+	} {
+		if dtk, err := parseToolkitRelease([]byte(buff)); err == nil {
+			t.Errorf("parseToolkitRelease(%q) = %+v, want error", buff, dtk)
+		}
+	}
+}
+
+func TestParseReleaseManifests(t *testing.T) {
+
+	release, err := parseReleaseManifests(map[string][]byte{
+		imageReferencesFile: []byte(`{"spec": {"tags": [
+			{"name": "cli", "from": {"name": "quay.io/ocp@sha256:1"}},
+			{"name": "driver-toolkit", "from": {"name": "quay.io/ocp@sha256:2"}}
+		]}}`),
+		releaseMetadataFile: []byte(`{"version": "4.8.0"}`),
+	})
+	if err != nil {
+		t.Fatalf("parseReleaseManifests failed: %v", err)
+	}
+
+	want := Release{Version: "4.8.0", DriverToolkit: "quay.io/ocp@sha256:2"}
+	if release != want {
+		t.Errorf("parseReleaseManifests = %+v, want %+v", release, want)
+	}
+}
+
+func TestParseReleaseManifestsInvalid(t *testing.T) {
+
+	for _, files := range []map[string][]byte{
+		{imageReferencesFile: []byte(`{"spec": `)},
+		{imageReferencesFile: []byte(`{"spec": {"tags": "driver-toolkit"}}`)},
+		{imageReferencesFile: []byte(`{"spec": {"tags": [{"name": "driver-toolkit", "from": {"name": 1}}]}}`)},
+		{releaseMetadataFile: []byte(`{"version": 4.8}`)},
+	} {
+		if release, err := parseReleaseManifests(files); err == nil {
+			t.Errorf("parseReleaseManifests(%s) = %+v, want error", files, release)
+		}
+	}
+}
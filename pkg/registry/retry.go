@@ -0,0 +1,108 @@
+package registry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Retry configures how often and how long registry requests are retried,
+// Timeout bounds a single request including reading the response body.
+var Retry = RetryConfig{
+	Steps:    5,
+	Duration: time.Second,
+	Factor:   2.0,
+	Timeout:  10 * time.Minute,
+}
+
+type RetryConfig struct {
+	Steps    int
+	Duration time.Duration
+	Factor   float64
+	Timeout  time.Duration
+}
+
+// retryTransport retries requests that failed with a network error, 429 or
+// a 5xx with exponential backoff.
+type retryTransport struct {
+	inner  http.RoundTripper
+	config RetryConfig
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	backoff := wait.Backoff{
+		Steps:    t.config.Steps,
+		Duration: t.config.Duration,
+		Factor:   t.config.Factor,
+		Jitter:   0.1,
+	}
+
+	for {
+		resp, cancel, err := t.roundTrip(req)
+
+		retriable := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		// Requests with a body can only be replayed if they can be rewound
+		replayable := req.Body == nil || req.GetBody != nil
+
+		if !retriable || !replayable || backoff.Steps <= 1 {
+			if resp != nil {
+				resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+			} else {
+				cancel()
+			}
+			return resp, err
+		}
+
+		if err != nil {
+			log.Info("Registry request failed, retrying", "url", req.URL.Redacted(), "error", err.Error())
+		} else {
+			log.Info("Registry request failed, retrying", "url", req.URL.Redacted(), "status", resp.StatusCode)
+			dclose(resp.Body)
+		}
+		cancel()
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff.Step()):
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+func (t *retryTransport) roundTrip(req *http.Request) (*http.Response, context.CancelFunc, error) {
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	if t.config.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(req.Context(), t.config.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(req.Context())
+	}
+
+	resp, err := t.inner.RoundTrip(req.Clone(ctx))
+	return resp, cancel, err
+}
+
+// cancelBody releases the request context once the body was consumed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
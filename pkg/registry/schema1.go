@@ -0,0 +1,134 @@
+package registry
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/pkg/errors"
+)
+
+// Schema1Error is returned for images only available as deprecated Docker
+// schema1 manifest where a v2 image is needed, e.g. to pull it as a whole
+type Schema1Error struct {
+	Image string
+}
+
+func (e *Schema1Error) Error() string {
+	return "Image " + e.Image + " has a Docker schema1 manifest which is not supported, " +
+		"copy it with a current client to convert it to schema2, e.g. " +
+		"skopeo copy --format v2s2 docker://" + e.Image + " docker://<registry>/<repository>:<tag>"
+}
+
+// schema1Error replaces the ErrSchema1 of go-containerregistry with the
+// actionable Schema1Error
+func schema1Error(image string, err error) error {
+	var schema1 *remote.ErrSchema1
+	if errors.As(err, &schema1) {
+		return &Schema1Error{Image: image}
+	}
+	return err
+}
+
+// schema1Manifest the fields of a Docker schema1 manifest, layers and
+// history are ordered from top to bottom
+type schema1Manifest struct {
+	SchemaVersion int `json:"schemaVersion"`
+	FSLayers      []struct {
+		BlobSum string `json:"blobSum"`
+	} `json:"fsLayers"`
+	History []struct {
+		V1Compatibility string `json:"v1Compatibility"`
+	} `json:"history"`
+}
+
+// v1Compatibility the docker v1 image JSON of a history entry
+type v1Compatibility struct {
+	Throwaway bool `json:"throwaway"`
+	Config    struct {
+		Labels     map[string]string `json:"Labels"`
+		Env        []string          `json:"Env"`
+		Entrypoint []string          `json:"Entrypoint"`
+		Cmd        []string          `json:"Cmd"`
+		WorkingDir string            `json:"WorkingDir"`
+		User       string            `json:"User"`
+	} `json:"config"`
+}
+
+// parseSchema1 returns the schema1 manifest of raw, false for other
+// manifests. The media type is not always set, the schemaVersion is.
+func parseSchema1(raw []byte) (*schema1Manifest, bool) {
+
+	manifest := &schema1Manifest{}
+	if err := json.Unmarshal(raw, manifest); err != nil || manifest.SchemaVersion != 1 {
+		return nil, false
+	}
+	return manifest, true
+}
+
+// layerDigests returns the digests from bottom to top, layers of history
+// entries without filesystem changes are skipped
+func (m *schema1Manifest) layerDigests() ([]string, error) {
+
+	if len(m.History) != 0 && len(m.History) != len(m.FSLayers) {
+		return nil, errors.New("Schema1 manifest has " + strconv.Itoa(len(m.FSLayers)) + " layers but " + strconv.Itoa(len(m.History)) + " history entries")
+	}
+
+	digests := []string{}
+	for idx := len(m.FSLayers) - 1; idx >= 0; idx-- {
+		if len(m.History) != 0 {
+			compat := v1Compatibility{}
+			if err := json.Unmarshal([]byte(m.History[idx].V1Compatibility), &compat); err != nil {
+				return nil, errors.Wrap(err, "Cannot parse history of schema1 manifest")
+			}
+			if compat.Throwaway {
+				continue
+			}
+		}
+		digests = append(digests, m.FSLayers[idx].BlobSum)
+	}
+
+	return digests, nil
+}
+
+// config returns the config of the top history entry, schema1 has no
+// config blob
+func (m *schema1Manifest) config() (ImageConfig, error) {
+
+	if len(m.History) == 0 {
+		return ImageConfig{}, errors.New("Schema1 manifest has no history")
+	}
+
+	compat := v1Compatibility{}
+	if err := json.Unmarshal([]byte(m.History[0].V1Compatibility), &compat); err != nil {
+		return ImageConfig{}, errors.Wrap(err, "Cannot parse history of schema1 manifest")
+	}
+
+	return ImageConfig{
+		Labels:     compat.Config.Labels,
+		Env:        compat.Config.Env,
+		Entrypoint: compat.Config.Entrypoint,
+		Cmd:        compat.Config.Cmd,
+		WorkingDir: compat.Config.WorkingDir,
+		User:       compat.Config.User,
+	}, nil
+}
+
+// schema1Config reads the config of a schema1 image from its manifest
+func schema1Config(image string, opts []crane.Option) (ImageConfig, error) {
+
+	raw, err := crane.Manifest(image, opts...)
+	if err != nil {
+		return ImageConfig{}, errors.Wrap(err, "Cannot get manifest of "+image)
+	}
+
+	manifest, ok := parseSchema1(raw)
+	if !ok {
+		return ImageConfig{}, errors.New("Expected a schema1 manifest for " + image)
+	}
+
+	log.Info("Reading config of schema1 manifest", "image", image)
+
+	return manifest.config()
+}
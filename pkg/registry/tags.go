@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// Repository returns the repository of an image reference without tag or
+// digest
+func Repository(image string) (string, error) {
+
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot parse image reference "+image)
+	}
+
+	return ref.Context().Name(), nil
+}
+
+// referenceDigest returns the digest of a digest reference, a tag next to
+// the digest is ignored like the container runtimes do
+func referenceDigest(entry string) (string, bool) {
+
+	digest, err := name.NewDigest(entry)
+	if err != nil {
+		return "", false
+	}
+
+	return digest.DigestStr(), true
+}
+
+// ListTags returns all tags of repo
+func ListTags(repo string) ([]string, error) {
+
+	opts, err := options()
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot setup registry transport")
+	}
+
+	tags, err := crane.ListTags(repo, forRefs(opts, repo)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot list tags of "+repo)
+	}
+
+	return tags, nil
+}
+
+// LatestTag returns the newest tag of repo matching the semver constraint,
+// e.g. ">=515.0.0 <520", tags that are not a version are ignored.
+func LatestTag(repo string, constraint string) (string, error) {
+
+	tags, err := ListTags(repo)
+	if err != nil {
+		return "", err
+	}
+
+	latest, err := NewestMatchingTag(tags, constraint)
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot select tag of "+repo)
+	}
+
+	log.Info("Selected tag", "repo", repo, "constraint", constraint, "tag", latest)
+	return latest, nil
+}
+
+// NewestMatchingTag picks the highest version from tags satisfying constraint
+func NewestMatchingTag(tags []string, constraint string) (string, error) {
+
+	latest := ""
+
+	for _, tag := range tags {
+		if !chartutil.IsCompatibleRange(constraint, tag) {
+			continue
+		}
+		if latest == "" || chartutil.IsCompatibleRange(">"+latest, tag) {
+			latest = tag
+		}
+	}
+
+	if latest == "" {
+		return "", errors.New("No tag matches constraint " + constraint)
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,127 @@
+package registry
+
+import (
+	"testing"
+)
+
+func TestRepository(t *testing.T) {
+
+	tests := []struct {
+		entry string
+		repo  string
+	}{
+		{"quay.io/org/repo:tag", "quay.io/org/repo"},
+		{"quay.io/org/repo", "quay.io/org/repo"},
+		{"quay.io/org/repo@" + testDigest, "quay.io/org/repo"},
+		{"quay.io/org/repo:tag@" + testDigest, "quay.io/org/repo"},
+		{"registry:5000/org/repo:tag", "registry:5000/org/repo"},
+		{"registry:5000/org/repo", "registry:5000/org/repo"},
+		{"registry:5000/org/repo@" + testDigest, "registry:5000/org/repo"},
+		{"registry:5000/org/repo:tag@" + testDigest, "registry:5000/org/repo"},
+		{"localhost:5000/repo:5000", "localhost:5000/repo"},
+		{"image-registry.openshift-image-registry.svc:5000/ns/driver:4.18.0-305.el8.x86_64", "image-registry.openshift-image-registry.svc:5000/ns/driver"},
+		{"registry.example.com/a/b/c/repo:tag", "registry.example.com/a/b/c/repo"},
+		{"[::1]:5000/repo:tag", "[::1]:5000/repo"},
+		{"busybox", "index.docker.io/library/busybox"},
+		{"busybox:latest", "index.docker.io/library/busybox"},
+		{"docker.io/org/repo:tag", "index.docker.io/org/repo"},
+	}
+
+	for _, test := range tests {
+		repo, err := Repository(test.entry)
+		if err != nil {
+			t.Errorf("Repository(%q) failed: %v", test.entry, err)
+			continue
+		}
+		if repo != test.repo {
+			t.Errorf("Repository(%q) = %q, want %q", test.entry, repo, test.repo)
+		}
+	}
+}
+
+func TestRepositoryInvalid(t *testing.T) {
+
+	for _, entry := range []string{
+		"",
+		"Quay.io/Org/Repo:tag",
+		"quay.io/org/repo:tag@sha256:short",
+		"quay.io/org/repo@" + testDigest + "@" + testDigest,
+		"quay.io/org/repo:t@g",
+	} {
+		if repo, err := Repository(entry); err == nil {
+			t.Errorf("Repository(%q) = %q, want error", entry, repo)
+		}
+	}
+}
+
+func TestNewestMatchingTag(t *testing.T) {
+
+	tags := []string{"latest", "470.141.03", "515.43.04", "515.65.01", "520.56.06", "515.65.01-rc1"}
+
+	tests := []struct {
+		constraint string
+		tag        string
+	}{
+		{">=515.0.0 <520", "515.65.01"},
+		{"~470", "470.141.03"},
+		{">=470", "520.56.06"},
+		{"515.43.04", "515.43.04"},
+	}
+
+	for _, test := range tests {
+		tag, err := NewestMatchingTag(tags, test.constraint)
+		if err != nil {
+			t.Errorf("NewestMatchingTag(%q) failed: %v", test.constraint, err)
+			continue
+		}
+		if tag != test.tag {
+			t.Errorf("NewestMatchingTag(%q) = %q, want %q", test.constraint, tag, test.tag)
+		}
+	}
+}
+
+func TestNewestMatchingTagNone(t *testing.T) {
+
+	// Tags that are not a version never match
+	for _, constraint := range []string{">=600", "latest", "not a constraint"} {
+		if tag, err := NewestMatchingTag([]string{"latest", "515.65.01"}, constraint); err == nil {
+			t.Errorf("NewestMatchingTag(%q) = %q, want error", constraint, tag)
+		}
+	}
+}
+
+func TestReferenceDigest(t *testing.T) {
+
+	tests := []struct {
+		entry  string
+		digest string
+		found  bool
+	}{
+		{"quay.io/org/repo@" + testDigest, testDigest, true},
+		{"quay.io/org/repo:tag@" + testDigest, testDigest, true},
+		{"registry:5000/org/repo@" + testDigest, testDigest, true},
+		{"registry:5000/org/repo:tag@" + testDigest, testDigest, true},
+		{"registry:5000/org/repo:tag", "", false},
+		{"quay.io/org/repo", "", false},
+		{"quay.io/org/repo@sha256:short", "", false},
+	}
+
+	for _, test := range tests {
+		digest, found := referenceDigest(test.entry)
+		if digest != test.digest || found != test.found {
+			t.Errorf("referenceDigest(%q) = %q, %v, want %q, %v", test.entry, digest, found, test.digest, test.found)
+		}
+	}
+}
+
+func TestResolveDigestOfDigestReference(t *testing.T) {
+
+	// Digest references are resolved without contacting the registry
+	digest, err := ResolveDigest("registry:5000/org/repo:tag@" + testDigest)
+	if err != nil {
+		t.Fatalf("ResolveDigest failed: %v", err)
+	}
+	if digest != testDigest {
+		t.Errorf("ResolveDigest = %q, want %q", digest, testDigest)
+	}
+}
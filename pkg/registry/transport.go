@@ -0,0 +1,169 @@
+package registry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/proxy"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RegistryCAConfigMap optional ConfigMap in the operator namespace holding
+// additional PEM encoded CAs, every key is added to the trust store.
+const RegistryCAConfigMap = "special-resource-registry-ca"
+
+// options returns the crane options used for all remote registry operations
+func options() ([]crane.Option, error) {
+	return optionsWith(DefaultProviders())
+}
+
+func optionsWith(providers []KeychainProvider) ([]crane.Option, error) {
+
+	transport, err := Transport()
+	if err != nil {
+		return nil, err
+	}
+
+	warn.OnError(errors.Wrap(Limits.update(), "Cannot update registry rate limits"))
+
+	kc, err := keychain(providers)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot setup registry credentials")
+	}
+
+	return []crane.Option{
+		crane.NilOption,
+		crane.WithTransport(&retryTransport{
+			inner:  &limitTransport{inner: &metricsTransport{inner: newInsecureTransport(transport)}, limits: Limits},
+			config: Retry,
+		}),
+		crane.WithAuthFromKeychain(kc),
+	}, nil
+}
+
+// Transport returns the default remote transport extended with the
+// additional trusted CAs and the proxy settings of the cluster.
+func Transport() (*http.Transport, error) {
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	// The proxy of the environment and the system CAs are used as is
+	if Standalone {
+		return transport, nil
+	}
+
+	image, err := imageConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := append([]string{}, InsecureRegistries...)
+	if image != nil {
+		hosts = append(hosts, image.Spec.RegistrySources.InsecureRegistries...)
+	}
+	setInsecureHosts(hosts)
+
+	pool, err := trustedCAs(image)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot assemble trusted CAs")
+	}
+
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+
+	// Image metadata is fetched by the operator itself, the cluster wide
+	// proxy has to be honored the same way as for the pods we create.
+	cfg, err := proxy.ClusterConfiguration()
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot get Proxy Configuration")
+	}
+
+	if cfg.HttpProxy != "" || cfg.HttpsProxy != "" {
+		log.Info("Using cluster proxy for registry access", "httpProxy", cfg.HttpProxy, "httpsProxy", cfg.HttpsProxy, "noProxy", cfg.NoProxy)
+		transport.Proxy = cfg.ProxyFunc()
+	}
+
+	return transport, nil
+}
+
+func trustedCAs(image *configv1.Image) (*x509.CertPool, error) {
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot load system cert pool, starting with an empty one"))
+		pool = x509.NewCertPool()
+	}
+
+	bundles := []map[string]string{}
+
+	// image.config.openshift.io/cluster references a ConfigMap in
+	// openshift-config where every key is a registry host
+	if image != nil && image.Spec.AdditionalTrustedCA.Name != "" {
+		data, err := caBundle("openshift-config", image.Spec.AdditionalTrustedCA.Name)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, data)
+	}
+
+	if namespace := os.Getenv("OPERATOR_NAMESPACE"); namespace != "" {
+		data, err := caBundle(namespace, RegistryCAConfigMap)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, data)
+	}
+
+	for _, bundle := range bundles {
+		for host, pem := range bundle {
+			if ok := pool.AppendCertsFromPEM([]byte(pem)); !ok {
+				warn.OnError(errors.New("Cannot parse CA for registry " + host))
+			}
+		}
+	}
+
+	return pool, nil
+}
+
+// imageConfig returns image.config.openshift.io/cluster, nil on vanilla k8s
+func imageConfig() (*configv1.Image, error) {
+
+	available, err := clients.HasResource(configv1.SchemeGroupVersion.WithResource("images"))
+	if err != nil {
+		return nil, errors.Wrap(err, "Error discovering images API resource")
+	}
+	if !available {
+		return nil, nil
+	}
+
+	image, err := clients.Interface.Images().Get(context.TODO(), "cluster", metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "ConfigClient unable to get image.config.openshift.io/cluster")
+	}
+
+	return image, nil
+}
+
+func caBundle(namespace string, name string) (map[string]string, error) {
+
+	cm, err := clients.GetConfigMap(namespace, name)
+	if apierrors.IsNotFound(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot get CA ConfigMap "+namespace+"/"+name)
+	}
+
+	return cm.Data, nil
+}
@@ -0,0 +1,436 @@
+package registry
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/pkg/errors"
+)
+
+const (
+	cosignSignatureAnnotation   = "dev.cosignproject.cosign/signature"
+	cosignCertificateAnnotation = "dev.sigstore.cosign/certificate"
+	cosignChainAnnotation       = "dev.sigstore.cosign/chain"
+	cosignBundleAnnotation      = "dev.sigstore.cosign/bundle"
+
+	// RekorKey the key of the keyless roots ConfigMap with the PEM encoded
+	// public keys of the transparency log
+	RekorKey = "rekor.pub"
+)
+
+var (
+	// Fulcio certificate extensions of the OIDC issuer, the first one is a
+	// raw string, the second one a DER encoded UTF8String
+	oidIssuer   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Verifier checks cosign signatures of images, either against a set of
+// public keys or, keyless, against the certificate embedded in the
+// signature. A keyless certificate has to chain up to one of the Roots at
+// the time the signature was logged in Rekor and has to be issued to
+// Identity by Issuer. Images of the Skip registries or repository prefixes
+// are not verified.
+type Verifier struct {
+	Keys      []crypto.PublicKey
+	Roots     *x509.CertPool
+	RekorKeys []crypto.PublicKey
+	Identity  string
+	Issuer    string
+	Skip      []string
+}
+
+// rekorBundle the offline proof of a Rekor entry cosign attaches, the
+// SignedEntryTimestamp signs the canonical JSON of the Payload
+type rekorBundle struct {
+	SignedEntryTimestamp []byte `json:"SignedEntryTimestamp"`
+	Payload              struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogIndex       int64  `json:"logIndex"`
+		LogID          string `json:"logID"`
+	} `json:"Payload"`
+}
+
+// hashedRekord the body of the Rekor entry of a signature
+type hashedRekord struct {
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content string `json:"content"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+type simpleSigning struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// NewVerifier loads the public keys from the Secret publicKeySecret and the
+// keyless roots from the ConfigMap rootsConfigMap in namespace, every key
+// of the objects is one PEM encoded key or certificate bundle, RekorKey of
+// the ConfigMap holds the keys of the transparency log. Keyless signatures
+// are only accepted for identity and issuer.
+func NewVerifier(namespace string, publicKeySecret string, rootsConfigMap string, identity string, issuer string) (*Verifier, error) {
+
+	verifier := &Verifier{Identity: identity, Issuer: issuer}
+
+	if publicKeySecret != "" {
+		secret, err := clients.GetSecret(namespace, publicKeySecret)
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot get public key Secret "+namespace+"/"+publicKeySecret)
+		}
+		for key, data := range secret.Data {
+			block, _ := pem.Decode(data)
+			if block == nil {
+				return nil, errors.New("Cannot decode PEM public key " + key)
+			}
+			pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, errors.Wrap(err, "Cannot parse public key "+key)
+			}
+			verifier.Keys = append(verifier.Keys, pub)
+		}
+	}
+
+	if rootsConfigMap != "" {
+		cm, err := clients.GetConfigMap(namespace, rootsConfigMap)
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot get keyless roots ConfigMap "+namespace+"/"+rootsConfigMap)
+		}
+		verifier.Roots = x509.NewCertPool()
+		for key, data := range cm.Data {
+			if key == RekorKey {
+				continue
+			}
+			if ok := verifier.Roots.AppendCertsFromPEM([]byte(data)); !ok {
+				return nil, errors.New("Cannot parse root certificates " + key)
+			}
+		}
+
+		rest := []byte(cm.Data[RekorKey])
+		for {
+			var block *pem.Block
+			if block, rest = pem.Decode(rest); block == nil {
+				break
+			}
+			pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, errors.Wrap(err, "Cannot parse Rekor public key")
+			}
+			verifier.RekorKeys = append(verifier.RekorKeys, pub)
+		}
+
+		if len(verifier.RekorKeys) == 0 {
+			return nil, errors.New("Keyless verification needs the Rekor public key " + RekorKey + " in " + namespace + "/" + rootsConfigMap)
+		}
+		if identity == "" || issuer == "" {
+			return nil, errors.New("Keyless verification needs the identity and issuer of the signer")
+		}
+	}
+
+	if len(verifier.Keys) == 0 && verifier.Roots == nil {
+		return nil, errors.New("Signature verification needs a public key or keyless roots")
+	}
+
+	return verifier, nil
+}
+
+// Verify succeeds if at least one cosign signature attached to image is
+// valid for the image digest. It returns the reference of the verified
+// digest, a tag can be moved to another image after the verification, the
+// digest reference cannot. Skipped images are returned as is.
+func (v *Verifier) Verify(image string) (string, error) {
+
+	if v.skipped(image) {
+		log.Info("Skipping signature verification", "image", image)
+		return image, nil
+	}
+
+	opts, err := options()
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot setup registry transport")
+	}
+
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot parse image reference "+image)
+	}
+
+	digest, err := crane.Digest(image, forRefs(opts, image)...)
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot resolve digest of "+image)
+	}
+
+	repo := ref.Context().Name()
+	sigTag := repo + ":" + strings.Replace(digest, ":", "-", 1) + ".sig"
+
+	manifest, err := crane.Manifest(sigTag, forRefs(opts, sigTag)...)
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot find cosign signature "+sigTag)
+	}
+
+	var signatures struct {
+		Layers []struct {
+			Digest      string            `json:"digest"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"layers"`
+	}
+
+	if err := json.Unmarshal(manifest, &signatures); err != nil {
+		return "", errors.Wrap(err, "Cannot unmarshal signature manifest "+sigTag)
+	}
+
+	var last error = errors.New("No signatures found in " + sigTag)
+
+	for _, layer := range signatures.Layers {
+
+		blob, err := crane.PullLayer(repo+"@"+layer.Digest, forRefs(opts, repo)...)
+		if err != nil {
+			return "", errors.Wrap(err, "Cannot pull signature payload "+layer.Digest)
+		}
+
+		rc, err := blob.Compressed()
+		if err != nil {
+			return "", errors.Wrap(err, "Cannot read signature payload "+layer.Digest)
+		}
+		payload, err := io.ReadAll(rc)
+		dclose(rc)
+		if err != nil {
+			return "", errors.Wrap(err, "Cannot read signature payload "+layer.Digest)
+		}
+
+		if last = v.verifyPayload(payload, layer.Annotations, digest); last == nil {
+			log.Info("Signature verified", "image", image, "digest", digest)
+			return repo + "@" + digest, nil
+		}
+		log.Info("Signature not valid", "image", image, "layer", layer.Digest, "error", last.Error())
+	}
+
+	return "", errors.Wrap(last, "Signature verification failed for "+image)
+}
+
+// skipped returns true if image is in one of the Skip registries or
+// repository prefixes
+func (v *Verifier) skipped(image string) bool {
+
+	repo, err := Repository(image)
+	if err != nil {
+		return false
+	}
+
+	for _, skip := range v.Skip {
+		prefix := normalizeSource(strings.TrimSuffix(skip, "/"))
+		if repo == prefix || strings.HasPrefix(repo, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *Verifier) verifyPayload(payload []byte, annotations map[string]string, digest string) error {
+
+	sig, err := base64.StdEncoding.DecodeString(annotations[cosignSignatureAnnotation])
+	if err != nil {
+		return errors.Wrap(err, "Cannot decode signature")
+	}
+
+	keys := make([]crypto.PublicKey, len(v.Keys), len(v.Keys)+1)
+	copy(keys, v.Keys)
+
+	if certPEM, found := annotations[cosignCertificateAnnotation]; found && v.Roots != nil {
+		cert, err := v.verifyCertificate(certPEM, annotations, payload, sig)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, cert.PublicKey)
+	}
+
+	verified := false
+	for _, key := range keys {
+		if verifySignature(key, payload, sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return errors.New("Signature does not match any trusted key")
+	}
+
+	var signed simpleSigning
+	if err := json.Unmarshal(payload, &signed); err != nil {
+		return errors.Wrap(err, "Cannot unmarshal signature payload")
+	}
+
+	if signed.Critical.Image.DockerManifestDigest != digest {
+		return errors.New("Signature is for " + signed.Critical.Image.DockerManifestDigest + " not " + digest)
+	}
+
+	return nil
+}
+
+// verifyCertificate checks the short lived certificate of a keyless
+// signature. The signature has to be logged in Rekor, the certificate has
+// to be valid at the integrated time of the entry, chain up to the roots
+// and be issued to the identity by the issuer of the Verifier.
+func (v *Verifier) verifyCertificate(certPEM string, annotations map[string]string, payload []byte, sig []byte) (*x509.Certificate, error) {
+
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return nil, errors.New("Cannot decode signing certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot parse signing certificate")
+	}
+
+	integrated, err := v.verifyBundle(annotations[cosignBundleAnnotation], payload, sig)
+	if err != nil {
+		return nil, err
+	}
+
+	intermediates := x509.NewCertPool()
+	intermediates.AppendCertsFromPEM([]byte(annotations[cosignChainAnnotation]))
+
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   integrated,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return nil, errors.Wrap(err, "Signing certificate not trusted")
+	}
+
+	identities := append(append([]string{}, cert.EmailAddresses...), cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	matched := false
+	for _, identity := range identities {
+		matched = matched || identity == v.Identity
+	}
+	if !matched {
+		return nil, errors.New("Signing certificate is issued to " + strings.Join(identities, ", ") + " not " + v.Identity)
+	}
+
+	if issuer := certificateIssuer(cert); issuer != v.Issuer {
+		return nil, errors.New("Signing certificate is issued by " + issuer + " not " + v.Issuer)
+	}
+
+	return cert, nil
+}
+
+// verifyBundle checks the signed entry timestamp of the Rekor bundle and
+// that the entry is the one of sig and payload, returns the time the entry
+// was integrated into the log
+func (v *Verifier) verifyBundle(annotation string, payload []byte, sig []byte) (time.Time, error) {
+
+	if annotation == "" {
+		return time.Time{}, errors.New("Keyless signature has no Rekor bundle")
+	}
+
+	var bundle rekorBundle
+	if err := json.Unmarshal([]byte(annotation), &bundle); err != nil {
+		return time.Time{}, errors.Wrap(err, "Cannot unmarshal Rekor bundle")
+	}
+
+	// The canonical JSON of the payload, its keys are sorted
+	canonical, err := json.Marshal(map[string]interface{}{
+		"body":           bundle.Payload.Body,
+		"integratedTime": bundle.Payload.IntegratedTime,
+		"logIndex":       bundle.Payload.LogIndex,
+		"logID":          bundle.Payload.LogID,
+	})
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "Cannot marshal Rekor bundle payload")
+	}
+
+	verified := false
+	for _, key := range v.RekorKeys {
+		verified = verified || verifySignature(key, canonical, bundle.SignedEntryTimestamp)
+	}
+	if !verified {
+		return time.Time{}, errors.New("Rekor bundle is not signed by a trusted transparency log")
+	}
+
+	body, err := base64.StdEncoding.DecodeString(bundle.Payload.Body)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "Cannot decode Rekor entry")
+	}
+	var entry hashedRekord
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return time.Time{}, errors.Wrap(err, "Cannot unmarshal Rekor entry")
+	}
+
+	sum := sha256.Sum256(payload)
+	if entry.Spec.Data.Hash.Algorithm != "sha256" || entry.Spec.Data.Hash.Value != hex.EncodeToString(sum[:]) {
+		return time.Time{}, errors.New("Rekor entry is not the one of the signed payload")
+	}
+	if entry.Spec.Signature.Content != base64.StdEncoding.EncodeToString(sig) {
+		return time.Time{}, errors.New("Rekor entry is not the one of the signature")
+	}
+
+	return time.Unix(bundle.Payload.IntegratedTime, 0), nil
+}
+
+// certificateIssuer returns the OIDC issuer of a Fulcio certificate
+func certificateIssuer(cert *x509.Certificate) string {
+
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidIssuerV2) {
+			var issuer string
+			if _, err := asn1.UnmarshalWithParams(ext.Value, &issuer, "utf8"); err == nil {
+				return issuer
+			}
+		}
+	}
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidIssuer) {
+			return string(ext.Value)
+		}
+	}
+
+	return ""
+}
+
+func verifySignature(key crypto.PublicKey, payload []byte, sig []byte) bool {
+
+	sum := sha256.Sum256(payload)
+
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, sum[:], sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, payload, sig)
+	default:
+		log.Info("Unsupported public key type, skipping")
+		return false
+	}
+}
@@ -0,0 +1,353 @@
+package registry
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestVerifierSkipped(t *testing.T) {
+
+	verifier := &Verifier{Skip: []string{"image-registry.openshift-image-registry.svc:5000", "quay.io/org/unsigned/", "docker.io/library/busybox"}}
+
+	tests := []struct {
+		image   string
+		skipped bool
+	}{
+		{"image-registry.openshift-image-registry.svc:5000/ns/driver:4.18.0-305.el8.x86_64", true},
+		{"quay.io/org/unsigned/driver:tag", true},
+		{"quay.io/org/unsigned@" + testDigest, true},
+		{"busybox:latest", true},
+		// A prefix has to end at a path component
+		{"quay.io/org/unsigned-driver:tag", false},
+		{"image-registry.openshift-image-registry.svc:5001/ns/driver:tag", false},
+		{"quay.io/org/driver:tag", false},
+		{"Invalid:Reference", false},
+	}
+
+	for _, test := range tests {
+		if skipped := verifier.skipped(test.image); skipped != test.skipped {
+			t.Errorf("skipped(%q) = %v, want %v", test.image, skipped, test.skipped)
+		}
+	}
+
+	// Nothing is skipped unless configured
+	if (&Verifier{}).skipped("image-registry.openshift-image-registry.svc:5000/ns/driver:tag") {
+		t.Errorf("skipped without Skip")
+	}
+}
+
+// keylessFixture a keyless signature of payload with a Fulcio like
+// certificate and its Rekor bundle
+type keylessFixture struct {
+	root      *x509.Certificate
+	rootKey   *ecdsa.PrivateKey
+	rekorKey  *ecdsa.PrivateKey
+	leafKey   *ecdsa.PrivateKey
+	leaf      *x509.Certificate
+	payload   []byte
+	signature []byte
+	bundle    rekorBundle
+	noBundle  bool
+}
+
+func newKeylessFixture(t *testing.T, identity string, issuer string, digest string) *keylessFixture {
+
+	f := &keylessFixture{}
+	f.rootKey = newTestKey(t)
+	f.rekorKey = newTestKey(t)
+	f.leafKey = newTestKey(t)
+
+	now := time.Now()
+
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fulcio"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	f.root = newTestCertificate(t, rootTemplate, rootTemplate, &f.rootKey.PublicKey, f.rootKey)
+
+	issuerValue, err := asn1.MarshalWithParams(issuer, "utf8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	identityURI, err := url.Parse(identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fulcio certificates are valid for minutes only
+	f.leaf = newTestCertificate(t, &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       now.Add(-5 * time.Minute),
+		NotAfter:        now.Add(5 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		URIs:            []*url.URL{identityURI},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuerValue}},
+	}, f.root, &f.leafKey.PublicKey, f.rootKey)
+
+	f.payload = []byte(`{"critical":{"identity":{"docker-reference":"quay.io/org/driver"},"image":{"docker-manifest-digest":"` + digest + `"},"type":"cosign container image signature"},"optional":null}`)
+	f.signature = signTestPayload(t, f.leafKey, f.payload)
+
+	f.setRekorEntry(t, f.payload, f.signature, now)
+
+	return f
+}
+
+// setRekorEntry logs payload and signature at integrated in the bundle
+func (f *keylessFixture) setRekorEntry(t *testing.T, payload []byte, signature []byte, integrated time.Time) {
+
+	var entry hashedRekord
+	sum := sha256.Sum256(payload)
+	entry.Spec.Data.Hash.Algorithm = "sha256"
+	entry.Spec.Data.Hash.Value = hex.EncodeToString(sum[:])
+	entry.Spec.Signature.Content = base64.StdEncoding.EncodeToString(signature)
+
+	body, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.bundle.Payload.Body = base64.StdEncoding.EncodeToString(body)
+	f.bundle.Payload.IntegratedTime = integrated.Unix()
+	f.bundle.Payload.LogIndex = 42
+	f.bundle.Payload.LogID = "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d"
+	f.signBundle(t, f.rekorKey)
+}
+
+func (f *keylessFixture) signBundle(t *testing.T, key *ecdsa.PrivateKey) {
+
+	canonical, err := json.Marshal(map[string]interface{}{
+		"body":           f.bundle.Payload.Body,
+		"integratedTime": f.bundle.Payload.IntegratedTime,
+		"logIndex":       f.bundle.Payload.LogIndex,
+		"logID":          f.bundle.Payload.LogID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.bundle.SignedEntryTimestamp = signTestPayload(t, key, canonical)
+}
+
+func (f *keylessFixture) annotations(t *testing.T) map[string]string {
+
+	bundle, err := json.Marshal(f.bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	annotations := map[string]string{
+		cosignSignatureAnnotation:   base64.StdEncoding.EncodeToString(f.signature),
+		cosignCertificateAnnotation: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: f.leaf.Raw})),
+		cosignBundleAnnotation:      string(bundle),
+	}
+	if f.noBundle {
+		delete(annotations, cosignBundleAnnotation)
+	}
+
+	return annotations
+}
+
+func (f *keylessFixture) verifier(identity string, issuer string) *Verifier {
+
+	roots := x509.NewCertPool()
+	roots.AddCert(f.root)
+
+	return &Verifier{
+		Roots:     roots,
+		RekorKeys: []crypto.PublicKey{&f.rekorKey.PublicKey},
+		Identity:  identity,
+		Issuer:    issuer,
+	}
+}
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *x509.Certificate, pub crypto.PublicKey, key *ecdsa.PrivateKey) *x509.Certificate {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func signTestPayload(t *testing.T, key *ecdsa.PrivateKey, payload []byte) []byte {
+	sum := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func TestVerifyKeyless(t *testing.T) {
+
+	const (
+		identity = "https://github.com/org/driver/.github/workflows/release.yaml@refs/heads/main"
+		issuer   = "https://token.actions.githubusercontent.com"
+	)
+
+	tests := []struct {
+		name     string
+		identity string
+		issuer   string
+		digest   string
+		modify   func(t *testing.T, f *keylessFixture)
+		valid    bool
+	}{
+		{
+			name:  "valid",
+			valid: true,
+		},
+		{
+			name:     "other identity",
+			identity: "https://github.com/attacker/driver/.github/workflows/release.yaml@refs/heads/main",
+		},
+		{
+			name:   "other issuer",
+			issuer: "https://accounts.google.com",
+		},
+		{
+			name:   "other digest",
+			digest: "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210",
+		},
+		{
+			name: "certificate of an untrusted root",
+			modify: func(t *testing.T, f *keylessFixture) {
+				other := newKeylessFixture(t, identity, issuer, testDigest)
+				f.leaf, f.leafKey = other.leaf, other.leafKey
+				f.signature = signTestPayload(t, f.leafKey, f.payload)
+				f.setRekorEntry(t, f.payload, f.signature, time.Now())
+			},
+		},
+		{
+			name: "logged after the certificate expired",
+			modify: func(t *testing.T, f *keylessFixture) {
+				f.setRekorEntry(t, f.payload, f.signature, time.Now().Add(30*time.Minute))
+			},
+		},
+		{
+			name: "bundle of an untrusted log",
+			modify: func(t *testing.T, f *keylessFixture) {
+				f.signBundle(t, newTestKey(t))
+			},
+		},
+		{
+			name: "bundle with a changed entry",
+			modify: func(t *testing.T, f *keylessFixture) {
+				f.bundle.Payload.LogIndex++
+			},
+		},
+		{
+			name: "entry of another payload",
+			modify: func(t *testing.T, f *keylessFixture) {
+				f.setRekorEntry(t, []byte(`{}`), f.signature, time.Now())
+			},
+		},
+		{
+			name: "entry of another signature",
+			modify: func(t *testing.T, f *keylessFixture) {
+				f.setRekorEntry(t, f.payload, signTestPayload(t, f.leafKey, f.payload), time.Now())
+			},
+		},
+		{
+			name: "no bundle",
+			modify: func(t *testing.T, f *keylessFixture) {
+				f.noBundle = true
+			},
+		},
+		{
+			name: "signature of another key",
+			modify: func(t *testing.T, f *keylessFixture) {
+				f.signature = signTestPayload(t, newTestKey(t), f.payload)
+				f.setRekorEntry(t, f.payload, f.signature, time.Now())
+			},
+		},
+	}
+
+	for _, test := range tests {
+
+		f := newKeylessFixture(t, identity, issuer, testDigest)
+		if test.modify != nil {
+			test.modify(t, f)
+		}
+
+		verifyIdentity, verifyIssuer, digest := identity, issuer, testDigest
+		if test.identity != "" {
+			verifyIdentity = test.identity
+		}
+		if test.issuer != "" {
+			verifyIssuer = test.issuer
+		}
+		if test.digest != "" {
+			digest = test.digest
+		}
+
+		err := f.verifier(verifyIdentity, verifyIssuer).verifyPayload(f.payload, f.annotations(t), digest)
+		if test.valid && err != nil {
+			t.Errorf("%s: verifyPayload failed: %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: verifyPayload succeeded, want error", test.name)
+		}
+	}
+}
+
+func TestVerifyKey(t *testing.T) {
+
+	key := newTestKey(t)
+	payload := []byte(`{"critical":{"image":{"docker-manifest-digest":"` + testDigest + `"}}}`)
+	annotations := map[string]string{
+		cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(signTestPayload(t, key, payload)),
+	}
+
+	tests := []struct {
+		name     string
+		verifier *Verifier
+		digest   string
+		valid    bool
+	}{
+		{"valid", &Verifier{Keys: []crypto.PublicKey{&key.PublicKey}}, testDigest, true},
+		{"other key", &Verifier{Keys: []crypto.PublicKey{&newTestKey(t).PublicKey}}, testDigest, false},
+		{"no key", &Verifier{}, testDigest, false},
+		{"other digest", &Verifier{Keys: []crypto.PublicKey{&key.PublicKey}}, "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210", false},
+	}
+
+	for _, test := range tests {
+		err := test.verifier.verifyPayload(payload, annotations, test.digest)
+		if test.valid && err != nil {
+			t.Errorf("%s: verifyPayload failed: %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: verifyPayload succeeded, want error", test.name)
+		}
+	}
+}
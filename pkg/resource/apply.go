@@ -0,0 +1,87 @@
+package resource
+
+import (
+	"context"
+	"strings"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// FieldManager owns the fields SRO applies, fields other managers set and
+// the manifests do not are left alone
+const FieldManager = "special-resource-operator"
+
+// Conflicts records the fields SRO took over from other field managers,
+// the reconciler reports them in the FieldConflicts condition
+var Conflicts []string
+
+// Diff the fields of an object SRO updated, see diff.Objects
+type Diff struct {
+	Object string
+	Fields []string
+}
+
+// Diffs records the objects SRO updated since the reconciler reset it
+var Diffs []Diff
+
+// Writer returns the client objects of the chart are applied and deleted
+// with
+func Writer() client.Client {
+	if Impersonated != nil {
+		return Impersonated
+	}
+	return clients.Workload()
+}
+
+// apply creates or updates obj with server-side apply, fields owned by
+// another manager are recorded in Conflicts and taken over.
+func apply(obj *unstructured.Unstructured) error {
+
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
+
+	err := Writer().Patch(context.TODO(), obj, client.Apply, client.FieldOwner(FieldManager))
+	if !apierrors.IsConflict(err) {
+		return err
+	}
+
+	conflict := describeConflict(obj, err)
+	log.Info("Taking over conflicting fields", "conflict", conflict)
+	Conflicts = append(Conflicts, conflict)
+
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
+
+	return Writer().Patch(context.TODO(), obj, client.Apply, client.FieldOwner(FieldManager), client.ForceOwnership)
+}
+
+// describeConflict lists the conflicting fields and their managers, e.g.
+// DaemonSet: ns/name: conflict with "kubectl-edit": .spec.template...
+func describeConflict(obj *unstructured.Unstructured, err error) string {
+
+	name := objectName(obj)
+
+	fields := []string{}
+	if status, ok := err.(apierrors.APIStatus); ok && status.Status().Details != nil {
+		for _, cause := range status.Status().Details.Causes {
+			fields = append(fields, cause.Message)
+		}
+	}
+
+	if len(fields) == 0 {
+		return name + ": " + err.Error()
+	}
+
+	return name + ": " + strings.Join(fields, ", ")
+}
+
+// objectName returns Kind: namespace/name of obj
+func objectName(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() != "" {
+		return obj.GetKind() + ": " + obj.GetNamespace() + "/" + obj.GetName()
+	}
+	return obj.GetKind() + ": " + obj.GetName()
+}
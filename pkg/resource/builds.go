@@ -0,0 +1,145 @@
+package resource
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/poll"
+	"github.com/openshift-psap/special-resource-operator/pkg/trace"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// MaxConcurrentBuilds the .spec.build.maxConcurrent of the SpecialResource,
+// above 1 the builds of the kernels of a state run in parallel between
+// StartBuilds and WaitForBuilds
+var MaxConcurrentBuilds int
+
+// buildPool bounds the builds that run at the same time and collects their
+// outcome, the waits are traced as children of the span of ctx
+type buildPool struct {
+	ctx     context.Context
+	slots   chan struct{}
+	running sync.WaitGroup
+	lock    sync.Mutex
+	builds  int
+	errs    []error
+}
+
+var builds *buildPool
+
+// StartBuilds defers the waits of the builds created until WaitForBuilds, at
+// most MaxConcurrentBuilds run at the same time. Nothing is deferred if
+// MaxConcurrentBuilds is 1 or less.
+func StartBuilds(ctx context.Context) {
+
+	if MaxConcurrentBuilds <= 1 {
+		return
+	}
+
+	builds = &buildPool{ctx: ctx, slots: make(chan struct{}, MaxConcurrentBuilds)}
+}
+
+// WaitForBuilds waits for the builds started since StartBuilds and returns
+// the first failure wrapped with the count of all of them
+func WaitForBuilds() error {
+
+	if builds == nil {
+		return nil
+	}
+
+	pool := builds
+	builds = nil
+
+	_, span := trace.Start(pool.ctx, "builds wait", "builds", strconv.Itoa(pool.builds))
+	pool.running.Wait()
+
+	err := pool.err()
+	span.End(err)
+
+	return err
+}
+
+// err the first failure of the pool wrapped with the count of all of them
+func (pool *buildPool) err() error {
+
+	if len(pool.errs) == 0 {
+		return nil
+	}
+
+	messages := []string{}
+	for _, err := range pool.errs[1:] {
+		messages = append(messages, err.Error())
+	}
+
+	msg := strconv.Itoa(len(pool.errs)) + " of " + strconv.Itoa(pool.builds) + " builds failed"
+	if len(messages) > 0 {
+		msg = msg + ", also: " + strings.Join(messages, "; ")
+	}
+
+	return errors.Wrap(pool.errs[0], msg)
+}
+
+// waitForResource waits for obj, a build that is not deferred to
+// WaitForBuilds is traced in a span of its own
+func waitForResource(obj *unstructured.Unstructured, kernelFullVersion string) error {
+
+	if obj.GetKind() != "BuildConfig" {
+		return poll.ForResource(obj)
+	}
+
+	_, span := trace.Start(context.Background(), "build wait", "build", obj.GetName(), "kernel", kernelFullVersion)
+	err := poll.ForResource(obj)
+	span.End(err)
+
+	return err
+}
+
+// deferBuild is true if the wait for obj is deferred to WaitForBuilds, obj
+// was translated from a BuildConfig and is waited for
+func deferBuild(obj *unstructured.Unstructured, fromBuildConfig bool) bool {
+	return builds != nil && fromBuildConfig && obj.GetAnnotations()["specialresource.openshift.io/wait"] == "true"
+}
+
+// acquire blocks until one of the MaxConcurrentBuilds slots is free
+func (pool *buildPool) acquire() {
+	pool.slots <- struct{}{}
+}
+
+// release frees a slot of a build that was not started
+func (pool *buildPool) release() {
+	<-pool.slots
+}
+
+// wait waits for the build obj in the background and frees its slot once it
+// completed, pending is done at the same time
+func (pool *buildPool) wait(obj *unstructured.Unstructured, kernelFullVersion string, pending *sync.WaitGroup) {
+
+	pool.lock.Lock()
+	pool.builds++
+	pool.lock.Unlock()
+
+	pool.running.Add(1)
+	pending.Add(1)
+
+	_, span := trace.Start(pool.ctx, "build wait", "build", obj.GetName(), "kernel", kernelFullVersion)
+
+	go func() {
+		defer pool.running.Done()
+		defer pending.Done()
+		defer pool.release()
+
+		err := poll.ForResource(obj)
+		if err == nil {
+			err = afterBuild(obj, kernelFullVersion)
+		}
+		span.End(err)
+		if err != nil {
+			pool.lock.Lock()
+			pool.errs = append(pool.errs, errors.Wrap(err, "Build "+obj.GetName()+" of kernel "+kernelFullVersion))
+			pool.lock.Unlock()
+		}
+	}()
+}
@@ -0,0 +1,85 @@
+package resource
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// NodeExclusion the .spec.nodeExclusionSelector of the SpecialResource, the
+// workloads of the chart are not scheduled to nodes with all of its labels
+var NodeExclusion map[string]string
+
+// setNodeExclusionTerms adds a NotIn requirement per label of NodeExclusion
+// to the required node affinity of the workloads of the chart. The terms
+// are ORed, each term of the chart is combined with each requirement so a
+// node is only excluded if it has all labels.
+func setNodeExclusionTerms(obj *unstructured.Unstructured) error {
+
+	if len(NodeExclusion) == 0 {
+		return nil
+	}
+
+	var fields []string
+
+	switch obj.GetKind() {
+	case "DaemonSet", "Deployment", "StatefulSet":
+		fields = []string{"spec", "template", "spec"}
+	case "Pod":
+		fields = []string{"spec"}
+	default:
+		return nil
+	}
+
+	fields = append(fields, "affinity", "nodeAffinity",
+		"requiredDuringSchedulingIgnoredDuringExecution", "nodeSelectorTerms")
+
+	terms, _, err := unstructured.NestedSlice(obj.Object, fields...)
+	if err != nil {
+		return errors.Wrap(err, "Cannot get nodeSelectorTerms of "+obj.GetName())
+	}
+	if len(terms) == 0 {
+		terms = []interface{}{map[string]interface{}{}}
+	}
+
+	keys := make([]string, 0, len(NodeExclusion))
+	for key := range NodeExclusion {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	excluded := []interface{}{}
+
+	for _, term := range terms {
+		for _, key := range keys {
+			copied := copyTerm(term)
+			expressions, _, err := unstructured.NestedSlice(copied, "matchExpressions")
+			if err != nil {
+				return errors.Wrap(err, "Cannot get matchExpressions of "+obj.GetName())
+			}
+			expressions = append(expressions, map[string]interface{}{
+				"key":      key,
+				"operator": "NotIn",
+				"values":   []interface{}{NodeExclusion[key]},
+			})
+			if err := unstructured.SetNestedSlice(copied, expressions, "matchExpressions"); err != nil {
+				return errors.Wrap(err, "Cannot set matchExpressions of "+obj.GetName())
+			}
+			excluded = append(excluded, copied)
+		}
+	}
+
+	return errors.Wrap(unstructured.SetNestedSlice(obj.Object, excluded, fields...),
+		"Cannot set nodeSelectorTerms of "+obj.GetName())
+}
+
+// copyTerm copies a nodeSelectorTerm of the chart
+func copyTerm(term interface{}) map[string]interface{} {
+
+	if term, ok := term.(map[string]interface{}); ok {
+		return runtime.DeepCopyJSON(term)
+	}
+	return map[string]interface{}{}
+}
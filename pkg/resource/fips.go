@@ -0,0 +1,84 @@
+package resource
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// FIPSLabel image label of driver containers built for FIPS mode
+const FIPSLabel = "specialresource.openshift.io/fips"
+
+var (
+	// FIPS is set if the cluster runs in FIPS mode, it is passed to the
+	// builds of the chart as build arg FIPS
+	FIPS bool
+	// FIPSStrict refuses driver containers without FIPSLabel on FIPS
+	// clusters
+	FIPSStrict bool
+)
+
+// setFIPSBuildArg adds the build arg FIPS to the docker strategy of a
+// BuildConfig unless the chart sets it
+func setFIPSBuildArg(obj *unstructured.Unstructured) error {
+
+	if obj.GetKind() != "BuildConfig" {
+		return nil
+	}
+	if _, found, _ := unstructured.NestedMap(obj.Object, "spec", "strategy", "dockerStrategy"); !found {
+		return nil
+	}
+
+	buildArgs, _, err := unstructured.NestedSlice(obj.Object, "spec", "strategy", "dockerStrategy", "buildArgs")
+	if err != nil {
+		return errors.Wrap(err, "Cannot get buildArgs")
+	}
+
+	for _, arg := range buildArgs {
+		if arg, ok := arg.(map[string]interface{}); ok && arg["name"] == "FIPS" {
+			return nil
+		}
+	}
+
+	buildArgs = append(buildArgs, map[string]interface{}{"name": "FIPS", "value": strconv.FormatBool(FIPS)})
+
+	return errors.Wrap(unstructured.SetNestedSlice(obj.Object, buildArgs, "spec", "strategy", "dockerStrategy", "buildArgs"),
+		"Cannot set buildArgs")
+}
+
+// verifyFIPS returns an error if a container image of the driver container
+// obj is not labeled as FIPS build. Images of the internal registry are
+// built by the chart with the FIPS build arg and are not checked.
+func verifyFIPS(obj *unstructured.Unstructured) error {
+
+	containers, found, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if err != nil || !found {
+		return err
+	}
+
+	for _, container := range containers {
+		container, ok := container.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		image, _, _ := unstructured.NestedString(container, "image")
+		if image == "" || strings.HasPrefix(image, "image-registry.openshift-image-registry.svc") {
+			continue
+		}
+
+		labels, err := registry.ImageLabels(image)
+		if err != nil {
+			return err
+		}
+		if labels[FIPSLabel] != "true" {
+			return errors.New("Cluster runs in FIPS mode, image " + image + " of " + obj.GetName() +
+				" is not labeled " + FIPSLabel + "=true")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package resource
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// KernelModulesAnnotation lists the modules a driver container DaemonSet
+// loads, comma separated
+const KernelModulesAnnotation = "specialresource.openshift.io/kernel-modules"
+
+// VerifyModules adds a readiness probe to the driver container that checks
+// the modules of KernelModulesAnnotation are loaded, the driver-ready node
+// label then means the modules are loaded and not only that the pod runs
+var VerifyModules bool
+
+// Modules returns the modules of KernelModulesAnnotation of obj
+func Modules(obj *unstructured.Unstructured) []string {
+
+	modules := []string{}
+	for _, module := range strings.Split(obj.GetAnnotations()[KernelModulesAnnotation], ",") {
+		if module = strings.TrimSpace(module); module != "" {
+			modules = append(modules, module)
+		}
+	}
+
+	return modules
+}
+
+// setModuleProbe sets the readiness probe of the first container of a
+// driver container DaemonSet, a probe of the chart is kept
+func setModuleProbe(obj *unstructured.Unstructured) error {
+
+	if !VerifyModules || !isDriverDaemonSet(obj) {
+		return nil
+	}
+
+	modules := Modules(obj)
+	if len(modules) == 0 {
+		log.Info("Driver container lists no kernel modules, cannot verify them", "DaemonSet", obj.GetName())
+		return nil
+	}
+
+	containers, _, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if err != nil {
+		return errors.Wrap(err, "Cannot get containers of "+obj.GetName())
+	}
+	if len(containers) == 0 {
+		return nil
+	}
+
+	container, ok := containers[0].(map[string]interface{})
+	if !ok {
+		return errors.New("Invalid container of " + obj.GetName())
+	}
+	if _, found := container["readinessProbe"]; found {
+		log.Info("Driver container has a readiness probe, not verifying modules", "DaemonSet", obj.GetName())
+		return nil
+	}
+
+	// sysfs names modules with underscores, /sys/module is the one of the
+	// host since modules are not namespaced
+	checks := []string{}
+	for _, module := range modules {
+		checks = append(checks, "test -d /sys/module/"+strings.ReplaceAll(module, "-", "_"))
+	}
+
+	container["readinessProbe"] = map[string]interface{}{
+		"exec": map[string]interface{}{
+			"command": []interface{}{"/bin/sh", "-c", strings.Join(checks, " && ")},
+		},
+		"initialDelaySeconds": int64(5),
+		"periodSeconds":       int64(10),
+	}
+	containers[0] = container
+
+	return errors.Wrap(unstructured.SetNestedSlice(obj.Object, containers, "spec", "template", "spec", "containers"),
+		"Cannot set readiness probe of "+obj.GetName())
+}
@@ -4,15 +4,24 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/go-logr/logr"
+	"github.com/openshift-psap/special-resource-operator/pkg/build"
 	"github.com/openshift-psap/special-resource-operator/pkg/clients"
 	"github.com/openshift-psap/special-resource-operator/pkg/color"
+	"github.com/openshift-psap/special-resource-operator/pkg/diff"
 	"github.com/openshift-psap/special-resource-operator/pkg/exit"
 	"github.com/openshift-psap/special-resource-operator/pkg/filter"
 	"github.com/openshift-psap/special-resource-operator/pkg/hash"
+	"github.com/openshift-psap/special-resource-operator/pkg/inventory"
 	"github.com/openshift-psap/special-resource-operator/pkg/poll"
 	"github.com/openshift-psap/special-resource-operator/pkg/proxy"
+	"github.com/openshift-psap/special-resource-operator/pkg/readiness"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/openshift-psap/special-resource-operator/pkg/rollout"
+	"github.com/openshift-psap/special-resource-operator/pkg/scan"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
 	"helm.sh/helm/v3/pkg/kube"
 
 	"github.com/openshift-psap/special-resource-operator/pkg/kernel"
@@ -34,8 +43,50 @@ var (
 	HelmClient    kube.Interface
 	RuntimeScheme *runtime.Scheme
 	UpdateVendor  string
+	// ImageScanner is set if the SpecialResource gates the rollout of driver
+	// containers on their vulnerability scan, nil otherwise
+	ImageScanner *scan.Scanner
+	// PromoteRepository external repository driver containers built
+	// in-cluster are copied to, PromotePushSecret holds its credentials
+	PromoteRepository string
+	PromotePushSecret string
+	// BuildBackend builds the BuildConfigs of the chart, nil keeps them
+	BuildBackend build.Backend
+	// SigningKeySecret signs the modules of the BuildConfigs of the chart
+	// with a second build, SigningImage is the image they are signed in
+	SigningKeySecret string
+	SigningImage     string
+	// Prebuilt skips the BuildConfigs of the chart, the driver container
+	// images were verified to exist by the reconciler
+	Prebuilt bool
+	// Rollout is set if the operator rolls out driver DaemonSets node by
+	// node, nil leaves the update strategy of the chart
+	Rollout *rollout.Options
+	// Applied records the objects of the chart manifests while it is set,
+	// objects of the previous reconcile missing from it are pruned
+	Applied *inventory.Inventory
+	// Changes counts the objects created, updated or pruned since the
+	// reconciler reset it, zero if the cluster already matched the chart
+	Changes int
+	// Impersonated applies the objects of the chart as the service account
+	// of the SpecialResource, nil applies them as the operator
+	Impersonated client.Client
+	// ProxyInjection injects the cluster proxy into the BuildConfigs, Jobs
+	// and DaemonSets of the chart, the proxy annotation overrides it
+	ProxyInjection bool
+	// SerializeBuilds creates a BuildConfig only if no other build of the
+	// cluster is running, the reconcile is requeued otherwise
+	SerializeBuilds bool
 )
 
+// ErrBuildsSerialized is returned while SerializeBuilds holds back a
+// BuildConfig
+var ErrBuildsSerialized = errors.New("builds are serialized")
+
+// OwnerAnnotation names the owning SpecialResource of objects applied to a
+// hosted cluster
+const OwnerAnnotation = "specialresource.openshift.io/owner"
+
 func init() {
 	log = zap.New(zap.UseDevMode(true)).WithName(color.Print("resource", color.Blue))
 	customCallback = make(resourceCallbacks)
@@ -46,6 +97,7 @@ func IsNamespaced(kind string) bool {
 		kind == "ClusterRole" ||
 		kind == "ClusterRoleBinding" ||
 		kind == "SecurityContextConstraint" ||
+		kind == "MachineConfig" ||
 		kind == "SpecialResource" {
 		return false
 	}
@@ -53,64 +105,12 @@ func IsNamespaced(kind string) bool {
 }
 
 func IsNotUpdateable(kind string) bool {
-	// ServiceAccounts cannot be updated, maybe delete and create?
-	if kind == "ServiceAccount" || kind == "Pod" {
-		return true
-	}
-	return false
-}
-
-// Some resources need an updated resourceversion, during updates
-func NeedsResourceVersionUpdate(kind string) bool {
-	if kind == "SecurityContextConstraints" ||
-		kind == "Service" ||
-		kind == "ServiceMonitor" ||
-		kind == "Route" ||
-		kind == "Build" ||
-		kind == "BuildRun" ||
-		kind == "BuildConfig" ||
-		kind == "ImageStream" ||
-		kind == "PrometheusRule" ||
-		kind == "CSIDriver" ||
-		kind == "Issuer" ||
-		kind == "CustomResourceDefinition" ||
-		kind == "Certificate" ||
-		kind == "SpecialResource" ||
-		kind == "OperatorGroup" ||
-		kind == "CertManager" ||
-		kind == "MutatingWebhookConfiguration" ||
-		kind == "ValidatingWebhookConfiguration" ||
-		kind == "Deployment" ||
-		kind == "ImagePolicy" {
+	// ServiceAccounts cannot be updated, maybe delete and create? The spec
+	// of a bound PersistentVolumeClaim is immutable
+	if kind == "ServiceAccount" || kind == "Pod" || kind == "PersistentVolumeClaim" {
 		return true
 	}
 	return false
-
-}
-
-func UpdateResourceVersion(req *unstructured.Unstructured, found *unstructured.Unstructured) error {
-
-	kind := found.GetKind()
-
-	if NeedsResourceVersionUpdate(kind) {
-		version, fnd, err := unstructured.NestedString(found.Object, "metadata", "resourceVersion")
-		exit.OnErrorOrNotFound(fnd, err)
-
-		if err := unstructured.SetNestedField(req.Object, version, "metadata", "resourceVersion"); err != nil {
-			return errors.Wrap(err, "Couldn't update ResourceVersion")
-		}
-
-	}
-	if kind == "Service" {
-		clusterIP, fnd, err := unstructured.NestedString(found.Object, "spec", "clusterIP")
-		exit.OnErrorOrNotFound(fnd, err)
-
-		if err := unstructured.SetNestedField(req.Object, clusterIP, "spec", "clusterIP"); err != nil {
-			return errors.Wrap(err, "Couldn't update clusterIP")
-		}
-		return nil
-	}
-	return nil
 }
 
 func SetNodeSelectorTerms(obj *unstructured.Unstructured, terms map[string]string) error {
@@ -163,10 +163,15 @@ func CreateFromYAML(yamlFile []byte,
 	namespace string,
 	nodeSelector map[string]string,
 	kernelFullVersion string,
-	operatingSystemMajorMinor string) error {
+	operatingSystemMajorMinor string,
+	verifier *registry.Verifier) error {
 
 	scanner := yamlutil.NewYAMLScanner(yamlFile)
 
+	// Objects wait for the deferred builds of the manifest before them, the
+	// builds of other kernels keep running
+	pending := &sync.WaitGroup{}
+
 	for scanner.Scan() {
 
 		yamlSpec := scanner.Bytes()
@@ -183,54 +188,89 @@ func CreateFromYAML(yamlFile []byte,
 		err = obj.UnmarshalJSON(jsonSpec)
 		exit.OnError(errors.Wrap(err, "Cannot unmarshall json spec, check your manifest: "+string(jsonSpec)))
 
-		//  Do not override the namespace if alreayd set
-		if IsNamespaced(obj.GetKind()) && obj.GetNamespace() == "" {
-			log.Info("Namespace empty settting", "namespace", namespace)
-			obj.SetNamespace(namespace)
-		}
-
-		// We used this for predicate filtering, we're watching a lot of
-		// API Objects we want to ignore all objects that do not have this
-		// label.
-		filter.SetLabel(obj)
-
-		// kernel affinity related attributes only set if there is an
-		// annotation specialresource.openshift.io/kernel-affine: true
-		if kernel.IsObjectAffine(obj) {
-			err := kernel.SetAffineAttributes(obj, kernelFullVersion,
-				operatingSystemMajorMinor)
-			exit.OnError(errors.Wrap(err, "Cannot set kernel affine attributes"))
-		}
-
-		// Add nodeSelector terms for the specialresource
-		// we do not want to spread HW enablement stacks on all nodes
-		err = SetNodeSelectorTerms(obj, nodeSelector)
-		exit.OnError(errors.Wrap(err, "setting NodeSelectorTerms failed"))
+		err = prepare(obj, namespace, nodeSelector, kernelFullVersion, operatingSystemMajorMinor)
+		exit.OnError(err)
 
 		// We are only building a driver-container if we cannot pull the image
 		// We are asuming that vendors provide pre compiled DriverContainers
 		// If err == nil, build a new container, if err != nil skip it
 		if err := rebuildDriverContainer(obj); err != nil {
 			log.Info("Skipping building driver-container", "Name", obj.GetName())
+			if Applied != nil {
+				Applied.SetPartial()
+			}
 			return nil
 		}
 
-		// Callbacks before CRUD will update the manifests
-		if err := BeforeCRUD(obj, owner); err != nil {
-			return errors.Wrap(err, "Before CRUD hooks failed")
+		if Prebuilt && obj.GetKind() == "BuildConfig" {
+			log.Info("Prebuilt driver container, skipping build", "Name", obj.GetName())
+			continue
 		}
-		// Create Update Delete Patch resources
-		err = CRUD(obj, releaseInstalled, owner, name, namespace)
-		// The mutating webhook needs a couple of secs to be ready
-		// sleep for 5 secs and requeue
-		if err != nil && strings.Contains(err.Error(), "failed calling webhook") {
-			return errors.Wrap(err, "Webhook not ready, requeue")
+
+		signed, err := build.Sign(obj, SigningKeySecret, SigningImage, kernelFullVersion)
+		if err != nil {
+			return errors.Wrap(err, "Cannot sign modules of "+obj.GetName())
 		}
-		exit.OnError(errors.Wrapf(err, "CRUD exited non-zero on Object: %+v", obj))
 
-		// Callbacks after CRUD will wait for ressource and check status
-		if err := AfterCRUD(obj, namespace); err != nil {
-			return errors.Wrap(err, "After CRUD hooks failed")
+		// Charts describe builds as BuildConfig, other backends replace
+		// them with their own objects building the same image
+		objs := []*unstructured.Unstructured{}
+		for _, obj := range signed {
+			translated, err := build.Translate(BuildBackend, obj)
+			if err != nil {
+				return errors.Wrap(err, "Cannot translate "+obj.GetName()+" to build backend")
+			}
+			objs = append(objs, translated...)
+		}
+
+		fromBuildConfig := obj.GetKind() == "BuildConfig"
+
+		for _, obj := range objs {
+			pending.Wait()
+
+			// Callbacks before CRUD will update the manifests
+			if err := BeforeCRUD(obj, owner, verifier); err != nil {
+				return errors.Wrap(err, "Before CRUD hooks failed")
+			}
+			recordImageDigests(obj)
+
+			// Hooks are run by helmer and deleted by their policy, they
+			// are not part of the inventory that is pruned
+			if _, hook := obj.GetAnnotations()["helm.sh/hook"]; Applied != nil && !hook {
+				Applied.Add(obj)
+			}
+
+			// Builds of other kernels may be running, the slot is taken
+			// before the build is created
+			deferred := deferBuild(obj, fromBuildConfig)
+			if deferred {
+				builds.acquire()
+			}
+
+			// Create Update Delete Patch resources
+			err = CRUD(obj, releaseInstalled, owner, name, namespace)
+			if err != nil && deferred {
+				builds.release()
+			}
+			// The mutating webhook needs a couple of secs to be ready
+			// sleep for 5 secs and requeue
+			if err != nil && strings.Contains(err.Error(), "failed calling webhook") {
+				return errors.Wrap(err, "Webhook not ready, requeue")
+			}
+			if errors.Is(err, ErrBuildsSerialized) {
+				return err
+			}
+			exit.OnError(errors.Wrapf(err, "CRUD exited non-zero on Object: %+v", obj))
+
+			if deferred {
+				builds.wait(obj, kernelFullVersion, pending)
+				continue
+			}
+
+			// Callbacks after CRUD will wait for ressource and check status
+			if err := AfterCRUD(obj, namespace, kernelFullVersion); err != nil {
+				return errors.Wrap(err, "After CRUD hooks failed")
+			}
 		}
 
 	}
@@ -241,6 +281,52 @@ func CreateFromYAML(yamlFile []byte,
 	return nil
 }
 
+// prepare sets the namespace, filter label, kernel affine attributes and
+// node selector of a manifest of the chart
+func prepare(obj *unstructured.Unstructured,
+	namespace string,
+	nodeSelector map[string]string,
+	kernelFullVersion string,
+	operatingSystemMajorMinor string) error {
+
+	//  Do not override the namespace if alreayd set
+	if IsNamespaced(obj.GetKind()) && obj.GetNamespace() == "" {
+		log.Info("Namespace empty settting", "namespace", namespace)
+		obj.SetNamespace(namespace)
+	}
+
+	// We used this for predicate filtering, we're watching a lot of
+	// API Objects we want to ignore all objects that do not have this
+	// label.
+	filter.SetLabel(obj)
+
+	// kernel affinity related attributes only set if there is an
+	// annotation specialresource.openshift.io/kernel-affine: true
+	if kernel.IsObjectAffine(obj) {
+		err := kernel.SetAffineAttributes(obj, kernelFullVersion,
+			operatingSystemMajorMinor)
+		if err != nil {
+			return errors.Wrap(err, "Cannot set kernel affine attributes")
+		}
+	}
+
+	if err := setFIPSBuildArg(obj); err != nil {
+		return errors.Wrap(err, "Cannot set FIPS build arg")
+	}
+
+	if err := setModuleProbe(obj); err != nil {
+		return errors.Wrap(err, "Cannot set module readiness probe")
+	}
+
+	if err := setNodeExclusionTerms(obj); err != nil {
+		return errors.Wrap(err, "Cannot set node exclusion terms")
+	}
+
+	// Add nodeSelector terms for the specialresource
+	// we do not want to spread HW enablement stacks on all nodes
+	return errors.Wrap(SetNodeSelectorTerms(obj, nodeSelector), "setting NodeSelectorTerms failed")
+}
+
 func IsOneTimer(obj *unstructured.Unstructured) bool {
 
 	// We are not recreating Pods that have restartPolicy: Never
@@ -267,7 +353,7 @@ func CRUD(obj *unstructured.Unstructured, releaseInstalled bool, owner v1.Object
 	// SpecialResource is the parent, all other objects are childs and need a reference
 	// but only set the ownerreference if created by SRO do not set ownerreference per default
 	if obj.GetKind() != "SpecialResource" && obj.GetKind() != "Namespace" {
-		err := controllerutil.SetControllerReference(owner, obj, RuntimeScheme)
+		err := setOwner(owner, obj)
 		exit.OnError(err)
 
 		SetMetaData(obj, name, namespace)
@@ -277,7 +363,7 @@ func CRUD(obj *unstructured.Unstructured, releaseInstalled bool, owner v1.Object
 
 	key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
 
-	err := clients.Interface.Get(context.TODO(), key, found)
+	err := Writer().Get(context.TODO(), key, found)
 
 	if apierrors.IsNotFound(err) {
 		// We are not recreating all objects if a release is already installed
@@ -288,28 +374,42 @@ func CRUD(obj *unstructured.Unstructured, releaseInstalled bool, owner v1.Object
 
 		logg.Info("Not found, creating")
 
+		if SerializeBuilds && obj.GetKind() == "BuildConfig" {
+			running, err := poll.ActiveBuild()
+			if err != nil {
+				return err
+			}
+			if running != "" {
+				return errors.Wrap(ErrBuildsSerialized, "Build "+running+" is running")
+			}
+		}
+
 		logg.Info("Release", "Installed", releaseInstalled)
 		logg.Info("Is", "OneTimer", IsOneTimer(obj))
 
 		hash.Annotate(obj)
 
 		// If we create the resource set the owner reference
-		err := controllerutil.SetControllerReference(owner, obj, RuntimeScheme)
+		err := setOwner(owner, obj)
 		exit.OnError(err)
 
 		SetMetaData(obj, name, namespace)
 
-		if err := clients.Interface.Create(context.TODO(), obj); err != nil {
+		if err := apply(obj); err != nil {
 			if apierrors.IsForbidden(err) {
 				return errors.Wrap(err, "API error is forbidden")
 			}
 			return errors.Wrap(err, "Unknown error")
 		}
+		Changes++
 
 		return nil
 	}
 
 	if apierrors.IsForbidden(err) {
+		if Impersonated != nil {
+			return errors.Wrap(err, "Forbidden check Role, ClusterRole and Bindings for the service account")
+		}
 		return errors.Wrap(err, "Forbidden check Role, ClusterRole and Bindings for operator")
 	}
 
@@ -329,21 +429,22 @@ func CRUD(obj *unstructured.Unstructured, releaseInstalled bool, owner v1.Object
 		return nil
 	}
 
-	logg.Info("Found, updating")
 	required := obj.DeepCopy()
 
 	hash.Annotate(required)
 
-	// required.ResourceVersion = found.ResourceVersion this is only needed
-	// before we update a resource, we do not care when creating, hence
-	// !leave this here!
-	if err := UpdateResourceVersion(required, found); err != nil {
-		return errors.Wrap(err, "Couldn't Update ResourceVersion")
+	changed := diff.Objects(found, required)
+	logg.Info("Found, updating", "diff", changed)
+	if len(changed) > 0 {
+		Diffs = append(Diffs, Diff{Object: objectName(required), Fields: changed})
 	}
 
-	if err := clients.Interface.Update(context.TODO(), required); err != nil {
+	// Server-side apply only changes the fields of the manifest, fields
+	// defaulted by the API server like the clusterIP of a Service are kept
+	if err := apply(required); err != nil {
 		return errors.Wrap(err, "Couldn't Update Resource")
 	}
+	Changes++
 
 	return nil
 }
@@ -370,6 +471,25 @@ func rebuildDriverContainer(obj *unstructured.Unstructured) error {
 	return nil
 }
 
+// setOwner sets the controller reference to owner, in HyperShift mode the
+// owner lives in the management cluster and is only recorded as annotation
+// since owner references cannot cross clusters.
+func setOwner(owner v1.Object, obj *unstructured.Unstructured) error {
+
+	if !clients.IsHosted() {
+		return controllerutil.SetControllerReference(owner, obj, RuntimeScheme)
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[OwnerAnnotation] = owner.GetName()
+	obj.SetAnnotations(annotations)
+
+	return nil
+}
+
 func SetMetaData(obj *unstructured.Unstructured, nm string, ns string) {
 
 	annotations := obj.GetAnnotations()
@@ -394,22 +514,76 @@ func SetMetaData(obj *unstructured.Unstructured, nm string, ns string) {
 	obj.SetLabels(labels)
 }
 
+// injectProxy returns true if the cluster proxy is injected into obj, an
+// explicit "true" or "false" proxy annotation wins over ProxyInjection. Pods
+// are only injected if annotated.
+func injectProxy(obj *unstructured.Unstructured) bool {
+
+	if inject, found := obj.GetAnnotations()["specialresource.openshift.io/proxy"]; found {
+		return inject == "true"
+	}
+
+	switch obj.GetKind() {
+	case "BuildConfig", "Job", "DaemonSet":
+		return ProxyInjection
+	}
+	return false
+}
+
 type resourceCallbacks map[string]func(obj *unstructured.Unstructured, sr interface{}) error
 
 var customCallback resourceCallbacks
 
-func BeforeCRUD(obj *unstructured.Unstructured, sr interface{}) error {
+// BeforeCRUD updates obj before it is applied, driver containers are
+// verified with verifier if it is not nil
+func BeforeCRUD(obj *unstructured.Unstructured, sr interface{}, verifier *registry.Verifier) error {
 
 	var found bool
 	todo := ""
 	annotations := obj.GetAnnotations()
 
-	if valid, found := annotations["specialresource.openshift.io/proxy"]; found && valid == "true" {
+	if injectProxy(obj) {
 		if err := proxy.Setup(obj); err != nil {
 			return errors.Wrap(err, "Could not setup Proxy")
 		}
 	}
 
+	if constraint, found := annotations["specialresource.openshift.io/image-version"]; found && constraint != "" {
+		if err := resolveImageVersion(obj, constraint); err != nil {
+			return errors.Wrap(err, "Could not resolve image version")
+		}
+	}
+
+	if state, found := annotations["specialresource.openshift.io/state"]; found && state == "driver-container" && verifier != nil {
+		if err := verifyDriverContainer(obj, verifier); err != nil {
+			return errors.Wrap(err, "Could not verify driver-container")
+		}
+	}
+
+	if isDriverDaemonSet(obj) && ImageScanner != nil {
+		if err := scanDriverContainer(obj); err != nil {
+			return errors.Wrap(err, "Vulnerability scan blocks driver-container")
+		}
+	}
+
+	if FIPS && FIPSStrict && isDriverDaemonSet(obj) {
+		if err := verifyFIPS(obj); err != nil {
+			return err
+		}
+	}
+
+	if isDriverDaemonSet(obj) && Rollout != nil {
+		if err := rollout.SetOnDelete(obj); err != nil {
+			return errors.Wrap(err, "Could not set OnDelete update strategy")
+		}
+	}
+
+	if readiness.IsDevicePlugin(obj) {
+		if err := readiness.SetNodeSelector(obj); err != nil {
+			return errors.Wrap(err, "Could not select nodes with a ready driver")
+		}
+	}
+
 	if todo, found = annotations["specialresource.openshift.io/callback"]; !found {
 		return nil
 	}
@@ -422,7 +596,188 @@ func BeforeCRUD(obj *unstructured.Unstructured, sr interface{}) error {
 	return nil
 }
 
-func AfterCRUD(obj *unstructured.Unstructured, namespace string) error {
+// verifyDriverContainer checks the signatures of prebuilt driver containers
+// and pins their images to the verified digests, images of the skipped
+// repositories of verifier, e.g. built in-cluster, are kept as is.
+func verifyDriverContainer(obj *unstructured.Unstructured, verifier *registry.Verifier) error {
+
+	containers, found, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if err != nil || !found {
+		return err
+	}
+
+	for i, container := range containers {
+		switch container := container.(type) {
+		case map[string]interface{}:
+			image, _, _ := unstructured.NestedString(container, "image")
+			if image == "" {
+				continue
+			}
+			pinned, err := verifier.Verify(image)
+			if err != nil {
+				return err
+			}
+			container["image"] = pinned
+			containers[i] = container
+		default:
+			log.Info("container", "DEFAULT NOT THE CORRECT TYPE", container)
+		}
+	}
+
+	return unstructured.SetNestedSlice(obj.Object, containers, "spec", "template", "spec", "containers")
+}
+
+// scanDriverContainer checks the vulnerability scan of the digest of every
+// container image, images of the internal registry are not scanned.
+func scanDriverContainer(obj *unstructured.Unstructured) error {
+
+	containers, found, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if err != nil || !found {
+		return err
+	}
+
+	for _, container := range containers {
+		switch container := container.(type) {
+		case map[string]interface{}:
+			image, _, _ := unstructured.NestedString(container, "image")
+			if image == "" || strings.HasPrefix(image, "image-registry.openshift-image-registry.svc") {
+				continue
+			}
+			digest, err := registry.Digests.Record(image)
+			if err != nil {
+				return err
+			}
+			if err := ImageScanner.Check(image, digest); err != nil {
+				return err
+			}
+		default:
+			log.Info("container", "DEFAULT NOT THE CORRECT TYPE", container)
+		}
+	}
+
+	return nil
+}
+
+// promoteBuild waits for the build and copies the resulting ImageStreamTag
+// from the internal registry to PromoteRepository.
+func promoteBuild(obj *unstructured.Unstructured) error {
+
+	namespace, tag, ok := buildOutput(obj)
+	if !ok {
+		log.Info("BuildConfig output is not an ImageStreamTag, skipping promotion", "name", obj.GetName())
+		return nil
+	}
+
+	if err := poll.ForResource(obj); err != nil {
+		return errors.Wrap(err, "Could not wait for build")
+	}
+
+	src := "image-registry.openshift-image-registry.svc:5000/" + namespace + "/" + tag
+	dst := strings.TrimSuffix(PromoteRepository, "/") + "/" + tag
+
+	return registry.Copy(src, dst, buildProviders(obj, namespace)...)
+}
+
+// buildOutput returns the namespace and name of the ImageStreamTag the
+// BuildConfig obj pushes to
+func buildOutput(obj *unstructured.Unstructured) (string, string, bool) {
+
+	kind, _, _ := unstructured.NestedString(obj.Object, "spec", "output", "to", "kind")
+	tag, _, _ := unstructured.NestedString(obj.Object, "spec", "output", "to", "name")
+	if kind != "ImageStreamTag" || tag == "" {
+		return "", "", false
+	}
+
+	namespace, _, _ := unstructured.NestedString(obj.Object, "spec", "output", "to", "namespace")
+	if namespace == "" {
+		namespace = obj.GetNamespace()
+	}
+
+	return namespace, tag, true
+}
+
+// buildProviders returns the credentials for the internal registry and
+// PromoteRepository
+func buildProviders(obj *unstructured.Unstructured, namespace string) []registry.KeychainProvider {
+
+	providers := []registry.KeychainProvider{}
+	if PromotePushSecret != "" {
+		providers = append(providers, registry.PullSecrets{Namespace: obj.GetNamespace(), ServiceAccount: "default", Secrets: []string{PromotePushSecret}})
+	}
+	// The builder ServiceAccount can read from the internal registry
+	return append(providers, registry.PullSecrets{Namespace: namespace, ServiceAccount: "builder"})
+}
+
+// resolveImageVersion replaces the tag of every container image with the
+// newest tag in the repository matching the semver constraint.
+func resolveImageVersion(obj *unstructured.Unstructured, constraint string) error {
+
+	fields := []string{"spec", "template", "spec", "containers"}
+	if obj.GetKind() == "Pod" {
+		fields = []string{"spec", "containers"}
+	}
+
+	containers, found, err := unstructured.NestedSlice(obj.Object, fields...)
+	if err != nil || !found {
+		return err
+	}
+
+	for i, container := range containers {
+		switch container := container.(type) {
+		case map[string]interface{}:
+			image, _, _ := unstructured.NestedString(container, "image")
+			if image == "" {
+				continue
+			}
+			repo, err := registry.Repository(image)
+			if err != nil {
+				return err
+			}
+			tag, err := registry.LatestTag(repo, constraint)
+			if err != nil {
+				return err
+			}
+			container["image"] = repo + ":" + tag
+			containers[i] = container
+		default:
+			log.Info("container", "DEFAULT NOT THE CORRECT TYPE", container)
+		}
+	}
+
+	return unstructured.SetNestedSlice(obj.Object, containers, fields...)
+}
+
+// recordImageDigests resolves the container images of obj so the digests
+// end up in the SpecialResource status.
+func recordImageDigests(obj *unstructured.Unstructured) {
+
+	fields := []string{"spec", "template", "spec", "containers"}
+	if obj.GetKind() == "Pod" {
+		fields = []string{"spec", "containers"}
+	}
+
+	containers, found, err := unstructured.NestedSlice(obj.Object, fields...)
+	if err != nil || !found {
+		return
+	}
+
+	for _, container := range containers {
+		switch container := container.(type) {
+		case map[string]interface{}:
+			image, _, _ := unstructured.NestedString(container, "image")
+			if image == "" {
+				continue
+			}
+			if _, err := registry.Digests.Record(image); err != nil {
+				log.Info("Cannot resolve image digest", "image", image, "error", err.Error())
+			}
+		default:
+			log.Info("container", "DEFAULT NOT THE CORRECT TYPE", container)
+		}
+	}
+}
+
+func AfterCRUD(obj *unstructured.Unstructured, namespace string, kernelFullVersion string) error {
 
 	annotations := obj.GetAnnotations()
 	clients.Namespace = namespace
@@ -434,9 +789,15 @@ func AfterCRUD(obj *unstructured.Unstructured, namespace string) error {
 		}
 	}
 
+	if isDriverDaemonSet(obj) && Rollout != nil {
+		if err := rollout.Update(obj, *Rollout); err != nil {
+			return errors.Wrap(err, "Could not roll out driver-container")
+		}
+	}
+
 	if wait, found := annotations["specialresource.openshift.io/wait"]; found && wait == "true" {
 		log.Info("specialresource.openshift.io/wait")
-		if err := poll.ForResource(obj); err != nil {
+		if err := waitForResource(obj, kernelFullVersion); err != nil {
 			return errors.Wrap(err, "Could not wait for resource")
 		}
 	}
@@ -448,6 +809,12 @@ func AfterCRUD(obj *unstructured.Unstructured, namespace string) error {
 		}
 	}
 
+	if isDriverDaemonSet(obj) {
+		if err := readiness.Update(obj); err != nil {
+			return errors.Wrap(err, "Could not label nodes with a ready driver")
+		}
+	}
+
 	if _, found := annotations["helm.sh/hook"]; found {
 		// In the case of hooks we're always waiting for all ressources
 		if err := poll.ForResource(obj); err != nil {
@@ -455,6 +822,10 @@ func AfterCRUD(obj *unstructured.Unstructured, namespace string) error {
 		}
 	}
 
+	if err := afterBuild(obj, kernelFullVersion); err != nil {
+		return err
+	}
+
 	// Always wait for CRDs to be present
 	if obj.GetKind() == "CustomResourceDefinition" {
 		if err := poll.ForResource(obj); err != nil {
@@ -465,6 +836,27 @@ func AfterCRUD(obj *unstructured.Unstructured, namespace string) error {
 	return nil
 }
 
+// afterBuild promotes and attaches the SBOM to the image of a completed
+// BuildConfig
+func afterBuild(obj *unstructured.Unstructured, kernelFullVersion string) error {
+
+	if obj.GetKind() == "BuildConfig" && PromoteRepository != "" {
+		if err := promoteBuild(obj); err != nil {
+			return errors.Wrap(err, "Could not promote driver-container")
+		}
+	}
+
+	if obj.GetKind() == "BuildConfig" && SBOMFormat != "" {
+		warn.OnError(errors.Wrap(attachSBOM(obj, kernelFullVersion), "Could not attach SBOM to driver-container"))
+	}
+
+	return nil
+}
+
+func isDriverDaemonSet(obj *unstructured.Unstructured) bool {
+	return obj.GetKind() == "DaemonSet" && obj.GetAnnotations()["specialresource.openshift.io/state"] == "driver-container"
+}
+
 func checkForImagePullBackOff(obj *unstructured.Unstructured, namespace string) error {
 
 	if err := poll.ForDaemonSet(obj); err == nil {
@@ -489,7 +881,7 @@ func checkForImagePullBackOff(obj *unstructured.Unstructured, namespace string)
 		client.MatchingLabels(find),
 	}
 
-	err := clients.Interface.List(context.TODO(), pods, opts...)
+	err := clients.Workload().List(context.TODO(), pods, opts...)
 	if err != nil {
 		log.Error(err, "Could not get PodList")
 		return err
@@ -0,0 +1,98 @@
+package resource
+
+import (
+	"strings"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/poll"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/openshift-psap/special-resource-operator/pkg/sbom"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var (
+	// SBOMFormat of the SBOM attached to driver containers built in-cluster,
+	// empty disables them
+	SBOMFormat string
+	// DriverToolkitImage the builds of the current kernel are based on
+	DriverToolkitImage string
+)
+
+// attachSBOM waits for the build of the BuildConfig obj and pushes the SBOM
+// of the image next to it, and next to the promoted copy. Images that
+// already have one are skipped.
+func attachSBOM(obj *unstructured.Unstructured, kernelFullVersion string) error {
+
+	namespace, tag, ok := buildOutput(obj)
+	if !ok {
+		log.Info("BuildConfig output is not an ImageStreamTag, skipping SBOM", "name", obj.GetName())
+		return nil
+	}
+
+	if err := poll.ForResource(obj); err != nil {
+		return errors.Wrap(err, "Could not wait for build")
+	}
+
+	images := []string{"image-registry.openshift-image-registry.svc:5000/" + namespace + "/" + tag}
+	if PromoteRepository != "" {
+		images = append(images, strings.TrimSuffix(PromoteRepository, "/")+"/"+tag)
+	}
+
+	providers := buildProviders(obj, namespace)
+
+	// DTK images of the release payload are referenced by digest already
+	toolkit := DriverToolkitImage
+	if toolkit != "" && !strings.Contains(toolkit, "@") {
+		digest, err := registry.Digests.Record(toolkit)
+		if err != nil {
+			return err
+		}
+		repo, err := registry.Repository(toolkit)
+		if err != nil {
+			return err
+		}
+		toolkit = repo + "@" + digest
+	}
+
+	for _, image := range images {
+
+		img, err := registry.Pull(image, providers...)
+		if err != nil {
+			return err
+		}
+		digest, err := img.Digest()
+		if err != nil {
+			return errors.Wrap(err, "Cannot get digest of "+image)
+		}
+
+		ref, found, err := registry.Attachment(image, digest.String(), "sbom", providers...)
+		if err != nil {
+			return err
+		}
+		if found {
+			continue
+		}
+
+		doc := sbom.Document{
+			Image:             image,
+			Digest:            digest.String(),
+			KernelFullVersion: kernelFullVersion,
+			DriverToolkit:     toolkit,
+		}
+		if err := sbom.Scan(img, &doc); err != nil {
+			return err
+		}
+
+		payload, mediaType, err := sbom.Encode(doc, SBOMFormat)
+		if err != nil {
+			return err
+		}
+
+		log.Info("Attaching SBOM", "image", image, "ref", ref, "modules", len(doc.Modules))
+		if err := registry.Attach(ref, payload, mediaType, providers...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package resource
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/build"
+	"github.com/openshift-psap/special-resource-operator/pkg/yamlutil"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// ValidationError the objects of a manifest the API server rejected in the
+// dry-run, one entry per object
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	// The message ends up in a condition, they are limited to 32768 characters
+	if len(e.Errors) > 10 {
+		return "Manifests rejected by the API server: " + strings.Join(e.Errors[:10], "; ") +
+			"; and " + strconv.Itoa(len(e.Errors)-10) + " more"
+	}
+	return "Manifests rejected by the API server: " + strings.Join(e.Errors, "; ")
+}
+
+// ValidateFromYAML applies every object of yamlFile as a server-side dry-run
+// and returns a ValidationError listing all objects that are invalid.
+// Objects whose kind or namespace is created by the manifest itself cannot be
+// validated before it is applied and are skipped, as are other errors like
+// a missing permission, CRUD reports them.
+func ValidateFromYAML(yamlFile []byte,
+	releaseInstalled bool,
+	owner v1.Object,
+	name string,
+	namespace string,
+	nodeSelector map[string]string,
+	kernelFullVersion string,
+	operatingSystemMajorMinor string) error {
+
+	invalid := &ValidationError{}
+
+	scanner := yamlutil.NewYAMLScanner(yamlFile)
+
+	for scanner.Scan() {
+
+		obj := &unstructured.Unstructured{
+			Object: map[string]interface{}{},
+		}
+
+		jsonSpec, err := yaml.YAMLToJSON(scanner.Bytes())
+		if err != nil {
+			return errors.Wrap(err, "Could not convert yaml file to json"+string(scanner.Bytes()))
+		}
+		if err := obj.UnmarshalJSON(jsonSpec); err != nil {
+			invalid.Errors = append(invalid.Errors, err.Error())
+			continue
+		}
+
+		if err := prepare(obj, namespace, nodeSelector, kernelFullVersion, operatingSystemMajorMinor); err != nil {
+			return err
+		}
+
+		// Same objects CreateFromYAML and CRUD leave alone
+		if (Prebuilt && obj.GetKind() == "BuildConfig") || IsNotUpdateable(obj.GetKind()) ||
+			(releaseInstalled && IsOneTimer(obj)) {
+			continue
+		}
+
+		objs, err := build.Translate(BuildBackend, obj)
+		if err != nil {
+			return errors.Wrap(err, "Cannot translate "+obj.GetName()+" to build backend")
+		}
+
+		for _, obj := range objs {
+
+			if obj.GetKind() != "SpecialResource" && obj.GetKind() != "Namespace" {
+				if err := setOwner(owner, obj); err != nil {
+					return err
+				}
+				SetMetaData(obj, name, namespace)
+			}
+
+			if err := dryRun(obj); err != nil {
+				invalid.Errors = append(invalid.Errors, err.Error())
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "Failed to scan manifest")
+	}
+
+	if len(invalid.Errors) > 0 {
+		return invalid
+	}
+	return nil
+}
+
+// dryRun returns an error if the API server rejects obj as invalid
+func dryRun(obj *unstructured.Unstructured) error {
+
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
+
+	err := Writer().Patch(context.TODO(), obj, client.Apply, client.FieldOwner(FieldManager),
+		client.ForceOwnership, client.DryRunAll)
+
+	if err == nil || meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	if !apierrors.IsInvalid(err) && !apierrors.IsBadRequest(err) {
+		log.Info("Skipping validation", "Kind", obj.GetKind(), "Name", obj.GetName(), "error", err.Error())
+		return nil
+	}
+
+	if IsNamespaced(obj.GetKind()) {
+		return errors.Wrap(err, obj.GetKind()+": "+obj.GetNamespace()+"/"+obj.GetName())
+	}
+	return errors.Wrap(err, obj.GetKind()+": "+obj.GetName())
+}
@@ -0,0 +1,174 @@
+package rollout
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/poll"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// CordonAnnotation marks nodes cordoned for a driver upgrade, nodes an
+// admin cordoned are never uncordoned by the operator
+const CordonAnnotation = "specialresource.openshift.io/cordoned"
+
+// Drain evicts the pods of a node requesting any of Resources or matching
+// PodSelector and waits up to Timeout for them to terminate.
+type Drain struct {
+	Resources   []string
+	PodSelector map[string]string
+	Timeout     time.Duration
+}
+
+func cordon(name string) error {
+
+	node, err := clients.Workload().CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "Cannot get node "+name)
+	}
+
+	if node.Spec.Unschedulable {
+		return nil
+	}
+
+	log.Info("Cordon", "Node", name)
+
+	return patchNode(name, true, "true")
+}
+
+func uncordon(name string) error {
+
+	node, err := clients.Workload().CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "Cannot get node "+name)
+	}
+
+	if _, found := node.GetAnnotations()[CordonAnnotation]; !found {
+		return nil
+	}
+
+	log.Info("Uncordon", "Node", name)
+
+	return patchNode(name, false, nil)
+}
+
+func patchNode(name string, unschedulable bool, annotation interface{}) error {
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{CordonAnnotation: annotation},
+		},
+		"spec": map[string]interface{}{"unschedulable": unschedulable},
+	})
+	if err != nil {
+		return errors.Wrap(err, "Cannot marshal node patch")
+	}
+
+	_, err = clients.Workload().CoreV1().Nodes().Patch(context.TODO(), name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return errors.Wrap(err, "Cannot patch node "+name)
+}
+
+// Node evicts the workloads using the device from the node, evictions
+// refused by a PodDisruptionBudget are retried until the timeout.
+func (d *Drain) Node(name string) error {
+
+	timeout := d.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Minute
+	}
+
+	log.Info("Drain", "Node", name)
+
+	err := wait.Poll(poll.RetryInterval, timeout, func() (bool, error) {
+
+		pods, err := d.pods(name)
+		if err != nil {
+			return false, err
+		}
+
+		for _, pod := range pods {
+			if pod.GetDeletionTimestamp() != nil {
+				continue
+			}
+			eviction := &policyv1beta1.Eviction{
+				ObjectMeta: metav1.ObjectMeta{Name: pod.GetName(), Namespace: pod.GetNamespace()},
+			}
+			err := clients.Workload().CoreV1().Pods(pod.GetNamespace()).Evict(context.TODO(), eviction)
+			if apierrors.IsTooManyRequests(err) {
+				log.Info("Eviction refused by PodDisruptionBudget", "Pod", pod.GetName(), "Namespace", pod.GetNamespace())
+				continue
+			}
+			if err != nil && !apierrors.IsNotFound(err) {
+				return false, errors.Wrap(err, "Cannot evict pod "+pod.GetName())
+			}
+		}
+
+		return len(pods) == 0, nil
+	})
+
+	return errors.Wrap(err, "Cannot drain node "+name)
+}
+
+// pods returns the pods of the node to evict, DaemonSet pods are skipped
+// as they would be recreated on the cordoned node.
+func (d *Drain) pods(node string) ([]v1.Pod, error) {
+
+	list, err := clients.Workload().CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot list pods on node "+node)
+	}
+
+	var selector labels.Selector
+	if len(d.PodSelector) > 0 {
+		selector = labels.SelectorFromSet(d.PodSelector)
+	}
+
+	pods := []v1.Pod{}
+	for _, pod := range list.Items {
+
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+
+		if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == "DaemonSet" {
+			continue
+		}
+
+		if (selector != nil && selector.Matches(labels.Set(pod.GetLabels()))) || d.requestsResource(&pod) {
+			pods = append(pods, pod)
+		}
+	}
+
+	return pods, nil
+}
+
+func (d *Drain) requestsResource(pod *v1.Pod) bool {
+
+	containers := append([]v1.Container{}, pod.Spec.InitContainers...)
+	containers = append(containers, pod.Spec.Containers...)
+
+	for _, container := range containers {
+		for _, resource := range d.Resources {
+			if _, found := container.Resources.Requests[v1.ResourceName(resource)]; found {
+				return true
+			}
+			if _, found := container.Resources.Limits[v1.ResourceName(resource)]; found {
+				return true
+			}
+		}
+	}
+
+	return false
+}
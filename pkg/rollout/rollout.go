@@ -0,0 +1,318 @@
+package rollout
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/color"
+	"github.com/openshift-psap/special-resource-operator/pkg/poll"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+const (
+	// StrategyOrdered upgrades the driver pods one batch of nodes at a time
+	StrategyOrdered = "Ordered"
+	// StrategyCanary upgrades the driver pods of the canary nodes first
+	StrategyCanary = "Canary"
+)
+
+var log logr.Logger
+
+func init() {
+	log = zap.New(zap.UseDevMode(true)).WithName(color.Print("rollout", color.Brown))
+}
+
+// Options of an operator driven rollout, the nodes are upgraded in batches
+// of MaxUnavailable nodes that are cordoned and optionally drained.
+type Options struct {
+	// Canary nodes are upgraded first, nil upgrades all nodes in batches
+	Canary         *Canary
+	MaxUnavailable intstr.IntOrString
+	// Drain evicts the workloads using the device, nil only cordons
+	Drain *Drain
+}
+
+// Canary rolls a new driver version to the canary nodes first, either the
+// nodes matching NodeSelector or the first Nodes nodes running the driver.
+type Canary struct {
+	Nodes        int
+	NodeSelector map[string]string
+}
+
+// SetOnDelete switches the DaemonSet obj to the OnDelete update strategy,
+// pods are only replaced by Update.
+func SetOnDelete(obj *unstructured.Unstructured) error {
+
+	strategy := map[string]interface{}{"type": string(appsv1.OnDeleteDaemonSetStrategyType)}
+
+	return unstructured.SetNestedMap(obj.Object, strategy, "spec", "updateStrategy")
+}
+
+// Update replaces the outdated pods of the DaemonSet obj one batch of nodes
+// at a time, the canary pods first. The remaining pods are only replaced
+// once the canary pods are ready, a failing batch aborts the rollout and
+// leaves its nodes cordoned.
+func Update(obj *unstructured.Unstructured, opts Options) error {
+
+	ds, err := clients.Workload().AppsV1().DaemonSets(obj.GetNamespace()).Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "Cannot get DaemonSet "+obj.GetName())
+	}
+
+	revision, err := currentRevision(ds)
+	if err != nil {
+		return err
+	}
+
+	pods, err := daemonSetPods(ds)
+	if err != nil {
+		return err
+	}
+
+	// Nodes of an interrupted rollout whose driver is up to date again
+	for _, pod := range pods {
+		if pod.GetLabels()[appsv1.DefaultDaemonSetUniqueLabelKey] == revision && podReady(&pod) {
+			if err := uncordon(pod.Spec.NodeName); err != nil {
+				return err
+			}
+		}
+	}
+
+	batch, err := intstr.GetScaledValueFromIntOrPercent(&opts.MaxUnavailable, len(pods), true)
+	if err != nil {
+		return errors.Wrap(err, "Invalid maxUnavailable")
+	}
+	if batch < 1 {
+		batch = 1
+	}
+
+	canaryNodes := make(map[string]bool)
+	if opts.Canary != nil {
+		if canaryNodes, err = selectCanaryNodes(pods, *opts.Canary); err != nil {
+			return err
+		}
+	}
+
+	canaries := []v1.Pod{}
+	outdated := []v1.Pod{}
+	for _, pod := range pods {
+		if pod.GetLabels()[appsv1.DefaultDaemonSetUniqueLabelKey] == revision {
+			continue
+		}
+		if canaryNodes[pod.Spec.NodeName] {
+			canaries = append(canaries, pod)
+		} else {
+			outdated = append(outdated, pod)
+		}
+	}
+
+	if len(canaries)+len(outdated) == 0 {
+		return nil
+	}
+
+	log.Info("Rollout", "DaemonSet", ds.GetName(), "revision", revision, "outdated", len(canaries)+len(outdated), "canaries", len(canaries), "maxUnavailable", batch)
+
+	if err := upgrade(ds, revision, canaries, batch, opts.Drain); err != nil {
+		return errors.Wrap(err, "Canary rollout of DaemonSet "+ds.GetName()+" aborted")
+	}
+
+	if err := upgrade(ds, revision, outdated, batch, opts.Drain); err != nil {
+		return errors.Wrap(err, "Rollout of DaemonSet "+ds.GetName()+" aborted")
+	}
+
+	return nil
+}
+
+// upgrade cordons, drains and replaces the driver pods of batch nodes at a
+// time and uncordons the nodes once their new driver pods are ready.
+func upgrade(ds *appsv1.DaemonSet, revision string, pods []v1.Pod, batch int, drain *Drain) error {
+
+	for start := 0; start < len(pods); start += batch {
+
+		end := start + batch
+		if end > len(pods) {
+			end = len(pods)
+		}
+
+		nodes := []string{}
+		for _, pod := range pods[start:end] {
+
+			node := pod.Spec.NodeName
+			nodes = append(nodes, node)
+
+			if err := cordon(node); err != nil {
+				return err
+			}
+
+			if drain != nil {
+				if err := drain.Node(node); err != nil {
+					return err
+				}
+			}
+
+			log.Info("Replacing driver pod", "Pod", pod.GetName(), "Node", node)
+			err := clients.Workload().CoreV1().Pods(pod.GetNamespace()).Delete(context.TODO(), pod.GetName(), metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return errors.Wrap(err, "Cannot delete pod "+pod.GetName())
+			}
+		}
+
+		if err := waitForNodes(ds, revision, nodes); err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			if err := uncordon(node); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// waitForNodes waits until the DaemonSet runs a ready pod of revision on
+// each of the nodes.
+func waitForNodes(ds *appsv1.DaemonSet, revision string, nodes []string) error {
+
+	return wait.Poll(poll.RetryInterval, poll.Timeout, func() (bool, error) {
+
+		current, err := daemonSetPods(ds)
+		if err != nil {
+			return false, err
+		}
+
+		ready := make(map[string]bool)
+		for _, pod := range current {
+			if pod.GetLabels()[appsv1.DefaultDaemonSetUniqueLabelKey] == revision && podReady(&pod) {
+				ready[pod.Spec.NodeName] = true
+			}
+		}
+
+		waiting := []string{}
+		for _, node := range nodes {
+			if !ready[node] {
+				waiting = append(waiting, node)
+			}
+		}
+
+		if len(waiting) > 0 {
+			log.Info("Waiting for driver pods", "DaemonSet", ds.GetName(), "nodes", strings.Join(waiting, ","))
+			return false, nil
+		}
+		return true, nil
+	})
+}
+
+// selectCanaryNodes returns the nodes of pods selected as canary
+func selectCanaryNodes(pods []v1.Pod, canary Canary) (map[string]bool, error) {
+
+	selected := make(map[string]bool)
+
+	if len(canary.NodeSelector) > 0 {
+		nodes, err := clients.ListNodes(labels.SelectorFromSet(canary.NodeSelector))
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot list canary nodes")
+		}
+		for _, node := range nodes {
+			selected[node.GetName()] = true
+		}
+		return selected, nil
+	}
+
+	names := []string{}
+	for _, pod := range pods {
+		names = append(names, pod.Spec.NodeName)
+	}
+	sort.Strings(names)
+
+	count := canary.Nodes
+	if count < 1 {
+		count = 1
+	}
+
+	for i := 0; i < len(names) && i < count; i++ {
+		selected[names[i]] = true
+	}
+
+	return selected, nil
+}
+
+// currentRevision returns the hash of the newest ControllerRevision of ds
+func currentRevision(ds *appsv1.DaemonSet) (string, error) {
+
+	selector, err := metav1.LabelSelectorAsSelector(ds.Spec.Selector)
+	if err != nil {
+		return "", errors.Wrap(err, "Invalid selector of DaemonSet "+ds.GetName())
+	}
+
+	revisions, err := clients.Workload().AppsV1().ControllerRevisions(ds.GetNamespace()).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot list ControllerRevisions of DaemonSet "+ds.GetName())
+	}
+
+	var newest *appsv1.ControllerRevision
+	for i, revision := range revisions.Items {
+		if !metav1.IsControlledBy(&revision, ds) {
+			continue
+		}
+		if newest == nil || revision.Revision > newest.Revision {
+			newest = &revisions.Items[i]
+		}
+	}
+
+	if newest == nil {
+		return "", errors.New("No ControllerRevision for DaemonSet " + ds.GetName())
+	}
+
+	return newest.GetLabels()[appsv1.DefaultDaemonSetUniqueLabelKey], nil
+}
+
+func daemonSetPods(ds *appsv1.DaemonSet) ([]v1.Pod, error) {
+
+	selector, err := metav1.LabelSelectorAsSelector(ds.Spec.Selector)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid selector of DaemonSet "+ds.GetName())
+	}
+
+	list, err := clients.Workload().CoreV1().Pods(ds.GetNamespace()).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot list pods of DaemonSet "+ds.GetName())
+	}
+
+	pods := []v1.Pod{}
+	for _, pod := range list.Items {
+		if metav1.IsControlledBy(&pod, ds) && pod.GetDeletionTimestamp() == nil {
+			pods = append(pods, pod)
+		}
+	}
+
+	return pods, nil
+}
+
+// podReady is true if the readiness probe of the driver container, usually
+// checking that the module is loaded, succeeded
+func podReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
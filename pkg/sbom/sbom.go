@@ -0,0 +1,341 @@
+package sbom
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+const (
+	SPDX      = "spdx"
+	CycloneDX = "cyclonedx"
+
+	tool = "special-resource-operator"
+)
+
+// Document what is known about a driver container, the packages of the
+// operating system are not listed
+type Document struct {
+	// Image reference and digest of the driver container
+	Image  string
+	Digest string
+	// KernelFullVersion the driver container was built for
+	KernelFullVersion string
+	// DriverToolkit image the build was based on, with digest if resolved
+	DriverToolkit string
+	// OperatingSystem ID and VERSION_ID of the os-release of the image
+	OperatingSystem        string
+	OperatingSystemVersion string
+	Modules                []Module
+}
+
+// Module a kernel module file in the driver container
+type Module struct {
+	Path   string
+	SHA256 string
+}
+
+// Scan reads the os-release and the kernel modules of the flattened
+// filesystem of img into doc
+func Scan(img v1.Image, doc *Document) error {
+
+	rc := mutate.Extract(img)
+	defer rc.Close()
+
+	tr := tar.NewReader(rc)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "Cannot read filesystem of "+doc.Image)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := "/" + strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+
+		switch {
+		case name == "/etc/os-release" || name == "/usr/lib/os-release":
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				return errors.Wrap(err, "Cannot read "+name)
+			}
+			if doc.OperatingSystem == "" || name == "/etc/os-release" {
+				doc.OperatingSystem, doc.OperatingSystemVersion = osRelease(content)
+			}
+		case isModule(name):
+			hash := sha256.New()
+			if _, err := io.Copy(hash, tr); err != nil {
+				return errors.Wrap(err, "Cannot read "+name)
+			}
+			doc.Modules = append(doc.Modules, Module{Path: name, SHA256: hex.EncodeToString(hash.Sum(nil))})
+		}
+	}
+
+	sort.Slice(doc.Modules, func(i, j int) bool { return doc.Modules[i].Path < doc.Modules[j].Path })
+
+	return nil
+}
+
+func isModule(name string) bool {
+	for _, ext := range []string{".ko", ".ko.xz", ".ko.gz", ".ko.zst"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// osRelease returns ID and VERSION_ID of an os-release file
+func osRelease(content []byte) (string, string) {
+
+	var id, version string
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.Trim(kv[1], `"'`)
+		switch kv[0] {
+		case "ID":
+			id = value
+		case "VERSION_ID":
+			version = value
+		}
+	}
+
+	return id, version
+}
+
+// Encode returns doc in format and the media type of the format
+func Encode(doc Document, format string) ([]byte, types.MediaType, error) {
+
+	switch format {
+	case SPDX:
+		out, err := json.MarshalIndent(spdx(doc), "", "  ")
+		return out, "application/spdx+json", err
+	case CycloneDX:
+		out, err := json.MarshalIndent(cycloneDX(doc), "", "  ")
+		return out, "application/vnd.cyclonedx+json", err
+	}
+
+	return nil, "", errors.New("Unknown SBOM format " + format)
+}
+
+// hexDigest strips the algorithm of a sha256:<hex> digest
+func hexDigest(digest string) string {
+	return strings.TrimPrefix(digest, "sha256:")
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Files             []spdxFile         `json:"files,omitempty"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID                string         `json:"SPDXID"`
+	Name                  string         `json:"name"`
+	VersionInfo           string         `json:"versionInfo,omitempty"`
+	DownloadLocation      string         `json:"downloadLocation"`
+	PrimaryPackagePurpose string         `json:"primaryPackagePurpose,omitempty"`
+	Checksums             []spdxChecksum `json:"checksums,omitempty"`
+}
+
+type spdxFile struct {
+	SPDXID    string         `json:"SPDXID"`
+	FileName  string         `json:"fileName"`
+	Checksums []spdxChecksum `json:"checksums"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+func spdx(doc Document) spdxDocument {
+
+	out := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              doc.Image,
+		DocumentNamespace: "https://openshift.io/spdx/" + tool + "/" + string(uuid.NewUUID()),
+		CreationInfo: spdxCreationInfo{
+			Created:  time.Now().UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + tool},
+		},
+		Packages: []spdxPackage{{
+			SPDXID:                "SPDXRef-DriverContainer",
+			Name:                  doc.Image,
+			VersionInfo:           doc.Digest,
+			DownloadLocation:      "NOASSERTION",
+			PrimaryPackagePurpose: "CONTAINER",
+			Checksums:             []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: hexDigest(doc.Digest)}},
+		}, {
+			SPDXID:                "SPDXRef-Kernel",
+			Name:                  "kernel",
+			VersionInfo:           doc.KernelFullVersion,
+			DownloadLocation:      "NOASSERTION",
+			PrimaryPackagePurpose: "OPERATING-SYSTEM",
+		}},
+		Relationships: []spdxRelationship{
+			{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: "SPDXRef-DriverContainer"},
+			{SPDXElementID: "SPDXRef-DriverContainer", RelationshipType: "DEPENDS_ON", RelatedSPDXElement: "SPDXRef-Kernel"},
+		},
+	}
+
+	if doc.OperatingSystem != "" {
+		out.Packages = append(out.Packages, spdxPackage{
+			SPDXID:                "SPDXRef-OperatingSystem",
+			Name:                  doc.OperatingSystem,
+			VersionInfo:           doc.OperatingSystemVersion,
+			DownloadLocation:      "NOASSERTION",
+			PrimaryPackagePurpose: "OPERATING-SYSTEM",
+		})
+		out.Relationships = append(out.Relationships, spdxRelationship{
+			SPDXElementID: "SPDXRef-DriverContainer", RelationshipType: "CONTAINS", RelatedSPDXElement: "SPDXRef-OperatingSystem",
+		})
+	}
+
+	if doc.DriverToolkit != "" {
+		out.Packages = append(out.Packages, spdxPackage{
+			SPDXID:                "SPDXRef-DriverToolkit",
+			Name:                  "driver-toolkit",
+			VersionInfo:           doc.DriverToolkit,
+			DownloadLocation:      doc.DriverToolkit,
+			PrimaryPackagePurpose: "CONTAINER",
+		})
+		out.Relationships = append(out.Relationships, spdxRelationship{
+			SPDXElementID: "SPDXRef-DriverToolkit", RelationshipType: "BUILD_TOOL_OF", RelatedSPDXElement: "SPDXRef-DriverContainer",
+		})
+	}
+
+	for idx, module := range doc.Modules {
+		id := "SPDXRef-Module-" + strconv.Itoa(idx)
+		out.Files = append(out.Files, spdxFile{
+			SPDXID:    id,
+			FileName:  module.Path,
+			Checksums: []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: module.SHA256}},
+		})
+		out.Relationships = append(out.Relationships, spdxRelationship{
+			SPDXElementID: "SPDXRef-DriverContainer", RelationshipType: "CONTAINS", RelatedSPDXElement: id,
+		})
+	}
+
+	return out
+}
+
+type cycloneDXDocument struct {
+	BOMFormat    string               `json:"bomFormat"`
+	SpecVersion  string               `json:"specVersion"`
+	SerialNumber string               `json:"serialNumber"`
+	Version      int                  `json:"version"`
+	Metadata     cycloneDXMetadata    `json:"metadata"`
+	Components   []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     []cycloneDXTool    `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXTool struct {
+	Name string `json:"name"`
+}
+
+type cycloneDXComponent struct {
+	Type    string          `json:"type"`
+	Name    string          `json:"name"`
+	Version string          `json:"version,omitempty"`
+	Hashes  []cycloneDXHash `json:"hashes,omitempty"`
+}
+
+type cycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+func cycloneDX(doc Document) cycloneDXDocument {
+
+	out := cycloneDXDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.4",
+		SerialNumber: "urn:uuid:" + string(uuid.NewUUID()),
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools:     []cycloneDXTool{{Name: tool}},
+			Component: cycloneDXComponent{
+				Type:    "container",
+				Name:    doc.Image,
+				Version: doc.Digest,
+				Hashes:  []cycloneDXHash{{Alg: "SHA-256", Content: hexDigest(doc.Digest)}},
+			},
+		},
+		Components: []cycloneDXComponent{
+			{Type: "operating-system", Name: "kernel", Version: doc.KernelFullVersion},
+		},
+	}
+
+	if doc.OperatingSystem != "" {
+		out.Components = append(out.Components, cycloneDXComponent{
+			Type: "operating-system", Name: doc.OperatingSystem, Version: doc.OperatingSystemVersion,
+		})
+	}
+
+	if doc.DriverToolkit != "" {
+		out.Components = append(out.Components, cycloneDXComponent{
+			Type: "container", Name: "driver-toolkit", Version: doc.DriverToolkit,
+		})
+	}
+
+	for _, module := range doc.Modules {
+		out.Components = append(out.Components, cycloneDXComponent{
+			Type:   "file",
+			Name:   module.Path,
+			Hashes: []cycloneDXHash{{Alg: "SHA-256", Content: module.SHA256}},
+		})
+	}
+
+	return out
+}
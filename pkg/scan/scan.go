@@ -0,0 +1,259 @@
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/color"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var log logr.Logger
+
+func init() {
+	log = zap.New(zap.UseDevMode(true)).WithName(color.Print("scan", color.Purple))
+}
+
+// severities ranks the severities of Clair, Quay and the webhook
+var severities = map[string]int{
+	"unknown":    0,
+	"negligible": 0,
+	"low":        1,
+	"medium":     2,
+	"high":       3,
+	"critical":   4,
+	"defcon1":    5,
+}
+
+// Scanner checks the vulnerability scan of image digests with the security
+// API of Quay or a webhook
+type Scanner struct {
+	Quay     string
+	Webhook  string
+	Token    string
+	Severity string
+	client   *http.Client
+}
+
+// Vulnerability found by the scan of an image
+type Vulnerability struct {
+	ID       string `json:"id"`
+	Package  string `json:"package"`
+	Severity string `json:"severity"`
+}
+
+// VulnerabilityError the image has vulnerabilities of the blocking
+// severity or above
+type VulnerabilityError struct {
+	Image           string
+	Severity        string
+	Vulnerabilities []Vulnerability
+}
+
+func (e *VulnerabilityError) Error() string {
+
+	found := []string{}
+	for _, v := range e.Vulnerabilities {
+		found = append(found, v.ID+" ("+v.Package+", "+v.Severity+")")
+	}
+	// The message ends up in a condition, they are limited to 32768 characters
+	if len(found) > 10 {
+		found = append(found[:10], "and "+strconv.Itoa(len(found)-10)+" more")
+	}
+
+	return "Image " + e.Image + " has " + strconv.Itoa(len(e.Vulnerabilities)) + " vulnerabilities of severity " +
+		e.Severity + " or above: " + strings.Join(found, ", ")
+}
+
+// NewScanner returns a Scanner for the Quay instance at quay or the webhook,
+// the key token of the Secret tokenSecret in namespace is sent as bearer
+// token. Severity defaults to High.
+func NewScanner(namespace string, quay string, webhook string, tokenSecret string, severity string) (*Scanner, error) {
+
+	if severity == "" {
+		severity = "High"
+	}
+	if _, ok := severities[strings.ToLower(severity)]; !ok {
+		return nil, errors.New("Unknown severity " + severity)
+	}
+
+	scanner := &Scanner{
+		Quay:     strings.TrimSuffix(quay, "/"),
+		Webhook:  webhook,
+		Severity: severity,
+	}
+
+	if tokenSecret != "" {
+		secret, err := clients.GetSecret(namespace, tokenSecret)
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot get scan token Secret "+namespace+"/"+tokenSecret)
+		}
+		scanner.Token = strings.TrimSpace(string(secret.Data["token"]))
+	}
+
+	transport, err := registry.Transport()
+	if err != nil {
+		return nil, err
+	}
+	scanner.client = &http.Client{Transport: transport, Timeout: 30 * time.Second}
+
+	return scanner, nil
+}
+
+// Check returns a VulnerabilityError if the scan of the image digest found
+// vulnerabilities of Severity or above, and an error if the scan did not
+// complete yet
+func (s *Scanner) Check(image string, digest string) error {
+
+	var vulnerabilities []Vulnerability
+	var err error
+
+	if s.Webhook != "" {
+		vulnerabilities, err = s.webhook(image, digest)
+	} else {
+		vulnerabilities, err = s.quay(image, digest)
+	}
+	if err != nil {
+		return err
+	}
+
+	threshold := severities[strings.ToLower(s.Severity)]
+
+	blocking := &VulnerabilityError{Image: image + "@" + digest, Severity: s.Severity}
+	for _, v := range vulnerabilities {
+		if severities[strings.ToLower(v.Severity)] >= threshold {
+			blocking.Vulnerabilities = append(blocking.Vulnerabilities, v)
+		}
+	}
+
+	log.Info("Scanned", "image", image, "digest", digest, "vulnerabilities", len(vulnerabilities),
+		"blocking", len(blocking.Vulnerabilities))
+
+	if len(blocking.Vulnerabilities) > 0 {
+		return blocking
+	}
+	return nil
+}
+
+// webhookRequest is POSTed to the webhook, it answers with a webhookResponse
+type webhookRequest struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
+}
+
+type webhookResponse struct {
+	// Status is scanned once the scan completed
+	Status          string          `json:"status"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+func (s *Scanner) webhook(image string, digest string) ([]Vulnerability, error) {
+
+	body, err := json.Marshal(webhookRequest{Image: image, Digest: digest})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot create scan request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	response := webhookResponse{}
+	if err := s.do(req, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Status != "scanned" {
+		return nil, errors.New("Scan of " + image + "@" + digest + " not completed, status: " + response.Status)
+	}
+
+	return response.Vulnerabilities, nil
+}
+
+// quaySecurity the manifest security response of the Quay API
+type quaySecurity struct {
+	Status string `json:"status"`
+	Data   struct {
+		Layer struct {
+			Features []struct {
+				Name            string `json:"Name"`
+				Version         string `json:"Version"`
+				Vulnerabilities []struct {
+					Name     string `json:"Name"`
+					Severity string `json:"Severity"`
+				} `json:"Vulnerabilities"`
+			} `json:"Features"`
+		} `json:"Layer"`
+	} `json:"data"`
+}
+
+func (s *Scanner) quay(image string, digest string) ([]Vulnerability, error) {
+
+	repo, err := registry.Repository(image)
+	if err != nil {
+		return nil, err
+	}
+	// Strip the registry host, Quay wants namespace/repository
+	if idx := strings.Index(repo, "/"); idx > 0 {
+		repo = repo[idx+1:]
+	}
+
+	url := s.Quay + "/api/v1/repository/" + repo + "/manifest/" + digest + "/security?vulnerabilities=true"
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot create scan request")
+	}
+
+	security := quaySecurity{}
+	if err := s.do(req, &security); err != nil {
+		return nil, err
+	}
+
+	if security.Status != "scanned" {
+		return nil, errors.New("Scan of " + image + "@" + digest + " not completed, status: " + security.Status)
+	}
+
+	vulnerabilities := []Vulnerability{}
+	for _, feature := range security.Data.Layer.Features {
+		for _, v := range feature.Vulnerabilities {
+			vulnerabilities = append(vulnerabilities, Vulnerability{
+				ID:       v.Name,
+				Package:  feature.Name + "-" + feature.Version,
+				Severity: v.Severity,
+			})
+		}
+	}
+
+	return vulnerabilities, nil
+}
+
+func (s *Scanner) do(req *http.Request, out interface{}) error {
+
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "Cannot query vulnerability scan")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return errors.New("Cannot query vulnerability scan " + req.URL.String() + ": " + resp.Status)
+	}
+
+	return errors.Wrap(json.NewDecoder(resp.Body).Decode(out), "Cannot decode vulnerability scan")
+}
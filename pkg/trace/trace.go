@@ -0,0 +1,264 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/openshift-psap/special-resource-operator/pkg/color"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+const (
+	serviceName = "special-resource-operator"
+	// batchSize spans are exported at once, at least every flushInterval
+	batchSize     = 512
+	flushInterval = 5 * time.Second
+)
+
+var (
+	log logr.Logger
+	// Endpoint OTLP/HTTP traces URL spans are exported to, e.g.
+	// http://otel-collector:4318/v1/traces, tracing is disabled if empty
+	Endpoint string
+
+	// Spans are dropped if the exporter cannot keep up
+	ended  = make(chan *Span, 4*batchSize)
+	client = &http.Client{Timeout: 10 * time.Second}
+)
+
+func init() {
+	log = zap.New(zap.UseDevMode(true)).WithName(color.Print("trace", color.Brown))
+}
+
+type spanKey struct{}
+
+// Span a phase of a reconcile, nil if tracing is disabled. All methods
+// accept a nil Span.
+type Span struct {
+	traceID    string
+	spanID     string
+	parentID   string
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes []string
+	err        error
+}
+
+// Start starts the span name as child of the span of ctx, attributes are
+// key, value pairs. The returned context carries the span for its children.
+func Start(ctx context.Context, name string, attributes ...string) (context.Context, *Span) {
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if Endpoint == "" {
+		return ctx, nil
+	}
+
+	span := &Span{
+		spanID:     randomID(8),
+		name:       name,
+		start:      time.Now(),
+		attributes: attributes,
+	}
+
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		span.traceID = randomID(16)
+	}
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// End ends the span, err marks it as failed
+func (s *Span) End(err error) {
+
+	if s == nil {
+		return
+	}
+
+	s.end = time.Now()
+	s.err = err
+
+	select {
+	case ended <- s:
+	default:
+		log.Info("Exporter busy, dropping span", "name", s.name)
+	}
+}
+
+func randomID(size int) string {
+	id := make([]byte, size)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+// Run exports the ended spans until ctx is done, it returns immediately if
+// tracing is disabled
+func Run(ctx context.Context) error {
+
+	if Endpoint == "" {
+		return nil
+	}
+
+	log.Info("Exporting spans", "endpoint", Endpoint)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := []*Span{}
+
+	for {
+		select {
+		case span := <-ended:
+			if batch = append(batch, span); len(batch) < batchSize {
+				continue
+			}
+		case <-ticker.C:
+		case <-ctx.Done():
+			export(batch)
+			return nil
+		}
+
+		if len(batch) > 0 {
+			export(batch)
+			batch = []*Span{}
+		}
+	}
+}
+
+// export sends the spans as OTLP JSON, failures only drop the spans
+func export(spans []*Span) {
+
+	if len(spans) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(request(spans))
+	if err != nil {
+		log.Info("Cannot encode spans", "error", err.Error())
+		return
+	}
+
+	resp, err := client.Post(Endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Info("Cannot export spans", "error", errors.Wrap(err, Endpoint).Error())
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		log.Info("Cannot export spans", "endpoint", Endpoint, "status", resp.Status)
+	}
+}
+
+// OTLP JSON encoding of ExportTraceServiceRequest, trace and span IDs are
+// hex and timestamps decimal strings
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	spanKindInternal = 1
+	statusCodeOk     = 1
+	statusCodeError  = 2
+)
+
+func request(spans []*Span) exportRequest {
+
+	encoded := make([]span, 0, len(spans))
+
+	for _, s := range spans {
+
+		attributes := []keyValue{}
+		for idx := 0; idx+1 < len(s.attributes); idx += 2 {
+			attributes = append(attributes, keyValue{Key: s.attributes[idx], Value: anyValue{StringValue: s.attributes[idx+1]}})
+		}
+
+		state := status{Code: statusCodeOk}
+		if s.err != nil {
+			state = status{Code: statusCodeError, Message: s.err.Error()}
+		}
+
+		encoded = append(encoded, span{
+			TraceID:           s.traceID,
+			SpanID:            s.spanID,
+			ParentSpanID:      s.parentID,
+			Name:              s.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        attributes,
+			Status:            state,
+		})
+	}
+
+	return exportRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource: resource{Attributes: []keyValue{
+				{Key: "service.name", Value: anyValue{StringValue: serviceName}},
+			}},
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: serviceName},
+				Spans: encoded,
+			}},
+		}},
+	}
+}
@@ -0,0 +1,169 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/hash"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/openshift-psap/special-resource-operator/pkg/storage"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DTKCacheConfigMap optional ConfigMap in the operator namespace, if it
+// exists the DTK lookups survive operator restarts.
+const DTKCacheConfigMap = "special-resource-dtk-cache"
+
+const fingerprintKey = "fingerprint"
+
+// DTKCache remembers the DTK per kernel version, the DTK for a kernel never
+// changes for a given ClusterVersion. The cache is invalidated if the
+// ClusterVersion history changes.
+var DTKCache = &DriverToolkitCache{entries: make(map[string]registry.DriverToolkitEntry)}
+
+type DriverToolkitCache struct {
+	mutex       sync.Mutex
+	fingerprint string
+	entries     map[string]registry.DriverToolkitEntry
+}
+
+// Fingerprint identifies the ClusterVersion state the DTKs were looked up for
+func Fingerprint(history []string) string {
+	return hash.FNV64a(strings.Join(history, ","))
+}
+
+func (c *DriverToolkitCache) configMap() (types.NamespacedName, bool) {
+	namespace := os.Getenv("OPERATOR_NAMESPACE")
+	return types.NamespacedName{Namespace: namespace, Name: DTKCacheConfigMap}, namespace != ""
+}
+
+// Lookup fills the DTK of every kernel in info from the cache, hit is only
+// true if all kernels were found for the current fingerprint.
+func (c *DriverToolkitCache) Lookup(fingerprint string, info map[string]NodeVersion) (map[string]NodeVersion, bool) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.fingerprint != fingerprint {
+		c.load(fingerprint)
+	}
+
+	for kernel := range info {
+		if _, found := c.entries[kernel]; !found {
+			return info, false
+		}
+	}
+
+	for kernel, nodeVersion := range info {
+		nodeVersion.DriverToolkit = c.entries[kernel]
+		nodeVersion.OSVersion = c.entries[kernel].OSVersion
+		info[kernel] = nodeVersion
+	}
+
+	log.Info("DTK cache hit", "fingerprint", fingerprint)
+	return info, true
+}
+
+// Store remembers the DTKs found for fingerprint
+func (c *DriverToolkitCache) Store(fingerprint string, info map[string]NodeVersion) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.fingerprint != fingerprint {
+		log.Info("ClusterVersion changed, invalidating DTK cache", "fingerprint", fingerprint)
+		c.fingerprint = fingerprint
+		c.entries = make(map[string]registry.DriverToolkitEntry)
+	}
+
+	for kernel, nodeVersion := range info {
+		if nodeVersion.DriverToolkit.ImageURL == "" {
+			continue
+		}
+		c.entries[kernel] = nodeVersion.DriverToolkit
+	}
+
+	c.save()
+}
+
+// load reads the entries from the ConfigMap, callers hold the mutex
+func (c *DriverToolkitCache) load(fingerprint string) {
+
+	c.fingerprint = fingerprint
+	c.entries = make(map[string]registry.DriverToolkitEntry)
+
+	ins, ok := c.configMap()
+	if !ok {
+		return
+	}
+
+	cm, err := storage.GetConfigMap(ins.Namespace, ins.Name)
+	if apierrors.IsNotFound(err) {
+		return
+	}
+	if err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot read DTK cache ConfigMap"))
+		return
+	}
+
+	data, _, err := unstructured.NestedStringMap(cm.Object, "data")
+	if err != nil || data[fingerprintKey] != fingerprint {
+		return
+	}
+
+	for kernel, value := range data {
+		if kernel == fingerprintKey {
+			continue
+		}
+		var entry registry.DriverToolkitEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			warn.OnError(errors.Wrap(err, "Cannot unmarshal DTK cache entry "+kernel))
+			continue
+		}
+		c.entries[kernel] = entry
+	}
+}
+
+// save writes the entries to the ConfigMap if it exists, callers hold the
+// mutex
+func (c *DriverToolkitCache) save() {
+
+	ins, ok := c.configMap()
+	if !ok {
+		return
+	}
+
+	cm, err := storage.GetConfigMap(ins.Namespace, ins.Name)
+	if apierrors.IsNotFound(err) {
+		return
+	}
+	if err != nil {
+		warn.OnError(errors.Wrap(err, "Cannot read DTK cache ConfigMap"))
+		return
+	}
+
+	data := map[string]string{fingerprintKey: c.fingerprint}
+	for kernel, entry := range c.entries {
+		value, err := json.Marshal(entry)
+		if err != nil {
+			warn.OnError(err)
+			continue
+		}
+		data[kernel] = string(value)
+	}
+
+	if err := unstructured.SetNestedStringMap(cm.Object, data, "data"); err != nil {
+		warn.OnError(err)
+		return
+	}
+
+	warn.OnError(errors.Wrap(clients.Interface.Update(context.TODO(), cm), "Cannot update DTK cache ConfigMap"))
+}
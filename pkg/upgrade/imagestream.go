@@ -0,0 +1,94 @@
+package upgrade
+
+import (
+	"context"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/registry"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var imageStreams = schema.GroupVersionResource{Group: "image.openshift.io", Version: "v1", Resource: "imagestreams"}
+
+// DriverToolkitFromImageStream reads the DTK images from the driver-toolkit
+// ImageStream in the openshift namespace, this avoids pulling the release
+// payload. found is false if the ImageStream does not exist or has no DTK
+// for any running kernel.
+func DriverToolkitFromImageStream(info map[string]NodeVersion) (map[string]NodeVersion, bool, error) {
+
+	available, err := clients.HasResource(imageStreams)
+	if err != nil {
+		return info, false, errors.Wrap(err, "Error discovering imagestreams API resource")
+	}
+	if !available {
+		log.Info("Warning: Could not find imagestreams API resource. Can be ignored on vanilla k8s.")
+		return info, false, nil
+	}
+
+	is := &unstructured.Unstructured{}
+	is.SetAPIVersion("image.openshift.io/v1")
+	is.SetKind("ImageStream")
+
+	err = clients.Interface.Get(context.TODO(), types.NamespacedName{Namespace: "openshift", Name: "driver-toolkit"}, is)
+	if apierrors.IsNotFound(err) {
+		log.Info("ImageStream openshift/driver-toolkit not found, falling back to the release payload")
+		return info, false, nil
+	}
+	if err != nil {
+		return info, false, errors.Wrap(err, "Cannot get ImageStream openshift/driver-toolkit")
+	}
+
+	tags, _, err := unstructured.NestedSlice(is.Object, "status", "tags")
+	if err != nil {
+		return info, false, errors.Wrap(err, "Cannot extract tags from ImageStream openshift/driver-toolkit")
+	}
+
+	seen := make(map[string]bool)
+	images := []string{}
+
+	for _, tag := range tags {
+
+		items, _, _ := unstructured.NestedSlice(tag.(map[string]interface{}), "items")
+		if len(items) == 0 {
+			continue
+		}
+		// The first item is the current image of the tag
+		imageURL, _, _ := unstructured.NestedString(items[0].(map[string]interface{}), "dockerImageReference")
+		if imageURL == "" || seen[imageURL] {
+			continue
+		}
+		seen[imageURL] = true
+		images = append(images, imageURL)
+	}
+
+	releases, errs := registry.ToolkitReleases(images)
+
+	for _, imageURL := range images {
+
+		if err, failed := errs[imageURL]; failed {
+			warn.OnError(errors.Wrap(err, "Cannot read DTK release of "+imageURL))
+			continue
+		}
+		dtk := releases[imageURL]
+
+		if info, err = UpdateInfo(info, dtk, imageURL); err != nil {
+			return info, true, err
+		}
+	}
+
+	// None of the tags matches a running kernel, e.g. during an upgrade
+	// the ImageStream may lag behind the payload.
+	for _, version := range info {
+		if version.DriverToolkit.ImageURL != "" {
+			return info, true, nil
+		}
+	}
+
+	log.Info("No DTK in ImageStream openshift/driver-toolkit matches the running kernels, falling back to the release payload")
+	return info, false, nil
+}
@@ -0,0 +1,82 @@
+package upgrade
+
+import (
+	"context"
+
+	"github.com/openshift-psap/special-resource-operator/pkg/clients"
+	"github.com/openshift-psap/special-resource-operator/pkg/slice"
+	"github.com/openshift-psap/special-resource-operator/pkg/warn"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var machineConfigPools = schema.GroupVersionResource{Group: "machineconfiguration.openshift.io", Version: "v1", Resource: "machineconfigpools"}
+
+type poolSelector struct {
+	name     string
+	selector labels.Selector
+}
+
+// machineConfigPoolSelectors returns the node selectors of all pools, empty
+// on clusters without MachineConfigPools.
+func machineConfigPoolSelectors() ([]poolSelector, error) {
+
+	pools := []poolSelector{}
+
+	available, err := clients.HasResource(machineConfigPools)
+	if err != nil {
+		return pools, errors.Wrap(err, "Error discovering machineconfigpools API resource")
+	}
+	if !available {
+		log.Info("Warning: Could not find machineconfigpools API resource. Can be ignored on vanilla k8s.")
+		return pools, nil
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetAPIVersion("machineconfiguration.openshift.io/v1")
+	list.SetKind("MachineConfigPoolList")
+
+	if err := clients.Interface.List(context.TODO(), list); err != nil {
+		return pools, errors.Wrap(err, "Client cannot get MachineConfigPoolList")
+	}
+
+	for _, pool := range list.Items {
+
+		obj, found, err := unstructured.NestedMap(pool.Object, "spec", "nodeSelector")
+		if err != nil || !found {
+			continue
+		}
+
+		var labelSelector metav1.LabelSelector
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, &labelSelector); err != nil {
+			warn.OnError(errors.Wrap(err, "Cannot convert nodeSelector of MachineConfigPool "+pool.GetName()))
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
+		if err != nil {
+			warn.OnError(errors.Wrap(err, "Invalid nodeSelector of MachineConfigPool "+pool.GetName()))
+			continue
+		}
+
+		pools = append(pools, poolSelector{name: pool.GetName(), selector: selector})
+	}
+
+	return pools, nil
+}
+
+// nodePools adds the pools selecting nodeLabels to names
+func nodePools(names []string, pools []poolSelector, nodeLabels map[string]string) []string {
+
+	for _, pool := range pools {
+		if pool.selector.Matches(labels.Set(nodeLabels)) && !slice.Contains(names, pool.name) {
+			names = append(names, pool.name)
+		}
+	}
+
+	return names
+}
@@ -7,12 +7,12 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/go-logr/logr"
-	v1 "github.com/google/go-containerregistry/pkg/v1"
 
 	"github.com/openshift-psap/special-resource-operator/pkg/cache"
 	"github.com/openshift-psap/special-resource-operator/pkg/cluster"
 	"github.com/openshift-psap/special-resource-operator/pkg/color"
 	"github.com/openshift-psap/special-resource-operator/pkg/exit"
+	"github.com/openshift-psap/special-resource-operator/pkg/kernel"
 	"github.com/openshift-psap/special-resource-operator/pkg/registry"
 	"github.com/openshift-psap/special-resource-operator/pkg/warn"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -30,6 +30,12 @@ type NodeVersion struct {
 	OSVersion      string                      `json:"OSVersion"`
 	ClusterVersion string                      `json:"clusterVersion"`
 	DriverToolkit  registry.DriverToolkitEntry `json:"driverToolkit"`
+	// MachineConfigPools with nodes running this kernel, a pool is listed
+	// for several kernels while it is updating.
+	MachineConfigPools []string `json:"machineConfigPools,omitempty"`
+	// RealTime nodes run the kernel-rt, drivers are built against the
+	// kernel-rt headers of the DTK
+	RealTime bool `json:"realTime"`
 }
 
 func ClusterInfo() (map[string]NodeVersion, error) {
@@ -40,9 +46,24 @@ func ClusterInfo() (map[string]NodeVersion, error) {
 	history, err := cluster.VersionHistory()
 	exit.OnError(errors.Wrap(err, "Could not get version history"))
 
-	versions, err := DriverToolkitVersion(history, info)
+	fingerprint := Fingerprint(history)
+
+	if versions, hit := DTKCache.Lookup(fingerprint, info); hit {
+		return versions, nil
+	}
+
+	// The driver-toolkit ImageStream is much cheaper than extracting the
+	// release payload, only fall back if it does not exist.
+	versions, found, err := DriverToolkitFromImageStream(info)
 	exit.OnError(err)
 
+	if !found {
+		versions, err = DriverToolkitVersion(history, info)
+		exit.OnError(err)
+	}
+
+	DTKCache.Store(fingerprint, versions)
+
 	return versions, nil
 
 }
@@ -52,6 +73,11 @@ func NodeVersionInfo() (map[string]NodeVersion, error) {
 	var found bool
 	var info = make(map[string]NodeVersion)
 
+	pools, err := machineConfigPoolSelectors()
+	if err != nil {
+		return nil, err
+	}
+
 	// Assuming all nodes are running the same kernel version,
 	// one could easily add driver-kernel-versions for each node.
 	for _, node := range cache.Node.List.Items {
@@ -61,30 +87,44 @@ func NodeVersionInfo() (map[string]NodeVersion, error) {
 		var clusterVersion string
 
 		labels := node.GetLabels()
-		// We only need to check for the key, the value
-		// is available if the key is there
-		short := "feature.node.kubernetes.io/kernel-version.full"
-		if kernelFullVersion, found = labels[short]; !found {
-			return nil, errors.New("Label " + short + " not found is NFD running? Check node labels")
+		// Without NFD the kernel is the one the kubelet reports
+		if kernelFullVersion, found = kernel.NodeFullVersion(node); !found {
+			return nil, errors.New("Cannot get the kernel of node " + node.GetName() + ", is NFD running? Check node labels")
 		}
 
-		short = "feature.node.kubernetes.io/system-os_release.RHEL_VERSION"
+		short := "feature.node.kubernetes.io/system-os_release.RHEL_VERSION"
 		if rhelVersion, found = labels[short]; !found {
 			log.Info("Warning: Label " + short + " not found. Can be ignored on vanilla k8s")
 		}
 
 		short = "feature.node.kubernetes.io/system-os_release.VERSION_ID"
 		if clusterVersion, found = labels[short]; !found {
-			return nil, errors.New("Label " + short + " not found is NFD running? Check node labels")
+			facts, err := cluster.GetFacts()
+			if err != nil {
+				return nil, err
+			}
+			if clusterVersion = facts.OSVersion; clusterVersion == "" {
+				return nil, errors.New("Label " + short + " not found is NFD running? Check node labels or set osVersion in ConfigMap " + cluster.FactsConfigMap)
+			}
 		}
 
-		info[kernelFullVersion] = NodeVersion{OSVersion: rhelVersion, ClusterVersion: clusterVersion}
+		// Clusters mid-upgrade or with heterogeneous pools run several
+		// kernels, every kernel gets its own DTK and DaemonSet.
+		nodeVersion := info[kernelFullVersion]
+		nodeVersion.OSVersion = rhelVersion
+		nodeVersion.ClusterVersion = clusterVersion
+		nodeVersion.MachineConfigPools = nodePools(nodeVersion.MachineConfigPools, pools, labels)
+		nodeVersion.RealTime = kernel.IsRealTime(kernelFullVersion)
+
+		info[kernelFullVersion] = nodeVersion
 	}
 
 	return info, nil
 }
 
-func UpdateInfo(info map[string]NodeVersion, dtk registry.DriverToolkitEntry, imageURL string) (map[string]NodeVersion, error) {
+// withArchitecture appends the architecture NFD labels carry to the kernels
+// of dtk, the DTK release does not
+func withArchitecture(dtk registry.DriverToolkitEntry) registry.DriverToolkitEntry {
 	// Assumes all nodes have the same architecture
 	runningArch := runtime.GOARCH
 	if runningArch == "amd64" {
@@ -96,6 +136,30 @@ func UpdateInfo(info map[string]NodeVersion, dtk registry.DriverToolkitEntry, im
 		dtk.RTKernelFullVersion = dtk.RTKernelFullVersion + "." + runningArch
 		log.Info("Updating version:", "dtk.KernelFullVersion", dtk.KernelFullVersion)
 	}
+	return dtk
+}
+
+// AddRelease adds the kernel of the DTK of a release no node runs yet to
+// info and returns it, kernels of nodes are kept as they are
+func AddRelease(info map[string]NodeVersion, dtk registry.DriverToolkitEntry, imageURL string, clusterVersion string) string {
+
+	dtk = withArchitecture(dtk)
+	dtk.ImageURL = imageURL
+
+	if _, ok := info[dtk.KernelFullVersion]; !ok {
+		info[dtk.KernelFullVersion] = NodeVersion{
+			OSVersion:      dtk.OSVersion,
+			ClusterVersion: clusterVersion,
+			DriverToolkit:  dtk,
+		}
+	}
+
+	return dtk.KernelFullVersion
+}
+
+func UpdateInfo(info map[string]NodeVersion, dtk registry.DriverToolkitEntry, imageURL string) (map[string]NodeVersion, error) {
+
+	dtk = withArchitecture(dtk)
 
 	// First check for the general kernel entry
 	if _, ok := info[dtk.KernelFullVersion]; ok {
@@ -133,8 +197,9 @@ func UpdateInfo(info map[string]NodeVersion, dtk registry.DriverToolkitEntry, im
 		nodeVersion := info[dtk.RTKernelFullVersion]
 		nodeVersion.OSVersion = dtk.OSVersion
 		nodeVersion.DriverToolkit = dtk
+		nodeVersion.RealTime = true
 
-		info[dtk.KernelFullVersion] = nodeVersion
+		info[dtk.RTKernelFullVersion] = nodeVersion
 
 	}
 	return info, nil
@@ -142,15 +207,19 @@ func UpdateInfo(info map[string]NodeVersion, dtk registry.DriverToolkitEntry, im
 
 func DriverToolkitVersion(entries []string, info map[string]NodeVersion) (map[string]NodeVersion, error) {
 
+	// The release manifests of the whole history are extracted in one batch,
+	// the payloads share most of their layers
+	releases, errs := registry.Releases(entries)
+
 	for _, entry := range entries {
 
 		log.Info("History", "entry", entry)
-		var layer v1.Layer
-		if layer = registry.LastLayer(entry); layer == nil {
+		if err, failed := errs[entry]; failed {
+			warn.OnError(errors.Wrap(err, "Cannot get release payload "+entry))
 			continue
 		}
 		// For each entry we're fetching the cluster version and dtk URL
-		version, imageURL := registry.ReleaseManifests(layer)
+		version, imageURL := releases[entry].Version, releases[entry].DriverToolkit
 		if version == "" {
 			exit.OnError(errors.New("Could not extract version from payload"))
 		}
@@ -160,11 +229,7 @@ func DriverToolkitVersion(entries []string, info map[string]NodeVersion) (map[st
 			return info, nil
 		}
 
-		if layer = registry.LastLayer(imageURL); layer == nil {
-			exit.OnError(errors.New("Cannot extract last layer for DTK from: " + imageURL))
-		}
-
-		dtk, err := registry.ExtractToolkitRelease(layer)
+		dtk, err := registry.ToolkitRelease(imageURL)
 		exit.OnError(err)
 
 		// info has the kernels that are currently "running" on the cluster
@@ -173,9 +238,24 @@ func DriverToolkitVersion(entries []string, info map[string]NodeVersion) (map[st
 		// We could have many entries with DTKs that are from an old update
 		// The objects that are kernel affine should only be replicated
 		// for valid kernels.
-		return UpdateInfo(info, dtk, imageURL)
+		info, err = UpdateInfo(info, dtk, imageURL)
+		exit.OnError(err)
 
+		// During an upgrade the nodes run the kernels of several payloads,
+		// continue with the history until every kernel has a DTK.
+		if allKernelsHaveDTK(info) {
+			return info, nil
+		}
 	}
 
 	return info, nil
 }
+
+func allKernelsHaveDTK(info map[string]NodeVersion) bool {
+	for _, version := range info {
+		if version.DriverToolkit.ImageURL == "" {
+			return false
+		}
+	}
+	return true
+}